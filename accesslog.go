@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Access log formats and rotation periods
+const (
+	accessLogCombined = "combined"
+	accessLogJSON     = "json"
+
+	rotateDaily  = "daily"
+	rotateHourly = "hourly"
+	rotateNever  = "never"
+
+	defaultAccessLogMaxSizeMB = 100
+)
+
+// AccessLogConfig selects the file HTTP requests are logged to, apart from
+// the application log, for deployments without a log-shipping sidecar
+type AccessLogConfig struct {
+	File       string `yaml:"file"`        // ACCESS_LOG_FILE: empty disables the access log
+	Format     string `yaml:"format"`      // ACCESS_LOG_FORMAT: combined (default) or json
+	MaxSizeMB  int    `yaml:"max_size_mb"` // ACCESS_LOG_MAX_SIZE_MB: rotate at this size (default: 100)
+	Rotate     string `yaml:"rotate"`      // ACCESS_LOG_ROTATE: daily (default), hourly or never
+	MaxBackups int    `yaml:"max_backups"` // ACCESS_LOG_MAX_BACKUPS: rotated files kept (default: all)
+}
+
+// validate checks the access log settings and fills in the defaults
+func (c *AccessLogConfig) validate() error {
+	if c.File == "" {
+		return nil
+	}
+	if c.Format == "" {
+		c.Format = accessLogCombined
+	}
+	if c.Format != accessLogCombined && c.Format != accessLogJSON {
+		return fmt.Errorf("format must be combined or json")
+	}
+	if c.Rotate == "" {
+		c.Rotate = rotateDaily
+	}
+	if c.Rotate != rotateDaily && c.Rotate != rotateHourly && c.Rotate != rotateNever {
+		return fmt.Errorf("rotate must be daily, hourly or never")
+	}
+	if c.MaxSizeMB == 0 {
+		c.MaxSizeMB = defaultAccessLogMaxSizeMB
+	}
+	if c.MaxSizeMB < 0 || c.MaxBackups < 0 {
+		return fmt.Errorf("max_size_mb and max_backups cannot be negative")
+	}
+	return nil
+}
+
+// mode describes the access log for the startup banner
+func (c AccessLogConfig) mode() string {
+	if c.File == "" {
+		return "disabled"
+	}
+	return fmt.Sprintf("%s (%s, rotated %s or at %d MB)", c.File, c.Format, c.Rotate, c.MaxSizeMB)
+}
+
+// AccessLogEntry is a request as written in the JSON access log
+type AccessLogEntry struct {
+	Time       time.Time `json:"time"`
+	IP         string    `json:"ip"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// combined formats the entry in the Combined Log Format
+func (e AccessLogEntry) combined() string {
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	return fmt.Sprintf("%s - - [%s] %s %d %d %s %s\n",
+		e.IP, e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(e.Method+" "+e.URI+" "+e.Proto), e.Status, e.Bytes,
+		strconv.Quote(dash(e.Referer)), strconv.Quote(dash(e.UserAgent)))
+}
+
+// AccessLog appends requests to a file, rotating it by size and period.
+// Rotated files get the time of rotation appended to their name.
+type AccessLog struct {
+	mu     sync.Mutex
+	config AccessLogConfig
+	file   *os.File
+	size   int64
+	period time.Time // start of the period the open file covers
+}
+
+// accessLog writes the access log; nil when it is disabled
+var accessLog *AccessLog
+
+// NewAccessLog opens the configured access log, or returns nil when it is
+// disabled
+func NewAccessLog(config AccessLogConfig) (*AccessLog, error) {
+	if config.File == "" {
+		return nil, nil
+	}
+	l := &AccessLog{config: config}
+	if err := os.MkdirAll(filepath.Dir(config.File), 0o755); err != nil {
+		return nil, err
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	// A file left from an earlier period is rotated on the first write
+	if info, err := l.file.Stat(); err == nil && info.Size() > 0 {
+		l.period = l.periodStart(info.ModTime())
+	}
+	return l, nil
+}
+
+// open opens the log file for appending
+func (l *AccessLog) open() error {
+	file, err := os.OpenFile(l.config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.size, l.period = file, info.Size(), l.periodStart(time.Now())
+	return nil
+}
+
+// periodStart returns the start of the rotation period containing t
+func (l *AccessLog) periodStart(t time.Time) time.Time {
+	t = t.UTC()
+	switch l.config.Rotate {
+	case rotateHourly:
+		return t.Truncate(time.Hour)
+	case rotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Time{}
+}
+
+// Log writes a request to the access log
+func (l *AccessLog) Log(entry AccessLogEntry) {
+	if l == nil {
+		return
+	}
+	var line []byte
+	if l.config.Format == accessLogJSON {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		line = append(data, '\n')
+	} else {
+		line = []byte(entry.combined())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	full := l.size > 0 && l.size+int64(len(line)) > int64(l.config.MaxSizeMB)<<20
+	// Requests that started before the current period are written to it
+	period := l.periodStart(entry.Time)
+	if full || period.After(l.period) {
+		if err := l.rotate(); err != nil {
+			logAt(logLevelWarn, "Warning: Could not rotate the access log: %v", err)
+		}
+		if period.After(l.period) {
+			l.period = period
+		}
+	}
+	if l.file == nil {
+		return
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not write the access log: %v", err)
+	}
+}
+
+// rotate renames the current file and starts a new one; callers must hold l.mu
+func (l *AccessLog) rotate() error {
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	if l.size > 0 {
+		stamp := time.Now().UTC().Format("20060102-150405")
+		name := l.config.File + "." + stamp
+		for i := 1; ; i++ {
+			if _, err := os.Stat(name); os.IsNotExist(err) {
+				break
+			}
+			name = fmt.Sprintf("%s.%s-%d", l.config.File, stamp, i)
+		}
+		if err := os.Rename(l.config.File, name); err != nil {
+			l.open()
+			return err
+		}
+		l.pruneBackups()
+	}
+	return l.open()
+}
+
+// pruneBackups deletes the oldest rotated files beyond max_backups
+func (l *AccessLog) pruneBackups() {
+	if l.config.MaxBackups == 0 {
+		return
+	}
+	backups, err := filepath.Glob(l.config.File + ".*")
+	if err != nil {
+		return
+	}
+	// Rotation stamps sort in time order
+	sort.Strings(backups)
+	for _, name := range backups[:max(0, len(backups)-l.config.MaxBackups)] {
+		if strings.HasPrefix(filepath.Base(name), filepath.Base(l.config.File)+".") {
+			os.Remove(name)
+		}
+	}
+}
+
+// accessLogWriter captures the status and size of a response for the access log
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (aw *accessLogWriter) WriteHeader(code int) {
+	if aw.status == 0 {
+		aw.status = code
+	}
+	aw.ResponseWriter.WriteHeader(code)
+}
+
+func (aw *accessLogWriter) Write(b []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	n, err := aw.ResponseWriter.Write(b)
+	aw.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes flushes through for streaming responses
+func (aw *accessLogWriter) Flush() {
+	if flusher, ok := aw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// accessLogMiddleware writes every request to the access log, with the
+// response size as sent to the client
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accessLog == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		started := time.Now()
+		aw := &accessLogWriter{ResponseWriter: w}
+		next.ServeHTTP(aw, r)
+		if aw.status == 0 {
+			aw.status = http.StatusOK
+		}
+		accessLog.Log(AccessLogEntry{
+			Time:       started,
+			IP:         clientIP(r),
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     aw.status,
+			Bytes:      aw.bytes,
+			DurationMs: time.Since(started).Milliseconds(),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		})
+	})
+}
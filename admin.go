@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// AdminScansResponse lists the active scans
+type AdminScansResponse struct {
+	Scans []ScanJobStatus `json:"scans"`
+	Queue QueueStats      `json:"queue"`
+}
+
+// QueueLimitsRequest changes the scan queue capacity; omitted fields keep their value
+type QueueLimitsRequest struct {
+	MaxConcurrent *int `json:"max_concurrent,omitempty"`
+	MaxQueued     *int `json:"max_queued,omitempty"`
+}
+
+// requireAdmin restricts a handler to requests bearing the configured admin token
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := currentConfig().AdminToken
+		if token == "" {
+			sendError(w, "Admin API disabled", http.StatusForbidden, "Set admin_token (ADMIN_TOKEN) to enable the admin API")
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			sendError(w, "Unauthorized", http.StatusUnauthorized, "A valid admin bearer token is required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminScans handles GET /api/v1/admin/scans requests
+func handleAdminScans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	scans := scanJobs.List()
+	for i := range scans {
+		scans[i].Links = jobLinks(apiVersion(r), scans[i].ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminScansResponse{Scans: scans, Queue: scanQueue.Stats()})
+}
+
+// handleAdminScan handles GET and DELETE /api/v1/admin/scans/{id} requests
+func handleAdminScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and DELETE methods are supported")
+		return
+	}
+
+	job, ok := scanJobs.Get(r.PathValue("id"))
+	if !ok {
+		sendError(w, "Scan not found", http.StatusNotFound, "No active scan with this ID")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodDelete {
+		// The scan stops at its next checkpoint and returns its partial result to the client
+		job.Cancel()
+		w.WriteHeader(http.StatusAccepted)
+	}
+	status := job.Status()
+	status.Links = jobLinks(apiVersion(r), status.ID)
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleAdminScanFrontier handles GET /api/v1/admin/scans/{id}/frontier requests
+func handleAdminScanFrontier(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	job, ok := scanJobs.Get(r.PathValue("id"))
+	if !ok {
+		sendError(w, "Scan not found", http.StatusNotFound, "No active scan with this ID")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Frontier())
+}
+
+// handleAdminQueue handles GET and PUT /api/v1/admin/queue requests
+func handleAdminQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and PUT methods are supported")
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req QueueLimitsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+			return
+		}
+
+		stats := scanQueue.Stats()
+		maxConcurrent, maxQueued := stats.MaxConcurrent, stats.MaxQueued
+		if req.MaxConcurrent != nil {
+			maxConcurrent = *req.MaxConcurrent
+		}
+		if req.MaxQueued != nil {
+			maxQueued = *req.MaxQueued
+		}
+		if maxConcurrent < 1 {
+			sendError(w, "Invalid max_concurrent", http.StatusBadRequest, "max_concurrent must be at least 1")
+			return
+		}
+		if maxQueued < 0 {
+			sendError(w, "Invalid max_queued", http.StatusBadRequest, "max_queued cannot be negative")
+			return
+		}
+		scanQueue.SetLimits(maxConcurrent, maxQueued)
+		logAt(logLevelWarn, "Scan queue limits changed by %s: %d concurrent, %d queued", clientIP(r), maxConcurrent, maxQueued)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scanQueue.Stats())
+}
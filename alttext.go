@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// defaultMaxAltLength is the alt text length above which screen reader users
+// lose track, following the common 125-character advice
+const defaultMaxAltLength = 125
+
+// defaultPlaceholderWords are alt texts that say an image is there but not
+// what it shows
+var defaultPlaceholderWords = []string{
+	"alt", "banner", "graphic", "icon", "image", "img", "photo", "photograph",
+	"pic", "picture", "placeholder", "spacer", "thumbnail", "untitled",
+}
+
+// imageFilenamePattern matches alt text that is a file name or a camera's
+// default photo name
+var imageFilenamePattern = regexp.MustCompile(`(?i)^[\w\-. ()]+\.(jpe?g|png|gif|webp|avif|svg|bmp|tiff?|heic|ico)$|^(img|dsc|dscn|dscf|dcim|pxl|mvimg|screenshot|screen shot)[ _-]?\d[\d _-]*$`)
+
+// AltTextPolicy tunes the alt text heuristics of a profile or scan. Each
+// heuristic reports its own audit ID, so ignore_audits turns it off.
+type AltTextPolicy struct {
+	MaxLength        int      `json:"max_length,omitempty"`        // default 125
+	PlaceholderWords []string `json:"placeholder_words,omitempty"` // replace the defaults
+}
+
+// validate checks the alt text policy is usable
+func (p *AltTextPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxLength < 0 {
+		return errors.New("alt_text max_length cannot be negative")
+	}
+	for _, word := range p.PlaceholderWords {
+		if strings.TrimSpace(word) == "" {
+			return errors.New("alt_text placeholder_words cannot be empty")
+		}
+	}
+	return nil
+}
+
+// maxLength returns the alt text length limit, applying the default
+func (p *AltTextPolicy) maxLength() int {
+	if p == nil || p.MaxLength == 0 {
+		return defaultMaxAltLength
+	}
+	return p.MaxLength
+}
+
+// placeholders returns the set of placeholder alt texts, applying the defaults
+func (p *AltTextPolicy) placeholders() map[string]bool {
+	words := defaultPlaceholderWords
+	if p != nil && len(p.PlaceholderWords) > 0 {
+		words = p.PlaceholderWords
+	}
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[normalizeAltText(word)] = true
+	}
+	return set
+}
+
+// normalizeAltText lowercases alt text and collapses its spaces and
+// surrounding punctuation, so near-identical texts compare equal
+func normalizeAltText(alt string) string {
+	alt = strings.Join(strings.Fields(strings.ToLower(alt)), " ")
+	return strings.Trim(alt, " .,:;!-_*")
+}
+
+// isPlaceholderAlt reports whether alt text is a placeholder word, alone or
+// numbered ("image 3")
+func isPlaceholderAlt(alt string, placeholders map[string]bool) bool {
+	alt = normalizeAltText(alt)
+	if placeholders[alt] {
+		return true
+	}
+	return placeholders[strings.TrimRight(alt, " 0123456789#_-")]
+}
+
+// checkAltText flags alt text that is present but does not describe the
+// image: a file name, a placeholder word, text too long to follow, or the
+// same text on images with different sources. Missing alt text is left to
+// Lighthouse, and alt="" marks decorative images.
+func checkAltText(doc *html.Node, policy *AltTextPolicy) []AccessibilityIssue {
+	placeholders := policy.placeholders()
+	maxLength := policy.maxLength()
+
+	var issues []AccessibilityIssue
+	issue := func(n *html.Node, auditID, impact string, title *checkTitle, description string) {
+		issues = append(issues, AccessibilityIssue{
+			AuditID:     auditID,
+			title:       title,
+			Description: description,
+			Impact:      impact,
+			Selector:    elementPath(n),
+			Snippet:     elementSnippet(n),
+		})
+	}
+
+	type altUse struct {
+		node *html.Node
+		src  string
+	}
+	byText := make(map[string][]altUse)
+	var order []string
+	audit := func(n *html.Node) {
+		alt := strings.TrimSpace(attrValue(n, "alt"))
+		if alt == "" {
+			return
+		}
+		// Sizes of one image differ only in their query string
+		src, _, _ := strings.Cut(attrValue(n, "src"), "?")
+
+		generic := true
+		switch {
+		case imageFilenamePattern.MatchString(alt) || strings.EqualFold(alt, path.Base(src)):
+			issue(n, "alt-text-filename", "serious",
+				titled("default", "{alt}", alt),
+				"Screen readers read out the file name, which says nothing about what the image shows.")
+		case isPlaceholderAlt(alt, placeholders):
+			issue(n, "alt-text-placeholder", "moderate",
+				titled("default", "{alt}", alt),
+				"Screen reader users learn that there is an image, but not what it shows.")
+		default:
+			generic = false
+		}
+		if length := utf8.RuneCountInString(alt); length > maxLength {
+			issue(n, "alt-text-too-long", "minor",
+				titled("default", "{length}", strconv.Itoa(length), "{max}", strconv.Itoa(maxLength)),
+				"Long alt text cannot be navigated or paused like the text of the page. Describe the image briefly and put details in the page or a caption.")
+		}
+
+		if generic {
+			return // already flagged; sharing it adds nothing
+		}
+		key := normalizeAltText(alt)
+		if _, seen := byText[key]; !seen {
+			order = append(order, key)
+		}
+		byText[key] = append(byText[key], altUse{node: n, src: src})
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "template", "head":
+				return
+			}
+			if hiddenFromAT(n) {
+				return
+			}
+			if n.Data == "img" || (n.Data == "input" && strings.EqualFold(attrValue(n, "type"), "image")) {
+				audit(n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	// The first image with a text keeps it; other images with the same text
+	// and another source are flagged
+	for _, key := range order {
+		uses := byText[key]
+		for _, use := range uses[1:] {
+			if use.src == uses[0].src || use.src == "" {
+				continue
+			}
+			issue(use.node, "alt-text-duplicate", "minor",
+				titled("default", "{alt}", attrValue(use.node, "alt")),
+				"Screen reader users cannot tell apart images described the same way.")
+		}
+	}
+	return issues
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// AMP modes of a scan. By default AMP pages are crawled like any other page
+// when linked, and paired with their canonical page when both are scanned.
+const (
+	ampSkip = "skip" // never scan AMP variants
+	ampPair = "pair" // follow rel="amphtml" links so each page is scanned with its AMP variant
+)
+
+// AMPPair compares a canonical page with its AMP variant in one scan
+type AMPPair struct {
+	CanonicalURL    string   `json:"canonical_url"`
+	AMPURL          string   `json:"amp_url"`
+	CanonicalScore  float64  `json:"canonical_score"`
+	AMPScore        float64  `json:"amp_score"`
+	CanonicalIssues int      `json:"canonical_issues"`
+	AMPIssues       int      `json:"amp_issues"`
+	AMPOnlyAudits   []string `json:"amp_only_audits,omitempty"` // audits failing on the AMP page only
+}
+
+// ampLinks is what a page says about AMP: whether it is an AMP page, and the
+// canonical page or AMP variant it links to
+type ampLinks struct {
+	isAMP     bool
+	canonical string
+	amphtml   string
+}
+
+// validateAMPMode checks an amp setting
+func validateAMPMode(mode string) error {
+	if mode != "" && mode != ampSkip && mode != ampPair {
+		return errors.New("amp must be \"skip\" or \"pair\"")
+	}
+	return nil
+}
+
+// readAMPLinks reads the AMP markers of an element: the amp or ⚡ attribute of
+// <html> and rel="canonical" and rel="amphtml" links
+func readAMPLinks(n *html.Node, links *ampLinks) {
+	switch n.Data {
+	case "html":
+		for _, attr := range n.Attr {
+			if attr.Key == "amp" || attr.Key == "⚡" {
+				links.isAMP = true
+			}
+		}
+	case "link":
+		var rel, href string
+		for _, attr := range n.Attr {
+			switch attr.Key {
+			case "rel":
+				rel = strings.ToLower(attr.Val)
+			case "href":
+				href = attr.Val
+			}
+		}
+		for _, token := range strings.Fields(rel) {
+			if token == "canonical" && links.canonical == "" {
+				links.canonical = href
+			} else if token == "amphtml" && links.amphtml == "" {
+				links.amphtml = href
+			}
+		}
+	}
+}
+
+// recordAMP remembers the AMP pairing a page declared. An AMP page names its
+// canonical page, a canonical page names its AMP variant.
+func (s *AccessibilityScanner) recordAMP(pageURL string, links ampLinks) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if links.isAMP && links.canonical != "" && links.canonical != pageURL {
+		s.ampCanonical[pageURL] = links.canonical
+	} else if !links.isAMP && links.amphtml != "" && links.amphtml != pageURL {
+		s.ampCanonical[links.amphtml] = pageURL
+	}
+}
+
+// ampCanonicalOf returns the canonical page of a known AMP page
+func (s *AccessibilityScanner) ampCanonicalOf(pageURL string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	canonical, ok := s.ampCanonical[pageURL]
+	return canonical, ok
+}
+
+// skipsAMP reports whether a URL is an AMP variant the scan must not scan
+func (s *AccessibilityScanner) skipsAMP(pageURL string) bool {
+	if s.amp != ampSkip {
+		return false
+	}
+	_, ok := s.ampCanonicalOf(pageURL)
+	return ok
+}
+
+// pairAMPPages marks the AMP pages of a scan with their canonical page and
+// pairs those whose canonical page was scanned too
+func (s *AccessibilityScanner) pairAMPPages(result *ScanResult) {
+	byURL := make(map[string]int, len(result.PageResults))
+	for i := range result.PageResults {
+		page := &result.PageResults[i]
+		if canonical, ok := s.ampCanonicalOf(page.URL); ok && page.CanonicalURL == "" {
+			page.CanonicalURL = canonical
+		}
+		byURL[page.URL] = i
+	}
+
+	result.AMPPairs = nil
+	for i := range result.PageResults {
+		ampPage := &result.PageResults[i]
+		if ampPage.CanonicalURL == "" {
+			continue
+		}
+		j, ok := byURL[ampPage.CanonicalURL]
+		if !ok {
+			continue
+		}
+		canonicalPage := &result.PageResults[j]
+		canonicalPage.AMPURL = ampPage.URL
+		if ampPage.Error != "" || canonicalPage.Error != "" {
+			continue
+		}
+
+		canonicalAudits := make(map[string]bool)
+		for _, issue := range canonicalPage.Issues {
+			canonicalAudits[issue.AuditID] = true
+		}
+		pair := AMPPair{
+			CanonicalURL:    canonicalPage.URL,
+			AMPURL:          ampPage.URL,
+			CanonicalScore:  canonicalPage.AccessibilityScore,
+			AMPScore:        ampPage.AccessibilityScore,
+			CanonicalIssues: len(canonicalPage.Issues),
+			AMPIssues:       len(ampPage.Issues),
+		}
+		for _, issue := range ampPage.Issues {
+			if !canonicalAudits[issue.AuditID] {
+				canonicalAudits[issue.AuditID] = true
+				pair.AMPOnlyAudits = append(pair.AMPOnlyAudits, issue.AuditID)
+			}
+		}
+		sort.Strings(pair.AMPOnlyAudits)
+		result.AMPPairs = append(result.AMPPairs, pair)
+	}
+}
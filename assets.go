@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// defaultSkipExtensions are file types that are never HTML pages
+var defaultSkipExtensions = []string{
+	"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "odt", "ods", "odp", "rtf", "csv", "txt",
+	"jpg", "jpeg", "png", "gif", "webp", "avif", "svg", "ico", "bmp", "tif", "tiff",
+	"mp3", "m4a", "wav", "ogg", "mp4", "m4v", "webm", "mov", "avi", "wmv",
+	"zip", "gz", "tgz", "tar", "rar", "7z", "bz2",
+	"css", "js", "json", "xml", "rss", "atom", "woff", "woff2", "ttf", "otf", "eot",
+	"exe", "msi", "dmg", "pkg", "apk", "iso",
+}
+
+// defaultSkipPaths are URL paths of feeds and endpoints that are not pages,
+// mostly WordPress ones, as include/exclude style patterns
+var defaultSkipPaths = []string{
+	"*/feed", "*/feed/*", "*/trackback", "*/trackback/*",
+	"/xmlrpc.php", "/wp-login.php", "/wp-json/*", "/wp-admin/*",
+}
+
+// assetFilter keeps links to non-page resources out of the crawl frontier
+type assetFilter struct {
+	extensions map[string]bool
+	paths      []*regexp.Regexp
+}
+
+// newAssetFilter builds a filter from file extensions, with or without the
+// leading dot, and URL path patterns
+func newAssetFilter(extensions, paths []string) *assetFilter {
+	filter := &assetFilter{extensions: make(map[string]bool, len(extensions)), paths: compilePathPatterns(paths)}
+	for _, extension := range extensions {
+		filter.extensions[strings.ToLower(strings.TrimPrefix(extension, "."))] = true
+	}
+	return filter
+}
+
+// skips reports whether a URL path points at a resource that is not a page
+func (f *assetFilter) skips(urlPath string) bool {
+	if f == nil {
+		return false
+	}
+	if extension := strings.TrimPrefix(path.Ext(urlPath), "."); extension != "" && f.extensions[strings.ToLower(extension)] {
+		return true
+	}
+	for _, pattern := range f.paths {
+		if pattern.MatchString(urlPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Audit statistics limits
+const (
+	defaultAuditStatsLimit = 50
+	maxAuditStatsLimit     = 500
+)
+
+// AuditFrequency counts how often one audit failed across scans
+type AuditFrequency struct {
+	AuditID  string  `json:"audit_id"`
+	Title    string  `json:"title"`
+	Impact   string  `json:"impact"` // most severe impact seen
+	Scans    int     `json:"scans"`  // scans with at least one failing page
+	ScanRate float64 `json:"scan_rate"`
+	Sites    int     `json:"sites"`
+	Pages    int     `json:"pages"` // failing pages, summed over scans
+	Issues   int     `json:"issues"`
+}
+
+// AuditStats ranks the audits failing most often in a set of scans
+type AuditStats struct {
+	Scope     string           `json:"scope"` // project or instance
+	ProjectID string           `json:"project_id,omitempty"`
+	Since     *time.Time       `json:"since,omitempty"`
+	Until     *time.Time       `json:"until,omitempty"`
+	Scans     int              `json:"scans_analyzed"`
+	Sites     int              `json:"sites_analyzed"`
+	Pages     int              `json:"pages_analyzed"`
+	Audits    []AuditFrequency `json:"audits"`
+	Total     int              `json:"total_audits"` // failing audits before the limit
+}
+
+// auditStatsWindow is the time range and size of an audit statistics request
+type auditStatsWindow struct {
+	since, until time.Time
+	limit        int
+}
+
+// parseAuditStatsWindow reads since, until and limit
+func parseAuditStatsWindow(r *http.Request) (auditStatsWindow, error) {
+	window := auditStatsWindow{limit: defaultAuditStatsLimit}
+	var err error
+	if window.since, err = parseScanTime(r, "since"); err != nil {
+		return window, err
+	}
+	if window.until, err = parseScanTime(r, "until"); err != nil {
+		return window, err
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		window.limit, err = strconv.Atoi(value)
+		if err != nil || window.limit < 1 || window.limit > maxAuditStatsLimit {
+			return window, fmt.Errorf("limit must be between 1 and %d", maxAuditStatsLimit)
+		}
+	}
+	return window, nil
+}
+
+// buildAuditStats aggregates the failing audits of the finished scans of the
+// given sites, or of every site when sites is nil
+func buildAuditStats(sites map[string]bool, window auditStatsWindow) (AuditStats, error) {
+	stats := AuditStats{Audits: make([]AuditFrequency, 0)}
+	if !window.since.IsZero() {
+		stats.Since = &window.since
+	}
+	if !window.until.IsZero() {
+		stats.Until = &window.until
+	}
+
+	scans, err := scanStore.List()
+	if err != nil {
+		return stats, err
+	}
+	audits := make(map[string]*AuditFrequency)
+	auditSites := make(map[string]map[string]bool)
+	analyzedSites := make(map[string]bool)
+	for _, scan := range scans {
+		if sites != nil && !sites[scan.Site] {
+			continue
+		}
+		if scan.Status != "completed" && scan.Status != "partial" {
+			continue
+		}
+		if (!window.since.IsZero() && scan.ScanTime.Before(window.since)) || (!window.until.IsZero() && scan.ScanTime.After(window.until)) {
+			continue
+		}
+		result, err := scanStore.Get(scan.ID)
+		if err != nil {
+			logAt(logLevelWarn, "Warning: Could not load scan %s for audit statistics: %v", scan.ID, err)
+			continue
+		}
+		stats.Scans++
+		analyzedSites[scan.Site] = true
+
+		inScan := make(map[string]bool)
+		for _, page := range result.PageResults {
+			if page.Error != "" {
+				continue
+			}
+			stats.Pages++
+			onPage := make(map[string]bool)
+			for _, issue := range page.Issues {
+				audit := audits[issue.AuditID]
+				if audit == nil {
+					audit = &AuditFrequency{AuditID: issue.AuditID, Title: issue.Title}
+					audits[issue.AuditID] = audit
+					auditSites[issue.AuditID] = make(map[string]bool)
+				}
+				if impactWeights[issue.Impact] > impactWeights[audit.Impact] {
+					audit.Impact = issue.Impact
+				}
+				audit.Issues++
+				if !onPage[issue.AuditID] {
+					onPage[issue.AuditID] = true
+					audit.Pages++
+				}
+				if !inScan[issue.AuditID] {
+					inScan[issue.AuditID] = true
+					audit.Scans++
+					auditSites[issue.AuditID][scan.Site] = true
+				}
+			}
+		}
+	}
+	stats.Sites = len(analyzedSites)
+
+	for id, audit := range audits {
+		audit.Sites = len(auditSites[id])
+		audit.ScanRate = math.Round(float64(audit.Scans)/float64(stats.Scans)*1000) / 1000
+		stats.Audits = append(stats.Audits, *audit)
+	}
+	// Most widespread first, then the most issues
+	sort.Slice(stats.Audits, func(i, j int) bool {
+		a, b := stats.Audits[i], stats.Audits[j]
+		if a.Scans != b.Scans {
+			return a.Scans > b.Scans
+		}
+		if a.Issues != b.Issues {
+			return a.Issues > b.Issues
+		}
+		return a.AuditID < b.AuditID
+	})
+	stats.Total = len(stats.Audits)
+	if len(stats.Audits) > window.limit {
+		stats.Audits = stats.Audits[:window.limit]
+	}
+	return stats, nil
+}
+
+// writeAuditStats answers an audit statistics request for the given sites
+func writeAuditStats(w http.ResponseWriter, r *http.Request, sites map[string]bool, scope, projectID string) {
+	window, err := parseAuditStatsWindow(r)
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	stats, err := buildAuditStats(sites, window)
+	if err != nil {
+		logAt(logLevelError, "Failed to list scans: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not list stored scans")
+		return
+	}
+	stats.Scope, stats.ProjectID = scope, projectID
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleProjectAuditStats handles GET /api/v1/projects/{project}/audit-stats
+// requests: the audits failing most often in scans of the project's sites
+func handleProjectAuditStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	projectID := r.PathValue("project")
+	projectSites, err := orgStore.Sites(projectID)
+	if err != nil {
+		sendOrgStoreError(w, err)
+		return
+	}
+	hosts := make(map[string]bool, len(projectSites))
+	for _, site := range projectSites {
+		hosts[site.Host] = true
+	}
+	writeAuditStats(w, r, hosts, "project", projectID)
+}
+
+// handleAdminAuditStats handles GET /api/v1/admin/audit-stats requests: the
+// audits failing most often in every scan of the instance
+func handleAdminAuditStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	writeAuditStats(w, r, nil, "instance", "")
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditTrailDayLayout names the daily files of the audit trail
+const auditTrailDayLayout = "2006-01-02"
+
+// Results of an audited request
+const (
+	auditSuccess = "success"
+	auditDenied  = "denied" // authentication or authorization failed
+	auditFailure = "failure"
+)
+
+// AuditTrailEntry records one API request: who made it, what it did, when,
+// from where and with what result
+type AuditTrailEntry struct {
+	Time       time.Time `json:"time"`
+	Client     string    `json:"client,omitempty"` // API client, admin or anonymous; empty when the API key was rejected
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route,omitempty"` // matched endpoint, such as /api/v1/scans/{id}
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Status     int       `json:"status"`
+	Result     string    `json:"result"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// AuditTrail appends API requests to one JSON Lines file per UTC day. Files
+// are only ever appended to; the API has no way to change or delete entries.
+type AuditTrail struct {
+	mu   sync.Mutex
+	dir  string
+	day  string
+	file *os.File
+}
+
+// auditTrail records API requests
+var auditTrail *AuditTrail
+
+// NewAuditTrail creates an audit trail in dir, creating the directory if needed
+func NewAuditTrail(dir string) (*AuditTrail, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &AuditTrail{dir: dir}, nil
+}
+
+// Append adds an entry to the file of its day
+func (t *AuditTrail) Append(entry AuditTrailEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if day := entry.Time.UTC().Format(auditTrailDayLayout); day != t.day || t.file == nil {
+		if t.file != nil {
+			t.file.Close()
+		}
+		t.file, err = os.OpenFile(filepath.Join(t.dir, day+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			t.file = nil
+			logAt(logLevelError, "Could not open the audit trail: %v", err)
+			return
+		}
+		t.day = day
+	}
+	if _, err := t.file.Write(append(data, '\n')); err != nil {
+		logAt(logLevelError, "Could not write to the audit trail: %v", err)
+	}
+}
+
+// auditFilter selects audit trail entries
+type auditFilter struct {
+	client string
+	method string
+	path   string // path prefix
+	result string
+	since  time.Time
+	until  time.Time
+}
+
+// matches reports whether an entry passes the filter
+func (f auditFilter) matches(entry AuditTrailEntry) bool {
+	return (f.client == "" || entry.Client == f.client) &&
+		(f.method == "" || entry.Method == f.method) &&
+		(f.path == "" || strings.HasPrefix(entry.Path, f.path)) &&
+		(f.result == "" || entry.Result == f.result) &&
+		(f.since.IsZero() || !entry.Time.Before(f.since)) &&
+		(f.until.IsZero() || !entry.Time.After(f.until))
+}
+
+// Query returns the entries matching a filter, newest first. Only the files
+// of the days in the filter's time range are read. Requests are not held up
+// while a query runs; a line still being appended is skipped.
+func (t *AuditTrail) Query(filter auditFilter) ([]AuditTrailEntry, error) {
+	names, err := filepath.Glob(filepath.Join(t.dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	slices.Reverse(names)
+
+	entries := make([]AuditTrailEntry, 0)
+	for _, name := range names {
+		day, err := time.Parse(auditTrailDayLayout, strings.TrimSuffix(filepath.Base(name), ".jsonl"))
+		if err != nil {
+			continue
+		}
+		if (!filter.since.IsZero() && day.AddDate(0, 0, 1).Before(filter.since)) || (!filter.until.IsZero() && day.After(filter.until)) {
+			continue
+		}
+
+		dayEntries, err := readAuditTrailFile(name, filter)
+		if err != nil {
+			return nil, err
+		}
+		slices.Reverse(dayEntries)
+		entries = append(entries, dayEntries...)
+	}
+	return entries, nil
+}
+
+// readAuditTrailFile reads the entries of a daily file that match a filter,
+// oldest first. A line cut short by a crash is skipped.
+func readAuditTrailFile(name string, filter auditFilter) ([]AuditTrailEntry, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := make([]AuditTrailEntry, 0)
+	lines := bufio.NewScanner(file)
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lines.Scan() {
+		var entry AuditTrailEntry
+		if json.Unmarshal(lines.Bytes(), &entry) == nil && filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, lines.Err()
+}
+
+// auditResponseWriter captures the status of a response for the audit trail
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (aw *auditResponseWriter) WriteHeader(code int) {
+	if aw.status == 0 {
+		aw.status = code
+	}
+	aw.ResponseWriter.WriteHeader(code)
+}
+
+func (aw *auditResponseWriter) Write(b []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	return aw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through for streaming responses
+func (aw *auditResponseWriter) Flush() {
+	if flusher, ok := aw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// auditRequest records a finished API request in the audit trail
+func auditRequest(r *http.Request, client string, status int, started time.Time) {
+	if auditTrail == nil {
+		return
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	result := auditSuccess
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		result = auditDenied
+	case status >= 400:
+		result = auditFailure
+	}
+	auditTrail.Append(AuditTrailEntry{
+		Time:       started.UTC(),
+		Client:     client,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Route:      r.Pattern,
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		Status:     status,
+		Result:     result,
+		DurationMs: time.Since(started).Milliseconds(),
+	})
+}
+
+// handleAdminAuditTrail handles GET /api/v1/admin/audit-trail requests,
+// listing API requests newest first, filtered by client, method, path
+// prefix, result and time
+func handleAdminAuditTrail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := auditFilter{
+		client: query.Get("client"),
+		method: strings.ToUpper(query.Get("method")),
+		path:   query.Get("path"),
+		result: query.Get("result"),
+	}
+	if filter.result != "" && filter.result != auditSuccess && filter.result != auditDenied && filter.result != auditFailure {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "result must be success, denied or failure")
+		return
+	}
+	var err error
+	if filter.since, err = parseScanTime(r, "since"); err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.until, err = parseScanTime(r, "until"); err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	start, pageSize, err := parsePagination(r)
+	if err != nil {
+		sendError(w, "Invalid pagination", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := auditTrail.Query(filter)
+	if err != nil {
+		logAt(logLevelError, "Failed to read the audit trail: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not read the audit trail")
+		return
+	}
+	end := min(start+pageSize, len(entries))
+	start = min(start, end)
+	writePaginated(w, r, "", entries[start:end], len(entries), start, pageSize)
+}
@@ -1,34 +1,40 @@
 #!/bin/bash
 mkdir -p builds
 
-echo "Building for different platforms..."
+# Stamp the binaries with their version, commit and build date
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}
+COMMIT=$(git rev-parse HEAD 2>/dev/null)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+LDFLAGS="-X main.version=$VERSION -X main.commit=$COMMIT -X main.buildDate=$BUILD_DATE"
+
+echo "Building $VERSION for different platforms..."
 
 # Windows 64-bit
-GOOS=windows GOARCH=amd64 go build -o builds/accessibility-scanner-windows-amd64.exe main.go
+GOOS=windows GOARCH=amd64 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-windows-amd64.exe .
 echo "✅ Windows 64-bit built"
 
 # Windows 32-bit  
-GOOS=windows GOARCH=386 go build -o builds/accessibility-scanner-windows-386.exe main.go
+GOOS=windows GOARCH=386 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-windows-386.exe .
 echo "✅ Windows 32-bit built"
 
 # macOS 64-bit (Intel)
-GOOS=darwin GOARCH=amd64 go build -o builds/accessibility-scanner-macos-amd64 main.go
+GOOS=darwin GOARCH=amd64 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-macos-amd64 .
 echo "✅ macOS Intel built"
 
 # macOS ARM64 (Apple Silicon)
-GOOS=darwin GOARCH=arm64 go build -o builds/accessibility-scanner-macos-arm64 main.go
+GOOS=darwin GOARCH=arm64 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-macos-arm64 .
 echo "✅ macOS Apple Silicon built"
 
 # Linux 64-bit
-GOOS=linux GOARCH=amd64 go build -o builds/accessibility-scanner-linux-amd64 main.go
+GOOS=linux GOARCH=amd64 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-linux-amd64 .
 echo "✅ Linux 64-bit built"
 
 # Linux 32-bit
-GOOS=linux GOARCH=386 go build -o builds/accessibility-scanner-linux-386 main.go
+GOOS=linux GOARCH=386 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-linux-386 .
 echo "✅ Linux 32-bit built"
 
 # Linux ARM64 (for servers/Raspberry Pi)
-GOOS=linux GOARCH=arm64 go build -o builds/accessibility-scanner-linux-arm64 main.go
+GOOS=linux GOARCH=arm64 go build -ldflags "$LDFLAGS" -o builds/accessibility-scanner-linux-arm64 .
 echo "✅ Linux ARM64 built"
 
 echo "🎉 All builds completed in ./builds/ directory"
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Build information, set at build time with
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// Without them, the version and commit fall back to what the Go toolchain
+// recorded in the binary.
+var (
+	version   = "dev"
+	commit    string
+	buildDate string
+)
+
+// BuildInfo identifies the build of the scanner that served a request or
+// produced a result
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a working tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// buildInfo returns the build information of the running binary
+var buildInfo = sync.OnceValue(func() BuildInfo {
+	info := BuildInfo{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = commit == "" && setting.Value == "true"
+		}
+	}
+	return info
+})
+
+// handleVersion handles GET /version requests
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildInfo())
+}
+
+// shortCommit abbreviates a commit hash for logs
+func shortCommit(commit string) string {
+	if commit == "" {
+		return "unknown commit"
+	}
+	return commit[:min(len(commit), 12)]
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Bot challenge modes of a scan: what the crawler does with a page answered
+// by a bot challenge or interstitial instead of the site
+const (
+	challengeBlock = "block" // report the page as blocked (default)
+	challengeRetry = "retry" // wait and fetch the page again, then block
+	challengeAbort = "abort" // stop the scan
+)
+
+// Bot challenge settings
+const (
+	challengePeekSize   = 64 << 10 // bytes of a page checked for challenge markers
+	maxChallengeRetries = 2
+	challengeRetryDelay = 5 * time.Second // doubled on each retry
+)
+
+// challengeMarkers map what bot challenge pages contain to the service
+// serving them. Markers are specific to challenge pages, so widgets such as
+// Turnstile or reCAPTCHA embedded in ordinary forms do not match.
+var challengeMarkers = []struct {
+	vendor  string
+	markers []string
+}{
+	{"cloudflare", []string{"/cdn-cgi/challenge-platform/", "cf-browser-verification", "window._cf_chl_opt", "<title>Just a moment...</title>", "Attention Required! | Cloudflare"}},
+	{"imperva", []string{"_Incapsula_Resource", "Incapsula incident ID"}},
+	{"perimeterx", []string{"px-captcha", "_pxCaptcha"}},
+	{"datadome", []string{"captcha-delivery.com", "geo.captcha-delivery"}},
+	{"akamai", []string{"/_sec/cp_challenge/", "sec-if-cpt-container"}},
+	{"sucuri", []string{"sucuri_cloudproxy_js", "Sucuri WebSite Firewall - Access Denied"}},
+	{"siteground", []string{"/.well-known/sgcaptcha/"}},
+	{"ddos-guard", []string{"<title>DDoS-Guard</title>", "ddos-guard.net/js/"}},
+}
+
+// challengeError is returned for a page answered by a bot challenge
+type challengeError struct {
+	vendor string
+}
+
+func (e *challengeError) Error() string {
+	return fmt.Sprintf("blocked by a %s bot challenge", e.vendor)
+}
+
+// validateChallengeMode checks a bot_challenge setting
+func validateChallengeMode(mode string) error {
+	if mode != "" && mode != challengeBlock && mode != challengeRetry && mode != challengeAbort {
+		return errors.New("bot_challenge must be \"block\", \"retry\" or \"abort\"")
+	}
+	return nil
+}
+
+// detectChallenge returns the service whose bot challenge answered a
+// request, judging by the response headers and the start of the body, or ""
+func detectChallenge(resp *http.Response, head []byte) string {
+	// Cloudflare marks challenge responses, whatever their status
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return "cloudflare"
+	}
+	if resp.Header.Get("X-Datadome") != "" && resp.StatusCode == http.StatusForbidden {
+		return "datadome"
+	}
+	for _, kind := range challengeMarkers {
+		for _, marker := range kind.markers {
+			if bytes.Contains(head, []byte(marker)) {
+				return kind.vendor
+			}
+		}
+	}
+	return ""
+}
+
+// challengeVendor returns the service of a bot challenge error, or ""
+func challengeVendor(err error) string {
+	var challenge *challengeError
+	if errors.As(err, &challenge) {
+		return challenge.vendor
+	}
+	return ""
+}
+
+// challengeMode returns the scan's bot challenge mode
+func (s *AccessibilityScanner) challengeMode() string {
+	if s.botChallenge == "" {
+		return challengeBlock
+	}
+	return s.botChallenge
+}
+
+// waitForChallenge waits before fetching a challenged page again, reporting
+// whether to retry
+func (s *AccessibilityScanner) waitForChallenge(ctx context.Context, pageURL string, attempt int) bool {
+	if s.challengeMode() != challengeRetry || attempt >= maxChallengeRetries {
+		return false
+	}
+	delay := challengeRetryDelay << attempt
+	logAt(logLevelDebug, "Bot challenge on %s, fetching again in %s", pageURL, delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// blockedPage reports a page the crawler could not fetch past a bot
+// challenge. Its Lighthouse audit is dropped: a site that challenges the
+// crawler may well have shown PageSpeed Insights the challenge too.
+func blockedPage(pageURL, vendor string) PageResult {
+	return PageResult{
+		URL:          pageURL,
+		Error:        fmt.Sprintf("Blocked by a %s bot challenge; allow the scanner's user agent to audit this page", vendor),
+		ErrorCode:    "target_blocked",
+		BotChallenge: vendor,
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// checkstyleSourcePrefix namespaces audit IDs in the source attribute, the
+// way Checkstyle names its checks
+const checkstyleSourcePrefix = "accessibility."
+
+// checkstyleReport is the root of a Checkstyle XML report
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+// checkstyleFile holds the findings of one page, named by its URL
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+// checkstyleError is one finding. Pages have no lines, so findings are
+// reported on line 0, which tools treat as the whole file.
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleSeverity maps an issue impact to a Checkstyle severity: critical
+// and serious issues are errors, the rest warnings
+func checkstyleSeverity(impact string) string {
+	if impact == "critical" || impact == "serious" {
+		return "error"
+	}
+	return "warning"
+}
+
+// writeCheckstyle writes a scan as a Checkstyle XML report with one file per
+// page and one error or warning per issue. Pages that could not be audited
+// get an error of source accessibility.page-error. It returns the number of
+// findings.
+func writeCheckstyle(w io.Writer, result *ScanResult) int {
+	report := checkstyleReport{Version: "4.3", Files: make([]checkstyleFile, 0, len(result.PageResults))}
+	findings := 0
+	for _, page := range result.PageResults {
+		file := checkstyleFile{Name: page.URL, Errors: make([]checkstyleError, 0, len(page.Issues))}
+		if page.Error != "" {
+			file.Errors = append(file.Errors, checkstyleError{
+				Severity: "error",
+				Message:  "Page could not be audited: " + page.Error,
+				Source:   checkstyleSourcePrefix + "page-error",
+			})
+		}
+		for _, issue := range page.Issues {
+			if isFalsePositive(issue) {
+				continue
+			}
+			message := issue.Title
+			if issue.Selector != "" {
+				message = fmt.Sprintf("%s (%s)", issue.Title, issue.Selector)
+			}
+			file.Errors = append(file.Errors, checkstyleError{
+				Severity: checkstyleSeverity(issue.Impact),
+				Message:  message,
+				Source:   checkstyleSourcePrefix + issue.AuditID,
+			})
+		}
+		findings += len(file.Errors)
+		report.Files = append(report.Files, file)
+	}
+
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logAt(logLevelWarn, "Warning: Could not write Checkstyle report of scan %s: %v", result.ID, err)
+	}
+	io.WriteString(w, "\n")
+	return findings
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client names used when a request carries no client API key
+const (
+	anonymousClient = "anonymous"
+	adminClient     = "admin"
+)
+
+// APIClient is a consumer of the API identified by its key
+type APIClient struct {
+	Name    string `yaml:"name"`
+	Key     string `yaml:"key"`
+	Project string `yaml:"project"`
+	Quota   Quota  `yaml:"quota"`
+}
+
+// clientKey is the request context key holding the authenticated client name
+type clientKey struct{}
+
+// parseAPIClients parses a comma-separated list of name:key or name:key:project entries
+func parseAPIClients(value string) ([]APIClient, error) {
+	clients := make([]APIClient, 0)
+	for _, entry := range splitValues(value) {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("entries must be name:key or name:key:project")
+		}
+		client := APIClient{Name: strings.TrimSpace(parts[0]), Key: strings.TrimSpace(parts[1])}
+		if len(parts) == 3 {
+			client.Project = strings.TrimSpace(parts[2])
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// validateAPIClients checks client names and keys are set and unique
+func validateAPIClients(clients []APIClient) error {
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for _, client := range clients {
+		if client.Name == "" || client.Key == "" {
+			return fmt.Errorf("every client needs a name and a key")
+		}
+		if client.Name == anonymousClient || client.Name == adminClient {
+			return fmt.Errorf("client name %q is reserved", client.Name)
+		}
+		if names[client.Name] || keys[client.Key] {
+			return fmt.Errorf("client %q is not unique", client.Name)
+		}
+		if client.Quota.ScansPerDay < 0 || client.Quota.PagesPerDay < 0 || client.Quota.MaxConcurrentScans < 0 {
+			return fmt.Errorf("quota of client %q cannot be negative", client.Name)
+		}
+		names[client.Name] = true
+		keys[client.Key] = true
+	}
+	return nil
+}
+
+// authenticateClient identifies the caller from its X-API-Key header; the
+// admin bearer token identifies the admin client. When no client keys are
+// configured, callers without a key are anonymous.
+func authenticateClient(r *http.Request) (string, bool) {
+	config := currentConfig()
+
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		for _, client := range config.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(client.Key)) == 1 {
+				return client.Name, true
+			}
+		}
+		return "", false
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && config.AdminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) == 1 {
+		return adminClient, true
+	}
+	return anonymousClient, len(config.APIKeys) == 0
+}
+
+// clientName returns the client resolved by withClient
+func clientName(r *http.Request) string {
+	if name, ok := r.Context().Value(clientKey{}).(string); ok {
+		return name
+	}
+	return anonymousClient
+}
+
+// withClient authenticates the API client, counts the request against its
+// usage and makes the client available to the handler. Every request,
+// including rejected ones, is recorded in the audit trail.
+func withClient(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		aw := &auditResponseWriter{ResponseWriter: w}
+		name, ok := authenticateClient(r)
+		defer func() { auditRequest(r, name, aw.status, started) }()
+		if !ok {
+			sendError(aw, "Invalid API key", http.StatusUnauthorized, "Send a valid API key in the X-API-Key header")
+			return
+		}
+		usageStore.Record(name, UsageCounters{Requests: 1})
+		next(aw, r.WithContext(context.WithValue(r.Context(), clientKey{}, name)))
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Limits for assignees and comments
+const (
+	maxAssigneeLength = 200
+	maxCommentLength  = 10000
+)
+
+// errIssueNotTracked is returned when a fingerprint has no issue record for the site
+var errIssueNotTracked = errors.New("issue not tracked")
+
+// errParentNotFound is returned when a reply refers to an unknown comment
+var errParentNotFound = errors.New("parent comment not found")
+
+// Comment is a note attached to a tracked issue; replies refer to their parent
+type Comment struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentThread is a comment with its replies nested beneath it
+type CommentThread struct {
+	Comment
+	Replies []*CommentThread `json:"replies"`
+}
+
+// AssigneeRequest represents an API request to assign an issue
+type AssigneeRequest struct {
+	Assignee string `json:"assignee"`
+}
+
+// CommentRequest represents an API request to comment on an issue
+type CommentRequest struct {
+	Author   string `json:"author"`
+	Body     string `json:"body"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+// newCommentID generates a random comment identifier
+func newCommentID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// updateIssueRecord applies modify to a tracked issue of a site
+func updateIssueRecord(host, fingerprint string, modify func(record *IssueRecord) error) (*IssueRecord, error) {
+	issues := SiteIssues{}
+	var record *IssueRecord
+	err := siteStore.Update(host, "issues", &issues, func() error {
+		var ok bool
+		if record, ok = issues[fingerprint]; !ok {
+			return errIssueNotTracked
+		}
+		return modify(record)
+	})
+	return record, err
+}
+
+// threadComments nests replies under their parents, preserving creation order
+func threadComments(comments []Comment) []*CommentThread {
+	threads := make([]*CommentThread, 0)
+	byID := make(map[string]*CommentThread, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = &CommentThread{Comment: comment, Replies: []*CommentThread{}}
+	}
+	for _, comment := range comments {
+		thread := byID[comment.ID]
+		if parent, ok := byID[comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, thread)
+		} else {
+			threads = append(threads, thread)
+		}
+	}
+	return threads
+}
+
+// handleIssueAssignee handles PUT and DELETE /api/v1/sites/{host}/issues/{fingerprint}/assignee requests
+func handleIssueAssignee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only PUT and DELETE methods are supported")
+		return
+	}
+
+	fingerprint := r.PathValue("fingerprint")
+	if !fingerprintPattern.MatchString(fingerprint) {
+		sendError(w, "Invalid fingerprint", http.StatusBadRequest, "fingerprint must be a 16 character hex string")
+		return
+	}
+
+	var req AssigneeRequest
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+			return
+		}
+		req.Assignee = strings.TrimSpace(req.Assignee)
+		if req.Assignee == "" || len(req.Assignee) > maxAssigneeLength {
+			sendError(w, "Invalid assignee", http.StatusBadRequest, "assignee is required and must be at most 200 characters")
+			return
+		}
+	}
+
+	record, err := updateIssueRecord(siteHostParam(r), fingerprint, func(record *IssueRecord) error {
+		record.Assignee = req.Assignee
+		return nil
+	})
+	if err != nil {
+		sendIssueRecordError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// handleIssueComments handles GET and POST /api/v1/sites/{host}/issues/{fingerprint}/comments requests
+func handleIssueComments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+		return
+	}
+
+	host := siteHostParam(r)
+	fingerprint := r.PathValue("fingerprint")
+	if !fingerprintPattern.MatchString(fingerprint) {
+		sendError(w, "Invalid fingerprint", http.StatusBadRequest, "fingerprint must be a 16 character hex string")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		issues := SiteIssues{}
+		if err := siteStore.Load(host, "issues", &issues); err != nil {
+			sendSiteStoreError(w, err)
+			return
+		}
+		record, ok := issues[fingerprint]
+		if !ok {
+			sendIssueRecordError(w, errIssueNotTracked)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(threadComments(record.Comments))
+		return
+	}
+
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return
+	}
+	req.Author = strings.TrimSpace(req.Author)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Author == "" || len(req.Author) > maxAssigneeLength {
+		sendError(w, "Invalid author", http.StatusBadRequest, "author is required and must be at most 200 characters")
+		return
+	}
+	if req.Body == "" || len(req.Body) > maxCommentLength {
+		sendError(w, "Invalid body", http.StatusBadRequest, "body is required and must be at most 10000 characters")
+		return
+	}
+
+	comment := Comment{
+		ID:        newCommentID(),
+		ParentID:  req.ParentID,
+		Author:    req.Author,
+		Body:      req.Body,
+		CreatedAt: time.Now().UTC(),
+	}
+	_, err := updateIssueRecord(host, fingerprint, func(record *IssueRecord) error {
+		if comment.ParentID != "" {
+			found := false
+			for _, existing := range record.Comments {
+				if existing.ID == comment.ParentID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return errParentNotFound
+			}
+		}
+		record.Comments = append(record.Comments, comment)
+		return nil
+	})
+	if err != nil {
+		sendIssueRecordError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// sendIssueRecordError reports a failure to read or update a tracked issue
+func sendIssueRecordError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errIssueNotTracked):
+		sendError(w, "Issue not found", http.StatusNotFound, "No issue with this fingerprint has been seen on this site")
+	case errors.Is(err, errParentNotFound):
+		sendError(w, "Invalid parent_id", http.StatusBadRequest, "parent_id must refer to an existing comment on this issue")
+	default:
+		sendSiteStoreError(w, err)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComparedScan is one side of a scan comparison
+type ComparedScan struct {
+	ScanID         string            `json:"scan_id"`
+	Site           string            `json:"site"`
+	ScanTime       time.Time         `json:"scan_time"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	SiteScore      float64           `json:"site_score"`
+	AverageScore   float64           `json:"average_score"`
+	PagesScanned   int               `json:"pages_scanned"`
+	TotalIssues    int               `json:"total_issues"`
+	IssuesPerPage  float64           `json:"issues_per_page"`
+	IssuesByImpact map[string]int    `json:"issues_by_impact"`
+}
+
+// AuditComparison counts the issues and affected pages of one audit on
+// each side of a comparison
+type AuditComparison struct {
+	AuditID string `json:"audit_id"`
+	Title   string `json:"title"`
+	Impact  string `json:"impact"` // most severe impact seen
+	IssuesA int    `json:"issues_a"`
+	IssuesB int    `json:"issues_b"`
+	PagesA  int    `json:"pages_a"`
+	PagesB  int    `json:"pages_b"`
+}
+
+// ScanComparison sets two scans side by side, typically the latest scans of
+// two sites, or of one site's production and staging hosts
+type ScanComparison struct {
+	A                   ComparedScan      `json:"a"`
+	B                   ComparedScan      `json:"b"`
+	SiteScoreDifference float64           `json:"site_score_difference"` // b minus a
+	SharedAudits        []AuditComparison `json:"shared_audits"`         // failing in both
+	OnlyA               []AuditComparison `json:"only_a"`
+	OnlyB               []AuditComparison `json:"only_b"`
+}
+
+// compareSide describes one scan of a comparison
+func compareSide(result ScanResult) ComparedScan {
+	side := ComparedScan{
+		ScanID:         result.ID,
+		Site:           siteKey(result.BaseURL),
+		ScanTime:       result.ScanTime,
+		Tags:           result.Tags,
+		SiteScore:      result.SiteScore,
+		AverageScore:   result.Summary.AverageScore,
+		PagesScanned:   result.Summary.PagesScanned,
+		TotalIssues:    result.Summary.TotalIssues,
+		IssuesByImpact: result.Summary.IssuesByImpact,
+	}
+	if result.Site != nil {
+		side.Site = result.Site.Host
+	}
+	if side.PagesScanned > 0 {
+		side.IssuesPerPage = math.Round(float64(side.TotalIssues)/float64(side.PagesScanned)*10) / 10
+	}
+	return side
+}
+
+// compareScans compares the failing audits of two scans
+func compareScans(a, b ScanResult) ScanComparison {
+	comparison := ScanComparison{
+		A:                   compareSide(a),
+		B:                   compareSide(b),
+		SiteScoreDifference: math.Round((b.SiteScore-a.SiteScore)*1000) / 1000,
+		SharedAudits:        make([]AuditComparison, 0),
+		OnlyA:               make([]AuditComparison, 0),
+		OnlyB:               make([]AuditComparison, 0),
+	}
+
+	audits := make(map[string]*AuditComparison)
+	count := func(result ScanResult, issues, pages func(*AuditComparison) *int) {
+		for _, page := range result.PageResults {
+			onPage := make(map[string]bool)
+			for _, issue := range page.Issues {
+				audit := audits[issue.AuditID]
+				if audit == nil {
+					audit = &AuditComparison{AuditID: issue.AuditID, Title: issue.Title}
+					audits[issue.AuditID] = audit
+				}
+				if impactWeights[issue.Impact] > impactWeights[audit.Impact] {
+					audit.Impact = issue.Impact
+				}
+				*issues(audit)++
+				if !onPage[issue.AuditID] {
+					onPage[issue.AuditID] = true
+					*pages(audit)++
+				}
+			}
+		}
+	}
+	count(a, func(c *AuditComparison) *int { return &c.IssuesA }, func(c *AuditComparison) *int { return &c.PagesA })
+	count(b, func(c *AuditComparison) *int { return &c.IssuesB }, func(c *AuditComparison) *int { return &c.PagesB })
+
+	for _, audit := range audits {
+		switch {
+		case audit.IssuesA > 0 && audit.IssuesB > 0:
+			comparison.SharedAudits = append(comparison.SharedAudits, *audit)
+		case audit.IssuesA > 0:
+			comparison.OnlyA = append(comparison.OnlyA, *audit)
+		default:
+			comparison.OnlyB = append(comparison.OnlyB, *audit)
+		}
+	}
+	// Most severe first, then the most issues
+	for _, list := range [][]AuditComparison{comparison.SharedAudits, comparison.OnlyA, comparison.OnlyB} {
+		sort.Slice(list, func(i, j int) bool {
+			if wi, wj := impactWeights[list[i].Impact], impactWeights[list[j].Impact]; wi != wj {
+				return wi > wj
+			}
+			if ni, nj := list[i].IssuesA+list[i].IssuesB, list[j].IssuesA+list[j].IssuesB; ni != nj {
+				return ni > nj
+			}
+			return list[i].AuditID < list[j].AuditID
+		})
+	}
+	return comparison
+}
+
+// loadComparedScan loads one side of a comparison: a scan ID, or the host of
+// a site whose latest scan with the given tags is used
+func loadComparedScan(w http.ResponseWriter, r *http.Request, side string) (ScanResult, bool) {
+	query := r.URL.Query()
+	target := asciiHost(strings.TrimSpace(query.Get(side)))
+	if target == "" {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, fmt.Sprintf("%s must be a site host or a scan ID", side))
+		return ScanResult{}, false
+	}
+	filters, err := parseTagFilters(query["tag_"+side])
+	if err != nil {
+		sendError(w, "Invalid tag", http.StatusBadRequest, err.Error())
+		return ScanResult{}, false
+	}
+
+	var result ScanResult
+	switch {
+	case scanIDPattern.MatchString(target):
+		result, err = scanStore.Get(target)
+	case validSiteHost(target):
+		result, err = latestTaggedScan(target, filters)
+	default:
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, fmt.Sprintf("%s must be a site host or a scan ID", side))
+		return ScanResult{}, false
+	}
+	if errors.Is(err, errNoSiteScan) || errors.Is(err, errScanNotFound) {
+		sendError(w, "Scan not found", http.StatusNotFound, fmt.Sprintf("No completed scan matches %s=%s", side, target))
+		return result, false
+	}
+	if err != nil {
+		logAt(logLevelError, "Failed to load scan %s for a comparison: %v", target, err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the scans to compare")
+		return result, false
+	}
+	return result, true
+}
+
+// handleCompare handles GET /api/v1/compare requests, comparing the latest
+// scans of two sites, or two given scans
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	a, ok := loadComparedScan(w, r, "a")
+	if !ok {
+		return
+	}
+	b, ok := loadComparedScan(w, r, "b")
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, compareScans(a, b))
+}
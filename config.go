@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read when present and no other config file is given
+const defaultConfigFile = "config.yaml"
+
+// Config holds the service settings. Values are layered with increasing
+// precedence: defaults, config file, environment variables (including .env),
+// then command-line flags.
+type Config struct {
+	Port               string           `yaml:"port"`
+	DataDir            string           `yaml:"data_dir"`
+	GoogleAPIKey       string           `yaml:"google_api_key"`
+	GoogleCredentials  string           `yaml:"google_credentials"` // OAuth credentials JSON file, or "metadata"
+	RemediationFile    string           `yaml:"remediation_file"`
+	CustomRulesFile    string           `yaml:"custom_rules_file"`
+	MaxConcurrentScans int              `yaml:"max_concurrent_scans"`
+	MaxQueuedScans     *int             `yaml:"max_queued_scans"` // 0 rejects scans instead of queueing them
+	TrustedProxies     []string         `yaml:"trusted_proxies"`
+	LogLevel           string           `yaml:"log_level"`
+	AdminToken         string           `yaml:"admin_token"`
+	ShareSecret        string           `yaml:"share_secret"` // signs report share links; changing it revokes them
+	APIKeys            []APIClient      `yaml:"api_keys"`
+	ProjectQuotas      map[string]Quota `yaml:"project_quotas"`
+	SkipExtensions     []string         `yaml:"skip_extensions"`     // file extensions never crawled (default: documents, media, archives, feeds)
+	SkipPaths          []string         `yaml:"skip_paths"`          // URL path patterns never crawled (default: feeds, xmlrpc.php, wp-json, wp-admin)
+	URLTrailingSlash   string           `yaml:"url_trailing_slash"`  // "strip" (default) or "add"
+	URLLowercasePaths  bool             `yaml:"url_lowercase_paths"` // treat /About and /about as one page
+
+	RetentionDays          int `yaml:"retention_days"`
+	RetentionMaxScans      int `yaml:"retention_max_scans_per_site"`
+	JanitorIntervalMinutes int `yaml:"janitor_interval_minutes"`
+	SecretsRefreshMinutes  int `yaml:"secrets_refresh_minutes"`
+
+	EncryptionKeys     []string `yaml:"encryption_keys"`     // id:base64key entries; the first encrypts new records
+	StorageCompression string   `yaml:"storage_compression"` // "gzip" (default), "zstd" or "none"
+
+	TLS       TLSConfig       `yaml:"tls"`
+	EventBus  EventBusConfig  `yaml:"event_bus"`
+	AccessLog AccessLogConfig `yaml:"access_log"`
+
+	file       string             // config file the settings were read from, if any
+	proxyNets  []*net.IPNet       // parsed TrustedProxies
+	assets     *assetFilter       // parsed SkipExtensions and SkipPaths
+	urls       *urlNormalizer     // parsed URLTrailingSlash and URLLowercasePaths
+	logVerbose int                // parsed LogLevel
+	secrets    []secretField      // settings read from a secrets manager
+	keys       *keyring           // parsed EncryptionKeys
+	rules      []*CustomRule      // rules of CustomRulesFile
+	google     *googleCredentials // parsed GoogleCredentials
+}
+
+// configSetting binds a config field to its environment variables and flag
+type configSetting struct {
+	env   []string // environment variables, first set one wins
+	flag  string   // command-line flag, if any
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// configSettings lists every setting that can come from the environment or flags
+var configSettings = []configSetting{
+	{[]string{"PORT"}, "port", "HTTP(S) port (default: 8080, or 443 with autocert)",
+		func(c *Config, v string) error { c.Port = v; return nil }},
+	{[]string{"DATA_DIR"}, "data-dir", "directory for stored scans and site data (default: data)",
+		func(c *Config, v string) error { c.DataDir = v; return nil }},
+	{[]string{"GOOGLE_API_KEY", "PAGESPEED_API_KEY", "LIGHTHOUSE_API_KEY"}, "", "",
+		func(c *Config, v string) error { c.GoogleAPIKey = v; return nil }},
+	{[]string{"GOOGLE_CREDENTIALS", "GOOGLE_APPLICATION_CREDENTIALS"}, "google-credentials", "service account or authorized user JSON file, or \"metadata\", authenticating PageSpeed Insights requests with OAuth",
+		func(c *Config, v string) error { c.GoogleCredentials = v; return nil }},
+	{[]string{"REMEDIATION_FILE"}, "remediation-file", "YAML file overriding the remediation guidance",
+		func(c *Config, v string) error { c.RemediationFile = v; return nil }},
+	{[]string{"CUSTOM_RULES_FILE"}, "custom-rules-file", "YAML or JSON file of custom rules checked on every crawled page",
+		func(c *Config, v string) error { c.CustomRulesFile = v; return nil }},
+	{[]string{"MAX_CONCURRENT_SCANS"}, "max-concurrent-scans", "full-site scans running at once (default: 2)",
+		func(c *Config, v string) error { return setInt(&c.MaxConcurrentScans, v) }},
+	{[]string{"MAX_QUEUED_SCANS"}, "max-queued-scans", "scans waiting for a slot (default: 10)",
+		func(c *Config, v string) error { c.MaxQueuedScans = new(int); return setInt(c.MaxQueuedScans, v) }},
+	{[]string{"TRUSTED_PROXIES"}, "trusted-proxies", "comma-separated proxy CIDRs whose forwarding headers are honored",
+		func(c *Config, v string) error { c.TrustedProxies = splitValues(v); return nil }},
+	{[]string{"LOG_LEVEL"}, "log-level", "debug, info, warn or error (default: info)",
+		func(c *Config, v string) error { c.LogLevel = v; return nil }},
+	{[]string{"ADMIN_TOKEN"}, "", "",
+		func(c *Config, v string) error { c.AdminToken = v; return nil }},
+	{[]string{"SHARE_SECRET"}, "", "",
+		func(c *Config, v string) error { c.ShareSecret = v; return nil }},
+	{[]string{"API_KEYS"}, "", "",
+		func(c *Config, v string) (err error) { c.APIKeys, err = parseAPIClients(v); return err }},
+	{[]string{"SKIP_EXTENSIONS"}, "skip-extensions", "comma-separated file extensions the crawler never follows (default: documents, images, media, archives, feeds)",
+		func(c *Config, v string) error { c.SkipExtensions = splitValues(v); return nil }},
+	{[]string{"SKIP_PATHS"}, "skip-paths", "comma-separated URL path patterns the crawler never follows (default: feeds, /xmlrpc.php, /wp-json/*, ...)",
+		func(c *Config, v string) error { c.SkipPaths = splitValues(v); return nil }},
+	{[]string{"URL_TRAILING_SLASH"}, "url-trailing-slash", "how crawled URLs end: strip (default) or add a trailing slash",
+		func(c *Config, v string) error { c.URLTrailingSlash = v; return nil }},
+	{[]string{"URL_LOWERCASE_PATHS"}, "url-lowercase-paths", "lowercase crawled URL paths, for servers with case-insensitive paths",
+		func(c *Config, v string) (err error) { c.URLLowercasePaths, err = strconv.ParseBool(v); return err }},
+	{[]string{"RETENTION_DAYS"}, "retention-days", "delete stored scans older than this many days (default: keep forever)",
+		func(c *Config, v string) error { return setInt(&c.RetentionDays, v) }},
+	{[]string{"RETENTION_MAX_SCANS_PER_SITE"}, "retention-max-scans", "keep only the newest N stored scans per site (default: all)",
+		func(c *Config, v string) error { return setInt(&c.RetentionMaxScans, v) }},
+	{[]string{"JANITOR_INTERVAL_MINUTES"}, "", "",
+		func(c *Config, v string) error { return setInt(&c.JanitorIntervalMinutes, v) }},
+	{[]string{"SECRETS_REFRESH_MINUTES"}, "", "",
+		func(c *Config, v string) error { return setInt(&c.SecretsRefreshMinutes, v) }},
+	{[]string{"ENCRYPTION_KEYS"}, "", "",
+		func(c *Config, v string) error { c.EncryptionKeys = splitValues(v); return nil }},
+	{[]string{"STORAGE_COMPRESSION"}, "storage-compression", "how stored scans and raw reports are compressed: gzip (default), zstd or none",
+		func(c *Config, v string) error { c.StorageCompression = v; return nil }},
+	{[]string{"TLS_CERT_FILE"}, "tls-cert", "TLS certificate file",
+		func(c *Config, v string) error { c.TLS.CertFile = v; return nil }},
+	{[]string{"TLS_KEY_FILE"}, "tls-key", "TLS private key file",
+		func(c *Config, v string) error { c.TLS.KeyFile = v; return nil }},
+	{[]string{"AUTOCERT_DOMAINS"}, "autocert-domains", "comma-separated domains to obtain Let's Encrypt certificates for",
+		func(c *Config, v string) error { c.TLS.Domains = splitValues(v); return nil }},
+	{[]string{"AUTOCERT_EMAIL"}, "", "",
+		func(c *Config, v string) error { c.TLS.Email = v; return nil }},
+	{[]string{"AUTOCERT_CACHE_DIR"}, "", "",
+		func(c *Config, v string) error { c.TLS.CacheDir = v; return nil }},
+	{[]string{"HTTP_REDIRECT_PORT"}, "http-redirect-port", "plain HTTP port redirecting to HTTPS",
+		func(c *Config, v string) error { c.TLS.RedirectPort = v; return nil }},
+	{[]string{"EVENT_BUS_DRIVER"}, "event-bus", "publish scan events to nats or kafka (default: disabled)",
+		func(c *Config, v string) error { c.EventBus.Driver = strings.ToLower(v); return nil }},
+	{[]string{"EVENT_BUS_URL"}, "event-bus-url", "NATS server URL, or Kafka REST Proxy base URL",
+		func(c *Config, v string) error { c.EventBus.URL = v; return nil }},
+	{[]string{"EVENT_BUS_TOPIC_PREFIX"}, "", "",
+		func(c *Config, v string) error { c.EventBus.TopicPrefix = v; return nil }},
+	{[]string{"EVENT_BUS_SKIP_PAGES"}, "", "",
+		func(c *Config, v string) (err error) { c.EventBus.SkipPages, err = strconv.ParseBool(v); return err }},
+	{[]string{"EVENT_BUS_KAFKA_API_KEY"}, "", "",
+		func(c *Config, v string) error { c.EventBus.KafkaAPIKey = v; return nil }},
+	{[]string{"EVENT_BUS_KAFKA_SECRET"}, "", "",
+		func(c *Config, v string) error { c.EventBus.KafkaSecret = v; return nil }},
+	{[]string{"ACCESS_LOG_FILE"}, "access-log", "write an access log to this file (default: disabled)",
+		func(c *Config, v string) error { c.AccessLog.File = v; return nil }},
+	{[]string{"ACCESS_LOG_FORMAT"}, "access-log-format", "access log format: combined or json (default: combined)",
+		func(c *Config, v string) error { c.AccessLog.Format = strings.ToLower(v); return nil }},
+	{[]string{"ACCESS_LOG_MAX_SIZE_MB"}, "", "",
+		func(c *Config, v string) error { return setInt(&c.AccessLog.MaxSizeMB, v) }},
+	{[]string{"ACCESS_LOG_ROTATE"}, "", "",
+		func(c *Config, v string) error { c.AccessLog.Rotate = strings.ToLower(v); return nil }},
+	{[]string{"ACCESS_LOG_MAX_BACKUPS"}, "", "",
+		func(c *Config, v string) error { return setInt(&c.AccessLog.MaxBackups, v) }},
+}
+
+// Log levels
+const (
+	logLevelDebug = iota
+	logLevelInfo
+	logLevelWarn
+	logLevelError
+)
+
+// logLevels maps log level names to levels
+var logLevels = map[string]int{
+	"debug": logLevelDebug,
+	"info":  logLevelInfo,
+	"warn":  logLevelWarn,
+	"error": logLevelError,
+}
+
+// activeConfig holds the current configuration; reloads swap it atomically
+var activeConfig atomic.Pointer[Config]
+
+// currentConfig returns the active configuration
+func currentConfig() *Config {
+	if config := activeConfig.Load(); config != nil {
+		return config
+	}
+	return &Config{logVerbose: logLevelInfo}
+}
+
+// logAt logs a message when level is at or above the configured log level
+func logAt(level int, format string, args ...any) {
+	if level >= currentConfig().logVerbose {
+		log.Printf(format, args...)
+	}
+}
+
+// setInt parses an integer setting
+func setInt(target *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("must be an integer")
+	}
+	*target = n
+	return nil
+}
+
+// splitValues parses a comma-separated setting into a list
+func splitValues(value string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// envFileKeys are the variables set from .env rather than by the process
+// environment, so a reload can pick up edits to the file
+var envFileKeys = make(map[string]bool)
+
+// loadEnvFile loads KEY=VALUE lines from a file into the environment.
+// Variables set by the process environment take precedence over the file.
+func loadEnvFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		if (strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)) ||
+			(strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`)) {
+			value = value[1 : len(value)-1]
+		}
+
+		if _, set := os.LookupEnv(key); !set || envFileKeys[key] {
+			os.Setenv(key, value)
+			envFileKeys[key] = true
+		}
+	}
+
+	return scanner.Err()
+}
+
+// loadConfig builds the configuration from defaults, the config file, the
+// environment and the command-line arguments
+func loadConfig(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("accessibility-scanner-api", flag.ContinueOnError)
+	configFile := fs.String("config", "", "YAML config file (default: $CONFIG_FILE or "+defaultConfigFile+" if present)")
+	flagValues := make(map[string]*string)
+	for _, setting := range configSettings {
+		if setting.flag != "" {
+			flagValues[setting.flag] = fs.String(setting.flag, "", setting.usage)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+
+	// Config file
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err == nil {
+			path = defaultConfigFile
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		config.file = path
+	}
+
+	// Environment variables
+	for _, setting := range configSettings {
+		for _, name := range setting.env {
+			if value := os.Getenv(name); value != "" {
+				if err := setting.set(config, value); err != nil {
+					return nil, fmt.Errorf("%s %v", name, err)
+				}
+				break
+			}
+		}
+	}
+
+	// Command-line flags
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, setting := range configSettings {
+			if setting.flag == f.Name && flagErr == nil {
+				if err := setting.set(config, *flagValues[f.Name]); err != nil {
+					flagErr = fmt.Errorf("-%s %v", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	// Secret references such as vault:secret/data/scanner#google_api_key
+	if err := config.resolveSecrets(); err != nil {
+		return nil, fmt.Errorf("fetching secrets: %w", err)
+	}
+
+	if err := config.applyDefaults(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// applyDefaults fills in unset values and validates the configuration
+func (c *Config) applyDefaults() error {
+	if c.DataDir == "" {
+		c.DataDir = "data"
+	}
+	if c.MaxConcurrentScans == 0 {
+		c.MaxConcurrentScans = defaultMaxConcurrentScans
+	}
+	if c.MaxQueuedScans == nil {
+		queued := defaultMaxQueuedScans
+		c.MaxQueuedScans = &queued
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = "info"
+	}
+	if c.JanitorIntervalMinutes == 0 {
+		c.JanitorIntervalMinutes = defaultJanitorIntervalMinutes
+	}
+	if c.SecretsRefreshMinutes == 0 {
+		c.SecretsRefreshMinutes = defaultSecretsRefreshMinutes
+	}
+	if c.SkipExtensions == nil {
+		c.SkipExtensions = defaultSkipExtensions
+	}
+	if c.SkipPaths == nil {
+		c.SkipPaths = defaultSkipPaths
+	}
+	c.assets = newAssetFilter(c.SkipExtensions, c.SkipPaths)
+	if c.URLTrailingSlash == "" {
+		c.URLTrailingSlash = trailingSlashStrip
+	}
+	if c.StorageCompression == "" {
+		c.StorageCompression = compressionGzip
+	}
+
+	if c.MaxConcurrentScans < 1 {
+		return fmt.Errorf("max_concurrent_scans must be at least 1")
+	}
+	if *c.MaxQueuedScans < 0 {
+		return fmt.Errorf("max_queued_scans cannot be negative")
+	}
+	if c.RetentionDays < 0 || c.RetentionMaxScans < 0 {
+		return fmt.Errorf("retention_days and retention_max_scans_per_site cannot be negative")
+	}
+	if c.JanitorIntervalMinutes < 1 {
+		return fmt.Errorf("janitor_interval_minutes must be at least 1")
+	}
+	if c.SecretsRefreshMinutes < 1 {
+		return fmt.Errorf("secrets_refresh_minutes must be at least 1")
+	}
+	urls, err := newURLNormalizer(c.URLTrailingSlash, c.URLLowercasePaths)
+	if err != nil {
+		return err
+	}
+	c.urls = urls
+	if err := validateStorageCompression(c.StorageCompression); err != nil {
+		return err
+	}
+	keys, err := parseEncryptionKeys(c.EncryptionKeys)
+	if err != nil {
+		return fmt.Errorf("encryption_keys: %w", err)
+	}
+	c.keys = keys
+	rules, err := loadCustomRules(c.CustomRulesFile)
+	if err != nil {
+		return fmt.Errorf("custom_rules_file: %w", err)
+	}
+	c.rules = rules
+	google, err := loadGoogleCredentials(c.GoogleCredentials)
+	if err != nil {
+		return fmt.Errorf("google_credentials: %w", err)
+	}
+	c.google = google
+	if c.ShareSecret != "" && len(c.ShareSecret) < minShareSecretLen {
+		return fmt.Errorf("share_secret must be at least %d characters", minShareSecretLen)
+	}
+	if err := validateAPIClients(c.APIKeys); err != nil {
+		return fmt.Errorf("api_keys: %w", err)
+	}
+	for project, quota := range c.ProjectQuotas {
+		if quota.ScansPerDay < 0 || quota.PagesPerDay < 0 || quota.MaxConcurrentScans < 0 {
+			return fmt.Errorf("project_quotas: quota of project %q cannot be negative", project)
+		}
+	}
+	level, ok := logLevels[strings.ToLower(c.LogLevel)]
+	if !ok {
+		return fmt.Errorf("log_level must be one of: debug, info, warn, error")
+	}
+	c.logVerbose = level
+
+	nets, err := parseTrustedProxies(strings.Join(c.TrustedProxies, ","))
+	if err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+	c.proxyNets = nets
+
+	if err := c.TLS.validate(c.DataDir); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	if err := c.EventBus.validate(); err != nil {
+		return fmt.Errorf("event_bus: %w", err)
+	}
+	if err := c.AccessLog.validate(); err != nil {
+		return fmt.Errorf("access_log: %w", err)
+	}
+	if c.Port == "" && len(c.TLS.Domains) > 0 {
+		c.Port = "443"
+	} else if c.Port == "" {
+		c.Port = "8080"
+	}
+	return nil
+}
+
+// restartRequired lists settings that differ from next but only take effect on restart
+func (c *Config) restartRequired(next *Config) []string {
+	changed := make([]string, 0)
+	if c.Port != next.Port {
+		changed = append(changed, "port")
+	}
+	if c.DataDir != next.DataDir {
+		changed = append(changed, "data_dir")
+	}
+	if c.RemediationFile != next.RemediationFile {
+		changed = append(changed, "remediation_file")
+	}
+	if fmt.Sprint(c.TLS) != fmt.Sprint(next.TLS) {
+		changed = append(changed, "tls")
+	}
+	if c.EventBus != next.EventBus {
+		changed = append(changed, "event_bus")
+	}
+	if c.AccessLog != next.AccessLog {
+		changed = append(changed, "access_log")
+	}
+	return changed
+}
+
+// reloadConfig re-reads .env and the configuration and applies the settings that
+// can change at runtime: the API key and credentials, admin token, share secret,
+// client API keys and quotas, encryption keys, custom rules, scan queue limits,
+// trusted proxies, retention policy and log level
+func reloadConfig(args []string) error {
+	if err := loadEnvFile(".env"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading .env: %w", err)
+	}
+	next, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	previous := currentConfig()
+	if changed := previous.restartRequired(next); len(changed) > 0 {
+		logAt(logLevelWarn, "Warning: Changes to %s take effect after a restart", strings.Join(changed, ", "))
+		next.Port = previous.Port
+		next.DataDir = previous.DataDir
+		next.RemediationFile = previous.RemediationFile
+		next.TLS = previous.TLS
+	}
+
+	activeConfig.Store(next)
+	scanQueue.SetLimits(next.MaxConcurrentScans, *next.MaxQueuedScans)
+	return nil
+}
+
+// watchReloadSignal reloads the configuration whenever the process receives SIGHUP
+func watchReloadSignal(args []string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if err := reloadConfig(args); err != nil {
+				logAt(logLevelError, "Configuration reload failed, keeping current settings: %v", err)
+				continue
+			}
+			config := currentConfig()
+			log.Printf("🔄 Configuration reloaded: %d concurrent scans, %d queued, log level %s",
+				config.MaxConcurrentScans, *config.MaxQueuedScans, config.LogLevel)
+		}
+	}()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// errContinuationNotFound is returned for unknown or already used continuation tokens
+var errContinuationNotFound = errors.New("continuation token not found")
+
+// CrawlFrontier is the saved state of a crawl that stopped early, from which
+// a later request can resume instead of starting over
+type CrawlFrontier struct {
+	Token          string         `json:"token"`
+	ScanID         string         `json:"scan_id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Request        ScanRequest    `json:"request"`
+	Queue          []string       `json:"queue"`
+	URLIndex       int            `json:"url_index"`
+	Visited        []string       `json:"visited"`
+	Depth          map[string]int `json:"depth"`
+	UrlsDiscovered []string       `json:"urls_discovered"`
+	Store          bool           `json:"store,omitempty"` // the crawl state is in a disk-backed frontier file
+
+	pages     []PageResult    // page results of the scan being continued
+	sampling  *SamplingReport // sampling report of the scan being continued
+	storePath string          // disk-backed frontier to keep with the continuation
+}
+
+// captureFrontier records the crawl state so the scan can be resumed later.
+// A disk-backed frontier is kept as a file once the crawl closes it.
+func (s *AccessibilityScanner) captureFrontier(urlIndex int) {
+	s.frontier = &CrawlFrontier{
+		Token:     newScanID(),
+		CreatedAt: time.Now().UTC(),
+		URLIndex:  urlIndex,
+	}
+	memory, ok := s.urls.(*memoryURLStore)
+	if !ok {
+		return
+	}
+	visited := make([]string, 0, len(memory.depth))
+	for pageURL := range memory.depth {
+		visited = append(visited, pageURL)
+	}
+	s.frontier.Queue = append([]string{}, memory.queue...)
+	s.frontier.Visited = visited
+	s.frontier.Depth = memory.depth
+	s.frontier.UrlsDiscovered = memory.order
+}
+
+// resume restores the crawl state of a frontier into the scanner
+func (s *AccessibilityScanner) resume(frontier *CrawlFrontier) error {
+	s.resumed = frontier
+	if frontier.ScanID != "" {
+		if err := scanStore.LinkRawReports(frontier.ScanID, s.id); err != nil {
+			logAt(logLevelWarn, "Warning: Could not carry over the Lighthouse reports of scan %s: %v", frontier.ScanID, err)
+		}
+	}
+	if frontier.Store {
+		path, err := scanStore.TakeFrontierStore(frontier.Token, s.id)
+		if err != nil {
+			return err
+		}
+		urls, err := openDiskURLStore(path)
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+		s.urls = urls
+		return nil
+	}
+
+	for _, pageURL := range frontier.Visited {
+		s.urls.Visit(pageURL, frontier.Depth[pageURL])
+	}
+	for _, pageURL := range frontier.UrlsDiscovered {
+		s.urls.Discover(pageURL)
+	}
+	for _, pageURL := range frontier.Queue {
+		s.urls.Push(pageURL)
+	}
+	return nil
+}
+
+// useDiskFrontier switches the scanner to a disk-backed frontier
+func (s *AccessibilityScanner) useDiskFrontier() error {
+	urls, err := openDiskURLStore(scanStore.workingFrontierPath(s.id))
+	if err != nil {
+		return err
+	}
+	s.urls = urls
+	return nil
+}
+
+// frontierMode reports where the scan keeps its crawl frontier
+func (s *AccessibilityScanner) frontierMode() string {
+	if _, disk := s.urls.(*diskURLStore); disk {
+		return frontierDisk
+	}
+	return frontierMemory
+}
+
+// closeFrontier releases the crawl frontier once the crawl is over, keeping a
+// disk-backed one when the scan can be continued
+func (s *AccessibilityScanner) closeFrontier() {
+	path, err := s.urls.Close(s.frontier != nil)
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not keep crawl frontier: %v", err)
+		if _, disk := s.urls.(*diskURLStore); disk {
+			s.frontier = nil
+		}
+		return
+	}
+	if s.frontier != nil {
+		s.frontier.storePath = path
+	}
+}
+
+// resumedPages returns how many page results were carried over from the continued scan
+func (s *AccessibilityScanner) resumedPages() int {
+	if s.resumed == nil {
+		return 0
+	}
+	return len(s.resumed.pages)
+}
+
+// frontierPath returns the file path of a saved frontier
+func (s *ScanStore) frontierPath(token string) string {
+	return filepath.Join(s.dir, "continuations", token+".json")
+}
+
+// SaveFrontier stores a crawl frontier under its token
+func (s *ScanStore) SaveFrontier(frontier *CrawlFrontier) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.frontierPath(frontier.Token)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if frontier.storePath != "" {
+		if err := os.Rename(frontier.storePath, s.frontierStorePath(frontier.Token)); err != nil {
+			os.Remove(frontier.storePath)
+			return err
+		}
+		frontier.Store = true
+	}
+	data, err := json.Marshal(frontier)
+	if err != nil {
+		return err
+	}
+	return writeRecord(path, data)
+}
+
+// GetFrontier loads a saved crawl frontier by token
+func (s *ScanStore) GetFrontier(token string) (*CrawlFrontier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !scanIDPattern.MatchString(token) {
+		return nil, errContinuationNotFound
+	}
+	data, err := readRecord(s.frontierPath(token))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errContinuationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var frontier CrawlFrontier
+	if err := json.Unmarshal(data, &frontier); err != nil {
+		return nil, err
+	}
+	return &frontier, nil
+}
+
+// DeleteFrontier removes a frontier once its scan has been resumed
+func (s *ScanStore) DeleteFrontier(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.frontierStorePath(token)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	err := os.Remove(s.frontierPath(token))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// customRulePrefix starts the audit IDs of custom rules, so they never clash
+// with Lighthouse audits
+const customRulePrefix = "custom-"
+
+// customRuleImpacts are the impacts a custom rule can report, as in axe-core
+var customRuleImpacts = []string{"critical", "serious", "moderate", "minor"}
+
+// CustomRule is a client-specific requirement checked on every crawled page:
+// the elements matching a CSS selector must meet a condition
+type CustomRule struct {
+	ID          string        `yaml:"id"`
+	Selector    string        `yaml:"selector"`
+	Condition   RuleCondition `yaml:"condition"`
+	Message     string        `yaml:"message"`               // issue title
+	Description string        `yaml:"description,omitempty"` // how to fix it
+	Impact      string        `yaml:"impact"`                // critical, serious, moderate (default) or minor
+	Sites       []string      `yaml:"sites,omitempty"`       // hosts the rule applies to; empty for every site
+
+	selector cssSelector
+	pattern  *regexp.Regexp
+}
+
+// RuleCondition is what each element matching a rule's selector must meet.
+// Exactly one condition is set.
+type RuleCondition struct {
+	Forbidden    bool   `yaml:"forbidden,omitempty"`     // no element may match
+	HasAttribute string `yaml:"has_attribute,omitempty"` // the attribute is set and not blank
+	Attribute    string `yaml:"attribute,omitempty"`     // the attribute matches pattern
+	Pattern      string `yaml:"pattern,omitempty"`
+	Contains     string `yaml:"contains,omitempty"` // a descendant matches this selector
+	HasText      bool   `yaml:"has_text,omitempty"` // the element has text content
+	MinCount     int    `yaml:"min_count,omitempty"`
+	MaxCount     int    `yaml:"max_count,omitempty"`
+
+	contains cssSelector
+}
+
+// customRulesFile is the layout of a custom rules file
+type customRulesFile struct {
+	Rules []*CustomRule `yaml:"rules"`
+}
+
+// loadCustomRules reads and validates a YAML or JSON custom rules file
+func loadCustomRules(path string) ([]*CustomRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file customRulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	ids := make(map[string]bool)
+	for i, rule := range file.Rules {
+		if rule == nil {
+			return nil, fmt.Errorf("rules[%d] is empty", i)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("rules[%d]: id %q is used twice", i, rule.ID)
+		}
+		ids[rule.ID] = true
+	}
+	return file.Rules, nil
+}
+
+// compile validates a rule and parses its selectors and pattern
+func (rule *CustomRule) compile() error {
+	if !auditIDPattern.MatchString(rule.ID) {
+		return fmt.Errorf("id must be lowercase letters, digits and dashes, such as \"table-caption\"")
+	}
+	if strings.TrimSpace(rule.Message) == "" {
+		return errors.New("message is required")
+	}
+	if rule.Impact == "" {
+		rule.Impact = "moderate"
+	}
+	if !slices.Contains(customRuleImpacts, rule.Impact) {
+		return fmt.Errorf("impact must be one of: %s", strings.Join(customRuleImpacts, ", "))
+	}
+	for i, site := range rule.Sites {
+		rule.Sites[i] = strings.ToLower(site)
+	}
+
+	selector, err := parseSelector(rule.Selector)
+	if err != nil {
+		return err
+	}
+	rule.selector = selector
+
+	condition := &rule.Condition
+	set := 0
+	for _, isSet := range []bool{condition.Forbidden, condition.HasAttribute != "", condition.Attribute != "" || condition.Pattern != "",
+		condition.Contains != "", condition.HasText, condition.MinCount != 0, condition.MaxCount != 0} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("condition must set exactly one of forbidden, has_attribute, attribute with pattern, contains, has_text, min_count or max_count")
+	}
+	switch {
+	case condition.Attribute != "" || condition.Pattern != "":
+		if condition.Attribute == "" || condition.Pattern == "" {
+			return errors.New("condition.attribute and condition.pattern go together")
+		}
+		if rule.pattern, err = regexp.Compile(condition.Pattern); err != nil {
+			return fmt.Errorf("condition.pattern: %w", err)
+		}
+		condition.Attribute = strings.ToLower(condition.Attribute)
+	case condition.Contains != "":
+		if condition.contains, err = parseSelector(condition.Contains); err != nil {
+			return fmt.Errorf("condition.contains: %w", err)
+		}
+	case condition.MinCount < 0 || condition.MaxCount < 0:
+		return errors.New("condition.min_count and condition.max_count cannot be negative")
+	}
+	condition.HasAttribute = strings.ToLower(condition.HasAttribute)
+	return nil
+}
+
+// appliesTo reports whether a rule applies to a site
+func (rule *CustomRule) appliesTo(host string) bool {
+	return len(rule.Sites) == 0 || slices.Contains(rule.Sites, host)
+}
+
+// issue reports an element that breaks a rule; a nil element stands for the page
+func (rule *CustomRule) issue(n *html.Node) AccessibilityIssue {
+	issue := AccessibilityIssue{
+		AuditID:     customRulePrefix + rule.ID,
+		Title:       rule.Message,
+		Description: rule.Description,
+		Impact:      rule.Impact,
+	}
+	if n != nil {
+		issue.Selector = elementPath(n)
+		issue.Snippet = elementSnippet(n)
+	}
+	return issue
+}
+
+// check evaluates a rule on a page, returning an issue per offending element
+func (rule *CustomRule) check(doc *html.Node) []AccessibilityIssue {
+	condition := rule.Condition
+	matched := rule.selector.selectAll(doc)
+
+	var issues []AccessibilityIssue
+	switch {
+	case condition.MinCount > 0:
+		if len(matched) < condition.MinCount {
+			issues = append(issues, rule.issue(nil))
+		}
+	case condition.MaxCount > 0:
+		for _, n := range matched[min(condition.MaxCount, len(matched)):] {
+			issues = append(issues, rule.issue(n))
+		}
+	default:
+		for _, n := range matched {
+			if !condition.holds(n, rule.pattern) {
+				issues = append(issues, rule.issue(n))
+			}
+		}
+	}
+	return issues
+}
+
+// holds reports whether an element meets a per-element condition
+func (condition RuleCondition) holds(n *html.Node, pattern *regexp.Regexp) bool {
+	switch {
+	case condition.Forbidden:
+		return false
+	case condition.HasAttribute != "":
+		return strings.TrimSpace(attrValue(n, condition.HasAttribute)) != ""
+	case condition.Attribute != "":
+		value, ok := attrLookup(n, condition.Attribute)
+		return ok && pattern.MatchString(value)
+	case condition.Contains != "":
+		return len(condition.contains.selectAll(n)) > 0
+	case condition.HasText:
+		return strings.TrimSpace(textContent(n)) != ""
+	}
+	return true
+}
+
+// textContent returns the text of an element and its descendants
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// checkCustomRules evaluates the custom rules of a site on a page it crawled
+func (s *AccessibilityScanner) checkCustomRules(pageURL string, doc *html.Node) []AccessibilityIssue {
+	host := siteKey(pageURL)
+	var issues []AccessibilityIssue
+	for _, rule := range s.rules {
+		if rule.appliesTo(host) {
+			issues = append(issues, rule.check(doc)...)
+		}
+	}
+	return issues
+}
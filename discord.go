@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// discordTimeout bounds one Discord webhook call
+const discordTimeout = 10 * time.Second
+
+// Embed colors by site score, matching the score histogram buckets
+const (
+	discordColorExcellent = 0x2ECC71 // 90-100
+	discordColorGood      = 0xA3D977 // 80-90
+	discordColorNeedsWork = 0xF39C12 // 50-80
+	discordColorFailing   = 0xE74C3C // below 50, or a failed scan
+)
+
+// discordHosts are the hosts Discord serves webhooks from
+var discordHosts = map[string]bool{
+	"discord.com":        true,
+	"discordapp.com":     true,
+	"ptb.discord.com":    true,
+	"canary.discord.com": true,
+}
+
+// discordWebhook is the body of a Discord webhook message
+type discordWebhook struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+// discordEmbed is a rich message card
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+// discordField is a name/value pair shown in an embed
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// discordFooter is the small print under an embed
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// validate checks the notification targets are well-formed URLs
+func (n *NotificationSettings) validate() error {
+	if n == nil {
+		return nil
+	}
+	if n.WebhookURL != "" {
+		parsed, err := url.Parse(n.WebhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("webhook_url must be an absolute http(s) URL")
+		}
+	}
+	if n.DiscordWebhookURL != "" {
+		parsed, err := url.Parse(n.DiscordWebhookURL)
+		if err != nil || parsed.Scheme != "https" || !discordHosts[parsed.Host] || !strings.HasPrefix(parsed.Path, "/api/webhooks/") {
+			return errors.New("discord_webhook_url must be a Discord webhook URL (https://discord.com/api/webhooks/...)")
+		}
+	}
+	return nil
+}
+
+// discordWebhookID returns the webhook ID of a Discord webhook URL, the path
+// segment before its token
+func discordWebhookID(webhookURL string) string {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(parsed.Path, "/api/webhooks/"), "/")
+	return id
+}
+
+// redacted returns the settings as shown in responses. A Discord webhook URL
+// holds the token that posts to the channel, so only its ID is shown.
+func (n NotificationSettings) redacted() NotificationSettings {
+	if n.DiscordWebhookURL != "" {
+		n.DiscordWebhookID = discordWebhookID(n.DiscordWebhookURL)
+		n.DiscordWebhookURL = ""
+	}
+	return n
+}
+
+// keepDiscordWebhook completes settings written back as they were read: a
+// discord_webhook_id without a URL keeps the current webhook when the IDs
+// match. The ID itself is never stored.
+func (n *NotificationSettings) keepDiscordWebhook(current NotificationSettings) {
+	if n.DiscordWebhookURL == "" && n.DiscordWebhookID != "" && n.DiscordWebhookID == discordWebhookID(current.DiscordWebhookURL) {
+		n.DiscordWebhookURL = current.DiscordWebhookURL
+	}
+	n.DiscordWebhookID = ""
+}
+
+// discordColor picks the embed color for a scan
+func discordColor(result *ScanResult) int {
+	score := result.SiteScore * 100
+	switch {
+	case result.Status == "failed" || score < 50:
+		return discordColorFailing
+	case score < 80:
+		return discordColorNeedsWork
+	case score < 90:
+		return discordColorGood
+	default:
+		return discordColorExcellent
+	}
+}
+
+// discordEmbedFor summarizes a finished scan as an embed
+func discordEmbedFor(result *ScanResult) discordEmbed {
+	embed := discordEmbed{
+		Title:       "Accessibility scan of " + unicodeHost(siteKey(result.BaseURL)),
+		URL:         result.BaseURL,
+		Description: fmt.Sprintf("Scan `%s` finished with status **%s**.", result.ID, result.Status),
+		Color:       discordColor(result),
+		Footer:      &discordFooter{Text: "Accessibility Scanner API"},
+		Timestamp:   result.ScanTime.UTC().Format(time.RFC3339),
+	}
+	if result.StopReason != "" {
+		embed.Description += fmt.Sprintf(" Stopped early: %s.", result.StopReason)
+	}
+
+	embed.Fields = append(embed.Fields,
+		discordField{Name: "Site score", Value: fmt.Sprintf("%.0f / 100", result.SiteScore*100), Inline: true},
+		discordField{Name: "Pages scanned", Value: fmt.Sprint(result.TotalPages), Inline: true},
+		discordField{Name: "Issues", Value: fmt.Sprint(result.Summary.TotalIssues), Inline: true},
+	)
+	impacts := make([]string, 0, 4)
+	for _, impact := range []string{"critical", "serious", "moderate", "minor"} {
+		if count := result.Summary.IssuesByImpact[impact]; count > 0 {
+			impacts = append(impacts, fmt.Sprintf("%s: %d", impact, count))
+		}
+	}
+	if len(impacts) > 0 {
+		embed.Fields = append(embed.Fields, discordField{Name: "By impact", Value: strings.Join(impacts, "\n"), Inline: true})
+	}
+	if result.Summary.PagesWithErrors > 0 {
+		embed.Fields = append(embed.Fields, discordField{Name: "Pages with errors", Value: fmt.Sprint(result.Summary.PagesWithErrors), Inline: true})
+	}
+	if result.Thresholds != nil {
+		verdict := "✅ passed"
+		if !result.Thresholds.Passed {
+			failed := make([]string, 0, len(result.Thresholds.Failures))
+			for _, failure := range result.Thresholds.Failures {
+				failed = append(failed, failure.Threshold)
+			}
+			verdict = "❌ failed: " + strings.Join(failed, ", ")
+		}
+		embed.Fields = append(embed.Fields, discordField{Name: "Thresholds", Value: verdict})
+	}
+	return embed
+}
+
+// discordTargets returns the Discord webhooks configured for a scan's
+// project, site and profile, without duplicates
+func discordTargets(result *ScanResult) []string {
+	settings := make([]NotificationSettings, 0, 3)
+	if result.Site != nil {
+		if project, err := orgStore.Project(result.Site.ProjectID); err == nil {
+			settings = append(settings, project.Notifications)
+		}
+		if site, err := orgStore.Site(result.Site.Host); err == nil {
+			settings = append(settings, site.Notifications)
+		}
+	}
+	if result.ScanConfig.Profile != "" {
+		if profile, err := profileStore.Get(result.ScanConfig.Profile); err == nil {
+			settings = append(settings, profile.Notifications)
+		}
+	}
+
+	targets := make([]string, 0, len(settings))
+	seen := make(map[string]bool)
+	for _, s := range settings {
+		if s.DiscordWebhookURL != "" && !seen[s.DiscordWebhookURL] {
+			seen[s.DiscordWebhookURL] = true
+			targets = append(targets, s.DiscordWebhookURL)
+		}
+	}
+	return targets
+}
+
+// notifyDiscord announces a finished scan on the Discord webhooks configured
+// for it. Messages are sent in the background; failures are only logged.
+func notifyDiscord(result *ScanResult) {
+	if result.Status == "cancelled" {
+		return
+	}
+	targets := discordTargets(result)
+	if len(targets) == 0 {
+		return
+	}
+	id := result.ID
+	message := discordWebhook{Username: "Accessibility Scanner", Embeds: []discordEmbed{discordEmbedFor(result)}}
+	for _, target := range targets {
+		go func() {
+			if err := postDiscord(target, message); err != nil {
+				logAt(logLevelWarn, "Warning: Could not send Discord notification for scan %s: %v", id, err)
+			}
+		}()
+	}
+}
+
+// postDiscord sends a message to a Discord webhook
+func postDiscord(webhookURL string, message discordWebhook) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	resp, err := newOutboundClient(discordTimeout).Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Discord answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/sync/errgroup"
+)
+
+// Link extraction limits
+const (
+	linkExtractionWorkers = 8   // pages one scan fetches for links at once
+	maxConcurrentFetches  = 32  // page fetches across all scans at once
+	linkBatchSize         = 100 // pages taken from the queue per extraction batch
+)
+
+// fetchSlots bounds link extraction across all running scans, so parallel
+// crawls together never open more than maxConcurrentFetches page fetches
+var fetchSlots = make(chan struct{}, maxConcurrentFetches)
+
+// pageLinks is the outcome of extracting the links of one page
+type pageLinks struct {
+	links []string
+	err   error
+}
+
+// fetchLinks extracts the links of a page once a shared fetch slot is free.
+// A page the target site throttles is fetched again after backing off, as is
+// one answered by a bot challenge in retry mode.
+func (s *AccessibilityScanner) fetchLinks(ctx context.Context, pageURL string) pageLinks {
+	for attempt := 0; ; attempt++ {
+		if !s.throttle.Wait(ctx) {
+			return pageLinks{err: ctx.Err()}
+		}
+		found := s.fetchLinksOnce(ctx, pageURL)
+		switch {
+		case found.err == nil:
+			s.throttle.ReportHealthy()
+		case isThrottled(found.err):
+			s.throttle.ReportThrottled(found.err)
+			if attempt < maxTargetFetchRetries && sleepContext(ctx, s.throttle.Delay()) {
+				continue
+			}
+		case challengeVendor(found.err) != "":
+			if s.waitForChallenge(ctx, pageURL, attempt) {
+				continue
+			}
+		}
+		return found
+	}
+}
+
+// fetchLinksOnce extracts the links of a page in a shared fetch slot
+func (s *AccessibilityScanner) fetchLinksOnce(ctx context.Context, pageURL string) pageLinks {
+	select {
+	case fetchSlots <- struct{}{}:
+	case <-ctx.Done():
+		return pageLinks{err: ctx.Err()}
+	}
+	defer func() { <-fetchSlots }()
+
+	links, err := s.extractLinks(ctx, pageURL)
+	return pageLinks{links: links, err: err}
+}
+
+// extractLinksConcurrently extracts the links of several pages in parallel,
+// returning them in the order of pages. It fails only if ctx is done.
+func (s *AccessibilityScanner) extractLinksConcurrently(ctx context.Context, pages []string) ([]pageLinks, error) {
+	found := make([]pageLinks, len(pages))
+	var group errgroup.Group
+	group.SetLimit(linkExtractionWorkers)
+	for i, pageURL := range pages {
+		group.Go(func() error {
+			found[i] = s.fetchLinks(ctx, pageURL)
+			return ctx.Err()
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// prefetchLinks starts extracting the links of a page in the background, so
+// discovery overlaps the page's Lighthouse audit
+func (s *AccessibilityScanner) prefetchLinks(ctx context.Context, pageURL string) <-chan pageLinks {
+	done := make(chan pageLinks, 1)
+	go func() {
+		done <- s.fetchLinks(ctx, pageURL)
+	}()
+	return done
+}
+
+// popBatch takes up to max URLs from the front of the crawl queue
+func (s *AccessibilityScanner) popBatch(max int) []string {
+	batch := make([]string, 0, min(max, s.urls.Len()))
+	for len(batch) < max {
+		pageURL, ok := s.urls.Pop()
+		if !ok {
+			break
+		}
+		batch = append(batch, pageURL)
+	}
+	return batch
+}
+
+// unpopBatch puts a batch back at the front of the crawl queue in its original order
+func (s *AccessibilityScanner) unpopBatch(batch []string) {
+	for i := len(batch) - 1; i >= 0; i-- {
+		s.urls.PushFront(batch[i])
+	}
+}
+
+// documentBase returns the URL a page's relative links resolve against: the
+// href of its first <base> element with one, resolved against the page, or
+// else the page itself. Like browsers, it ignores bases that are not http or
+// https, such as javascript: or data: URLs.
+func documentBase(doc *html.Node, page *url.URL) *url.URL {
+	var find func(*html.Node) (string, bool)
+	find = func(n *html.Node) (string, bool) {
+		if n.Type == html.ElementNode && n.Data == "base" {
+			if href, ok := attrLookup(n, "href"); ok {
+				return href, true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if href, ok := find(c); ok {
+				return href, true
+			}
+		}
+		return "", false
+	}
+
+	href, ok := find(doc)
+	if !ok {
+		return page
+	}
+	baseURL, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return page
+	}
+	base := page.ResolveReference(baseURL)
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return page
+	}
+	return base
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// idReferenceAttributes are the attributes that point to elements by ID
+var idReferenceAttributes = []string{
+	"aria-activedescendant", "aria-controls", "aria-describedby", "aria-details",
+	"aria-errormessage", "aria-flowto", "aria-labelledby", "aria-owns",
+	"for", "form", "headers", "list",
+}
+
+// checkDuplicateIDs flags elements whose id another element of the page
+// already has. Browsers resolve an ID to its first element, so a label,
+// aria-labelledby or headers reference meant for a later one silently points
+// to the wrong element. Duplicates that something references are serious;
+// the others are reported as minor.
+func checkDuplicateIDs(doc *html.Node) []AccessibilityIssue {
+	byID := make(map[string][]*html.Node)
+	var ids []string
+	referenced := make(map[string]map[string]bool) // ID to the attributes referencing it
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "template":
+				return
+			}
+			if id := attrValue(n, "id"); id != "" {
+				if _, seen := byID[id]; !seen {
+					ids = append(ids, id)
+				}
+				byID[id] = append(byID[id], n)
+			}
+			for _, attr := range idReferenceAttributes {
+				for _, id := range strings.Fields(attrValue(n, attr)) {
+					if referenced[id] == nil {
+						referenced[id] = make(map[string]bool)
+					}
+					referenced[id][attr] = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	unique := func(id string) bool { return len(byID[id]) == 1 }
+	var issues []AccessibilityIssue
+	for _, id := range ids {
+		elements := byID[id]
+		if len(elements) < 2 {
+			continue
+		}
+		first := elementPathFrom(elements[0], unique)
+		issue := AccessibilityIssue{
+			AuditID:     "duplicate-id",
+			Impact:      "minor",
+			Description: "IDs must be unique in a page. A reference to a duplicated ID reaches only the first element with it.",
+		}
+		if attrs := referenced[id]; len(attrs) > 0 {
+			names := make([]string, 0, len(attrs))
+			for attr := range attrs {
+				names = append(names, attr)
+			}
+			sort.Strings(names)
+			issue.AuditID = "duplicate-id-referenced"
+			issue.Impact = "serious"
+			issue.Description = fmt.Sprintf("The id is referenced by %s, which reaches only the first element with it, so a label, description or header meant for this element goes to the other one.", strings.Join(names, ", "))
+		}
+		for _, n := range elements[1:] {
+			issue.title = titled("default", "{id}", id, "{first}", first)
+			issue.Selector = elementPathFrom(n, unique)
+			issue.Snippet = elementSnippet(n)
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ElementScanRequest represents an API request to check one element or audit on a page
+type ElementScanRequest struct {
+	URL      string `json:"url"`
+	Selector string `json:"selector,omitempty"`
+	AuditID  string `json:"audit_id,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// ElementScanResult reports the issues of one page that match a selector or audit
+type ElementScanResult struct {
+	URL                string               `json:"url"`
+	Selector           string               `json:"selector,omitempty"`
+	AuditID            string               `json:"audit_id,omitempty"`
+	Passed             bool                 `json:"passed"`
+	AccessibilityScore float64              `json:"accessibility_score"`
+	Issues             []AccessibilityIssue `json:"issues"`
+	ScanTime           time.Time            `json:"scan_time"`
+	DurationMs         int64                `json:"duration_ms"`
+}
+
+// selectorMatches reports whether an issue's selector is the requested selector
+// or ends with it, so "img.logo" matches "header > a > img.logo"
+func selectorMatches(issueSelector, selector string) bool {
+	issueSelector = strings.TrimSpace(issueSelector)
+	if issueSelector == selector {
+		return true
+	}
+	return strings.HasSuffix(issueSelector, " "+selector) || strings.HasSuffix(issueSelector, ">"+selector)
+}
+
+// handleElementScan handles POST /api/v1/scan/element requests
+func handleElementScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+
+	var req ElementScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return
+	}
+
+	if req.URL == "" {
+		sendError(w, "Missing URL", http.StatusBadRequest, "URL is required")
+		return
+	}
+	if _, err := url.Parse(req.URL); err != nil {
+		sendError(w, "Invalid URL", http.StatusBadRequest, "URL must be valid")
+		return
+	}
+
+	req.Selector = strings.TrimSpace(req.Selector)
+	req.AuditID = strings.TrimSpace(req.AuditID)
+	if req.Selector == "" && req.AuditID == "" {
+		sendError(w, "Missing filter", http.StatusBadRequest, "selector or audit_id is required")
+		return
+	}
+	if req.Language == "" {
+		req.Language = negotiateLanguage(r.Header.Get("Accept-Language"), remediationCatalogs.Languages())
+	} else if !languageTagPattern.MatchString(req.Language) {
+		sendError(w, "Invalid language", http.StatusBadRequest, "language must be a language tag such as \"en\" or \"pt-BR\"")
+		return
+	}
+
+	apiKey := getAPIKey()
+	if !psiConfigured() {
+		sendError(w, "Configuration error", http.StatusInternalServerError, "Google API key not configured")
+		return
+	}
+
+	if _, _, exceeded := admitQuota(clientName(r), 1, false); exceeded != nil {
+		sendQuotaExceeded(w, exceeded)
+		return
+	}
+
+	started := time.Now()
+	scanner := NewAccessibilityScanner(apiKey, req.URL, 1, 0, 1)
+	scanner.language = req.Language
+	page := scanner.scanPageWithLighthouse(r.Context(), req.URL)
+	usageStore.Record(clientName(r), UsageCounters{PagesScanned: 1, LighthouseCalls: 1})
+	if page.Error != "" {
+		sendPageError(w, r, "Scan failed", page)
+		return
+	}
+
+	result := ElementScanResult{
+		URL:                page.URL,
+		Selector:           req.Selector,
+		AuditID:            req.AuditID,
+		AccessibilityScore: page.AccessibilityScore,
+		Issues:             make([]AccessibilityIssue, 0),
+		ScanTime:           started,
+	}
+	for _, issue := range page.Issues {
+		if req.AuditID != "" && issue.AuditID != req.AuditID {
+			continue
+		}
+		if req.Selector != "" && !selectorMatches(issue.Selector, req.Selector) {
+			continue
+		}
+		issue.Fingerprint = issueFingerprint(page.URL, issue)
+		result.Issues = append(result.Issues, issue)
+	}
+	result.Passed = len(result.Issues) == 0
+	result.DurationMs = time.Since(started).Milliseconds()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
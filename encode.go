@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// pageResultsMember is how a scan result without pages encodes its page list.
+// Quotes inside JSON strings are escaped, so only the member itself can match.
+var pageResultsMember = []byte(`"page_results":null`)
+
+// encodeScanResult writes result as JSON followed by a newline, like
+// json.Encoder, but encodes the page results one at a time so the encoding of
+// a large scan is never held in memory as a whole
+func encodeScanResult(w io.Writer, result *ScanResult) error {
+	envelope := *result
+	envelope.PageResults = nil
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	at := bytes.Index(data, pageResultsMember)
+	if at < 0 {
+		return errors.New("scan result has no page_results member")
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(data[:at])
+	if result.PageResults == nil {
+		bw.Write(pageResultsMember)
+	} else {
+		bw.WriteString(`"page_results":[`)
+		for i := range result.PageResults {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			page, err := json.Marshal(&result.PageResults[i])
+			if err != nil {
+				return err
+			}
+			bw.Write(page)
+		}
+		bw.WriteByte(']')
+	}
+	bw.Write(data[at+len(pageResultsMember):])
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
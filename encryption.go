@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// encryptedMagic starts every encrypted record. JSON never starts with a NUL
+// byte, so records written before encryption was enabled stay readable.
+var encryptedMagic = []byte("\x00ASE1")
+
+// encryptionKeyIDPattern matches the IDs of encryption keys
+var encryptionKeyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// errEncryptionKeyUnknown is returned for records encrypted with a key that
+// is not configured
+var errEncryptionKeyUnknown = errors.New("record is encrypted with a key that is not configured")
+
+// keyring holds the AES-GCM keys of encryption at rest. New records are
+// encrypted with the active key, the others only decrypt records written
+// before a rotation.
+type keyring struct {
+	active string
+	ids    []string
+	keys   map[string]cipher.AEAD
+}
+
+// parseEncryptionKeys reads keys given as id:base64key, the first being the
+// active one. Keys are 16, 24 or 32 bytes for AES-128, AES-192 or AES-256.
+func parseEncryptionKeys(entries []string) (*keyring, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	ring := &keyring{keys: make(map[string]cipher.AEAD, len(entries))}
+	for _, entry := range entries {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || !encryptionKeyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("%q must be id:base64key with an ID of letters, digits, '.', '_' or '-'", redactKey(entry))
+		}
+		if _, ok := ring.keys[id]; ok {
+			return nil, fmt.Errorf("key ID %q is listed twice", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q must be 16, 24 or 32 bytes, not %d", id, len(key))
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys[id] = aead
+		ring.ids = append(ring.ids, id)
+	}
+	ring.active = ring.ids[0]
+	return ring, nil
+}
+
+// redactKey hides the key material of an encryption key entry in errors
+func redactKey(entry string) string {
+	if id, _, ok := strings.Cut(entry, ":"); ok {
+		return id + ":***"
+	}
+	return "***"
+}
+
+// recordKeyID returns the ID of the key a record is encrypted with
+func recordKeyID(data []byte) (string, bool) {
+	if !bytes.HasPrefix(data, encryptedMagic) || len(data) < len(encryptedMagic)+1 {
+		return "", false
+	}
+	rest := data[len(encryptedMagic):]
+	size := int(rest[0])
+	if len(rest) < 1+size {
+		return "", false
+	}
+	return string(rest[1 : 1+size]), true
+}
+
+// seal encrypts a record with the active key. The record holds the magic
+// bytes, the key ID, the nonce and the ciphertext; the key ID is
+// authenticated with it. A nil keyring leaves records in plain text.
+func (k *keyring) seal(plaintext []byte) ([]byte, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header := append(append(bytes.Clone(encryptedMagic), byte(len(k.active))), k.active...)
+	record := append(bytes.Clone(header), nonce...)
+	return aead.Seal(record, nonce, plaintext, header), nil
+}
+
+// open decrypts a record with the key it names. Plain text records are
+// returned as they are.
+func (k *keyring) open(data []byte) ([]byte, error) {
+	id, ok := recordKeyID(data)
+	if !ok {
+		if bytes.HasPrefix(data, encryptedMagic) {
+			return nil, errors.New("encrypted record is truncated")
+		}
+		return data, nil
+	}
+	var aead cipher.AEAD
+	if k != nil {
+		aead = k.keys[id]
+	}
+	if aead == nil {
+		return nil, fmt.Errorf("%w: %q", errEncryptionKeyUnknown, id)
+	}
+	headerSize := len(encryptedMagic) + 1 + len(id)
+	if len(data) < headerSize+aead.NonceSize() {
+		return nil, errors.New("encrypted record is truncated")
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt record with key %q: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// readRecord reads a stored record, decrypting and decompressing it if needed
+func readRecord(path string) ([]byte, error) {
+	data, err := readCompressedRecord(path)
+	if err != nil {
+		return nil, err
+	}
+	return decompressRecord(data)
+}
+
+// readCompressedRecord reads a stored record, decrypting it if needed but
+// leaving it compressed
+func readCompressedRecord(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return currentConfig().keys.open(data)
+}
+
+// writeRecord replaces a stored record, encrypting it when encryption at
+// rest is enabled
+func writeRecord(path string, data []byte) error {
+	data, err := currentConfig().keys.seal(data)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// openRecord returns a reader of a record's plain text. Plain text records
+// are streamed; encrypted ones are decrypted in memory, since GCM
+// authenticates the record as a whole.
+func openRecord(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(len(encryptedMagic)); !bytes.Equal(magic, encryptedMagic) {
+		return reader, nil
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := currentConfig().keys.open(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(plaintext), nil
+}
+
+// EncryptionStatus counts the stored records by the key they are encrypted with
+type EncryptionStatus struct {
+	Enabled     bool           `json:"enabled"`
+	ActiveKey   string         `json:"active_key,omitempty"`
+	Keys        []string       `json:"keys,omitempty"`
+	Records     map[string]int `json:"records"`               // encrypted records by key ID
+	Plaintext   int            `json:"plaintext"`             // records written before encryption was enabled
+	Unreadable  int            `json:"unreadable,omitempty"`  // records encrypted with a key that is not configured
+	Reencrypted int            `json:"reencrypted,omitempty"` // records rewritten with the active key by this request
+}
+
+// count adds a record to the status
+func (status *EncryptionStatus) count(data []byte, keys *keyring) {
+	id, ok := recordKeyID(data)
+	switch {
+	case !ok:
+		status.Plaintext++
+	case keys == nil || keys.keys[id] == nil:
+		status.Unreadable++
+	default:
+		status.Records[id]++
+	}
+}
+
+// storedRecords lists the files of a directory holding JSON records
+func storedRecords(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths
+}
+
+// rotateRecords counts the records at paths, rewriting those not encrypted
+// with the active key when rotate is set
+func rotateRecords(paths []string, rotate bool, status *EncryptionStatus) error {
+	keys := currentConfig().keys
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if id, _ := recordKeyID(data); !rotate || keys == nil || id == keys.active {
+			status.count(data, keys)
+			continue
+		}
+		plaintext, err := keys.open(data)
+		if err != nil {
+			status.count(data, keys)
+			continue
+		}
+		if err := writeRecord(path, plaintext); err != nil {
+			return err
+		}
+		status.Records[keys.active]++
+		status.Reencrypted++
+	}
+	return nil
+}
+
+// rotateEncryption counts the stored scans, continuation tokens, raw
+// Lighthouse reports and site documents by key, re-encrypting them with the
+// active key when rotate is set
+func rotateEncryption(rotate bool) (EncryptionStatus, error) {
+	status := EncryptionStatus{Records: make(map[string]int)}
+	if keys := currentConfig().keys; keys != nil {
+		status.Enabled = true
+		status.ActiveKey = keys.active
+		status.Keys = keys.ids
+	}
+
+	scanStore.mu.Lock()
+	err := rotateRecords(storedRecords(scanStore.dir), rotate, &status)
+	if err == nil {
+		err = rotateRecords(storedRecords(filepath.Join(scanStore.dir, "continuations")), rotate, &status)
+	}
+	scans, _ := os.ReadDir(filepath.Join(scanStore.dir, "raw"))
+	for _, scan := range scans {
+		if err == nil && scan.IsDir() {
+			err = rotateRecords(rawReportRecords(scanStore.rawReportDir(scan.Name())), rotate, &status)
+		}
+	}
+	scanStore.mu.Unlock()
+	if err != nil {
+		return status, err
+	}
+
+	siteStore.mu.Lock()
+	defer siteStore.mu.Unlock()
+	hosts, _ := os.ReadDir(siteStore.dir)
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name() < hosts[j].Name() })
+	for _, host := range hosts {
+		if !host.IsDir() {
+			continue
+		}
+		if err := rotateRecords(storedRecords(filepath.Join(siteStore.dir, host.Name())), rotate, &status); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+// handleAdminEncryption reports how stored records are encrypted. POST
+// re-encrypts every record with the active key, after which retired keys
+// can be removed from encryption_keys.
+func handleAdminEncryption(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+		return
+	}
+	rotate := r.Method == http.MethodPost
+	if rotate && currentConfig().keys == nil {
+		sendError(w, "Encryption not configured", http.StatusConflict, "Set encryption_keys to encrypt stored records")
+		return
+	}
+
+	status, err := rotateEncryption(rotate)
+	if err != nil {
+		sendError(w, "Re-encryption failed", http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
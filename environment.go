@@ -0,0 +1,52 @@
+package main
+
+// AuditEnvironment describes how Lighthouse audited a page, so results of two
+// scans can be told apart when the tooling or conditions changed
+type AuditEnvironment struct {
+	LighthouseVersion string             `json:"lighthouse_version,omitempty"`
+	FetchTime         string             `json:"fetch_time,omitempty"`
+	UserAgent         string             `json:"user_agent,omitempty"` // sent to the page by Lighthouse
+	FormFactor        string             `json:"form_factor,omitempty"`
+	Locale            string             `json:"locale,omitempty"`
+	Network           *NetworkConditions `json:"network,omitempty"`
+	RequestedURL      string             `json:"requested_url,omitempty"`
+	FinalURL          string             `json:"final_url,omitempty"` // after redirects, as displayed
+}
+
+// NetworkConditions are the throttling settings of a Lighthouse run
+type NetworkConditions struct {
+	ThrottlingMethod      string  `json:"throttling_method,omitempty"`
+	RTTMs                 float64 `json:"rtt_ms"`
+	ThroughputKbps        float64 `json:"throughput_kbps"`
+	CPUSlowdownMultiplier float64 `json:"cpu_slowdown_multiplier"`
+}
+
+// auditEnvironment reads the environment of a Lighthouse report. Lighthouse
+// 10 renamed finalUrl to finalDisplayedUrl; either is accepted.
+func auditEnvironment(report *LighthouseResult) *AuditEnvironment {
+	lhr := report.LighthouseResult
+	if lhr.LighthouseVersion == "" && lhr.FetchTime == "" {
+		return nil
+	}
+	env := &AuditEnvironment{
+		LighthouseVersion: lhr.LighthouseVersion,
+		FetchTime:         lhr.FetchTime,
+		UserAgent:         lhr.Environment.NetworkUserAgent,
+		FormFactor:        lhr.ConfigSettings.FormFactor,
+		Locale:            lhr.ConfigSettings.Locale,
+		RequestedURL:      lhr.RequestedURL,
+		FinalURL:          lhr.FinalDisplayedURL,
+	}
+	if env.FinalURL == "" {
+		env.FinalURL = lhr.FinalURL
+	}
+	if settings := lhr.ConfigSettings; settings.ThrottlingMethod != "" {
+		env.Network = &NetworkConditions{
+			ThrottlingMethod:      settings.ThrottlingMethod,
+			RTTMs:                 settings.Throttling.RTTMs,
+			ThroughputKbps:        settings.Throttling.ThroughputKbps,
+			CPUSlowdownMultiplier: settings.Throttling.CPUSlowdownMultiplier,
+		}
+	}
+	return env
+}
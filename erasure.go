@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DeletionReceipt confirms what was erased for a site
+type DeletionReceipt struct {
+	ReceiptID            string    `json:"receipt_id"`
+	Site                 string    `json:"site"`
+	DeletedAt            time.Time `json:"deleted_at"`
+	ScansDeleted         []string  `json:"scans_deleted"`
+	ContinuationsDeleted int       `json:"continuations_deleted"`
+	SiteDocuments        []string  `json:"site_documents_deleted"`
+	ScansCancelled       []string  `json:"scans_cancelled"`
+	BytesDeleted         int64     `json:"bytes_deleted"`
+	Complete             bool      `json:"complete"`
+	ScansUnreadable      []string  `json:"scans_unreadable,omitempty"` // stored scans whose site could not be read, which may belong to the site
+}
+
+// DeleteSite removes every document stored for a site and returns their names
+// and total size
+func (s *SiteStore) DeleteSite(host string) ([]string, int64, error) {
+	if !validSiteHost(host) {
+		return nil, 0, errInvalidSite
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, host)
+	names := make([]string, 0)
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return names, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(names)
+	return names, size, os.RemoveAll(dir)
+}
+
+// DeleteSiteFrontiers removes the continuation tokens of a site's scans and
+// returns how many were removed and the bytes freed
+func (s *ScanStore) DeleteSiteFrontiers(host string) (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, "continuations")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed, freed := 0, int64(0)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		data, err := readRecord(path)
+		if err != nil {
+			continue
+		}
+		var frontier CrawlFrontier
+		if json.Unmarshal(data, &frontier) != nil || siteKey(frontier.Request.URL) != host {
+			continue
+		}
+		if frontier.Store {
+			if info, err := os.Stat(s.frontierStorePath(frontier.Token)); err == nil {
+				if err := os.Remove(s.frontierStorePath(frontier.Token)); err != nil {
+					return removed, freed, err
+				}
+				freed += info.Size()
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += info.Size()
+	}
+	return removed, freed, nil
+}
+
+// erasureScanWait is how long an erasure waits for cancelled scans to wind down
+const erasureScanWait = time.Minute
+
+// errScansStillRunning is returned when cancelled scans do not finish in time
+var errScansStillRunning = errors.New("cancelled scans are still running")
+
+// CancelSite cancels the active scans of a site and waits for them to finish,
+// so none stores a result after the site's data is deleted. It returns their IDs.
+func (r *JobRegistry) CancelSite(host string, wait time.Duration) ([]string, error) {
+	r.mu.Lock()
+	jobs := make([]*ScanJob, 0)
+	for _, job := range r.jobs {
+		if siteKey(job.url) == host {
+			jobs = append(jobs, job)
+		}
+	}
+	r.mu.Unlock()
+
+	cancelled := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		job.Cancel()
+		cancelled = append(cancelled, job.id)
+	}
+	sort.Strings(cancelled)
+
+	deadline := time.After(wait)
+	for _, job := range jobs {
+		select {
+		case <-job.done:
+		case <-deadline:
+			return cancelled, errScansStillRunning
+		}
+	}
+	return cancelled, nil
+}
+
+// Forget drops completed idempotency keys that point at the given scans
+func (s *IdempotencyStore) Forget(scanIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make(map[string]bool, len(scanIDs))
+	for _, id := range scanIDs {
+		deleted[id] = true
+	}
+	for key, entry := range s.entries {
+		if entry.scanID != "" && deleted[entry.scanID] {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// eraseSite deletes all stored data of a site: its scans, continuation tokens
+// and per-site documents (triage, issue history, comments). Active scans of the
+// site are cancelled and finish first, so they cannot store new results afterwards.
+// Scans whose site cannot be read, such as scans encrypted with a key no
+// longer configured, are listed in the receipt, which is then not complete.
+func eraseSite(host string) (DeletionReceipt, error) {
+	if !validSiteHost(host) {
+		return DeletionReceipt{}, errInvalidSite
+	}
+	receipt := DeletionReceipt{
+		ReceiptID:    newScanID(),
+		Site:         host,
+		DeletedAt:    time.Now().UTC(),
+		ScansDeleted: make([]string, 0),
+	}
+	cancelled, err := scanJobs.CancelSite(host, erasureScanWait)
+	receipt.ScansCancelled = cancelled
+	if err != nil {
+		return receipt, err
+	}
+
+	scans, err := scanStore.List()
+	if err != nil {
+		return receipt, err
+	}
+	for _, scan := range scans {
+		if scan.Unreadable {
+			receipt.ScansUnreadable = append(receipt.ScansUnreadable, scan.ID)
+			continue
+		}
+		if scan.Site != host {
+			continue
+		}
+		freed, err := scanStore.Delete(scan.ID)
+		if errors.Is(err, errScanNotFound) {
+			continue
+		}
+		if err != nil {
+			return receipt, err
+		}
+		receipt.ScansDeleted = append(receipt.ScansDeleted, scan.ID)
+		receipt.BytesDeleted += freed
+	}
+	idempotencyKeys.Forget(receipt.ScansDeleted)
+
+	count, freed, err := scanStore.DeleteSiteFrontiers(host)
+	if err != nil {
+		return receipt, err
+	}
+	receipt.ContinuationsDeleted = count
+	receipt.BytesDeleted += freed
+
+	documents, size, err := siteStore.DeleteSite(host)
+	if err != nil {
+		return receipt, err
+	}
+	receipt.SiteDocuments = documents
+	receipt.BytesDeleted += size
+	receipt.Complete = len(receipt.ScansUnreadable) == 0
+	return receipt, nil
+}
+
+// handleSiteData handles DELETE /api/v1/sites/{host}/data requests
+func handleSiteData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only DELETE method is supported")
+		return
+	}
+
+	host := siteHostParam(r)
+	receipt, err := eraseSite(host)
+	if errors.Is(err, errInvalidSite) {
+		sendSiteStoreError(w, err)
+		return
+	}
+	if err != nil {
+		logAt(logLevelError, "Erasure of %s incomplete: %v", host, err)
+		sendError(w, "Deletion incomplete", http.StatusInternalServerError, "Some data could not be deleted; retry the request to finish")
+		return
+	}
+
+	logAt(logLevelWarn, "Site data erased: %s (receipt %s, %d scans, %d bytes) by %s",
+		host, receipt.ReceiptID, len(receipt.ScansDeleted), receipt.BytesDeleted, clientIP(r))
+	w.Header().Set("Content-Type", "application/json")
+	if !receipt.Complete {
+		// The unreadable scans may belong to the site, so erasure is not confirmed
+		logAt(logLevelError, "Erasure of %s incomplete: %d stored scans could not be read", host, len(receipt.ScansUnreadable))
+		w.WriteHeader(http.StatusConflict)
+	}
+	json.NewEncoder(w).Encode(receipt)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Event bus drivers
+const (
+	eventBusNATS  = "nats"
+	eventBusKafka = "kafka"
+)
+
+// Event bus limits
+const (
+	defaultEventTopicPrefix = "accessibility"
+	eventBusBuffer          = 1000 // events waiting to be published before new ones are dropped
+	eventBusTimeout         = 10 * time.Second
+)
+
+// Scan lifecycle events published on the bus, besides the webhook events
+const (
+	eventScanQueued    = "scan.queued"
+	eventScanStarted   = "scan.started"
+	eventScanPage      = "scan.page"
+	eventScanCancelled = "scan.cancelled"
+)
+
+// EventBusConfig selects where scan events are published
+type EventBusConfig struct {
+	Driver      string `yaml:"driver"`        // EVENT_BUS_DRIVER: nats or kafka; empty disables publishing
+	URL         string `yaml:"url"`           // EVENT_BUS_URL: NATS server URL, or Kafka REST Proxy base URL
+	TopicPrefix string `yaml:"topic_prefix"`  // EVENT_BUS_TOPIC_PREFIX (default: accessibility)
+	SkipPages   bool   `yaml:"skip_pages"`    // EVENT_BUS_SKIP_PAGES: do not publish scan.page events
+	KafkaAPIKey string `yaml:"kafka_api_key"` // EVENT_BUS_KAFKA_API_KEY: basic auth user for the REST Proxy
+	KafkaSecret string `yaml:"kafka_secret"`  // EVENT_BUS_KAFKA_SECRET
+}
+
+// validate checks the event bus settings and fills in the defaults
+func (c *EventBusConfig) validate() error {
+	switch c.Driver {
+	case "":
+		return nil
+	case eventBusNATS, eventBusKafka:
+	default:
+		return fmt.Errorf("driver must be nats or kafka")
+	}
+	if c.URL == "" {
+		return fmt.Errorf("url is required with driver %s", c.Driver)
+	}
+	if c.Driver == eventBusKafka {
+		parsed, err := url.Parse(c.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("url must be the http(s) base URL of a Kafka REST Proxy")
+		}
+	}
+	if c.TopicPrefix == "" {
+		c.TopicPrefix = defaultEventTopicPrefix
+	}
+	return nil
+}
+
+// mode describes the event bus for the startup log
+func (c EventBusConfig) mode() string {
+	if c.Driver == "" {
+		return "disabled"
+	}
+	return fmt.Sprintf("%s, topics %s.*", c.Driver, c.TopicPrefix)
+}
+
+// BusEvent is the message published for every event. Messages are keyed by
+// scan ID, so Kafka keeps the events of one scan in order on one partition.
+type BusEvent struct {
+	Event  string      `json:"event"`
+	ScanID string      `json:"scan_id"`
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data"`
+}
+
+// BusScanQueued is the data of scan.queued and scan.started events
+type BusScanQueued struct {
+	URL   string `json:"url"`
+	Limit int    `json:"limit"`
+}
+
+// BusPage is the data of scan.page events
+type BusPage struct {
+	BaseURL  string     `json:"base_url"`
+	Page     PageResult `json:"page"`
+	Replaces bool       `json:"replaces,omitempty"` // audited again by the retry pass, replacing the earlier event for the page
+}
+
+// eventPublisher sends one message to a topic
+type eventPublisher interface {
+	Publish(topic, key string, message []byte) error
+	Ping(ctx context.Context) error // checks the broker answers
+}
+
+// busMessage is an event waiting to be published
+type busMessage struct {
+	topic   string
+	key     string
+	message []byte
+}
+
+// EventBus publishes scan events in the background, so a slow or unreachable
+// broker never holds up a scan. Events that do not fit the buffer are dropped.
+type EventBus struct {
+	config    EventBusConfig
+	publisher eventPublisher
+	queue     chan busMessage
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+// eventBus publishes scan events; nil when no event bus is configured
+var eventBus *EventBus
+
+// NewEventBus connects to the configured broker, or returns nil when the
+// event bus is disabled
+func NewEventBus(config EventBusConfig) (*EventBus, error) {
+	var publisher eventPublisher
+	switch config.Driver {
+	case "":
+		return nil, nil
+	case eventBusNATS:
+		conn, err := nats.Connect(config.URL,
+			nats.Name("accessibility-scanner-api"),
+			nats.RetryOnFailedConnect(true),
+			nats.MaxReconnects(-1),
+			nats.Timeout(eventBusTimeout),
+		)
+		if err != nil {
+			return nil, err
+		}
+		publisher = natsPublisher{conn}
+	case eventBusKafka:
+		publisher = &kafkaRESTPublisher{
+			baseURL: strings.TrimSuffix(config.URL, "/"),
+			user:    config.KafkaAPIKey,
+			secret:  config.KafkaSecret,
+			client:  newOutboundClient(eventBusTimeout),
+		}
+	}
+	bus := &EventBus{config: config, publisher: publisher, queue: make(chan busMessage, eventBusBuffer)}
+	go bus.run()
+	return bus, nil
+}
+
+// run publishes queued events until the process exits
+func (b *EventBus) run() {
+	for msg := range b.queue {
+		if err := b.publisher.Publish(msg.topic, msg.key, msg.message); err != nil {
+			// Log the first failure and then every hundredth, not every event of an outage
+			if failed := b.failed.Add(1); failed%100 == 1 {
+				logAt(logLevelWarn, "Warning: Could not publish to %s (%d failures so far): %v", msg.topic, failed, err)
+			}
+		}
+	}
+}
+
+// publish queues an event for the topic <prefix>.<event>
+func (b *EventBus) publish(event, scanID string, data interface{}) {
+	if b == nil {
+		return
+	}
+	message, err := json.Marshal(BusEvent{Event: event, ScanID: scanID, Time: time.Now().UTC(), Data: data})
+	if err != nil {
+		logAt(logLevelError, "Could not encode %s event: %v", event, err)
+		return
+	}
+	select {
+	case b.queue <- busMessage{topic: b.config.TopicPrefix + "." + event, key: scanID, message: message}:
+	default:
+		if dropped := b.dropped.Add(1); dropped%100 == 1 {
+			logAt(logLevelWarn, "Warning: Event bus buffer is full; %d events dropped so far", dropped)
+		}
+	}
+}
+
+// check reports whether the broker answers, with the events dropped or not
+// published so far
+func (b *EventBus) check(ctx context.Context) DependencyCheck {
+	ctx, cancel := context.WithTimeout(ctx, pageSpeedCheckTimeout)
+	defer cancel()
+	started := time.Now()
+	result := DependencyCheck{Status: "ok"}
+	if err := b.publisher.Ping(ctx); err != nil {
+		result = DependencyCheck{Status: "failing", Detail: b.config.Driver + " unreachable: " + err.Error()}
+	} else {
+		result.Detail = fmt.Sprintf("%s; %d events dropped, %d failed to publish", b.config.mode(), b.dropped.Load(), b.failed.Load())
+	}
+	result.LatencyMs = time.Since(started).Milliseconds()
+	return result
+}
+
+// ScanQueued publishes a scan.queued event
+func (b *EventBus) ScanQueued(scanID, url string, limit int) {
+	b.publish(eventScanQueued, scanID, BusScanQueued{URL: url, Limit: limit})
+}
+
+// ScanStarted publishes a scan.started event
+func (b *EventBus) ScanStarted(scanID, url string, limit int) {
+	b.publish(eventScanStarted, scanID, BusScanQueued{URL: url, Limit: limit})
+}
+
+// Page publishes a scan.page event with the full page result
+func (b *EventBus) Page(scanID, baseURL string, page PageResult, replaces bool) {
+	if b == nil || b.config.SkipPages {
+		return
+	}
+	b.publish(eventScanPage, scanID, BusPage{BaseURL: baseURL, Page: page, Replaces: replaces})
+}
+
+// ScanFinished publishes scan.completed, scan.failed or scan.cancelled with
+// the same scan summary webhooks receive
+func (b *EventBus) ScanFinished(result *ScanResult) {
+	event := eventScanCompleted
+	switch result.Status {
+	case "failed":
+		event = eventScanFailed
+	case "cancelled":
+		event = eventScanCancelled
+	}
+	b.publish(event, result.ID, webhookScan(result))
+}
+
+// natsPublisher publishes to NATS subjects
+type natsPublisher struct {
+	conn *nats.Conn
+}
+
+// Publish sends a message to a NATS subject; the key is carried in a header
+func (p natsPublisher) Publish(topic, key string, message []byte) error {
+	msg := nats.NewMsg(topic)
+	msg.Header.Set("Scan-Id", key)
+	msg.Data = message
+	return p.conn.PublishMsg(msg)
+}
+
+// Ping makes a round trip to the NATS server
+func (p natsPublisher) Ping(ctx context.Context) error {
+	if !p.conn.IsConnected() {
+		return fmt.Errorf("not connected (%s)", p.conn.Status())
+	}
+	return p.conn.FlushWithContext(ctx)
+}
+
+// kafkaRESTPublisher produces to Kafka topics through the REST Proxy API
+// (v2), as served by Confluent REST Proxy and Redpanda's HTTP Proxy
+type kafkaRESTPublisher struct {
+	baseURL      string
+	user, secret string
+	client       *http.Client
+}
+
+// kafkaRecords is the body of a REST Proxy produce request
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+// kafkaRecord is one record to produce
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// kafkaOffsets is the REST Proxy answer to a produce request; records that
+// failed carry an error even when the request as a whole succeeded
+type kafkaOffsets struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish produces a record to a topic
+func (p *kafkaRESTPublisher) Publish(topic, key string, message []byte) error {
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: key, Value: message}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.user != "" {
+		req.SetBasicAuth(p.user, p.secret)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("REST Proxy answered %s", resp.Status)
+	}
+	var offsets kafkaOffsets
+	if json.NewDecoder(resp.Body).Decode(&offsets) == nil {
+		for _, offset := range offsets.Offsets {
+			if offset.ErrorCode != nil {
+				return fmt.Errorf("REST Proxy rejected the record: %s (error code %d)", offset.Error, *offset.ErrorCode)
+			}
+		}
+	}
+	return nil
+}
+
+// Ping checks the REST Proxy answers by listing its topics
+func (p *kafkaRESTPublisher) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/topics", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.user != "" {
+		req.SetBasicAuth(p.user, p.secret)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	drainAndClose(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("REST Proxy answered %s", resp.Status)
+	}
+	return nil
+}
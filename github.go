@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// githubDataEscaper escapes the message of a GitHub Actions workflow command
+var githubDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+// githubPropertyEscaper escapes workflow command properties such as title,
+// where : and , would end the property
+var githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+// githubAnnotation prints one ::error or ::warning workflow command
+func githubAnnotation(w io.Writer, level, title, message string) {
+	fmt.Fprintf(w, "::%s title=%s::%s\n", level, githubPropertyEscaper.Replace(title), githubDataEscaper.Replace(message))
+}
+
+// writeGitHubAnnotations writes a scan as GitHub Actions workflow commands,
+// one annotation per issue titled with the audit and page URL, and a notice
+// summarizing the scan. Critical and serious issues and pages that could not
+// be audited are errors, the rest warnings. It returns the number of
+// annotations besides the notice.
+func writeGitHubAnnotations(w io.Writer, result *ScanResult) int {
+	errorCount, warningCount := 0, 0
+	for _, page := range result.PageResults {
+		if page.Error != "" {
+			githubAnnotation(w, "error", "Page could not be audited: "+page.URL, page.Error)
+			errorCount++
+		}
+		for _, issue := range page.Issues {
+			if isFalsePositive(issue) {
+				continue
+			}
+			level := checkstyleSeverity(issue.Impact)
+			if level == "error" {
+				errorCount++
+			} else {
+				warningCount++
+			}
+			message := issue.Title
+			if issue.Selector != "" {
+				message += "\nElement: " + issue.Selector
+			}
+			if issue.Impact != "" {
+				message += "\nImpact: " + issue.Impact
+			}
+			githubAnnotation(w, level, issue.AuditID+" on "+page.URL, message)
+		}
+	}
+	fmt.Fprintf(w, "::notice title=Accessibility scan of %s::%d errors and %d warnings on %d pages, site score %.2f\n",
+		githubPropertyEscaper.Replace(displayURL(result.BaseURL)), errorCount, warningCount, len(result.PageResults), result.SiteScore)
+	return errorCount + warningCount
+}
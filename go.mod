@@ -3,3 +3,22 @@ module github.com/panoslyrakis/accessibility-scanner-api
 go 1.23.12
 
 require golang.org/x/net v0.43.0
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/nats-io/nats.go v1.47.0
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/sync v0.16.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
+
+require (
+	golang.org/x/crypto v0.41.0
+	golang.org/x/text v0.28.0 // indirect
+)
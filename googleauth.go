@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Google OAuth settings
+const (
+	googleCredentialsMetadata = "metadata" // use the metadata server's service account
+	googleTokenURI            = "https://oauth2.googleapis.com/token"
+	googlePSIScope            = "https://www.googleapis.com/auth/cloud-platform"
+	googleTokenLifetime       = time.Hour
+	googleTokenMargin         = 2 * time.Minute // refresh tokens this long before they expire
+)
+
+// googleToken is an OAuth access token response
+type googleToken struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int    `json:"expires_in"` // seconds
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// googleCredentials authenticate PageSpeed Insights requests with OAuth
+// access tokens instead of, or as well as, an API key. Tokens are cached
+// and refreshed shortly before they expire.
+type googleCredentials struct {
+	kind         string // service_account, authorized_user or metadata
+	clientEmail  string
+	privateKeyID string
+	privateKey   *rsa.PrivateKey
+	clientID     string
+	clientSecret string
+	refreshToken string
+	tokenURI     string
+	quotaProject string // billed for requests, sent as X-Goog-User-Project
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// credentialsFile is a Google credentials JSON file, as written by
+// gcloud auth application-default login or for a service account key
+type credentialsFile struct {
+	Type           string `json:"type"`
+	ClientEmail    string `json:"client_email"`
+	PrivateKeyID   string `json:"private_key_id"`
+	PrivateKey     string `json:"private_key"`
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	RefreshToken   string `json:"refresh_token"`
+	TokenURI       string `json:"token_uri"`
+	QuotaProjectID string `json:"quota_project_id"`
+}
+
+// loadGoogleCredentials reads the google_credentials setting: the path of a
+// service account key or authorized user JSON file, or "metadata"
+func loadGoogleCredentials(setting string) (*googleCredentials, error) {
+	if setting == "" {
+		return nil, nil
+	}
+	if setting == googleCredentialsMetadata {
+		return &googleCredentials{kind: googleCredentialsMetadata}, nil
+	}
+	data, err := os.ReadFile(setting)
+	if err != nil {
+		return nil, err
+	}
+	var file credentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", setting, err)
+	}
+
+	creds := &googleCredentials{
+		kind:         file.Type,
+		clientEmail:  file.ClientEmail,
+		privateKeyID: file.PrivateKeyID,
+		clientID:     file.ClientID,
+		clientSecret: file.ClientSecret,
+		refreshToken: file.RefreshToken,
+		tokenURI:     file.TokenURI,
+		quotaProject: file.QuotaProjectID,
+	}
+	if creds.tokenURI == "" {
+		creds.tokenURI = googleTokenURI
+	}
+	switch file.Type {
+	case "service_account":
+		if file.ClientEmail == "" {
+			return nil, errors.New("service account credentials have no client_email")
+		}
+		if creds.privateKey, err = parseRSAPrivateKey(file.PrivateKey); err != nil {
+			return nil, fmt.Errorf("service account private_key: %w", err)
+		}
+	case "authorized_user":
+		if file.ClientID == "" || file.ClientSecret == "" || file.RefreshToken == "" {
+			return nil, errors.New("authorized user credentials need client_id, client_secret and refresh_token")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported credentials type %q; use a service_account or authorized_user file", file.Type)
+	}
+	return creds, nil
+}
+
+// parseRSAPrivateKey reads a PEM RSA private key in PKCS #8 or PKCS #1 form
+func parseRSAPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM key found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA key")
+	}
+	return key, nil
+}
+
+// authorize adds a current access token to a request
+func (c *googleCredentials) authorize(req *http.Request) error {
+	token, err := c.accessToken(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if c.quotaProject != "" {
+		req.Header.Set("X-Goog-User-Project", c.quotaProject)
+	}
+	return nil
+}
+
+// accessToken returns the cached access token, fetching a new one when it
+// is about to expire
+func (c *googleCredentials) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.expiry) > googleTokenMargin {
+		return c.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, secretFetchTimeout)
+	defer cancel()
+	var token googleToken
+	var err error
+	switch c.kind {
+	case googleCredentialsMetadata:
+		token, err = gcpMetadataToken(ctx)
+	case "service_account":
+		var assertion string
+		if assertion, err = c.signAssertion(time.Now()); err == nil {
+			token, err = postTokenForm(ctx, c.tokenURI, url.Values{
+				"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+				"assertion":  {assertion},
+			})
+		}
+	default:
+		token, err = postTokenForm(ctx, c.tokenURI, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {c.clientID},
+			"client_secret": {c.clientSecret},
+			"refresh_token": {c.refreshToken},
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("fetching a Google access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("fetching a Google access token: response has no access_token")
+	}
+
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = googleTokenLifetime
+	}
+	c.token, c.expiry = token.AccessToken, time.Now().Add(lifetime)
+	logAt(logLevelDebug, "Fetched a Google access token valid for %s", lifetime)
+	return c.token, nil
+}
+
+// signAssertion signs the JWT a service account exchanges for an access token
+func (c *googleCredentials) signAssertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": c.privateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   c.clientEmail,
+		"scope": googlePSIScope,
+		"aud":   c.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(googleTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// postTokenForm requests an access token from an OAuth token endpoint
+func postTokenForm(ctx context.Context, endpoint string, form url.Values) (googleToken, error) {
+	var token googleToken
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := newOutboundClient(secretFetchTimeout).Do(req)
+	if err != nil {
+		return token, withoutURL(err)
+	}
+	defer drainAndClose(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSecretResponseLength))
+	if err != nil {
+		return token, err
+	}
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return token, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if token.Error != "" {
+			return token, fmt.Errorf("HTTP error %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+		}
+		return token, fmt.Errorf("HTTP error %d", resp.StatusCode)
+	}
+	return token, nil
+}
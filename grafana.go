@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// grafanaAllSites is the site part of a target that charts every site
+const grafanaAllSites = "*"
+
+// grafanaTableColumns are the columns of a "table" query, one row per scan
+var grafanaTableColumns = []GrafanaColumn{
+	{Text: "Time", Type: "time"},
+	{Text: "Site", Type: "string"},
+	{Text: "Scan", Type: "string"},
+	{Text: "Status", Type: "string"},
+	{Text: "Site score", Type: "number"},
+	{Text: "Issues", Type: "number"},
+	{Text: "Critical", Type: "number"},
+	{Text: "Serious", Type: "number"},
+	{Text: "Moderate", Type: "number"},
+	{Text: "Minor", Type: "number"},
+	{Text: "Pages", Type: "number"},
+	{Text: "Pages with errors", Type: "number"},
+}
+
+// GrafanaSearchRequest is the body Grafana posts to /search
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is the body Grafana posts to /query
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	MaxDataPoints int             `json:"maxDataPoints"`
+	Targets       []GrafanaTarget `json:"targets"`
+}
+
+// GrafanaTarget is one query of a panel: "metric:host", with host "*" for every site
+type GrafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"` // "timeserie" (default) or "table"
+}
+
+// GrafanaSeries is a time series answer: [value, unix milliseconds] pairs
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaTable is a table answer
+type GrafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []GrafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// GrafanaColumn describes a table column
+type GrafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// grafanaScan is the part of a stored scan charted in Grafana
+type grafanaScan struct {
+	ID       string
+	Site     string
+	Status   string
+	ScanTime time.Time
+	Score    float64
+	Summary  ScanSummary
+}
+
+// parseGrafanaTarget splits a "metric:host" target
+func parseGrafanaTarget(target string) (metric, site string, err error) {
+	metric, site, found := strings.Cut(strings.TrimSpace(target), ":")
+	if !found || !scanMetricKnown(metric) {
+		return "", "", fmt.Errorf("target %q must be metric:host, with metric one of %s", target, strings.Join(scanMetrics, ", "))
+	}
+	site = asciiHost(site)
+	if site != grafanaAllSites && !validSiteHost(site) {
+		return "", "", fmt.Errorf("target %q names an invalid host; use a host such as example.com or *", target)
+	}
+	return metric, site, nil
+}
+
+// grafanaScans loads the completed and partial scans of a site (or every
+// site) started within a time range, oldest first. Summaries are only read
+// from the stored results when withSummary is set.
+func grafanaScans(site string, from, to time.Time, withSummary bool) ([]grafanaScan, error) {
+	stored, err := scanStore.List()
+	if err != nil {
+		return nil, err
+	}
+	scans := make([]grafanaScan, 0)
+	for i := len(stored) - 1; i >= 0; i-- {
+		scan := stored[i]
+		if site != grafanaAllSites && scan.Site != site {
+			continue
+		}
+		if scan.Status != "completed" && scan.Status != "partial" {
+			continue
+		}
+		if (!from.IsZero() && scan.ScanTime.Before(from)) || (!to.IsZero() && scan.ScanTime.After(to)) {
+			continue
+		}
+		charted := grafanaScan{ID: scan.ID, Site: scan.Site, Status: scan.Status, ScanTime: scan.ScanTime, Score: scan.SiteScore}
+		if withSummary {
+			result, err := scanStore.Get(scan.ID)
+			if errors.Is(err, errScanNotFound) {
+				continue // deleted since it was listed
+			}
+			if err != nil {
+				return nil, err
+			}
+			charted.Summary = result.Summary
+		}
+		scans = append(scans, charted)
+	}
+	return scans, nil
+}
+
+// grafanaSeries turns scans into one time series per site, keeping at most
+// maxPoints of the newest points in each
+func grafanaSeries(metric string, scans []grafanaScan, maxPoints int) []GrafanaSeries {
+	bySite := make(map[string]*GrafanaSeries)
+	sites := make([]string, 0)
+	for _, scan := range scans {
+		series, ok := bySite[scan.Site]
+		if !ok {
+			series = &GrafanaSeries{Target: metric + ":" + scan.Site, Datapoints: make([][2]float64, 0)}
+			bySite[scan.Site] = series
+			sites = append(sites, scan.Site)
+		}
+		series.Datapoints = append(series.Datapoints, [2]float64{scanMetric(metric, scan.Score, scan.Summary), float64(scan.ScanTime.UnixMilli())})
+	}
+	sort.Strings(sites)
+
+	answer := make([]GrafanaSeries, 0, len(sites))
+	for _, site := range sites {
+		series := bySite[site]
+		if maxPoints > 0 && len(series.Datapoints) > maxPoints {
+			series.Datapoints = series.Datapoints[len(series.Datapoints)-maxPoints:]
+		}
+		answer = append(answer, *series)
+	}
+	return answer
+}
+
+// grafanaTable lists scans as table rows
+func grafanaTable(scans []grafanaScan) GrafanaTable {
+	table := GrafanaTable{Type: "table", Columns: grafanaTableColumns, Rows: make([][]interface{}, 0, len(scans))}
+	for _, scan := range scans {
+		impacts := scan.Summary.IssuesByImpact
+		table.Rows = append(table.Rows, []interface{}{
+			scan.ScanTime.UnixMilli(), scan.Site, scan.ID, scan.Status, scan.Score,
+			scan.Summary.TotalIssues, impacts["critical"], impacts["serious"], impacts["moderate"], impacts["minor"],
+			scan.Summary.PagesScanned, scan.Summary.PagesWithErrors,
+		})
+	}
+	return table
+}
+
+// handleGrafana handles GET /api/v1/grafana requests, which Grafana uses to
+// test the datasource connection
+func handleGrafana(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleGrafanaSearch handles POST /api/v1/grafana/search requests, listing
+// the "metric:host" targets that contain the search text
+func handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+	var req GrafanaSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return
+	}
+
+	stored, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelError, "Failed to list scans: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not list stored scans")
+		return
+	}
+	seen := make(map[string]bool)
+	sites := []string{grafanaAllSites}
+	for _, scan := range stored {
+		if scan.Site != "" && !seen[scan.Site] {
+			seen[scan.Site] = true
+			sites = append(sites, scan.Site)
+		}
+	}
+	sort.Strings(sites[1:])
+
+	search := strings.ToLower(req.Target)
+	targets := make([]string, 0, len(sites)*len(scanMetrics))
+	for _, metric := range scanMetrics {
+		for _, site := range sites {
+			if target := metric + ":" + site; strings.Contains(target, search) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, targets)
+}
+
+// handleGrafanaQuery handles POST /api/v1/grafana/query requests, answering
+// each target with time series, or with a table of scans for "table" targets
+func handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+	var req GrafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON with range.from and range.to as RFC 3339 times")
+		return
+	}
+
+	answer := make([]interface{}, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Target == "" {
+			continue // a panel query that has not been filled in yet
+		}
+		metric, site, err := parseGrafanaTarget(target.Target)
+		if err != nil {
+			sendError(w, "Invalid target", http.StatusBadRequest, err.Error())
+			return
+		}
+		scans, err := grafanaScans(site, req.Range.From, req.Range.To, target.Type == "table" || metric != "site_score")
+		if err != nil {
+			logAt(logLevelError, "Failed to load scans for Grafana: %v", err)
+			sendError(w, "Storage error", http.StatusInternalServerError, "Could not load stored scans")
+			return
+		}
+		switch target.Type {
+		case "table":
+			answer = append(answer, grafanaTable(scans))
+		case "", "timeserie", "timeseries":
+			for _, series := range grafanaSeries(metric, scans, req.MaxDataPoints) {
+				answer = append(answer, series)
+			}
+		default:
+			sendError(w, "Invalid target", http.StatusBadRequest, "type must be timeserie or table")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, answer)
+}
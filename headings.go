@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxHeadingTextLength caps the text of a heading in the outline
+const maxHeadingTextLength = 120
+
+// Heading is a heading of a page's outline, with the headings under it
+type Heading struct {
+	Level    int        `json:"level"`
+	Text     string     `json:"text"`
+	Selector string     `json:"selector"`
+	Children []*Heading `json:"children,omitempty"`
+
+	node *html.Node
+}
+
+// headingLevel returns the level of an h1–h6 element or an ARIA heading,
+// or 0 for other elements
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode {
+		return 0
+	}
+	if len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6' {
+		return int(n.Data[1] - '0')
+	}
+	if attrValue(n, "role") == "heading" {
+		if level, err := strconv.Atoi(attrValue(n, "aria-level")); err == nil && level >= 1 {
+			return level
+		}
+		return 2
+	}
+	return 0
+}
+
+// accessibleName approximates the name assistive technology gives an
+// element: the text of the elements aria-labelledby names, aria-label, or
+// its text with the alt text of its images
+func accessibleName(n *html.Node) string {
+	if name := labelledByText(n); name != "" {
+		return name
+	}
+	if label := strings.TrimSpace(attrValue(n, "aria-label")); label != "" {
+		return strings.Join(strings.Fields(label), " ")
+	}
+	return strings.Join(strings.Fields(visibleText(n)), " ")
+}
+
+// labelledByText returns the text of the elements an element's
+// aria-labelledby names
+func labelledByText(n *html.Node) string {
+	ids := strings.Fields(attrValue(n, "aria-labelledby"))
+	if len(ids) == 0 {
+		return ""
+	}
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	var parts []string
+	for _, id := range ids {
+		if label := elementByID(root, id); label != nil {
+			parts = append(parts, visibleText(label))
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// visibleText returns the text of an element as assistive technology reads
+// it: with the alt text of images, without aria-hidden content
+func visibleText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if attrValue(n, "aria-hidden") == "true" {
+				return
+			}
+			switch n.Data {
+			case "script", "style", "template":
+				return
+			case "img", "area":
+				b.WriteString(" " + attrValue(n, "alt") + " ")
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// pageHeadings returns the headings of a page in document order, leaving out
+// those hidden from assistive technology
+func pageHeadings(doc *html.Node) []*Heading {
+	var headings []*Heading
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "template", "head":
+				return
+			}
+			if _, hidden := attrLookup(n, "hidden"); hidden || attrValue(n, "aria-hidden") == "true" {
+				return
+			}
+			if level := headingLevel(n); level > 0 {
+				text := accessibleName(n)
+				if len(text) > maxHeadingTextLength {
+					text = truncateUTF8(text, maxHeadingTextLength) + "…"
+				}
+				headings = append(headings, &Heading{Level: level, Text: text, Selector: elementPath(n), node: n})
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return headings
+}
+
+// headingOutline nests headings under the closest preceding heading of a
+// higher level
+func headingOutline(headings []*Heading) []*Heading {
+	var outline, open []*Heading
+	for _, heading := range headings {
+		heading := &Heading{Level: heading.Level, Text: heading.Text, Selector: heading.Selector}
+		for len(open) > 0 && open[len(open)-1].Level >= heading.Level {
+			open = open[:len(open)-1]
+		}
+		if len(open) == 0 {
+			outline = append(outline, heading)
+		} else {
+			parent := open[len(open)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		open = append(open, heading)
+	}
+	return outline
+}
+
+// checkHeadings flags the defects of a page's heading outline: more than one
+// h1, levels skipped on the way down, and headings without text
+func checkHeadings(doc *html.Node) []AccessibilityIssue {
+	headings := pageHeadings(doc)
+	var issues []AccessibilityIssue
+	issue := func(heading *Heading, auditID, impact string, title *checkTitle, description string) {
+		issues = append(issues, AccessibilityIssue{
+			AuditID:     auditID,
+			title:       title,
+			Description: description,
+			Impact:      impact,
+			Selector:    heading.Selector,
+			Snippet:     elementSnippet(heading.node),
+		})
+	}
+
+	h1s := 0
+	for i, heading := range headings {
+		if heading.Level == 1 {
+			h1s++
+			if h1s > 1 {
+				issue(heading, "heading-multiple-h1", "minor",
+					titled("default", "{number}", strconv.Itoa(h1s)),
+					"A single h1 names what the page is about, so screen reader users can find the start of the content.")
+			}
+		}
+		if i > 0 && heading.Level > headings[i-1].Level+1 {
+			issue(heading, "heading-level-skipped", "moderate",
+				titled("default", "{level}", strconv.Itoa(heading.Level), "{previous}", strconv.Itoa(headings[i-1].Level)),
+				"Screen reader users navigate by heading level and take a skipped level for missing content.")
+		}
+		if heading.Text == "" {
+			issue(heading, "heading-empty", "minor",
+				titled("default", "{level}", strconv.Itoa(heading.Level)),
+				"Screen readers announce an empty heading without saying what the section is about.")
+		}
+	}
+	return issues
+}
@@ -0,0 +1,795 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned by the organization store
+var (
+	errOrgNotFound        = errors.New("organization not found")
+	errProjectNotFound    = errors.New("project not found")
+	errSiteRecordNotFound = errors.New("site not found")
+	errAlreadyExists      = errors.New("already exists")
+	errNotEmpty           = errors.New("still has children")
+)
+
+// slugPattern matches organization and project IDs
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Organization owns projects
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Project groups the sites of a team or client within an organization
+type Project struct {
+	ID             string               `json:"id"`
+	OrganizationID string               `json:"organization_id"`
+	Name           string               `json:"name"`
+	Notifications  NotificationSettings `json:"notifications"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+// ScanSettings are scan parameters a site applies when a request leaves them unset
+type ScanSettings struct {
+	MaxPages           int                `json:"max_pages,omitempty"`
+	Limit              int                `json:"limit,omitempty"`
+	Language           string             `json:"language,omitempty"`
+	TrafficHints       map[string]float64 `json:"traffic_hints,omitempty"`
+	Sampling           *SamplingConfig    `json:"sampling,omitempty"`
+	TimeoutSeconds     int                `json:"timeout_seconds,omitempty"`
+	PageTimeoutSeconds int                `json:"page_timeout_seconds,omitempty"`
+	MaxLighthouseCalls int                `json:"max_lighthouse_calls,omitempty"`
+	Frontier           string             `json:"frontier,omitempty"`
+	AMP                string             `json:"amp,omitempty"`
+	Iframes            bool               `json:"iframes,omitempty"`
+	PaginationLimit    int                `json:"pagination_limit,omitempty"`
+	StoreRaw           bool               `json:"store_raw,omitempty"`
+	Strategy           string             `json:"strategy,omitempty"`
+	BotChallenge       string             `json:"bot_challenge,omitempty"`
+}
+
+// NotificationSettings lists where scan results of a project, site or profile are announced
+type NotificationSettings struct {
+	Emails            []string `json:"emails,omitempty"`
+	WebhookURL        string   `json:"webhook_url,omitempty"`
+	DiscordWebhookURL string   `json:"discord_webhook_url,omitempty"` // accepted on write, never shown
+	DiscordWebhookID  string   `json:"discord_webhook_id,omitempty"`  // shown instead of the URL
+}
+
+// Site is a website registered in a project, identified by its canonical host
+type Site struct {
+	Host           string               `json:"host"`
+	ProjectID      string               `json:"project_id"`
+	OrganizationID string               `json:"organization_id"`
+	Name           string               `json:"name,omitempty"`
+	URL            string               `json:"url"`
+	Profile        string               `json:"profile,omitempty"`
+	DefaultScan    ScanSettings         `json:"default_scan"`
+	Notifications  NotificationSettings `json:"notifications"`
+	PublicWidget   bool                 `json:"public_widget"` // publish the latest score through /widget and /oembed
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+// redacted returns the project as shown in responses
+func (p Project) redacted() Project {
+	p.Notifications = p.Notifications.redacted()
+	return p
+}
+
+// redacted returns the site as shown in responses
+func (s Site) redacted() Site {
+	s.Notifications = s.Notifications.redacted()
+	return s
+}
+
+// SiteRef attaches a scan to its site record
+type SiteRef struct {
+	Host           string `json:"host"`
+	ProjectID      string `json:"project_id"`
+	OrganizationID string `json:"organization_id"`
+}
+
+// orgDirectory is the persisted organization tree
+type orgDirectory struct {
+	Organizations map[string]*Organization `json:"organizations"`
+	Projects      map[string]*Project      `json:"projects"`
+	Sites         map[string]*Site         `json:"sites"`
+}
+
+// OrgStore keeps organizations, projects and sites in one JSON file
+type OrgStore struct {
+	mu   sync.Mutex
+	path string
+	dir  orgDirectory
+}
+
+// orgStore holds the organization, project and site records
+var orgStore *OrgStore
+
+// NewOrgStore loads the organization tree stored in path, if any
+func NewOrgStore(path string) (*OrgStore, error) {
+	store := &OrgStore{path: path, dir: orgDirectory{
+		Organizations: make(map[string]*Organization),
+		Projects:      make(map[string]*Project),
+		Sites:         make(map[string]*Site),
+	}}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.dir); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// save writes the organization tree; callers must hold s.mu
+func (s *OrgStore) save() error {
+	data, err := json.Marshal(s.dir)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// update applies modify to the tree and saves it when modify succeeds
+func (s *OrgStore) update(modify func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := modify(); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// slugify derives an ID from a display name
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 63 {
+		slug = strings.TrimSuffix(slug[:63], "-")
+	}
+	return slug
+}
+
+// Organizations lists every organization by ID
+func (s *OrgStore) Organizations() []Organization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orgs := make([]Organization, 0, len(s.dir.Organizations))
+	for _, org := range s.dir.Organizations {
+		orgs = append(orgs, *org)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs
+}
+
+// Organization returns one organization
+func (s *OrgStore) Organization(id string) (Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org, ok := s.dir.Organizations[id]
+	if !ok {
+		return Organization{}, errOrgNotFound
+	}
+	return *org, nil
+}
+
+// Projects lists the projects of an organization by ID
+func (s *OrgStore) Projects(orgID string) ([]Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dir.Organizations[orgID]; !ok {
+		return nil, errOrgNotFound
+	}
+	projects := make([]Project, 0)
+	for _, project := range s.dir.Projects {
+		if project.OrganizationID == orgID {
+			projects = append(projects, *project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+// Project returns one project
+func (s *OrgStore) Project(id string) (Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	project, ok := s.dir.Projects[id]
+	if !ok {
+		return Project{}, errProjectNotFound
+	}
+	return *project, nil
+}
+
+// Sites lists the sites of a project by host
+func (s *OrgStore) Sites(projectID string) ([]Site, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dir.Projects[projectID]; !ok {
+		return nil, errProjectNotFound
+	}
+	sites := make([]Site, 0)
+	for _, site := range s.dir.Sites {
+		if site.ProjectID == projectID {
+			sites = append(sites, *site)
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Host < sites[j].Host })
+	return sites, nil
+}
+
+// AllSites lists every registered site by host
+func (s *OrgStore) AllSites() []Site {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sites := make([]Site, 0, len(s.dir.Sites))
+	for _, site := range s.dir.Sites {
+		sites = append(sites, *site)
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Host < sites[j].Host })
+	return sites
+}
+
+// Site returns the site registered for a host
+func (s *OrgStore) Site(host string) (Site, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	site, ok := s.dir.Sites[host]
+	if !ok {
+		return Site{}, errSiteRecordNotFound
+	}
+	return *site, nil
+}
+
+// SiteFor returns the reference of the site a URL belongs to, if it is registered
+func (s *OrgStore) SiteFor(rawURL string) *SiteRef {
+	if s == nil {
+		return nil
+	}
+	site, err := s.Site(siteKey(rawURL))
+	if err != nil {
+		return nil
+	}
+	return &SiteRef{Host: site.Host, ProjectID: site.ProjectID, OrganizationID: site.OrganizationID}
+}
+
+// applyTo fills the scan parameters a request left unset
+func (d ScanSettings) applyTo(req *ScanRequest) {
+	if req.MaxPages == 0 {
+		req.MaxPages = d.MaxPages
+	}
+	if req.Limit == 0 {
+		req.Limit = d.Limit
+	}
+	if req.Language == "" {
+		req.Language = d.Language
+	}
+	if req.TrafficHints == nil {
+		req.TrafficHints = d.TrafficHints
+	}
+	if req.Sampling == nil {
+		req.Sampling = d.Sampling
+	}
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = d.TimeoutSeconds
+	}
+	if req.PageTimeoutSeconds == 0 {
+		req.PageTimeoutSeconds = d.PageTimeoutSeconds
+	}
+	if req.MaxLighthouseCalls == 0 {
+		req.MaxLighthouseCalls = d.MaxLighthouseCalls
+	}
+	if req.Frontier == "" {
+		req.Frontier = d.Frontier
+	}
+	if req.AMP == "" {
+		req.AMP = d.AMP
+	}
+	if !req.Iframes {
+		req.Iframes = d.Iframes
+	}
+	if req.PaginationLimit == 0 {
+		req.PaginationLimit = d.PaginationLimit
+	}
+	if !req.StoreRaw {
+		req.StoreRaw = d.StoreRaw
+	}
+	if req.Strategy == "" {
+		req.Strategy = d.Strategy
+	}
+	if req.BotChallenge == "" {
+		req.BotChallenge = d.BotChallenge
+	}
+}
+
+// validate checks the settings can be used for a scan
+func (d ScanSettings) validate() error {
+	if d.MaxPages < 0 || d.Limit < 0 || d.TimeoutSeconds < 0 || d.PageTimeoutSeconds < 0 || d.MaxLighthouseCalls < 0 || d.PaginationLimit < 0 {
+		return errors.New("default_scan values cannot be negative")
+	}
+	if d.Frontier != "" && d.Frontier != frontierMemory && d.Frontier != frontierDisk {
+		return errors.New("frontier must be \"memory\" or \"disk\"")
+	}
+	if err := validateAMPMode(d.AMP); err != nil {
+		return err
+	}
+	if err := validateStrategy(d.Strategy); err != nil {
+		return err
+	}
+	if err := validateChallengeMode(d.BotChallenge); err != nil {
+		return err
+	}
+	if d.Language != "" && !languageTagPattern.MatchString(d.Language) {
+		return errors.New("default_scan.language must be a language tag such as \"en\" or \"pt-BR\"")
+	}
+	if d.Sampling != nil {
+		return d.Sampling.validate()
+	}
+	return nil
+}
+
+// sendOrgStoreError reports an organization store error
+func sendOrgStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errOrgNotFound):
+		sendError(w, "Organization not found", http.StatusNotFound, "No organization with this ID exists")
+	case errors.Is(err, errProjectNotFound):
+		sendError(w, "Project not found", http.StatusNotFound, "No project with this ID exists")
+	case errors.Is(err, errSiteRecordNotFound):
+		sendError(w, "Site not found", http.StatusNotFound, "No site is registered for this host")
+	case errors.Is(err, errAlreadyExists):
+		sendError(w, "Already exists", http.StatusConflict, "A record with this ID already exists")
+	case errors.Is(err, errNotEmpty):
+		sendError(w, "Not empty", http.StatusConflict, "Delete or move the records it contains first")
+	default:
+		logAt(logLevelError, "Organization storage error: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not access organization data")
+	}
+}
+
+// namedRequest is the body for creating or renaming an organization or project
+type namedRequest struct {
+	ID            string                `json:"id,omitempty"`
+	Name          string                `json:"name"`
+	Notifications *NotificationSettings `json:"notifications,omitempty"` // projects only
+}
+
+// decodeNamed reads and validates a namedRequest, deriving the ID from the name when omitted
+func decodeNamed(w http.ResponseWriter, r *http.Request) (namedRequest, bool) {
+	var req namedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		sendError(w, "Missing name", http.StatusBadRequest, "name is required")
+		return req, false
+	}
+	if req.ID == "" {
+		req.ID = slugify(req.Name)
+	}
+	if !slugPattern.MatchString(req.ID) {
+		sendError(w, "Invalid id", http.StatusBadRequest, "id must be lowercase letters, digits and dashes")
+		return req, false
+	}
+	if err := req.Notifications.validate(); err != nil {
+		sendError(w, "Invalid notifications", http.StatusBadRequest, err.Error())
+		return req, false
+	}
+	return req, true
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleOrganizations handles GET and POST /api/v1/orgs requests
+func handleOrganizations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, orgStore.Organizations())
+	case http.MethodPost:
+		req, ok := decodeNamed(w, r)
+		if !ok {
+			return
+		}
+		org := Organization{ID: req.ID, Name: req.Name, CreatedAt: time.Now().UTC()}
+		err := orgStore.update(func() error {
+			if _, exists := orgStore.dir.Organizations[org.ID]; exists {
+				return errAlreadyExists
+			}
+			orgStore.dir.Organizations[org.ID] = &org
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, org)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleOrganization handles GET, PUT and DELETE /api/v1/orgs/{org} requests
+func handleOrganization(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("org")
+	switch r.Method {
+	case http.MethodGet:
+		org, err := orgStore.Organization(id)
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, org)
+	case http.MethodPut:
+		var req namedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+			sendError(w, "Missing name", http.StatusBadRequest, "name is required")
+			return
+		}
+		var org Organization
+		err := orgStore.update(func() error {
+			existing, ok := orgStore.dir.Organizations[id]
+			if !ok {
+				return errOrgNotFound
+			}
+			existing.Name = strings.TrimSpace(req.Name)
+			org = *existing
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, org)
+	case http.MethodDelete:
+		err := orgStore.update(func() error {
+			if _, ok := orgStore.dir.Organizations[id]; !ok {
+				return errOrgNotFound
+			}
+			for _, project := range orgStore.dir.Projects {
+				if project.OrganizationID == id {
+					return errNotEmpty
+				}
+			}
+			delete(orgStore.dir.Organizations, id)
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
+
+// handleOrgProjects handles GET and POST /api/v1/orgs/{org}/projects requests
+func handleOrgProjects(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("org")
+	switch r.Method {
+	case http.MethodGet:
+		projects, err := orgStore.Projects(orgID)
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		for i := range projects {
+			projects[i] = projects[i].redacted()
+		}
+		writeJSON(w, http.StatusOK, projects)
+	case http.MethodPost:
+		req, ok := decodeNamed(w, r)
+		if !ok {
+			return
+		}
+		if req.ID == "leaderboard" {
+			sendError(w, "Invalid id", http.StatusBadRequest, "leaderboard is reserved for GET /api/v1/projects/leaderboard")
+			return
+		}
+		project := Project{ID: req.ID, OrganizationID: orgID, Name: req.Name, CreatedAt: time.Now().UTC()}
+		if req.Notifications != nil {
+			project.Notifications = *req.Notifications
+			project.Notifications.keepDiscordWebhook(NotificationSettings{})
+		}
+		err := orgStore.update(func() error {
+			if _, ok := orgStore.dir.Organizations[orgID]; !ok {
+				return errOrgNotFound
+			}
+			if _, exists := orgStore.dir.Projects[project.ID]; exists {
+				return errAlreadyExists
+			}
+			orgStore.dir.Projects[project.ID] = &project
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, project.redacted())
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleProject handles GET, PUT and DELETE /api/v1/projects/{project} requests
+func handleProject(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("project")
+	switch r.Method {
+	case http.MethodGet:
+		project, err := orgStore.Project(id)
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, project.redacted())
+	case http.MethodPut:
+		var req namedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+			sendError(w, "Missing name", http.StatusBadRequest, "name is required")
+			return
+		}
+		if err := req.Notifications.validate(); err != nil {
+			sendError(w, "Invalid notifications", http.StatusBadRequest, err.Error())
+			return
+		}
+		var project Project
+		err := orgStore.update(func() error {
+			existing, ok := orgStore.dir.Projects[id]
+			if !ok {
+				return errProjectNotFound
+			}
+			existing.Name = strings.TrimSpace(req.Name)
+			if req.Notifications != nil {
+				req.Notifications.keepDiscordWebhook(existing.Notifications)
+				existing.Notifications = *req.Notifications
+			}
+			project = *existing
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, project.redacted())
+	case http.MethodDelete:
+		err := orgStore.update(func() error {
+			if _, ok := orgStore.dir.Projects[id]; !ok {
+				return errProjectNotFound
+			}
+			for _, site := range orgStore.dir.Sites {
+				if site.ProjectID == id {
+					return errNotEmpty
+				}
+			}
+			delete(orgStore.dir.Projects, id)
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
+
+// decodeSite reads a site body and fills in its host and canonical URL
+func decodeSite(w http.ResponseWriter, r *http.Request) (Site, bool) {
+	var site Site
+	if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return site, false
+	}
+	if site.URL == "" && site.Host != "" {
+		site.URL = "https://" + site.Host + "/"
+	}
+	parsed, err := url.Parse(site.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		sendError(w, "Invalid URL", http.StatusBadRequest, "url must be an absolute http(s) URL, or give host")
+		return site, false
+	}
+	site.Host = siteKey(site.URL)
+	if !validSiteHost(site.Host) {
+		sendError(w, "Invalid site", http.StatusBadRequest, "host must be a valid host name such as example.com")
+		return site, false
+	}
+	if site.Profile != "" {
+		if _, err := profileStore.Get(site.Profile); err != nil {
+			sendError(w, "Invalid profile", http.StatusBadRequest, "profile must name an existing scan profile")
+			return site, false
+		}
+	}
+	if err := site.DefaultScan.validate(); err != nil {
+		sendError(w, "Invalid default_scan", http.StatusBadRequest, err.Error())
+		return site, false
+	}
+	if err := site.Notifications.validate(); err != nil {
+		sendError(w, "Invalid notifications", http.StatusBadRequest, err.Error())
+		return site, false
+	}
+	return site, true
+}
+
+// handleProjectSites handles GET and POST /api/v1/projects/{project}/sites requests
+func handleProjectSites(w http.ResponseWriter, r *http.Request) {
+	projectID := r.PathValue("project")
+	switch r.Method {
+	case http.MethodGet:
+		sites, err := orgStore.Sites(projectID)
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		for i := range sites {
+			sites[i] = sites[i].redacted()
+		}
+		writeJSON(w, http.StatusOK, sites)
+	case http.MethodPost:
+		site, ok := decodeSite(w, r)
+		if !ok {
+			return
+		}
+		site.ProjectID = projectID
+		site.CreatedAt = time.Now().UTC()
+		site.Notifications.keepDiscordWebhook(NotificationSettings{})
+		err := orgStore.update(func() error {
+			project, ok := orgStore.dir.Projects[projectID]
+			if !ok {
+				return errProjectNotFound
+			}
+			if _, exists := orgStore.dir.Sites[site.Host]; exists {
+				return errAlreadyExists
+			}
+			site.OrganizationID = project.OrganizationID
+			orgStore.dir.Sites[site.Host] = &site
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, site.redacted())
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleSite handles GET, PUT and DELETE /api/v1/sites/{host} requests.
+// Deleting the record keeps the site's scans; DELETE /sites/{host}/data erases them.
+func handleSite(w http.ResponseWriter, r *http.Request) {
+	host := siteHostParam(r)
+	switch r.Method {
+	case http.MethodGet:
+		site, err := orgStore.Site(host)
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, site.redacted())
+	case http.MethodPut:
+		update, ok := decodeSite(w, r)
+		if !ok {
+			return
+		}
+		if update.Host != host {
+			sendError(w, "Invalid URL", http.StatusBadRequest, "url must stay on the site's host")
+			return
+		}
+		var site Site
+		err := orgStore.update(func() error {
+			existing, ok := orgStore.dir.Sites[host]
+			if !ok {
+				return errSiteRecordNotFound
+			}
+			if update.ProjectID != "" && update.ProjectID != existing.ProjectID {
+				project, ok := orgStore.dir.Projects[update.ProjectID]
+				if !ok {
+					return errProjectNotFound
+				}
+				existing.ProjectID = project.ID
+				existing.OrganizationID = project.OrganizationID
+			}
+			existing.Name = update.Name
+			existing.URL = update.URL
+			existing.Profile = update.Profile
+			existing.DefaultScan = update.DefaultScan
+			update.Notifications.keepDiscordWebhook(existing.Notifications)
+			existing.Notifications = update.Notifications
+			existing.PublicWidget = update.PublicWidget
+			site = *existing
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, site.redacted())
+	case http.MethodDelete:
+		err := orgStore.update(func() error {
+			if _, ok := orgStore.dir.Sites[host]; !ok {
+				return errSiteRecordNotFound
+			}
+			delete(orgStore.dir.Sites, host)
+			return nil
+		})
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
+
+// handleSiteScans handles GET /api/v1/sites/{host}/scans requests, listing the
+// site's stored scans newest first
+func handleSiteScans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	host := siteHostParam(r)
+	if !validSiteHost(host) {
+		sendSiteStoreError(w, errInvalidSite)
+		return
+	}
+	scans, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelError, "Failed to list scans: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not list stored scans")
+		return
+	}
+
+	history := make([]ScanListEntry, 0)
+	for _, scan := range scans {
+		if scan.Site == host {
+			history = append(history, scan.entry())
+		}
+	}
+	writeJSON(w, http.StatusOK, history)
+}
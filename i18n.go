@@ -0,0 +1,69 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLanguage is used when no requested language is supported
+const defaultLanguage = "en"
+
+// languageTagPattern loosely validates BCP 47 language tags such as "en", "pt-BR" or "zh-Hant"
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// negotiateLanguage picks the best supported language from an Accept-Language header.
+// Regional variants match their base language (de-AT → de).
+func negotiateLanguage(acceptLanguage string, supported []string) string {
+	type weightedTag struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []weightedTag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			tags = append(tags, weightedTag{strings.ToLower(tag), quality})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+
+	for _, tag := range tags {
+		base, _, _ := strings.Cut(tag.tag, "-")
+		for _, lang := range supported {
+			if tag.tag == lang || base == lang {
+				return lang
+			}
+		}
+	}
+
+	return defaultLanguage
+}
+
+// localizeRemediation replaces the remediation guidance on every issue with the given language's
+func localizeRemediation(pages []PageResult, lang string) []PageResult {
+	catalog := remediationCatalogs.For(lang)
+
+	localized := make([]PageResult, len(pages))
+	for i, page := range pages {
+		issues := make([]AccessibilityIssue, len(page.Issues))
+		for j, issue := range page.Issues {
+			issue.Remediation = catalog.Lookup(issue.AuditID)
+			issues[j] = issue
+		}
+		page.Issues = issues
+		localized[i] = page
+	}
+	return localized
+}
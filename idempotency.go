@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Idempotency key limits
+const (
+	idempotencyKeyTTL       = 24 * time.Hour
+	maxIdempotencyKeyLength = 255
+)
+
+// errIdempotencyMismatch is returned when a key is reused with a different request body
+var errIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// IdempotencyStore remembers which scan each Idempotency-Key produced, so
+// retried submissions return the original result instead of scanning again.
+// Keys belong to the client that sent them, so two clients choosing the same
+// key never see each other's scans.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[clientIdempotencyKey]*idempotencyEntry
+}
+
+// clientIdempotencyKey is an Idempotency-Key header value of one API client
+type clientIdempotencyKey struct {
+	client string
+	key    string
+}
+
+// idempotencyEntry tracks a scan started under an idempotency key
+type idempotencyEntry struct {
+	requestHash string
+	scanID      string // set once the scan has been stored
+	done        chan struct{}
+	expires     time.Time
+}
+
+// idempotencyKeys holds the idempotency keys of recent scan submissions
+var idempotencyKeys = &IdempotencyStore{entries: make(map[clientIdempotencyKey]*idempotencyEntry)}
+
+// requestHash fingerprints a decoded request so retries can be told apart from key reuse
+func requestHash(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin claims a client's key for a new scan, or returns the ID of the scan
+// already stored under it for a request with the same fingerprint. While
+// another request holds the key, Begin waits for it to finish so a retry
+// never starts a duplicate scan.
+func (s *IdempotencyStore) Begin(ctx context.Context, client, header, hash string) (scanID string, err error) {
+	key := clientIdempotencyKey{client, header}
+	for {
+		s.mu.Lock()
+		s.purge()
+
+		entry, ok := s.entries[key]
+		if !ok {
+			s.entries[key] = &idempotencyEntry{requestHash: hash, done: make(chan struct{})}
+			s.mu.Unlock()
+			return "", nil
+		}
+		if entry.requestHash != hash {
+			s.mu.Unlock()
+			return "", errIdempotencyMismatch
+		}
+		if entry.scanID != "" {
+			s.mu.Unlock()
+			return entry.scanID, nil
+		}
+		done := entry.done
+		s.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Complete records the stored scan for a key claimed with Begin
+func (s *IdempotencyStore) Complete(client, header, scanID string) {
+	key := clientIdempotencyKey{client, header}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && entry.scanID == "" {
+		entry.scanID = scanID
+		entry.expires = time.Now().Add(idempotencyKeyTTL)
+		close(entry.done)
+	}
+}
+
+// Abandon releases a key claimed with Begin whose scan produced no stored result
+func (s *IdempotencyStore) Abandon(client, header string) {
+	key := clientIdempotencyKey{client, header}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && entry.scanID == "" {
+		delete(s.entries, key)
+		close(entry.done)
+	}
+}
+
+// purge removes expired keys; callers must hold s.mu
+func (s *IdempotencyStore) purge() {
+	now := time.Now()
+	for key, entry := range s.entries {
+		if entry.scanID != "" && now.After(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
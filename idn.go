@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// splitHost separates a host name from its port, if it has one. IP literals
+// come back with ok false, since they are not domain names.
+func splitHost(host string) (name, port string, ok bool) {
+	name = host
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		name, port = h, p
+	}
+	if strings.HasPrefix(name, "[") || net.ParseIP(name) != nil {
+		return "", "", false
+	}
+	return name, port, true
+}
+
+// joinHost puts a port back on a host name
+func joinHost(name, port string) string {
+	if port == "" {
+		return name
+	}
+	return name + ":" + port
+}
+
+// asciiHost returns a host in the form hosts are compared and stored in:
+// lowercase, with internationalized domain names in punycode, so bücher.de,
+// BÜCHER.de and xn--bcher-kva.de are one host. The port is kept. Hosts IDNA
+// rejects, and IP addresses, are only lowercased.
+func asciiHost(host string) string {
+	if isASCII(host) {
+		return strings.ToLower(host)
+	}
+	name, port, ok := splitHost(host)
+	if !ok {
+		return strings.ToLower(host)
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return strings.ToLower(host)
+	}
+	return joinHost(ascii, port)
+}
+
+// unicodeHost returns a host with its punycode labels in Unicode, for
+// display: xn--bcher-kva.de becomes bücher.de. Hosts that do not convert
+// are returned unchanged.
+func unicodeHost(host string) string {
+	if !strings.Contains(strings.ToLower(host), "xn--") {
+		return host
+	}
+	name, port, ok := splitHost(host)
+	if !ok {
+		return host
+	}
+	display, err := idna.Display.ToUnicode(name)
+	if err != nil {
+		return host
+	}
+	return joinHost(display, port)
+}
+
+// displayURL returns a URL with its host in Unicode, for reports read by
+// people. The rest of the URL is left as it is.
+func displayURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+	display := unicodeHost(parsed.Host)
+	if display == parsed.Host {
+		return rawURL
+	}
+	return strings.Replace(rawURL, parsed.Host, display, 1)
+}
+
+// siteHostParam returns the {host} of a request path as a site key
+func siteHostParam(r *http.Request) string {
+	return asciiHost(r.PathValue("host"))
+}
+
+// isASCII reports whether s holds only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/url"
+)
+
+// maxFramesPerPage caps the iframes audited for one page
+const maxFramesPerPage = 5
+
+// FrameAudit is the audit of an iframe document embedded in a page. Its
+// issues are listed with the page's, marked with frame_url.
+type FrameAudit struct {
+	URL                string  `json:"url"`
+	AccessibilityScore float64 `json:"accessibility_score"`
+	Issues             int     `json:"issues"`
+	Error              string  `json:"error,omitempty"`
+}
+
+// frameURL resolves an iframe src against its page, returning it only for
+// same-origin documents. The query string is kept, since embedded widgets
+// are usually configured through it.
+func (s *AccessibilityScanner) frameURL(page *url.URL, src string) (string, bool) {
+	srcURL, err := url.Parse(src)
+	if err != nil || src == "" {
+		return "", false
+	}
+	resolved := page.ResolveReference(srcURL)
+	frame := s.normalizer.normalize(resolved)
+	origin := s.normalizer.normalize(page)
+	if frame.Scheme != origin.Scheme || frame.Host != origin.Host {
+		return "", false
+	}
+	frame.RawQuery = resolved.RawQuery
+	return frame.String(), frame.String() != origin.String()
+}
+
+// recordFrames remembers the same-origin iframes found on a page
+func (s *AccessibilityScanner) recordFrames(pageURL string, frames []string) {
+	if len(frames) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames[pageURL] = frames
+}
+
+// auditFrames audits the iframes of a scanned page and adds their issues to
+// it. An iframe embedded on several pages is audited once per scan. Frame
+// audits count as Lighthouse calls and stop when the budget is used up.
+func (s *AccessibilityScanner) auditFrames(ctx context.Context, page *PageResult) {
+	s.mu.Lock()
+	frames := s.frames[page.URL]
+	s.mu.Unlock()
+
+	for _, frameURL := range frames {
+		audit, ok := s.frameAudits[frameURL]
+		if !ok {
+			if s.budgetExhausted() || !s.pause(ctx) {
+				return
+			}
+			audit = s.scanPageWithLighthouse(ctx, frameURL)
+			s.frameAudits[frameURL] = audit
+		}
+
+		page.Frames = append(page.Frames, FrameAudit{
+			URL:                frameURL,
+			AccessibilityScore: audit.AccessibilityScore,
+			Issues:             len(audit.Issues),
+			Error:              audit.Error,
+		})
+		for _, issue := range audit.Issues {
+			issue.FrameURL = frameURL
+			page.Issues = append(page.Issues, issue)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errOperatorCancelled is the cancellation cause of scans stopped through the admin API
+var errOperatorCancelled = errors.New("scan cancelled by an operator")
+
+// ScanJob tracks a scan request from the moment it is queued until it finishes
+type ScanJob struct {
+	mu        sync.Mutex
+	id        string
+	url       string
+	state     string // "queued" or "running"
+	createdAt time.Time
+	startedAt time.Time
+	cancel    context.CancelCauseFunc
+	done      chan struct{} // closed once the scan has finished and stored its result
+
+	currentURL      string
+	pending         []string // the first pending URLs, up to frontierPeekLimit
+	pendingCount    int
+	pagesScanned    int
+	pagesLimit      int
+	visited         int
+	lighthouseCalls int
+}
+
+// ScanJobStatus describes an active scan for the admin API
+type ScanJobStatus struct {
+	ID              string     `json:"id"`
+	URL             string     `json:"url"`
+	State           string     `json:"state"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	ElapsedSeconds  int        `json:"elapsed_seconds"`
+	CurrentURL      string     `json:"current_url,omitempty"`
+	PagesScanned    int        `json:"pages_scanned"`
+	PagesLimit      int        `json:"pages_limit"`
+	PagesPending    int        `json:"pages_pending"`
+	UrlsVisited     int        `json:"urls_visited"`
+	LighthouseCalls int        `json:"lighthouse_calls"`
+	// EffectiveConcurrency is how many scans may run at once right now,
+	// reduced while the PageSpeed API is rate limiting
+	EffectiveConcurrency int `json:"effective_concurrency"`
+
+	Links map[string]Link `json:"_links,omitempty"`
+}
+
+// FrontierPeek is a snapshot of the URLs an active scan has yet to visit
+type FrontierPeek struct {
+	ID           string   `json:"id"`
+	CurrentURL   string   `json:"current_url,omitempty"`
+	Pending      []string `json:"pending"` // the first pending URLs, up to 1000
+	PendingTotal int      `json:"pending_total"`
+	Visited      int      `json:"urls_visited"`
+}
+
+// JobRegistry holds the scans that are queued or running
+type JobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*ScanJob
+}
+
+// scanJobs tracks the active scans of this instance
+var scanJobs = &JobRegistry{jobs: make(map[string]*ScanJob)}
+
+// Register adds a queued scan and returns it with its cancellable context
+func (r *JobRegistry) Register(ctx context.Context, id, url string, limit int) (*ScanJob, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	job := &ScanJob{
+		id:         id,
+		url:        url,
+		state:      "queued",
+		createdAt:  time.Now().UTC(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		pagesLimit: limit,
+	}
+
+	r.mu.Lock()
+	r.jobs[id] = job
+	r.mu.Unlock()
+	return job, ctx
+}
+
+// Remove drops a finished scan from the registry
+func (r *JobRegistry) Remove(job *ScanJob) {
+	r.mu.Lock()
+	delete(r.jobs, job.id)
+	r.mu.Unlock()
+	job.cancel(nil)
+	close(job.done)
+}
+
+// Get returns an active scan by ID
+func (r *JobRegistry) Get(id string) (*ScanJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	return job, ok
+}
+
+// List returns the status of every active scan, oldest first
+func (r *JobRegistry) List() []ScanJobStatus {
+	r.mu.Lock()
+	jobs := make([]*ScanJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job)
+	}
+	r.mu.Unlock()
+
+	statuses := make([]ScanJobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].CreatedAt.Before(statuses[j].CreatedAt)
+	})
+	return statuses
+}
+
+// start marks a queued scan as running
+func (j *ScanJob) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = "running"
+	j.startedAt = time.Now().UTC()
+}
+
+// Cancel stops the scan; a queued scan leaves the queue without running
+func (j *ScanJob) Cancel() {
+	j.cancel(errOperatorCancelled)
+}
+
+// update records the crawl position of a running scan
+func (j *ScanJob) update(currentURL string, pending []string, pendingCount, scanned, visited, lighthouseCalls int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.currentURL = currentURL
+	j.pending = pending
+	j.pendingCount = pendingCount
+	j.pagesScanned = scanned
+	j.visited = visited
+	j.lighthouseCalls = lighthouseCalls
+}
+
+// Status returns the current progress of the scan
+func (j *ScanJob) Status() ScanJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := ScanJobStatus{
+		ID:              j.id,
+		URL:             j.url,
+		State:           j.state,
+		CreatedAt:       j.createdAt,
+		ElapsedSeconds:  int(time.Since(j.createdAt).Seconds()),
+		CurrentURL:      j.currentURL,
+		PagesScanned:    j.pagesScanned,
+		PagesLimit:      j.pagesLimit,
+		PagesPending:    j.pendingCount,
+		UrlsVisited:     j.visited,
+		LighthouseCalls: j.lighthouseCalls,
+
+		EffectiveConcurrency: scanQueue.Stats().EffectiveConcurrent,
+	}
+	if !j.startedAt.IsZero() {
+		started := j.startedAt
+		status.StartedAt = &started
+	}
+	return status
+}
+
+// Frontier returns a copy of the URLs the scan has yet to visit
+func (j *ScanJob) Frontier() FrontierPeek {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return FrontierPeek{
+		ID:           j.id,
+		CurrentURL:   j.currentURL,
+		Pending:      append([]string{}, j.pending...),
+		PendingTotal: j.pendingCount,
+		Visited:      j.visited,
+	}
+}
+
+// reportProgress publishes the crawl position to the scan's job, if any
+func (s *AccessibilityScanner) reportProgress(currentURL string, scanned int) {
+	if s.job != nil {
+		s.job.update(currentURL, s.urls.Pending(frontierPeekLimit), s.urls.Len(), scanned, s.urls.Visited(), s.lighthouseCalls)
+	}
+}
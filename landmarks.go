@@ -0,0 +1,200 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// landmarkRoles are the ARIA landmark roles
+var landmarkRoles = map[string]bool{
+	"banner":        true,
+	"navigation":    true,
+	"main":          true,
+	"contentinfo":   true,
+	"complementary": true,
+	"region":        true,
+	"form":          true,
+	"search":        true,
+}
+
+// sectioningElements scope header and footer to a section instead of the page
+var sectioningElements = map[string]bool{
+	"article": true,
+	"aside":   true,
+	"main":    true,
+	"nav":     true,
+	"section": true,
+}
+
+// Landmark is a landmark region of a page, with the landmarks inside it
+type Landmark struct {
+	Role     string      `json:"role"`
+	Label    string      `json:"label,omitempty"`
+	Selector string      `json:"selector"`
+	Children []*Landmark `json:"children,omitempty"`
+}
+
+// landmarkLabel returns the name given to a landmark by aria-labelledby,
+// aria-label or title, which tells landmarks of the same role apart
+func landmarkLabel(n *html.Node) string {
+	label := labelledByText(n)
+	if label == "" {
+		label = strings.Join(strings.Fields(attrValue(n, "aria-label")), " ")
+	}
+	if label == "" {
+		label = strings.TrimSpace(attrValue(n, "title"))
+	}
+	return truncateUTF8(label, maxHeadingTextLength)
+}
+
+// landmarkRole returns the landmark role of an element, from its role
+// attribute or its tag, or "" if it is not a landmark. Sections and forms are
+// landmarks only when named; headers and footers only outside sections.
+func landmarkRole(n *html.Node) string {
+	if n.Type != html.ElementNode {
+		return ""
+	}
+	role := strings.TrimSpace(attrValue(n, "role"))
+	if role == "" {
+		switch n.Data {
+		case "header":
+			role = "banner"
+		case "footer":
+			role = "contentinfo"
+		case "nav":
+			role = "navigation"
+		case "main":
+			role = "main"
+		case "aside":
+			role = "complementary"
+		case "section":
+			role = "region"
+		case "form":
+			role = "form"
+		case "search":
+			role = "search"
+		default:
+			return ""
+		}
+		if (n.Data == "header" || n.Data == "footer") && hasAncestor(n, func(p *html.Node) bool { return sectioningElements[p.Data] }) {
+			return ""
+		}
+	}
+	if !landmarkRoles[role] {
+		return ""
+	}
+	if (role == "region" || role == "form") && landmarkLabel(n) == "" {
+		return ""
+	}
+	return role
+}
+
+// hiddenFromAT reports whether an element is hidden from assistive technology
+func hiddenFromAT(n *html.Node) bool {
+	_, hidden := attrLookup(n, "hidden")
+	return hidden || attrValue(n, "aria-hidden") == "true"
+}
+
+// pageLandmarks returns the landmark regions of a page, nested as in the
+// document
+func pageLandmarks(doc *html.Node) []*Landmark {
+	var landmarks []*Landmark
+	var walk func(n *html.Node, siblings *[]*Landmark)
+	walk = func(n *html.Node, siblings *[]*Landmark) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || hiddenFromAT(c) {
+				continue
+			}
+			switch c.Data {
+			case "script", "style", "template", "head":
+				continue
+			}
+			if role := landmarkRole(c); role != "" {
+				landmark := &Landmark{Role: role, Label: landmarkLabel(c), Selector: elementPath(c)}
+				*siblings = append(*siblings, landmark)
+				walk(c, &landmark.Children)
+				continue
+			}
+			walk(c, siblings)
+		}
+	}
+	walk(doc, &landmarks)
+	return landmarks
+}
+
+// checkLandmarks flags pages without a main landmark and content outside
+// every landmark, which screen reader users skip when they move between
+// landmarks. Each element of content outside is reported once, at the
+// outermost element holding no landmark.
+func checkLandmarks(doc *html.Node) []AccessibilityIssue {
+	body := firstElement(doc, func(n *html.Node) bool { return n.Data == "body" })
+	if body == nil {
+		return nil
+	}
+	isLandmark := func(n *html.Node) bool { return landmarkRole(n) != "" && !hiddenFromAT(n) }
+
+	var issues []AccessibilityIssue
+	if firstElement(body, func(n *html.Node) bool { return landmarkRole(n) == "main" }) == nil {
+		issues = append(issues, AccessibilityIssue{
+			AuditID:     "landmark-main-missing",
+			title:       titled("default"),
+			Description: "Screen reader users jump to the main landmark to reach the content of the page.",
+			Impact:      "moderate",
+			Selector:    "body",
+			Snippet:     elementSnippet(body),
+		})
+	}
+
+	outside := func(n *html.Node, title *checkTitle) {
+		issues = append(issues, AccessibilityIssue{
+			AuditID:     "landmark-content-outside",
+			title:       title,
+			Description: "Screen reader users moving from landmark to landmark miss content that is not in any of them.",
+			Impact:      "moderate",
+			Selector:    elementPath(n),
+			Snippet:     elementSnippet(n),
+		})
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		textFlagged := false
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				if !textFlagged && strings.TrimSpace(c.Data) != "" {
+					textFlagged = true
+					outside(n, titled("text", "{element}", n.Data))
+				}
+			case c.Type != html.ElementNode || hiddenFromAT(c) || isLandmark(c) || isExemptFromLandmarks(c):
+			case firstElement(c, isLandmark) != nil:
+				walk(c)
+			case strings.TrimSpace(visibleText(c)) != "" || firstElement(c, isFocusable) != nil:
+				outside(c, titled("content", "{element}", c.Data))
+			}
+		}
+	}
+	walk(body)
+	return issues
+}
+
+// isExemptFromLandmarks reports whether an element may stand outside
+// landmarks: scripts, dialogs and skip links
+func isExemptFromLandmarks(n *html.Node) bool {
+	switch n.Data {
+	case "script", "style", "template", "noscript", "dialog":
+		return true
+	}
+	if role := attrValue(n, "role"); role == "dialog" || role == "alertdialog" {
+		return true
+	}
+	// A skip link, alone or in a wrapper of its own
+	link := firstElement(n, isFocusable)
+	if link == nil {
+		return false
+	}
+	if _, ok := skipLinkFragment(link); !ok {
+		return false
+	}
+	return strings.TrimSpace(textContent(n)) == strings.TrimSpace(textContent(link))
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Minimum evidence before the language of a page is guessed
+const (
+	minLanguageWords   = 30 // words of Latin-script text
+	minLanguageLetters = 60 // letters of text in other scripts
+	minStopwordHits    = 8
+	minStopwordShare   = 0.2 // of the words; text in a language without a profile scores lower
+)
+
+// languageProfile recognizes a language written in the Latin or Cyrillic
+// script by its most frequent words
+type languageProfile struct {
+	code       string
+	name       string
+	script     *unicode.RangeTable
+	compatible []string // declared languages that are not a mismatch
+	stopwords  map[string]bool
+}
+
+// stopwordSet builds a set of stopwords
+func stopwordSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+// languageProfiles are the languages told apart by their stopwords
+var languageProfiles = []languageProfile{
+	{"en", "English", unicode.Latin, nil, stopwordSet("the and of to is in that it for with you are this on be as have was not your we they from by or but what which will can more about our all")},
+	{"de", "German", unicode.Latin, []string{"gsw", "lb"}, stopwordSet("der die und das ist nicht ein eine zu den mit sich des auf für im dem auch es an werden aus er sie wir ihr sind oder bei nach wird noch wie über einen")},
+	{"fr", "French", unicode.Latin, nil, stopwordSet("le la les et des est une un du que pour dans en qui pas sur au avec ce il sont vous nous plus par ou mais cette aux être été leur")},
+	{"es", "Spanish", unicode.Latin, []string{"ca", "gl"}, stopwordSet("el la los las y de que en un una es por con para del se no su al lo como más pero sus le ha este esta son también está muy")},
+	{"it", "Italian", unicode.Latin, nil, stopwordSet("il di che e la per un una è non sono del della le con si gli da in al dei nel più anche come questo alla delle ma ci essere")},
+	{"pt", "Portuguese", unicode.Latin, []string{"gl"}, stopwordSet("o a os as de que e do da em um uma para com não por mais dos das se no na ao é são você mas como foi seu sua também")},
+	{"nl", "Dutch", unicode.Latin, []string{"af", "li"}, stopwordSet("de het een en van is dat op te in voor met niet zijn er aan ook als bij door worden wordt u je naar maar dit uit om hebben deze kan")},
+	{"sv", "Swedish", unicode.Latin, []string{"no", "nb", "nn"}, stopwordSet("och att det som en är av för på med till den inte har de ett om var jag vi men kan från så eller också ska sig dig här")},
+	{"da", "Danish", unicode.Latin, []string{"no", "nb", "nn"}, stopwordSet("og at det er en til på af for med den ikke som har de et om jeg vi men kan fra så eller også skal sig dig her vil være")},
+	{"pl", "Polish", unicode.Latin, nil, stopwordSet("i w na z się nie do jest to że o jak a od za po dla są przez ale co tak oraz jego ich może tylko już które który")},
+	{"tr", "Turkish", unicode.Latin, nil, stopwordSet("ve bir bu da de için ile olan çok daha gibi ne var olarak en ama sonra kadar her değil mi ben sen biz")},
+	{"ru", "Russian", unicode.Cyrillic, []string{"be", "bg", "mk", "sr"}, stopwordSet("и в не на что с по как это к но из у за от о для так же все его вы мы они только или был была быть есть")},
+	{"uk", "Ukrainian", unicode.Cyrillic, []string{"be", "bg", "mk", "sr"}, stopwordSet("і в не на що з до як це у та за від для але так його ви ми вони тільки або був була бути є які який також ще")},
+}
+
+// scriptLanguage is a script that, by itself, narrows down the language of
+// a page
+type scriptLanguage struct {
+	script     *unicode.RangeTable
+	code       string // empty when the script is shared by several languages
+	name       string
+	compatible []string
+}
+
+// scriptLanguages are the scripts other than Latin, Han and kana that
+// narrow down the language of a page
+var scriptLanguages = []scriptLanguage{
+	{unicode.Greek, "el", "Greek", nil},
+	{unicode.Hebrew, "he", "Hebrew", []string{"iw", "yi"}},
+	{unicode.Arabic, "", "Arabic script", []string{"ar", "fa", "ur", "ps", "ku", "sd", "ug"}},
+	{unicode.Hangul, "ko", "Korean", nil},
+	{unicode.Thai, "th", "Thai", nil},
+	{unicode.Devanagari, "", "Devanagari script", []string{"hi", "mr", "ne", "sa", "kok"}},
+	{unicode.Bengali, "", "Bengali script", []string{"bn", "as"}},
+	{unicode.Tamil, "ta", "Tamil", nil},
+	{unicode.Georgian, "ka", "Georgian", nil},
+	{unicode.Armenian, "hy", "Armenian", nil},
+	{unicode.Cyrillic, "", "Cyrillic script", []string{"ru", "uk", "bg", "sr", "mk", "be", "kk", "ky", "mn", "tg", "tt", "ba", "cv"}},
+}
+
+// detectedLanguage is the language a page's text appears to be written in
+type detectedLanguage struct {
+	code       string // empty when only the script is known
+	name       string
+	compatible []string
+}
+
+// matches reports whether a declared language tag fits the detected language
+func (d detectedLanguage) matches(tag string) bool {
+	primary := primaryLanguage(tag)
+	if primary == d.code {
+		return true
+	}
+	for _, code := range d.compatible {
+		if primary == code {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names the detected language for an issue title
+func (d detectedLanguage) describe() string {
+	if d.code == "" {
+		return d.name
+	}
+	return fmt.Sprintf("%s (%s)", d.name, d.code)
+}
+
+// primaryLanguage returns the lowercase primary subtag of a language tag
+func primaryLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// detectLanguage guesses the language of a text from its script and, for
+// the Latin and Cyrillic scripts, its most frequent words. It reports false
+// when the text is too short or too mixed to tell.
+func detectLanguage(text string) (detectedLanguage, bool) {
+	letters, han, kana := 0, 0, 0
+	byScript := make([]int, len(scriptLanguages))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+			continue
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+			continue
+		}
+		for i, script := range scriptLanguages {
+			if unicode.Is(script.script, r) {
+				byScript[i]++
+				break
+			}
+		}
+	}
+	if letters >= minLanguageLetters {
+		dominant := func(count int) bool { return count*10 >= letters*6 }
+
+		// Japanese mixes kana with Han characters
+		if dominant(han + kana) {
+			if kana*10 >= han+kana {
+				return detectedLanguage{code: "ja", name: "Japanese"}, true
+			}
+			return detectedLanguage{code: "zh", name: "Chinese", compatible: []string{"yue"}}, true
+		}
+		for i, script := range scriptLanguages {
+			if !dominant(byScript[i]) {
+				continue
+			}
+			if script.script == unicode.Cyrillic {
+				if detected, ok := detectByStopwords(text, unicode.Cyrillic); ok {
+					return detected, true
+				}
+			}
+			return detectedLanguage{code: script.code, name: script.name, compatible: script.compatible}, true
+		}
+	}
+	return detectByStopwords(text, unicode.Latin)
+}
+
+// detectByStopwords picks the language whose stopwords the text uses most,
+// provided they are common enough and it clearly leads the others
+func detectByStopwords(text string, script *unicode.RangeTable) (detectedLanguage, bool) {
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(tokens) < minLanguageWords {
+		return detectedLanguage{}, false
+	}
+
+	best, bestHits, secondHits := -1, 0, 0
+	for i, profile := range languageProfiles {
+		if profile.script != script {
+			continue
+		}
+		hits := 0
+		for _, token := range tokens {
+			if profile.stopwords[token] {
+				hits++
+			}
+		}
+		switch {
+		case hits > bestHits:
+			best, bestHits, secondHits = i, hits, bestHits
+		case hits > secondHits:
+			secondHits = hits
+		}
+	}
+	if best < 0 || bestHits < minStopwordHits || float64(bestHits) < minStopwordShare*float64(len(tokens)) || bestHits*2 < secondHits*3 {
+		return detectedLanguage{}, false
+	}
+	profile := languageProfiles[best]
+	return detectedLanguage{code: profile.code, name: profile.name, compatible: profile.compatible}, true
+}
+
+// languageText returns the text of a page in its declared language: hidden
+// content, scripts, code and passages with a lang attribute of their own are
+// left out
+func languageText(root *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template", "code", "pre", "head":
+				return
+			}
+			if n.Data != "html" && attrValue(n, "lang") != "" {
+				return
+			}
+			if _, hidden := attrLookup(n, "hidden"); hidden || attrValue(n, "aria-hidden") == "true" {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return b.String()
+}
+
+// checkPageLanguage compares the language a page declares on <html> with the
+// language its text is written in (WCAG 3.1.1). Pages too short or mixed to
+// tell are not flagged.
+func checkPageLanguage(doc *html.Node) []AccessibilityIssue {
+	var htmlNode *html.Node
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "html" {
+			htmlNode = c
+		}
+	}
+	if htmlNode == nil {
+		return nil
+	}
+	detected, ok := detectLanguage(languageText(htmlNode))
+	if !ok {
+		return nil
+	}
+
+	declared := attrValue(htmlNode, "lang")
+	if strings.TrimSpace(declared) == "" {
+		declared = attrValue(htmlNode, "xml:lang")
+	}
+	issue := AccessibilityIssue{
+		Impact:   "serious",
+		Selector: "html",
+		Snippet:  elementSnippet(htmlNode),
+	}
+	switch {
+	case strings.TrimSpace(declared) == "":
+		issue.AuditID = "page-lang-missing"
+		issue.title = titled("default", "{language}", detected.describe())
+		issue.Description = "Screen readers pick their voice and pronunciation from the lang attribute of the <html> element. Without it they fall back to the user's default language."
+	case !detected.matches(declared):
+		issue.AuditID = "page-lang-mismatch"
+		issue.title = titled("default", "{declared}", declared, "{language}", detected.describe())
+		issue.Description = "Screen readers read the page with the voice and pronunciation of the declared language, so content in another language is hard to understand."
+	default:
+		return nil
+	}
+	return []AccessibilityIssue{issue}
+}
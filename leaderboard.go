@@ -0,0 +1,189 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// leaderboardTrendThreshold is the site score change below which a site's
+// trend is steady
+const leaderboardTrendThreshold = 0.01
+
+// Trends of a site's score since its previous scan
+const (
+	trendUp     = "up"
+	trendDown   = "down"
+	trendSteady = "steady"
+	trendNew    = "new" // first finished scan
+)
+
+// trendArrows are shown next to the score on dashboards
+var trendArrows = map[string]string{
+	trendUp:     "↑",
+	trendDown:   "↓",
+	trendSteady: "→",
+	trendNew:    "•",
+}
+
+// LeaderboardEntry ranks one registered site by its latest finished scan
+type LeaderboardEntry struct {
+	Rank           int            `json:"rank"`
+	Host           string         `json:"host"`
+	Name           string         `json:"name,omitempty"`
+	ProjectID      string         `json:"project_id"`
+	OrganizationID string         `json:"organization_id"`
+	ScanID         string         `json:"scan_id"`
+	ScanTime       time.Time      `json:"scan_time"`
+	SiteScore      float64        `json:"site_score"`
+	PreviousScore  *float64       `json:"previous_score,omitempty"`
+	ScoreChange    float64        `json:"score_change"`
+	Trend          string         `json:"trend"`
+	TrendArrow     string         `json:"trend_arrow"`
+	PagesScanned   int            `json:"pages_scanned"`
+	TotalIssues    int            `json:"total_issues"`
+	IssuesPerPage  float64        `json:"issues_per_page"`
+	IssuesByImpact map[string]int `json:"issues_by_impact"`
+}
+
+// Leaderboard ranks the registered sites, those needing attention first
+type Leaderboard struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Sort        string             `json:"sort"`
+	Sites       []LeaderboardEntry `json:"sites"`
+	Unscanned   []string           `json:"unscanned"` // hosts with no finished scan yet
+}
+
+// scoreTrend classifies a site score change
+func scoreTrend(change float64) string {
+	switch {
+	case change >= leaderboardTrendThreshold:
+		return trendUp
+	case change <= -leaderboardTrendThreshold:
+		return trendDown
+	}
+	return trendSteady
+}
+
+// leaderboardEntry describes a site from its latest finished scan and the
+// score of the one before, if any
+func leaderboardEntry(site Site, latest ScanResult, previous *storedScan) LeaderboardEntry {
+	side := compareSide(latest)
+	entry := LeaderboardEntry{
+		Host:           site.Host,
+		Name:           site.Name,
+		ProjectID:      site.ProjectID,
+		OrganizationID: site.OrganizationID,
+		ScanID:         latest.ID,
+		ScanTime:       latest.ScanTime,
+		SiteScore:      latest.SiteScore,
+		Trend:          trendNew,
+		PagesScanned:   side.PagesScanned,
+		TotalIssues:    side.TotalIssues,
+		IssuesPerPage:  side.IssuesPerPage,
+		IssuesByImpact: side.IssuesByImpact,
+	}
+	if previous != nil {
+		score := previous.SiteScore
+		entry.PreviousScore = &score
+		entry.ScoreChange = math.Round((latest.SiteScore-score)*1000) / 1000
+		entry.Trend = scoreTrend(entry.ScoreChange)
+	}
+	entry.TrendArrow = trendArrows[entry.Trend]
+	return entry
+}
+
+// handleLeaderboard handles GET /api/v1/projects/leaderboard requests,
+// ranking every registered site by its latest score and issue density
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	query := r.URL.Query()
+	orgID, projectID := query.Get("org"), query.Get("project")
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = "score"
+	}
+	if sortBy != "score" && sortBy != "issues_per_page" {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "sort must be score or issues_per_page")
+		return
+	}
+	if orgID != "" {
+		if _, err := orgStore.Organization(orgID); err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+	}
+	if projectID != "" {
+		if _, err := orgStore.Project(projectID); err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+	}
+
+	scans, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelError, "Failed to list scans: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not list stored scans")
+		return
+	}
+	// Finished scans of each site, newest first
+	history := make(map[string][]storedScan)
+	for _, scan := range scans {
+		if scan.Status != "completed" && scan.Status != "partial" {
+			continue
+		}
+		history[scan.Site] = append(history[scan.Site], scan)
+	}
+	for _, siteScans := range history {
+		sort.Slice(siteScans, func(i, j int) bool { return siteScans[i].ScanTime.After(siteScans[j].ScanTime) })
+	}
+
+	board := Leaderboard{
+		GeneratedAt: time.Now().UTC(),
+		Sort:        sortBy,
+		Sites:       make([]LeaderboardEntry, 0),
+		Unscanned:   make([]string, 0),
+	}
+	for _, site := range orgStore.AllSites() {
+		if (orgID != "" && site.OrganizationID != orgID) || (projectID != "" && site.ProjectID != projectID) {
+			continue
+		}
+		siteScans := history[site.Host]
+		if len(siteScans) == 0 {
+			board.Unscanned = append(board.Unscanned, site.Host)
+			continue
+		}
+		latest, err := scanStore.Get(siteScans[0].ID)
+		if err != nil {
+			logAt(logLevelWarn, "Warning: Could not load scan %s for the leaderboard: %v", siteScans[0].ID, err)
+			board.Unscanned = append(board.Unscanned, site.Host)
+			continue
+		}
+		var previous *storedScan
+		if len(siteScans) > 1 {
+			previous = &siteScans[1]
+		}
+		board.Sites = append(board.Sites, leaderboardEntry(site, latest, previous))
+	}
+
+	// Lowest score or densest issues first, so sites needing attention lead
+	sort.SliceStable(board.Sites, func(i, j int) bool {
+		a, b := board.Sites[i], board.Sites[j]
+		if sortBy == "issues_per_page" && a.IssuesPerPage != b.IssuesPerPage {
+			return a.IssuesPerPage > b.IssuesPerPage
+		}
+		if a.SiteScore != b.SiteScore {
+			return a.SiteScore < b.SiteScore
+		}
+		return a.IssuesPerPage > b.IssuesPerPage
+	})
+	for i := range board.Sites {
+		board.Sites[i].Rank = i + 1
+	}
+	writeJSON(w, http.StatusOK, board)
+}
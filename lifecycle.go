@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// Lifecycle states of a tracked issue
+const (
+	lifecycleOpen      = "open"
+	lifecycleFixed     = "fixed"
+	lifecycleRegressed = "regressed"
+)
+
+// maxIssueHistory bounds the number of status changes kept per issue
+const maxIssueHistory = 50
+
+// IssueRecord tracks one issue fingerprint across the scan history of a site
+type IssueRecord struct {
+	Fingerprint string       `json:"fingerprint"`
+	AuditID     string       `json:"audit_id"`
+	Title       string       `json:"title"`
+	PageURL     string       `json:"page_url"`
+	Selector    string       `json:"selector"`
+	Status      string       `json:"status"`
+	FirstSeen   time.Time    `json:"first_seen"`
+	LastSeen    time.Time    `json:"last_seen"`
+	LastScanID  string       `json:"last_scan_id,omitempty"`
+	History     []IssueEvent `json:"history"`
+	Assignee    string       `json:"assignee,omitempty"`
+	Comments    []Comment    `json:"comments,omitempty"`
+}
+
+// IssueEvent is a status change of a tracked issue
+type IssueEvent struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	ScanID string    `json:"scan_id,omitempty"`
+	Source string    `json:"source"` // "scan" or "verify"
+}
+
+// SiteIssues maps fingerprints to issue records for one site
+type SiteIssues map[string]*IssueRecord
+
+// VerifyRequest represents an API request to re-verify an issue
+type VerifyRequest struct {
+	Site string `json:"site,omitempty"`
+}
+
+// VerifyResponse reports the outcome of re-auditing an issue's page
+type VerifyResponse struct {
+	Fingerprint string       `json:"fingerprint"`
+	Fixed       bool         `json:"fixed"`
+	Issue       *IssueRecord `json:"issue"`
+	Page        PageResult   `json:"page"`
+	CheckedAt   time.Time    `json:"checked_at"`
+}
+
+// setStatus records a status change, keeping the history bounded
+func (r *IssueRecord) setStatus(status string, at time.Time, scanID, source string) {
+	if r.Status == status {
+		return
+	}
+	r.Status = status
+	r.History = append(r.History, IssueEvent{Status: status, At: at, ScanID: scanID, Source: source})
+	if len(r.History) > maxIssueHistory {
+		r.History = r.History[len(r.History)-maxIssueHistory:]
+	}
+}
+
+// trackIssueLifecycle updates the site's issue records from freshly scanned pages
+// and marks each issue with its lifecycle status. Issues on successfully scanned
+// pages that no longer appear are marked fixed, as long as reports says their
+// audit was checked (nil for all audits); fixed issues that reappear are regressed.
+func trackIssueLifecycle(host string, pages []PageResult, scanID, source string, reports func(auditID string) bool) (SiteIssues, error) {
+	now := time.Now().UTC()
+	issues := SiteIssues{}
+
+	err := siteStore.Update(host, "issues", &issues, func() error {
+		scannedPages := make(map[string]bool)
+		seen := make(map[string]bool)
+
+		for i := range pages {
+			page := &pages[i]
+			if page.Error != "" {
+				continue
+			}
+			scannedPages[page.URL] = true
+
+			for j := range page.Issues {
+				issue := &page.Issues[j]
+				seen[issue.Fingerprint] = true
+
+				record, ok := issues[issue.Fingerprint]
+				if !ok {
+					record = &IssueRecord{
+						Fingerprint: issue.Fingerprint,
+						AuditID:     issue.AuditID,
+						Title:       issue.Title,
+						PageURL:     page.URL,
+						Selector:    issue.Selector,
+						FirstSeen:   now,
+					}
+					record.setStatus(lifecycleOpen, now, scanID, source)
+					issues[issue.Fingerprint] = record
+				} else if record.Status == lifecycleFixed {
+					record.setStatus(lifecycleRegressed, now, scanID, source)
+				}
+
+				record.LastSeen = now
+				if scanID != "" {
+					record.LastScanID = scanID
+				}
+				issue.Lifecycle = record.Status
+				issue.Assignee = record.Assignee
+			}
+		}
+
+		for fingerprint, record := range issues {
+			if record.Status != lifecycleFixed && scannedPages[record.PageURL] && !seen[fingerprint] && (reports == nil || reports(record.AuditID)) {
+				record.setStatus(lifecycleFixed, now, scanID, source)
+			}
+		}
+		return nil
+	})
+
+	return issues, err
+}
+
+// handleSiteIssues handles GET /api/v1/sites/{host}/issues requests
+func handleSiteIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	assignee := r.URL.Query().Get("assignee")
+	switch status {
+	case "", lifecycleOpen, lifecycleFixed, lifecycleRegressed:
+	default:
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "status must be one of: open, fixed, regressed")
+		return
+	}
+
+	issues := SiteIssues{}
+	if err := siteStore.Load(siteHostParam(r), "issues", &issues); err != nil {
+		sendSiteStoreError(w, err)
+		return
+	}
+
+	records := make([]*IssueRecord, 0, len(issues))
+	for _, record := range issues {
+		if (status == "" || record.Status == status) && (assignee == "" || record.Assignee == assignee) {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].PageURL != records[j].PageURL {
+			return records[i].PageURL < records[j].PageURL
+		}
+		return records[i].Fingerprint < records[j].Fingerprint
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// handleVerifyIssue handles POST /api/v1/issues/{fingerprint}/verify requests
+func handleVerifyIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+
+	fingerprint := r.PathValue("fingerprint")
+	if !fingerprintPattern.MatchString(fingerprint) {
+		sendError(w, "Invalid fingerprint", http.StatusBadRequest, "fingerprint must be a 16 character hex string")
+		return
+	}
+
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return
+	}
+
+	host, record, err := findIssueRecord(fingerprint, req.Site)
+	if err != nil {
+		sendSiteStoreError(w, err)
+		return
+	}
+	if record == nil {
+		sendError(w, "Issue not found", http.StatusNotFound, "No tracked issue has this fingerprint; include \"site\" if it belongs to a specific host")
+		return
+	}
+
+	apiKey := getAPIKey()
+	if !psiConfigured() {
+		sendError(w, "Configuration error", http.StatusInternalServerError, "Google API key not configured")
+		return
+	}
+
+	if _, _, exceeded := admitQuota(clientName(r), 1, false); exceeded != nil {
+		sendQuotaExceeded(w, exceeded)
+		return
+	}
+
+	scanner := verifyScanner(apiKey, record)
+	page := scanner.verifyPage(r.Context(), record.PageURL)
+	usageStore.Record(clientName(r), UsageCounters{PagesScanned: 1, LighthouseCalls: int64(scanner.lighthouseCalls)})
+	if page.Error != "" {
+		sendPageError(w, r, "Verification failed", page)
+		return
+	}
+
+	issues, err := trackIssueLifecycle(host, []PageResult{page}, "", "verify", scanner.reportsAudit)
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not update issue lifecycle for %s: %v", host, err)
+	}
+
+	response := VerifyResponse{
+		Fingerprint: fingerprint,
+		Issue:       issues[fingerprint],
+		Page:        page,
+		CheckedAt:   time.Now().UTC(),
+	}
+	if response.Issue == nil {
+		response.Issue = record
+	}
+	response.Fixed = response.Issue.Status == lifecycleFixed
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// verifyScanner returns a scanner that audits the way the scan that last saw
+// an issue did: its strategy, iframes, audit selection and severity policy.
+// Without that scan, for instance once retention deleted it, the defaults apply.
+func verifyScanner(apiKey string, record *IssueRecord) *AccessibilityScanner {
+	scanner := NewAccessibilityScanner(apiKey, record.PageURL, 1, 0, 1)
+	if record.LastScanID == "" {
+		return scanner
+	}
+	original, err := scanStore.Get(record.LastScanID)
+	if err != nil {
+		if !errors.Is(err, errScanNotFound) {
+			logAt(logLevelWarn, "Warning: Could not load scan %s to verify %s: %v", record.LastScanID, record.Fingerprint, err)
+		}
+		return scanner
+	}
+
+	config := original.ScanConfig
+	audits, err := parseLighthouseConfig(config.LighthouseConfig)
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Ignoring the Lighthouse config of scan %s: %v", original.ID, err)
+	}
+	scanner.baseURL = original.BaseURL
+	scanner.language = config.Language
+	scanner.strategy = config.Strategy
+	scanner.iframes = config.Iframes
+	scanner.botChallenge = config.BotChallenge
+	scanner.altText = config.AltText
+	scanner.audits, scanner.lighthouseConfig = audits.without(config.IgnoreAudits), config.LighthouseConfig
+	scanner.severityOverrides = config.SeverityOverrides
+	scanner.ignoreAudits = make(map[string]bool, len(config.IgnoreAudits))
+	for _, auditID := range config.IgnoreAudits {
+		scanner.ignoreAudits[auditID] = true
+	}
+	return scanner
+}
+
+// verifyPage re-audits a single page the way a scan does, with the checks of
+// its markup and its iframes, and fingerprints its issues
+func (s *AccessibilityScanner) verifyPage(ctx context.Context, pageURL string) PageResult {
+	ctx, cancel := context.WithTimeout(ctx, s.pageDeadline())
+	defer cancel()
+
+	links := s.prefetchLinks(ctx, pageURL)
+	page := s.scanPageWithLighthouse(ctx, pageURL)
+	found := <-links
+	switch vendor := challengeVendor(found.err); {
+	case vendor != "":
+		page = blockedPage(pageURL, vendor)
+	case found.err != nil && page.Error == "":
+		// Issues from the markup checks cannot be confirmed fixed without the markup
+		page.Error = fmt.Sprintf("Failed to fetch the page: %v", found.err)
+		page.ErrorCode = "target_unreachable"
+		if isThrottled(found.err) {
+			page.ErrorCode = "target_throttled"
+		}
+	}
+	s.addPageFindings(&page)
+	if s.iframes && found.err == nil {
+		s.auditFrames(ctx, &page)
+	}
+	s.applySeverity(&page)
+	for i := range page.Issues {
+		page.Issues[i].Fingerprint = issueFingerprint(page.URL, page.Issues[i])
+	}
+	return page
+}
+
+// findIssueRecord locates a tracked issue by fingerprint, in one site or across all sites
+func findIssueRecord(fingerprint, site string) (string, *IssueRecord, error) {
+	hosts := []string{site}
+	if site == "" {
+		entries, err := os.ReadDir(siteStore.dir)
+		if err != nil {
+			return "", nil, err
+		}
+		hosts = hosts[:0]
+		for _, entry := range entries {
+			if entry.IsDir() {
+				hosts = append(hosts, entry.Name())
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		issues := SiteIssues{}
+		if err := siteStore.Load(host, "issues", &issues); err != nil {
+			return "", nil, err
+		}
+		if record, ok := issues[fingerprint]; ok {
+			return host, record, nil
+		}
+	}
+	return "", nil, nil
+}
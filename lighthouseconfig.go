@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// auditIDPattern matches Lighthouse audit IDs such as color-contrast
+var auditIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// lighthouseSettings are the keys of a Lighthouse config's settings that the
+// pagespeed engine can honor
+var lighthouseSettings = map[string]bool{"onlyAudits": true, "skipAudits": true, "onlyCategories": true}
+
+// auditSet is the audit selection of a custom Lighthouse config: only the
+// listed audits, without the skipped ones. A nil set includes every audit.
+type auditSet struct {
+	only map[string]bool
+	skip map[string]bool
+}
+
+// includes reports whether an audit is part of the set
+func (a *auditSet) includes(auditID string) bool {
+	if a == nil {
+		return true
+	}
+	if a.only != nil && !a.only[auditID] {
+		return false
+	}
+	return !a.skip[auditID]
+}
+
+// parseLighthouseConfig reads a Lighthouse config JSON for the pagespeed
+// engine. PageSpeed Insights runs Google's default config, so only the audit
+// selection of settings can be applied, after the fact; plugins, custom
+// audits and other settings need a local Lighthouse and are rejected rather
+// than silently ignored.
+func parseLighthouseConfig(raw json.RawMessage) (*auditSet, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, errors.New("lighthouse_config must be a JSON object")
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "extends" || key == "settings" {
+			continue
+		}
+		return nil, fmt.Errorf("lighthouse_config.%s needs a local Lighthouse engine; the %s engine supports extends and settings.onlyAudits, skipAudits and onlyCategories", key, defaultEngine)
+	}
+
+	if extends, ok := config["extends"]; ok {
+		var base string
+		if err := json.Unmarshal(extends, &base); err != nil || base != "lighthouse:default" {
+			return nil, errors.New("lighthouse_config.extends must be \"lighthouse:default\"")
+		}
+	}
+
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(config["settings"], &settings); config["settings"] != nil && err != nil {
+		return nil, errors.New("lighthouse_config.settings must be a JSON object")
+	}
+	for key := range settings {
+		if !lighthouseSettings[key] {
+			return nil, fmt.Errorf("lighthouse_config.settings.%s needs a local Lighthouse engine; the %s engine supports onlyAudits, skipAudits and onlyCategories", key, defaultEngine)
+		}
+	}
+
+	var categories []string
+	if err := json.Unmarshal(settings["onlyCategories"], &categories); settings["onlyCategories"] != nil && err != nil {
+		return nil, errors.New("lighthouse_config.settings.onlyCategories must be a list of category IDs")
+	}
+	for _, category := range categories {
+		if category != "accessibility" {
+			return nil, fmt.Errorf("lighthouse_config.settings.onlyCategories: scans only run the accessibility category, not %q", category)
+		}
+	}
+
+	audits := &auditSet{}
+	for _, list := range []struct {
+		key    string
+		target *map[string]bool
+	}{{"onlyAudits", &audits.only}, {"skipAudits", &audits.skip}} {
+		if settings[list.key] == nil {
+			continue
+		}
+		var ids []string
+		if err := json.Unmarshal(settings[list.key], &ids); err != nil {
+			return nil, fmt.Errorf("lighthouse_config.settings.%s must be a list of audit IDs", list.key)
+		}
+		*list.target = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if !auditIDPattern.MatchString(id) {
+				return nil, fmt.Errorf("lighthouse_config.settings.%s: %q is not an audit ID such as \"color-contrast\"", list.key, id)
+			}
+			(*list.target)[id] = true
+		}
+	}
+	if audits.only == nil && audits.skip == nil {
+		return nil, nil
+	}
+	return audits, nil
+}
+
+// rescore recomputes the accessibility score over the audits of the set the
+// way Lighthouse does: the weighted mean of the scored audits. When none of
+// them applies to the page, the PageSpeed score is kept.
+func (a *auditSet) rescore(result *LighthouseResult) float64 {
+	category := result.LighthouseResult.Categories.Accessibility
+	total, weighted := 0.0, 0.0
+	for _, ref := range category.AuditRefs {
+		audit, ok := result.LighthouseResult.Audits[ref.ID]
+		if !ok || ref.Weight == 0 || !a.includes(ref.ID) || (audit.ScoreDisplayMode != "binary" && audit.ScoreDisplayMode != "numeric") {
+			continue
+		}
+		total += ref.Weight
+		weighted += ref.Weight * audit.Score
+	}
+	if total == 0 {
+		return category.Score
+	}
+	return weighted / total
+}
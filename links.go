@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// apiPrefix returns the path prefix of an API version
+func apiPrefix(version int) string {
+	return fmt.Sprintf("/api/v%d", version)
+}
+
+// scanLinks returns the HAL links of a stored scan: its pages, issues and
+// report, how to run it again, and the comparison with the scan before it,
+// which is the original of a rerun or else the site's previous scan
+func scanLinks(version int, result *ScanResult) map[string]Link {
+	prefix := apiPrefix(version)
+	scan := prefix + "/scans/" + result.ID
+	links := map[string]Link{
+		"self":   {Href: scan},
+		"pages":  {Href: scan + "/pages"},
+		"issues": {Href: scan + "/issues"},
+		"report": {Href: scan + "/vpat"},
+		"rerun":  {Href: scan + "/rerun"},
+	}
+	if result.Site != nil {
+		links["site"] = Link{Href: prefix + "/sites/" + url.PathEscape(result.Site.Host)}
+	}
+
+	previous := ""
+	if result.Rerun != nil {
+		previous = result.Rerun.ScanID
+	} else {
+		previous = previousSiteScan(result)
+	}
+	if previous != "" {
+		query := url.Values{"a": {previous}, "b": {result.ID}}
+		links["diff"] = Link{Href: prefix + "/compare?" + query.Encode()}
+	}
+	return links
+}
+
+// previousSiteScan returns the ID of the site's last completed or partial
+// scan stored before result, or "" if there is none
+func previousSiteScan(result *ScanResult) string {
+	previous, err := scanStore.PreviousSiteScan(siteKey(result.BaseURL), result.ScanTime, result.ID)
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not list scans to link scan %s: %v", result.ID, err)
+	}
+	return previous
+}
+
+// storedPageLinks returns the HAL links of the page at an index of a stored
+// scan's page results, with its neighbours in page_results as next and prev
+func storedPageLinks(version int, result *ScanResult, index int) map[string]Link {
+	scan := apiPrefix(version) + "/scans/" + result.ID
+	page := func(n int) Link {
+		return Link{Href: scan + "/pages/" + strconv.Itoa(n)}
+	}
+	links := map[string]Link{
+		"self":   page(index),
+		"scan":   {Href: scan},
+		"report": {Href: scan + "/vpat"},
+	}
+	if index > 0 {
+		links["prev"] = page(index - 1)
+	}
+	if index+1 < len(result.PageResults) {
+		links["next"] = page(index + 1)
+	}
+	if result.PageResults[index].RawReport {
+		links["raw"] = Link{Href: scan + "/pages/" + strconv.Itoa(index) + "/raw"}
+	}
+	return links
+}
+
+// jobLinks returns the HAL links of an active scan: its admin status and
+// frontier, and where its result will be stored once it finishes
+func jobLinks(version int, id string) map[string]Link {
+	prefix := apiPrefix(version)
+	return map[string]Link{
+		"self":     {Href: prefix + "/admin/scans/" + id},
+		"frontier": {Href: prefix + "/admin/scans/" + id + "/frontier"},
+		"scan":     {Href: prefix + "/scans/" + id},
+	}
+}
@@ -4,13 +4,21 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/html"
@@ -19,10 +27,32 @@ import (
 // LighthouseResult represents the structure of Lighthouse API response
 type LighthouseResult struct {
 	LighthouseResult struct {
+		LighthouseVersion string `json:"lighthouseVersion"`
+		FetchTime         string `json:"fetchTime"`
+		RequestedURL      string `json:"requestedUrl"`
+		FinalURL          string `json:"finalUrl"`
+		FinalDisplayedURL string `json:"finalDisplayedUrl"`
+		Environment       struct {
+			NetworkUserAgent string `json:"networkUserAgent"`
+		} `json:"environment"`
+		ConfigSettings struct {
+			FormFactor       string `json:"formFactor"`
+			Locale           string `json:"locale"`
+			ThrottlingMethod string `json:"throttlingMethod"`
+			Throttling       struct {
+				RTTMs                 float64 `json:"rttMs"`
+				ThroughputKbps        float64 `json:"throughputKbps"`
+				CPUSlowdownMultiplier float64 `json:"cpuSlowdownMultiplier"`
+			} `json:"throttling"`
+		} `json:"configSettings"`
 		Categories struct {
 			Accessibility struct {
-				Score float64 `json:"score"`
-				Title string  `json:"title"`
+				Score     float64 `json:"score"`
+				Title     string  `json:"title"`
+				AuditRefs []struct {
+					ID     string  `json:"id"`
+					Weight float64 `json:"weight"`
+				} `json:"auditRefs"`
 			} `json:"accessibility"`
 		} `json:"categories"`
 		Audits map[string]struct {
@@ -49,49 +79,151 @@ type LighthouseResult struct {
 
 // AccessibilityIssue represents a single accessibility issue
 type AccessibilityIssue struct {
-	AuditID     string `json:"audit_id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Impact      string `json:"impact"`
-	Selector    string `json:"selector"`
-	Snippet     string `json:"snippet"`
+	AuditID     string       `json:"audit_id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Impact      string       `json:"impact"`
+	Selector    string       `json:"selector"`
+	Snippet     string       `json:"snippet"`
+	Remediation *Remediation `json:"remediation,omitempty"`
+	Fingerprint string       `json:"fingerprint,omitempty"`
+	Triage      *IssueTriage `json:"triage,omitempty"`
+	FrameURL    string       `json:"frame_url,omitempty"` // iframe document the issue was found in
+	// OriginalImpact is the impact the audit reported, when a severity override changed it
+	OriginalImpact string `json:"original_impact,omitempty"`
+	Lifecycle      string `json:"lifecycle,omitempty"`
+	Assignee       string `json:"assignee,omitempty"`
+	// Strategies lists the strategies whose audit found the issue, in scans with strategy "both"
+	Strategies []string `json:"strategies,omitempty"`
+
+	title *checkTitle // catalog title of an issue found by a page check, set in the scan's language
 }
 
 // PageResult represents the accessibility results for a single page
 type PageResult struct {
 	URL                string               `json:"url"`
+	Depth              int                  `json:"depth"`
 	AccessibilityScore float64              `json:"accessibility_score"`
 	Issues             []AccessibilityIssue `json:"issues"`
 	Error              string               `json:"error,omitempty"`
+	ErrorCode          string               `json:"error_code,omitempty"` // problem code of the error
+	Frames             []FrameAudit         `json:"frames,omitempty"`
+	Environment        *AuditEnvironment    `json:"environment,omitempty"`
+	CanonicalURL       string               `json:"canonical_url,omitempty"` // set on AMP pages
+	AMPURL             string               `json:"amp_url,omitempty"`       // AMP variant scanned in the same scan
+	RawReport          bool                 `json:"raw_report,omitempty"`    // the full Lighthouse report is stored
+	Headings           []*Heading           `json:"headings,omitempty"`      // heading outline
+	Landmarks          []*Landmark          `json:"landmarks,omitempty"`     // landmark regions
+	TabOrder           []TabStop            `json:"tab_order,omitempty"`     // focusable elements in Tab order
+	Strategies         *StrategyAudits      `json:"strategies,omitempty"`    // mobile and desktop audits, with strategy "both"
+	BotChallenge       string               `json:"bot_challenge,omitempty"` // service whose bot challenge blocked the crawler
+	Retried            bool                 `json:"retried,omitempty"`       // audited again in the end-of-scan retry pass
+	Skipped            string               `json:"skipped,omitempty"`       // "slow" when not audited within the page timeout
+
+	lighthouseReport json.RawMessage // full Lighthouse report, until it is stored
 }
 
 // ScanConfig represents the configuration used for scanning
 type ScanConfig struct {
-	MaxPages int `json:"max_pages"`
-	Offset   int `json:"offset"`
-	Limit    int `json:"limit"`
+	MaxPages           int                `json:"max_pages"`
+	Offset             int                `json:"offset"`
+	Limit              int                `json:"limit"`
+	TrafficHints       map[string]float64 `json:"traffic_hints,omitempty"`
+	Language           string             `json:"language,omitempty"`
+	Sampling           *SamplingConfig    `json:"sampling,omitempty"`
+	TimeoutSeconds     int                `json:"timeout_seconds"`
+	PageTimeoutSeconds int                `json:"page_timeout_seconds"`
+	MaxLighthouseCalls int                `json:"max_lighthouse_calls,omitempty"`
+	Profile            string             `json:"profile,omitempty"`
+	Include            []string           `json:"include,omitempty"`
+	Exclude            []string           `json:"exclude,omitempty"`
+	Engine             string             `json:"engine"`
+	Frontier           string             `json:"frontier"`
+	AMP                string             `json:"amp,omitempty"`
+	Iframes            bool               `json:"iframes,omitempty"`
+	PaginationLimit    int                `json:"pagination_limit,omitempty"`
+	StoreRaw           bool               `json:"store_raw,omitempty"`
+	LighthouseConfig   json.RawMessage    `json:"lighthouse_config,omitempty"`
+	SeverityOverrides  map[string]string  `json:"severity_overrides,omitempty"`
+	IgnoreAudits       []string           `json:"ignore_audits,omitempty"`
+	AltText            *AltTextPolicy     `json:"alt_text,omitempty"`
+	Thresholds         *Thresholds        `json:"thresholds,omitempty"`
+	Strategy           string             `json:"strategy,omitempty"`
+	BotChallenge       string             `json:"bot_challenge,omitempty"`
 }
 
 // ScanResult represents the complete scan results
 type ScanResult struct {
-	BaseURL        string       `json:"base_url"`
-	ScanTime       time.Time    `json:"scan_time"`
-	TotalPages     int          `json:"total_pages"`
-	PageResults    []PageResult `json:"page_results"`
-	UrlsDiscovered []string     `json:"urls_discovered"`
-	UrlsVisited    []string     `json:"urls_visited"`
-	ScanConfig     ScanConfig   `json:"scan_config"`
-	Status         string       `json:"status"` // "completed", "failed", "partial"
+	ID                  string            `json:"id,omitempty"`
+	BaseURL             string            `json:"base_url"`
+	ScanTime            time.Time         `json:"scan_time"`
+	TotalPages          int               `json:"total_pages"`
+	PageResults         []PageResult      `json:"page_results"`
+	UrlsDiscovered      []string          `json:"urls_discovered"`
+	UrlsDiscoveredTotal int               `json:"urls_discovered_total,omitempty"` // set when urls_discovered is truncated
+	UrlsVisited         []string          `json:"urls_visited"`
+	ScanConfig          ScanConfig        `json:"scan_config"`
+	Status              string            `json:"status"` // "completed", "failed", "partial"
+	StopReason          string            `json:"stop_reason,omitempty"`
+	Continuation        string            `json:"continuation_token,omitempty"`
+	ResumedFrom         string            `json:"resumed_from,omitempty"`
+	SiteScore           float64           `json:"site_score"`
+	Summary             ScanSummary       `json:"summary"`
+	Sampling            *SamplingReport   `json:"sampling,omitempty"`
+	Site                *SiteRef          `json:"site,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+	Thresholds          *ThresholdReport  `json:"thresholds,omitempty"`
+	AMPPairs            []AMPPair         `json:"amp_pairs,omitempty"`
+	Pagination          []PaginatedSeries `json:"pagination,omitempty"`
+	Throttling          *ThrottleReport   `json:"throttling,omitempty"`
+	SkipLinks           *SkipLinkReport   `json:"skip_links,omitempty"`
+	Scanner             *BuildInfo        `json:"scanner,omitempty"` // build that produced the result
+	Rerun               *RerunComparison  `json:"rerun,omitempty"`   // comparison with the scan this one reran
+	Links               map[string]Link   `json:"_links,omitempty"`  // set in API responses, not stored
 }
 
 // ScanRequest represents an API scan request
 type ScanRequest struct {
-	URL      string `json:"url"`
-	MaxPages int    `json:"max_pages,omitempty"`
-	Offset   int    `json:"offset,omitempty"`
-	Limit    int    `json:"limit,omitempty"`
+	URL                string             `json:"url"`
+	Site               string             `json:"site,omitempty"`
+	MaxPages           int                `json:"max_pages,omitempty"`
+	Offset             int                `json:"offset,omitempty"`
+	Limit              int                `json:"limit,omitempty"`
+	TrafficHints       map[string]float64 `json:"traffic_hints,omitempty"`
+	Language           string             `json:"language,omitempty"`
+	Sampling           *SamplingConfig    `json:"sampling,omitempty"`
+	TimeoutSeconds     int                `json:"timeout_seconds,omitempty"`
+	PageTimeoutSeconds int                `json:"page_timeout_seconds,omitempty"`
+	MaxLighthouseCalls int                `json:"max_lighthouse_calls,omitempty"`
+	Frontier           string             `json:"frontier,omitempty"`
+	AMP                string             `json:"amp,omitempty"`
+	Iframes            bool               `json:"iframes,omitempty"`
+	PaginationLimit    int                `json:"pagination_limit,omitempty"`
+	StoreRaw           bool               `json:"store_raw,omitempty"`
+	LighthouseConfig   json.RawMessage    `json:"lighthouse_config,omitempty"`
+	SeverityOverrides  map[string]string  `json:"severity_overrides,omitempty"`
+	IgnoreAudits       []string           `json:"ignore_audits,omitempty"`
+	ContinuationToken  string             `json:"continuation_token,omitempty"`
+	Tags               map[string]string  `json:"tags,omitempty"`
+	Profile            string             `json:"profile,omitempty"`
+	Include            []string           `json:"include,omitempty"`
+	Exclude            []string           `json:"exclude,omitempty"`
+	Engine             string             `json:"engine,omitempty"`
+	Thresholds         *Thresholds        `json:"thresholds,omitempty"`
+	AltText            *AltTextPolicy     `json:"alt_text,omitempty"`
+	Strategy           string             `json:"strategy,omitempty"`
+	BotChallenge       string             `json:"bot_challenge,omitempty"`
+	Viewports          []Viewport         `json:"viewports,omitempty"` // screens a local engine emulates
 }
 
+// Scan timeout and budget limits
+const (
+	defaultScanTimeoutSeconds = 600
+	maxScanTimeoutSeconds     = 1800
+	defaultPageTimeoutSeconds = 30
+	maxPageTimeoutSeconds     = 120
+)
+
 // ErrorResponse represents an API error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -101,113 +233,217 @@ type ErrorResponse struct {
 
 // AccessibilityScanner handles the scanning process
 type AccessibilityScanner struct {
-	apiKey         string
-	baseURL        string
-	maxPages       int
-	offset         int
-	limit          int
-	visited        map[string]bool
-	urlsDiscovered []string
-	client         *http.Client
+	apiKey            string
+	google            *googleCredentials // OAuth credentials, used with or instead of apiKey
+	baseURL           string
+	maxPages          int
+	offset            int
+	limit             int
+	trafficHints      map[string]float64
+	language          string
+	tags              map[string]string
+	profile           string
+	audits            *auditSet // audit selection of a custom Lighthouse config
+	lighthouseConfig  json.RawMessage
+	severityOverrides map[string]string // impact levels by audit ID
+	ignoreAudits      map[string]bool   // audits whose issues are dropped
+	include           []*regexp.Regexp
+	exclude           []*regexp.Regexp
+	assets            *assetFilter
+	normalizer        *urlNormalizer
+	includeRaw        []string
+	excludeRaw        []string
+	thresholds        *Thresholds
+	altText           *AltTextPolicy // alt text heuristics of the profile or request
+	sampling          *SamplingConfig
+	amp               string
+	botChallenge      string // block, retry or abort; empty for block
+	throttle          *targetThrottle
+	strategy          string // Lighthouse strategy: mobile, desktop or both; empty for the PageSpeed default
+	signatures        map[string]map[string]bool
+	ampCanonical      map[string]string   // AMP page URL to its canonical page URL
+	iframes           bool                // audit same-origin iframes with their pages
+	frames            map[string][]string // page URL to its same-origin iframe URLs
+	frameAudits       map[string]PageResult
+	rules             []*CustomRule
+	pageFindings      map[string]*pageFindings // findings in the markup of crawled pages not audited yet
+	skippedAudits     map[string]bool          // Lighthouse audits the audit selection left out of the reports
+	storeRaw          bool                     // keep the full Lighthouse report of each page
+
+	paginationLimit   int                   // pages crawled per paginated series, 0 for all
+	nextPages         map[string]seriesPage // pages reached through rel="next", by URL
+	paginationSkipped map[string]seriesPage // pages left out by paginationLimit
+
+	mu     sync.Mutex // guards signatures, ampCanonical, frames, pageFindings, skippedAudits and the pagination maps while links are extracted concurrently
+	urls   urlStore
+	client *http.Client
+
+	timeout            time.Duration
+	maxLighthouseCalls int
+	lighthouseCalls    int
+	frontier           *CrawlFrontier // set when the crawl stopped early
+	resumed            *CrawlFrontier // set when continuing an earlier crawl
+	onPage             func(page PageResult, replaces bool)
+	job                *ScanJob // set for scans tracked by the admin API
+	id                 string
 }
 
 // NewAccessibilityScanner creates a new scanner instance
 func NewAccessibilityScanner(apiKey, baseURL string, maxPages, offset, limit int) *AccessibilityScanner {
 	return &AccessibilityScanner{
-		apiKey:         apiKey,
-		baseURL:        baseURL,
-		maxPages:       maxPages,
-		offset:         offset,
-		limit:          limit,
-		visited:        make(map[string]bool),
-		urlsDiscovered: make([]string, 0),
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		id:            newScanID(),
+		apiKey:        apiKey,
+		baseURL:       baseURL,
+		maxPages:      maxPages,
+		offset:        offset,
+		limit:         limit,
+		urls:          newMemoryURLStore(),
+		signatures:    make(map[string]map[string]bool),
+		ampCanonical:  make(map[string]string),
+		frames:        make(map[string][]string),
+		frameAudits:   make(map[string]PageResult),
+		pageFindings:  make(map[string]*pageFindings),
+		skippedAudits: make(map[string]bool),
+
+		nextPages:         make(map[string]seriesPage),
+		paginationSkipped: make(map[string]seriesPage),
+		assets:            currentConfig().assets,
+		normalizer:        currentConfig().urls,
+		rules:             currentConfig().rules,
+		throttle:          &targetThrottle{},
+		google:            currentConfig().google,
+		client:            newOutboundClient(30 * time.Second),
 	}
 }
 
-// loadEnvFile loads environment variables from a .env file
-func loadEnvFile(filename string) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		if (strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)) ||
-			(strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`)) {
-			value = value[1 : len(value)-1]
-		}
-
-		os.Setenv(key, value)
-	}
+// getAPIKey returns the configured Google API key
+func getAPIKey() string {
+	return currentConfig().GoogleAPIKey
+}
 
-	return scanner.Err()
+// psiConfigured reports whether PageSpeed Insights requests can be
+// authenticated, with an API key or OAuth credentials
+func psiConfigured() bool {
+	config := currentConfig()
+	return config.GoogleAPIKey != "" || config.google != nil
 }
 
-// getAPIKey tries to get the API key from various sources
-func getAPIKey() string {
-	if key := os.Getenv("GOOGLE_API_KEY"); key != "" {
-		return key
-	}
-	if key := os.Getenv("PAGESPEED_API_KEY"); key != "" {
-		return key
+// scanPageWithLighthouse scans a single page using Lighthouse API, with the
+// scan's strategy
+func (s *AccessibilityScanner) scanPageWithLighthouse(ctx context.Context, pageURL string) PageResult {
+	if s.strategy == strategyBoth {
+		return s.auditBothStrategies(ctx, pageURL)
 	}
-	if key := os.Getenv("LIGHTHOUSE_API_KEY"); key != "" {
-		return key
-	}
-	return ""
+	return s.runLighthouse(ctx, pageURL, s.strategy)
 }
 
-// scanPageWithLighthouse scans a single page using Lighthouse API
-func (s *AccessibilityScanner) scanPageWithLighthouse(pageURL string) PageResult {
+// runLighthouse audits a page with one Lighthouse strategy, or the PageSpeed
+// default when strategy is empty
+func (s *AccessibilityScanner) runLighthouse(ctx context.Context, pageURL, strategy string) PageResult {
 	result := PageResult{URL: pageURL}
+	s.lighthouseCalls++
+	logAt(logLevelDebug, "Lighthouse audit %d: %s", s.lighthouseCalls, pageURL)
 
 	lighthouseURL := fmt.Sprintf(
-		"https://www.googleapis.com/pagespeedonline/v5/runPagespeed?url=%s&category=accessibility&key=%s",
+		"https://www.googleapis.com/pagespeedonline/v5/runPagespeed?url=%s&category=accessibility",
 		url.QueryEscape(pageURL),
-		s.apiKey,
 	)
+	if s.apiKey != "" {
+		lighthouseURL += "&key=" + s.apiKey
+	}
+	if strategy != "" {
+		lighthouseURL += "&strategy=" + strategy
+	}
+	if s.language != "" {
+		lighthouseURL += "&locale=" + url.QueryEscape(s.language)
+	}
+	remediations := remediationCatalogs.For(s.language)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lighthouseURL, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to create Lighthouse request: %v", err)
+		result.ErrorCode = "lighthouse_error"
+		return result
+	}
+	if s.google != nil {
+		if err := s.google.authorize(req); err != nil {
+			result.Error = fmt.Sprintf("Failed to authenticate to the Lighthouse API: %v", err)
+			result.ErrorCode = requestErrorCode(err)
+			if result.ErrorCode == "lighthouse_error" {
+				result.ErrorCode = "lighthouse_auth_failed"
+			}
+			return result
+		}
+	}
 
-	resp, err := s.client.Get(lighthouseURL)
+	resp, err := s.client.Do(req)
 	if err != nil {
+		// Request errors include the URL; keep the API key out of results
 		result.Error = fmt.Sprintf("Failed to call Lighthouse API: %v", err)
+		if s.apiKey != "" {
+			result.Error = strings.ReplaceAll(result.Error, s.apiKey, "REDACTED")
+		}
+		result.ErrorCode = requestErrorCode(err)
 		return result
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
+
+	// Rate limiting slows the whole service down rather than failing every scan
+	if resp.StatusCode == http.StatusTooManyRequests {
+		scanQueue.ReportThrottled()
+	} else if resp.StatusCode == http.StatusOK {
+		scanQueue.ReportHealthy()
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		result.Error = fmt.Sprintf("Lighthouse API error (status %d): %s", resp.StatusCode, string(body))
+		result.ErrorCode = lighthouseErrorCode(resp.StatusCode, body)
 		return result
 	}
 
 	var lighthouseResult LighthouseResult
-	if err := json.NewDecoder(resp.Body).Decode(&lighthouseResult); err != nil {
+	if s.storeRaw {
+		// The full report is kept as well as the summary, so the body is read once
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to read Lighthouse response: %v", err)
+			result.ErrorCode = requestErrorCode(err)
+			return result
+		}
+		var raw struct {
+			LighthouseResult json.RawMessage `json:"lighthouseResult"`
+		}
+		if err := json.Unmarshal(body, &raw); err != nil {
+			result.Error = fmt.Sprintf("Failed to decode Lighthouse response: %v", err)
+			result.ErrorCode = "lighthouse_error"
+			return result
+		}
+		if err := json.Unmarshal(body, &lighthouseResult); err != nil {
+			result.Error = fmt.Sprintf("Failed to decode Lighthouse response: %v", err)
+			result.ErrorCode = "lighthouse_error"
+			return result
+		}
+		result.lighthouseReport = raw.LighthouseResult
+	} else if err := json.NewDecoder(resp.Body).Decode(&lighthouseResult); err != nil {
 		result.Error = fmt.Sprintf("Failed to decode Lighthouse response: %v", err)
+		result.ErrorCode = requestErrorCode(err)
 		return result
 	}
 
 	result.AccessibilityScore = lighthouseResult.LighthouseResult.Categories.Accessibility.Score
+	result.Environment = auditEnvironment(&lighthouseResult)
+	if s.audits != nil {
+		result.AccessibilityScore = s.audits.rescore(&lighthouseResult)
+	}
 
 	for auditID, audit := range lighthouseResult.LighthouseResult.Audits {
+		if !s.audits.includes(auditID) {
+			s.mu.Lock()
+			s.skippedAudits[auditID] = true
+			s.mu.Unlock()
+			continue
+		}
 		if audit.ScoreDisplayMode == "binary" && audit.Score < 1.0 {
 			for _, item := range audit.Details.Items {
 				issue := AccessibilityIssue{
@@ -217,6 +453,7 @@ func (s *AccessibilityScanner) scanPageWithLighthouse(pageURL string) PageResult
 					Impact:      item.Impact,
 					Selector:    item.Node.Selector,
 					Snippet:     item.Node.Snippet,
+					Remediation: remediations.Lookup(auditID),
 				}
 				result.Issues = append(result.Issues, issue)
 			}
@@ -229,6 +466,7 @@ func (s *AccessibilityScanner) scanPageWithLighthouse(pageURL string) PageResult
 					Impact:      "unknown",
 					Selector:    "",
 					Snippet:     "",
+					Remediation: remediations.Lookup(auditID),
 				}
 				result.Issues = append(result.Issues, issue)
 			}
@@ -238,9 +476,65 @@ func (s *AccessibilityScanner) scanPageWithLighthouse(pageURL string) PageResult
 	return result
 }
 
+// enqueueLinks records the links found on a page and adds new ones to the
+// crawl queue, up to max_pages
+func (s *AccessibilityScanner) enqueueLinks(fromURL string, links []string) {
+	depth := s.urls.Depth(fromURL) + 1
+	for _, link := range links {
+		if s.skipsAMP(link) || s.skipsPaginated(link) {
+			continue
+		}
+		s.urls.Discover(link)
+		if s.urls.Len() < s.maxPages && s.urls.Visit(link, depth) {
+			s.urls.Push(link)
+		}
+	}
+}
+
+// startURL is the normalized base URL the crawl starts from. Unlike crawled
+// links it keeps its query string.
+func (s *AccessibilityScanner) startURL() string {
+	base, err := url.Parse(s.baseURL)
+	if err != nil {
+		return s.baseURL
+	}
+	start := s.normalizer.normalize(base)
+	start.RawQuery = base.RawQuery
+	return start.String()
+}
+
+// pause waits between Lighthouse calls, longer while the target site is
+// throttling the scan, returning false if the scan was cancelled meanwhile
+func (s *AccessibilityScanner) pause(ctx context.Context) bool {
+	return sleepContext(ctx, 1*time.Second+s.throttle.Delay())
+}
+
+// notifyPage reports a scanned page to the onPage callback, if any
+func (s *AccessibilityScanner) notifyPage(page PageResult) {
+	s.publishPage(page, false)
+}
+
+// notifyRetriedPage reports a page the retry pass audited again; it replaces
+// the page reported for the same URL earlier in the scan
+func (s *AccessibilityScanner) notifyRetriedPage(page PageResult) {
+	s.publishPage(page, true)
+}
+
+func (s *AccessibilityScanner) publishPage(page PageResult, replaces bool) {
+	eventBus.Page(s.id, s.baseURL, page, replaces)
+	if s.onPage != nil {
+		s.onPage(page, replaces)
+	}
+}
+
+// budgetExhausted reports whether the scan has used its Lighthouse call budget
+func (s *AccessibilityScanner) budgetExhausted() bool {
+	return s.maxLighthouseCalls > 0 && s.lighthouseCalls >= s.maxLighthouseCalls
+}
+
 // extractLinks extracts all internal links from an HTML page
-func (s *AccessibilityScanner) extractLinks(pageURL string) ([]string, error) {
-	req, err := http.NewRequest("GET", pageURL, nil)
+func (s *AccessibilityScanner) extractLinks(ctx context.Context, pageURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -249,14 +543,22 @@ func (s *AccessibilityScanner) extractLinks(pageURL string) ([]string, error) {
 	req.Header.Set("User-Agent", customUA)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Connection", "keep-alive")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
+	// Challenge pages often answer 403 or 503, so they are recognized first
+	body := bufio.NewReaderSize(resp.Body, challengePeekSize)
+	head, _ := body.Peek(challengePeekSize)
+	if vendor := detectChallenge(resp, head); vendor != "" {
+		return nil, &challengeError{vendor: vendor}
+	}
+	if err := throttledResponse(resp); err != nil {
+		return nil, err
+	}
 	if resp.StatusCode >= 400 {
 		return nil, fmt.Errorf("HTTP error %d", resp.StatusCode)
 	}
@@ -265,65 +567,103 @@ func (s *AccessibilityScanner) extractLinks(pageURL string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	baseHost := s.normalizer.normalize(baseURLParsed).Host
 
 	currentURLParsed, err := url.Parse(pageURL)
 	if err != nil {
 		return nil, err
 	}
 
-	doc, err := html.Parse(resp.Body)
+	doc, err := html.Parse(body)
 	if err != nil {
 		return nil, err
 	}
 
+	if s.sampling != nil {
+		signature := structuralSignature(doc)
+		s.mu.Lock()
+		s.signatures[pageURL] = signature
+		s.mu.Unlock()
+	}
+	s.checkPage(pageURL, doc)
+
 	var links []string
+	var frames []string
+	var next string
+	var amp ampLinks
+
+	// Relative links resolve against the page's <base href> when it has one
+	base := documentBase(doc, currentURLParsed)
+	resolve := func(href string) (*url.URL, bool) {
+		linkURL, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return nil, false
+		}
+		return base.ResolveReference(linkURL), true
+	}
 
-	var findLinks func(*html.Node)
-	findLinks = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, attr := range n.Attr {
-				if attr.Key == "href" {
-					linkURL, err := url.Parse(attr.Val)
-					if err != nil {
-						continue
-					}
+	// follow resolves an href against the page and keeps it if it is a new
+	// in-scope page on the scanned host
+	follow := func(href string) {
+		linkURL, ok := resolve(href)
+		if !ok {
+			return
+		}
 
-					absoluteURL := currentURLParsed.ResolveReference(linkURL)
+		cleanURL := s.normalizer.normalize(linkURL)
 
-					if absoluteURL.Host == baseURLParsed.Host {
-						cleanURL := &url.URL{
-							Scheme: absoluteURL.Scheme,
-							Host:   absoluteURL.Host,
-							Path:   absoluteURL.Path,
-						}
-						finalURL := cleanURL.String()
-
-						isDuplicate := false
-						for _, existing := range links {
-							if existing == finalURL {
-								isDuplicate = true
-								break
-							}
-						}
+		if cleanURL.Host == baseHost && s.inScope(cleanURL.Path) && !s.assets.skips(cleanURL.Path) {
+			finalURL := cleanURL.String()
+
+			isDuplicate := false
+			for _, existing := range links {
+				if existing == finalURL {
+					isDuplicate = true
+					break
+				}
+			}
 
-						if !isDuplicate {
-							links = append(links, finalURL)
-
-							alreadyDiscovered := false
-							for _, discovered := range s.urlsDiscovered {
-								if discovered == finalURL {
-									alreadyDiscovered = true
-									break
-								}
-							}
-							if !alreadyDiscovered {
-								s.urlsDiscovered = append(s.urlsDiscovered, finalURL)
-							}
+			if !isDuplicate {
+				links = append(links, finalURL)
+			}
+		}
+	}
+
+	var findLinks func(*html.Node)
+	findLinks = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			href, hasHref := attrLookup(n, "href")
+			rel := attrValue(n, "rel")
+			switch n.Data {
+			case "a", "area":
+				if hasHref {
+					follow(href)
+				}
+			case "link":
+				// Pagination links in the head lead to pages like anchors do
+				if hasHref && hasRel(rel, "next", "prev", "previous") {
+					follow(href)
+				}
+			case "iframe":
+				if src := attrValue(n, "src"); src != "" && s.iframes && len(frames) < maxFramesPerPage {
+					if srcURL, ok := resolve(src); ok {
+						if frameURL, ok := s.frameURL(currentURLParsed, srcURL.String()); ok && !slices.Contains(frames, frameURL) {
+							frames = append(frames, frameURL)
 						}
 					}
-					break
 				}
 			}
+			// Cards and table rows made clickable by scripts carry their
+			// link in data-href instead of wrapping an anchor
+			if wrapped, ok := attrLookup(n, "data-href"); ok && !hasHref && wrapped != "" {
+				follow(wrapped)
+			}
+			if (n.Data == "a" || n.Data == "link") && next == "" && hasHref && href != "" && hasRel(rel, "next") {
+				if linkURL, ok := resolve(href); ok {
+					next = s.normalizer.normalize(linkURL).String()
+				}
+			}
+			readAMPLinks(n, &amp)
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
 			findLinks(c)
@@ -331,76 +671,241 @@ func (s *AccessibilityScanner) extractLinks(pageURL string) ([]string, error) {
 	}
 
 	findLinks(doc)
+
+	// AMP links are recorded before the links are queued, so a skipped AMP
+	// variant never enters the frontier
+	for _, href := range []*string{&amp.canonical, &amp.amphtml} {
+		if linkURL, ok := resolve(*href); *href != "" && ok {
+			*href = s.normalizer.normalize(linkURL).String()
+		}
+	}
+	s.recordAMP(pageURL, amp)
+	s.recordFrames(pageURL, frames)
+	s.recordNextPage(pageURL, next)
+	if s.amp == ampPair && amp.amphtml != "" && !amp.isAMP {
+		follow(amp.amphtml)
+	}
 	return links, nil
 }
 
 // crawlAndScan performs the scanning with context support for cancellation
 func (s *AccessibilityScanner) crawlAndScan(ctx context.Context) ScanResult {
+	defer s.closeFrontier()
+
 	result := ScanResult{
+		ID:       s.id,
 		BaseURL:  s.baseURL,
 		ScanTime: time.Now(),
 		ScanConfig: ScanConfig{
-			MaxPages: s.maxPages,
-			Offset:   s.offset,
-			Limit:    s.limit,
+			MaxPages:     s.maxPages,
+			Offset:       s.offset,
+			Limit:        s.limit,
+			TrafficHints: s.trafficHints,
+			Language:     s.language,
+			Sampling:     s.sampling,
+
+			TimeoutSeconds:     int(s.timeout.Seconds()),
+			PageTimeoutSeconds: int(s.client.Timeout.Seconds()),
+			MaxLighthouseCalls: s.maxLighthouseCalls,
+			Profile:            s.profile,
+			Include:            s.includeRaw,
+			Exclude:            s.excludeRaw,
+			Engine:             defaultEngine,
+			Frontier:           s.frontierMode(),
+			AMP:                s.amp,
+			Iframes:            s.iframes,
+			PaginationLimit:    s.paginationLimit,
+			StoreRaw:           s.storeRaw,
+			LighthouseConfig:   s.lighthouseConfig,
+			SeverityOverrides:  s.severityOverrides,
+			IgnoreAudits:       slices.Sorted(maps.Keys(s.ignoreAudits)),
+			AltText:            s.altText,
+			Thresholds:         s.thresholds,
+			Strategy:           s.strategy,
+			BotChallenge:       s.botChallenge,
 		},
 		Status: "completed",
+		Site:   orgStore.SiteFor(s.baseURL),
+		Tags:   s.tags,
 	}
+	build := buildInfo()
+	result.Scanner = &build
 
-	queue := []string{s.baseURL}
-	s.visited[s.baseURL] = true
-	s.urlsDiscovered = append(s.urlsDiscovered, s.baseURL)
+	if s.sampling != nil {
+		s.sampleAndScan(ctx, &result)
+		s.retryFailedPages(ctx, &result)
+		s.finalizeResult(&result)
+		return result
+	}
 
 	urlIndex := 0
+	if s.resumed != nil {
+		urlIndex = s.resumed.URLIndex
+		result.ResumedFrom = s.resumed.ScanID
+		result.PageResults = append(result.PageResults, s.resumed.pages...)
+		for _, page := range s.resumed.pages {
+			s.notifyPage(page)
+		}
+	} else {
+		start := s.startURL()
+		s.urls.Visit(start, 0)
+		s.urls.Discover(start)
+		s.urls.Push(start)
+	}
 
-	for len(queue) > 0 && len(result.PageResults) < s.limit {
-		select {
-		case <-ctx.Done():
+	// stop ends the crawl: a timeout saves the frontier so the scan can be
+	// continued, anything else (such as the client going away) cancels it
+	stop := func(index int) {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.StopReason = "timeout"
+			s.captureFrontier(index)
+		} else {
 			result.Status = "cancelled"
-			break
-		default:
 		}
+	}
 
-		currentURL := queue[0]
-		queue = queue[1:]
+	for s.urls.Len() > 0 && len(result.PageResults) < s.limit {
+		if err := s.urls.Err(); err != nil {
+			logAt(logLevelError, "Crawl frontier of %s failed: %v", s.baseURL, err)
+			result.StopReason = "frontier_error"
+			break
+		}
+		if ctx.Err() != nil {
+			stop(urlIndex)
+			break
+		}
 
+		// Pages before the offset are only crawled for links, in parallel batches
 		if urlIndex < s.offset {
-			urlIndex++
-			if len(queue) < s.maxPages {
-				links, err := s.extractLinks(currentURL)
-				if err == nil {
-					for _, link := range links {
-						if !s.visited[link] && len(queue) < s.maxPages {
-							s.visited[link] = true
-							queue = append(queue, link)
-						}
+			full := s.urls.Len() >= s.maxPages
+			batch := s.popBatch(min(s.offset-urlIndex, linkBatchSize))
+			s.reportProgress(batch[0], len(result.PageResults))
+			if !full {
+				found, err := s.extractLinksConcurrently(ctx, batch)
+				if err != nil {
+					s.unpopBatch(batch)
+					stop(urlIndex)
+					break
+				}
+				challenged := false
+				for i, pageURL := range batch {
+					if found[i].err == nil {
+						s.enqueueLinks(pageURL, found[i].links)
+					}
+					if isThrottled(found[i].err) {
+						s.throttle.GiveUp(pageURL)
 					}
+					challenged = challenged || challengeVendor(found[i].err) != ""
+				}
+				if challenged && s.challengeMode() == challengeAbort {
+					result.StopReason = "bot_challenge"
+					s.captureFrontier(urlIndex + len(batch))
+					break
 				}
 			}
+			urlIndex += len(batch)
 			continue
 		}
 
-		urlIndex++
-		pageResult := s.scanPageWithLighthouse(currentURL)
-		result.PageResults = append(result.PageResults, pageResult)
+		currentURL, _ := s.urls.Pop()
+		if s.skipsAMP(currentURL) {
+			// Found to be an AMP variant after it was queued
+			urlIndex++
+			continue
+		}
+		s.reportProgress(currentURL, len(result.PageResults))
 
-		time.Sleep(1 * time.Second)
+		if s.budgetExhausted() {
+			result.StopReason = "budget_exhausted"
+			s.urls.PushFront(currentURL)
+			s.captureFrontier(urlIndex)
+			break
+		}
 
-		if pageResult.Error == "" && len(queue) < s.maxPages {
-			links, err := s.extractLinks(currentURL)
-			if err == nil {
-				for _, link := range links {
-					if !s.visited[link] && len(queue) < s.maxPages {
-						s.visited[link] = true
-						queue = append(queue, link)
-					}
+		// Links are fetched with the scan's context, as backing off may take longer than a page
+		pageCtx, cancelPage := context.WithTimeout(ctx, s.pageDeadline())
+		links := s.prefetchLinks(ctx, currentURL)
+		pageResult := s.scanPageWithLighthouse(pageCtx, currentURL)
+		found := <-links
+		if vendor := challengeVendor(found.err); vendor != "" {
+			pageResult = blockedPage(currentURL, vendor)
+		}
+		if pageResult.ErrorCode == "target_throttled" {
+			// Audit the page again once at the end of the queue, after backing off
+			s.throttle.ReportThrottled(&throttledError{})
+			if ctx.Err() == nil && s.throttle.Defer(currentURL) {
+				cancelPage()
+				s.urls.Push(currentURL)
+				urlIndex++
+				if !s.pause(ctx) {
+					stop(urlIndex)
+					break
 				}
+				continue
 			}
+			s.throttle.GiveUp(currentURL)
+		} else if isThrottled(found.err) {
+			// Audited, but its links could not be fetched
+			s.throttle.GiveUp(currentURL)
+		}
+		s.addPageFindings(&pageResult)
+		if s.iframes && found.err == nil {
+			s.auditFrames(pageCtx, &pageResult)
+		}
+		cancelPage()
+		s.markSlowPage(&pageResult)
+		s.applySeverity(&pageResult)
+		if ctx.Err() != nil {
+			// The page was interrupted, so it is not part of the result
+			s.urls.PushFront(currentURL)
+			stop(urlIndex)
+			break
+		}
+		urlIndex++
+		pageResult.Depth = s.urls.Depth(currentURL)
+		s.saveRawReport(&pageResult)
+		result.PageResults = append(result.PageResults, pageResult)
+		s.notifyPage(pageResult)
+		// Queued before pausing, so a frontier saved while paused keeps the page's links
+		if found.err == nil && s.urls.Len() < s.maxPages {
+			s.enqueueLinks(currentURL, found.links)
+		}
+
+		if pageResult.BotChallenge != "" && s.challengeMode() == challengeAbort {
+			result.StopReason = "bot_challenge"
+			s.captureFrontier(urlIndex)
+			break
 		}
+		if !s.pause(ctx) {
+			stop(urlIndex)
+			break
+		}
+	}
+
+	s.retryFailedPages(ctx, &result)
+	s.finalizeResult(&result)
+	return result
+}
+
+// finalizeResult fills in the totals, summary and status once scanning has finished
+func (s *AccessibilityScanner) finalizeResult(result *ScanResult) {
+	applyTriage(result)
+	if _, err := trackIssueLifecycle(siteKey(result.BaseURL), result.PageResults, result.ID, "scan", s.reportsAudit); err != nil && !errors.Is(err, errInvalidSite) {
+		logAt(logLevelWarn, "Warning: Could not update issue lifecycle for %s: %v", result.BaseURL, err)
 	}
 
+	s.pairAMPPages(result)
+	result.Pagination = s.paginationReport()
+	result.Throttling = s.throttle.Report()
 	result.TotalPages = len(result.PageResults)
-	result.UrlsDiscovered = s.urlsDiscovered
+	discovered, total := s.urls.Discovered()
+	result.UrlsDiscovered = discovered
+	if total > len(discovered) {
+		result.UrlsDiscoveredTotal = total
+	}
+	result.Summary = buildSummary(result.PageResults)
+	result.SkipLinks = skipLinkReport(result.PageResults)
+	result.SiteScore = computeSiteScore(result.PageResults, s.trafficHints)
 
 	for _, pageResult := range result.PageResults {
 		result.UrlsVisited = append(result.UrlsVisited, pageResult.URL)
@@ -420,8 +925,12 @@ func (s *AccessibilityScanner) crawlAndScan(ctx context.Context) ScanResult {
 			result.Status = "partial"
 		}
 	}
-
-	return result
+	if s.frontier != nil {
+		result.Status = "partial"
+	}
+	if s.thresholds != nil {
+		result.Thresholds = s.thresholds.evaluate(result)
+	}
 }
 
 // API Handlers
@@ -433,12 +942,91 @@ func handleScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts, err := parseResultOptions(r.URL.Query())
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req ScanRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
 		return
 	}
 
+	// Continue an earlier scan from its saved frontier, with the original settings
+	var frontier *CrawlFrontier
+	if req.ContinuationToken != "" {
+		var err error
+		frontier, err = scanStore.GetFrontier(req.ContinuationToken)
+		if errors.Is(err, errContinuationNotFound) {
+			sendError(w, "Continuation not found", http.StatusNotFound, "The continuation token is unknown or has already been used")
+			return
+		}
+		if err == nil {
+			var previous ScanResult
+			previous, err = scanStore.Get(frontier.ScanID)
+			frontier.pages = previous.PageResults
+			frontier.sampling = previous.Sampling
+		}
+		if err != nil {
+			logAt(logLevelError, "Could not load continuation %s: %v", req.ContinuationToken, err)
+			sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the scan to continue")
+			return
+		}
+
+		resumed := frontier.Request
+		resumed.ContinuationToken = req.ContinuationToken
+		if req.TimeoutSeconds != 0 {
+			resumed.TimeoutSeconds = req.TimeoutSeconds
+		}
+		if req.PageTimeoutSeconds != 0 {
+			resumed.PageTimeoutSeconds = req.PageTimeoutSeconds
+		}
+		if req.MaxLighthouseCalls != 0 {
+			resumed.MaxLighthouseCalls = req.MaxLighthouseCalls
+		}
+		req = resumed
+	}
+
+	// Fill in unset settings from the requested profile, then from the site's
+	// own defaults and its default profile
+	var site *Site
+	if req.Site != "" {
+		record, err := orgStore.Site(strings.ToLower(req.Site))
+		if err != nil {
+			sendOrgStoreError(w, err)
+			return
+		}
+		if req.URL == "" {
+			req.URL = record.URL
+		} else if siteKey(req.URL) != record.Host {
+			sendError(w, "Invalid URL", http.StatusBadRequest, "URL must be on the host of the site")
+			return
+		}
+		site = &record
+	}
+	if req.Profile != "" {
+		profile, err := profileStore.Get(req.Profile)
+		if err != nil {
+			sendProfileStoreError(w, err)
+			return
+		}
+		profile.applyTo(&req)
+	}
+	if site != nil {
+		site.DefaultScan.applyTo(&req)
+		if req.Profile == "" && site.Profile != "" {
+			profile, err := profileStore.Get(site.Profile)
+			if err != nil {
+				sendProfileStoreError(w, err)
+				return
+			}
+			req.Profile = profile.Name
+			profile.applyTo(&req)
+		}
+	}
+
 	// Validate URL
 	if req.URL == "" {
 		sendError(w, "Missing URL", http.StatusBadRequest, "URL is required")
@@ -457,10 +1045,31 @@ func handleScan(w http.ResponseWriter, r *http.Request) {
 	if req.Limit == 0 {
 		req.Limit = 5
 	}
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = defaultScanTimeoutSeconds
+	}
+	if req.PageTimeoutSeconds == 0 {
+		req.PageTimeoutSeconds = defaultPageTimeoutSeconds
+	}
+	if req.Frontier == "" && req.MaxPages > maxPagesInMemory {
+		req.Frontier = frontierDisk
+	}
 
 	// Validate ranges
-	if req.MaxPages < 1 || req.MaxPages > 1000 {
-		sendError(w, "Invalid max_pages", http.StatusBadRequest, "max_pages must be between 1 and 1000")
+	if req.Frontier != "" && req.Frontier != frontierMemory && req.Frontier != frontierDisk {
+		sendError(w, "Invalid frontier", http.StatusBadRequest, "frontier must be \"memory\" or \"disk\"")
+		return
+	}
+	if req.Frontier == frontierDisk && req.Sampling != nil {
+		sendError(w, "Invalid frontier", http.StatusBadRequest, "sampling explores sites in memory and cannot use a disk frontier")
+		return
+	}
+	maxPages := maxPagesInMemory
+	if req.Frontier == frontierDisk {
+		maxPages = maxPagesOnDisk
+	}
+	if req.MaxPages < 1 || req.MaxPages > maxPages {
+		sendError(w, "Invalid max_pages", http.StatusBadRequest, fmt.Sprintf("max_pages must be between 1 and %d (%d with a disk frontier)", maxPagesInMemory, maxPagesOnDisk))
 		return
 	}
 	if req.Limit < 1 || req.Limit > 100 {
@@ -471,36 +1080,287 @@ func handleScan(w http.ResponseWriter, r *http.Request) {
 		sendError(w, "Invalid offset", http.StatusBadRequest, "offset cannot be negative")
 		return
 	}
+	if req.TimeoutSeconds < 10 || req.TimeoutSeconds > maxScanTimeoutSeconds {
+		sendError(w, "Invalid timeout_seconds", http.StatusBadRequest, fmt.Sprintf("timeout_seconds must be between 10 and %d", maxScanTimeoutSeconds))
+		return
+	}
+	if req.PageTimeoutSeconds < 5 || req.PageTimeoutSeconds > maxPageTimeoutSeconds {
+		sendError(w, "Invalid page_timeout_seconds", http.StatusBadRequest, fmt.Sprintf("page_timeout_seconds must be between 5 and %d", maxPageTimeoutSeconds))
+		return
+	}
+	if req.MaxLighthouseCalls < 0 {
+		sendError(w, "Invalid max_lighthouse_calls", http.StatusBadRequest, "max_lighthouse_calls cannot be negative")
+		return
+	}
+	if req.Sampling != nil {
+		if err := req.Sampling.validate(); err != nil {
+			sendError(w, "Invalid sampling", http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Language == "" {
+		req.Language = negotiateLanguage(r.Header.Get("Accept-Language"), remediationCatalogs.Languages())
+	} else if !languageTagPattern.MatchString(req.Language) {
+		sendError(w, "Invalid language", http.StatusBadRequest, "language must be a language tag such as \"en\" or \"pt-BR\"")
+		return
+	}
+	if err := validateEngine(req.Engine); err != nil {
+		sendError(w, "Invalid engine", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateViewports(req.Viewports, req.Engine); err != nil {
+		sendError(w, "Invalid viewports", http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.PaginationLimit < 0 {
+		sendError(w, "Invalid pagination_limit", http.StatusBadRequest, "pagination_limit cannot be negative")
+		return
+	}
+	audits, err := parseLighthouseConfig(req.LighthouseConfig)
+	if err != nil {
+		sendError(w, "Invalid lighthouse_config", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateSeverityPolicy(req.SeverityOverrides, req.IgnoreAudits); err != nil {
+		sendError(w, "Invalid severity settings", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateAMPMode(req.AMP); err != nil {
+		sendError(w, "Invalid amp", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateStrategy(req.Strategy); err != nil {
+		sendError(w, "Invalid strategy", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateChallengeMode(req.BotChallenge); err != nil {
+		sendError(w, "Invalid bot_challenge", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.Thresholds.validate(); err != nil {
+		sendError(w, "Invalid thresholds", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.AltText.validate(); err != nil {
+		sendError(w, "Invalid alt_text", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateTags(req.Tags); err != nil {
+		sendError(w, "Invalid tags", http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, weight := range req.TrafficHints {
+		if weight < 0 {
+			sendError(w, "Invalid traffic_hints", http.StatusBadRequest, "traffic_hints weights cannot be negative")
+			return
+		}
+	}
 
 	// Get API key
 	apiKey := getAPIKey()
-	if apiKey == "" {
+	if !psiConfigured() {
 		sendError(w, "Configuration error", http.StatusInternalServerError, "Google API key not configured")
 		return
 	}
 
+	// Replay the original scan for a retried request with the same Idempotency-Key
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		sendError(w, "Invalid Idempotency-Key", http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+		return
+	}
+	if idempotencyKey != "" {
+		scanID, err := idempotencyKeys.Begin(r.Context(), clientName(r), idempotencyKey, requestHash(req))
+		if errors.Is(err, errIdempotencyMismatch) {
+			sendError(w, "Idempotency-Key reused", http.StatusUnprocessableEntity, "This Idempotency-Key was already used for a different scan request")
+			return
+		}
+		if err != nil {
+			return
+		}
+		if scanID != "" {
+			result, err := scanStore.Get(scanID)
+			if err != nil {
+				logAt(logLevelError, "Could not load scan %s for idempotent replay: %v", scanID, err)
+				sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the original scan result")
+				return
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeScanResult(w, r, result, opts)
+			return
+		}
+		// Released without a result unless the scan below is stored
+		defer idempotencyKeys.Abandon(clientName(r), idempotencyKey)
+	}
+
+	scanner := NewAccessibilityScanner(apiKey, req.URL, req.MaxPages, req.Offset, req.Limit)
+
+	// Track the scan so operators can inspect or cancel it through the admin API
+	job, jobCtx := scanJobs.Register(r.Context(), scanner.id, req.URL, req.Limit)
+	defer scanJobs.Remove(job)
+	scanner.job = job
+
+	// Enforce the client's quotas; the scan may audit at most the pages left for today
+	pagesLeft, releaseQuota, exceeded := admitQuota(clientName(r), req.Limit, true)
+	if exceeded != nil {
+		sendQuotaExceeded(w, exceeded)
+		return
+	}
+	defer releaseQuota()
+	eventBus.ScanQueued(scanner.id, req.URL, req.Limit)
+
+	// Wait for a scan slot, or turn the request away when the queue is full
+	release, err := scanQueue.Acquire(jobCtx)
+	if errors.Is(err, errQueueFull) {
+		sendQueueFull(w)
+		return
+	}
+	if errors.Is(context.Cause(jobCtx), errOperatorCancelled) {
+		sendError(w, "Scan cancelled", http.StatusServiceUnavailable, "The scan was cancelled by an operator before it started")
+		return
+	}
+	if err != nil {
+		return
+	}
+	defer release()
+	job.start()
+	eventBus.ScanStarted(scanner.id, req.URL, req.Limit)
+
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(jobCtx, timeout)
 	defer cancel()
 
 	// Run scan
-	scanner := NewAccessibilityScanner(apiKey, req.URL, req.MaxPages, req.Offset, req.Limit)
+	scanner.timeout = timeout
+	scanner.client.Timeout = time.Duration(req.PageTimeoutSeconds) * time.Second
+	scanner.maxLighthouseCalls = req.MaxLighthouseCalls
+	if pagesLeft > 0 && (scanner.maxLighthouseCalls == 0 || pagesLeft < scanner.maxLighthouseCalls) {
+		scanner.maxLighthouseCalls = pagesLeft
+	}
+	if frontier != nil {
+		err = scanner.resume(frontier)
+	} else if req.Frontier == frontierDisk {
+		err = scanner.useDiskFrontier()
+	}
+	if errors.Is(err, errContinuationNotFound) {
+		sendError(w, "Continuation not found", http.StatusNotFound, "The continuation token is unknown or has already been used")
+		return
+	}
+	if err != nil {
+		logAt(logLevelError, "Could not open crawl frontier: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not prepare the crawl frontier")
+		return
+	}
+
+	// Stream each page as it completes when the client accepts NDJSON
+	var stream *ndjsonStream
+	if wantsNDJSON(r) {
+		stream = newNDJSONStream(w, opts.Filter)
+		scanner.onPage = stream.page
+	}
+	scanner.trafficHints = req.TrafficHints
+	scanner.language = req.Language
+	scanner.tags = req.Tags
+	scanner.profile = req.Profile
+	scanner.include, scanner.includeRaw = compilePathPatterns(req.Include), req.Include
+	scanner.exclude, scanner.excludeRaw = compilePathPatterns(req.Exclude), req.Exclude
+	scanner.thresholds = req.Thresholds
+	scanner.altText = req.AltText
+	scanner.sampling = req.Sampling
+	scanner.amp = req.AMP
+	scanner.strategy = req.Strategy
+	scanner.botChallenge = req.BotChallenge
+	scanner.iframes = req.Iframes
+	scanner.paginationLimit = req.PaginationLimit
+	scanner.storeRaw = req.StoreRaw
+	scanner.audits, scanner.lighthouseConfig = audits.without(req.IgnoreAudits), req.LighthouseConfig
+	scanner.severityOverrides = req.SeverityOverrides
+	scanner.ignoreAudits = make(map[string]bool, len(req.IgnoreAudits))
+	for _, auditID := range req.IgnoreAudits {
+		scanner.ignoreAudits[auditID] = true
+	}
 	result := scanner.crawlAndScan(ctx)
+	usageStore.Record(clientName(r), UsageCounters{
+		Scans:           1,
+		PagesScanned:    int64(len(result.PageResults) - scanner.resumedPages()),
+		LighthouseCalls: int64(scanner.lighthouseCalls),
+	})
+	if errors.Is(context.Cause(ctx), errOperatorCancelled) {
+		result.StopReason = "operator_cancelled"
+	}
+	if original, ok := r.Context().Value(rerunOfKey{}).(*ScanResult); ok {
+		result.Rerun = compareRerun(*original, result)
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	if scanner.frontier != nil {
+		scanner.frontier.ScanID = result.ID
+		scanner.frontier.Request = req
+		scanner.frontier.Request.ContinuationToken = ""
+		if err := scanStore.SaveFrontier(scanner.frontier); err != nil {
+			logAt(logLevelWarn, "Warning: Could not store scan continuation: %v", err)
+		} else {
+			result.Continuation = scanner.frontier.Token
+		}
+	}
+
+	if err := scanStore.Save(&result); err != nil {
+		logAt(logLevelWarn, "Warning: Could not store scan result: %v", err)
+	} else if idempotencyKey != "" {
+		idempotencyKeys.Complete(clientName(r), idempotencyKey, result.ID)
+	}
+	notifyDiscord(&result)
+	webhookStore.Dispatch(&result)
+	eventBus.ScanFinished(&result)
+	monitorStore.Evaluate(&result)
+	if frontier != nil {
+		if err := scanStore.DeleteFrontier(frontier.Token); err != nil {
+			logAt(logLevelWarn, "Warning: Could not remove used continuation: %v", err)
+		}
+	}
+
+	if stream != nil {
+		result.Links = scanLinks(apiVersion(r), &result)
+		stream.finish(result)
+		return
+	}
+
+	// v2 reports a scan where no page could be reached as an error, with the
+	// problem code of its first page; a crawl that found no pages is a result
+	if apiVersion(r) >= 2 && len(result.PageResults) > 0 && result.Summary.PagesWithErrors == len(result.PageResults) {
+		sendPageError(w, r, "Target unreachable", result.PageResults[0])
+		return
+	}
+
+	writeScanResult(w, r, result, opts)
 }
 
-// handleHealth handles GET /health requests
+// handleHealth handles GET /health requests. With ?deep=true it also checks
+// the external dependencies and answers 503 when one fails.
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC(),
-		"version":   "1.0.0",
+		"version":   buildInfo().Version,
+		"build":     buildInfo(),
 		"service":   "accessibility-scanner",
 	}
 
+	code := http.StatusOK
+	if deep, _ := strconv.ParseBool(r.URL.Query().Get("deep")); deep {
+		checks := deepHealthChecks(r.Context())
+		for _, check := range checks {
+			if check.Status == "failing" {
+				health["status"] = "unhealthy"
+				code = http.StatusServiceUnavailable
+			}
+		}
+		health["checks"] = checks
+		w.Header().Set("Cache-Control", "no-store")
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(health)
 }
 
@@ -508,27 +1368,12 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 func handleRoot(w http.ResponseWriter, r *http.Request) {
 	docs := map[string]interface{}{
 		"service": "WPMUDEV Accessibility Scanner API",
-		"version": "1.0.0",
-		"endpoints": map[string]interface{}{
-			"POST /api/v1/scan": map[string]interface{}{
-				"description": "Scan a website for accessibility issues",
-				"body": map[string]interface{}{
-					"url":       "Website URL to scan (required)",
-					"max_pages": "Maximum pages to discover (default: 50, max: 1000)",
-					"offset":    "Skip first N pages (default: 0)",
-					"limit":     "Maximum pages to scan (default: 5, max: 100)",
-				},
-				"example": map[string]interface{}{
-					"url":       "https://example.com",
-					"max_pages": 100,
-					"offset":    10,
-					"limit":     20,
-				},
-			},
-			"GET /health": map[string]interface{}{
-				"description": "Health check endpoint",
-			},
+		"version": buildInfo().Version,
+		"api_versions": map[string]interface{}{
+			"v1": "Deprecated, sunset " + v1Sunset.Format("2006-01-02") + "; errors are {error, code, message}",
+			"v2": "Same endpoints under /api/v2; errors are RFC 7807 application/problem+json with a machine-readable code",
 		},
+		"endpoints":  endpointDocs(),
 		"user_agent": "WPMUDEVAccessibilityScannerBot/1.0",
 	}
 
@@ -536,6 +1381,403 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(docs)
 }
 
+// endpointDocs documents each endpoint for the root page and the OpenAPI spec
+func endpointDocs() map[string]interface{} {
+	return map[string]interface{}{
+		"POST /api/v1/scan": map[string]interface{}{
+			"description": "Scan a website for accessibility issues",
+			"headers": map[string]interface{}{
+				"Accept":          "application/x-ndjson streams each page result as a JSON line as soon as it is scanned, ending with a summary line",
+				"Idempotency-Key": "Unique key per scan request; retries with the same key return the original scan instead of starting a new one (kept for 24 hours)",
+			},
+			"body": map[string]interface{}{
+				"url":                  "Website URL to scan (required unless site is given)",
+				"site":                 "Host of a registered site; its URL and default scan settings fill in what the request leaves out",
+				"max_pages":            "Maximum pages to discover (default: 50, max: 1000, or 500000 with a disk frontier)",
+				"offset":               "Skip first N pages (default: 0)",
+				"limit":                "Maximum pages to scan (default: 5, max: 100)",
+				"traffic_hints":        "Relative traffic per URL or path used to weight site_score (optional)",
+				"language":             "Language for audit texts and remediation guidance (default: negotiated from Accept-Language)",
+				"sampling":             "WCAG-EM sampling: {\"mode\": \"wcag-em\", \"random_pages\": 2, \"seed\": 42} (optional)",
+				"timeout_seconds":      "Overall scan time limit (default: 600, range: 10-1800)",
+				"page_timeout_seconds": "Time limit for each page request (default: 30, range: 5-120); slower pages are skipped as slow and counted in summary.skipped_slow_pages",
+				"max_lighthouse_calls": "Stop after this many Lighthouse calls (default: unlimited)",
+				"frontier":             "\"memory\" or \"disk\" to keep the crawl frontier in an embedded database for very large sites (default: disk above 1000 max_pages)",
+				"continuation_token":   "Resume a scan that stopped early; other settings come from the original scan",
+				"profile":              "Name of a scan profile whose settings fill in what the request leaves out (default: the site's profile)",
+				"include":              "Only crawl URL paths matching these patterns (* wildcard)",
+				"exclude":              "Never crawl URL paths matching these patterns (* wildcard)",
+				"engine":               "Audit engine (default and only option: pagespeed)",
+				"viewports":            "Screens to audit each page at, as device profile names (reflow, mobile, tablet, laptop, desktop) or {\"width\", \"height\", \"device_scale_factor\", \"mobile\"}. Reserved for local engines: this server only runs pagespeed, so requests with viewports are rejected",
+				"amp":                  "\"skip\" to never scan AMP variants, or \"pair\" to follow rel=amphtml links and compare each page with its AMP variant",
+				"strategy":             "\"mobile\", \"desktop\" or \"both\" to audit each page under both and compare them (default: the PageSpeed default, desktop); both counts two Lighthouse calls per page",
+				"bot_challenge":        "What to do with pages answered by a bot challenge (Cloudflare, Imperva, DataDome, ...): \"block\" to report them as blocked (default), \"retry\" to fetch them again after a wait, or \"abort\" to stop the scan",
+				"iframes":              "Also audit same-origin iframes of each page, listing their issues with the page (default: false)",
+				"pagination_limit":     "Crawl only the first N pages of each paginated series (/page/N, ?page=N, rel=next; default: all)",
+				"store_raw":            "Also store the full Lighthouse report of each page, served by GET /api/v1/scans/{id}/pages/{n}/raw (default: false)",
+				"lighthouse_config":    "Lighthouse config JSON selecting audits with settings.onlyAudits and settings.skipAudits (usually set in a profile)",
+				"severity_overrides":   "Impact level per audit ID, e.g. {\"image-alt\": \"critical\"}, applied to issues, summaries, thresholds and exports (usually set in a profile)",
+				"ignore_audits":        "Audit IDs whose issues are dropped and which no longer count toward the score (usually set in a profile)",
+				"thresholds":           "Pass criteria reported in the result: {\"min_site_score\": 0.9, \"min_page_score\": 0.8, \"max_issues\": {\"critical\": 0}}",
+				"alt_text":             "Alt text heuristics: {\"max_length\": 150, \"placeholder_words\": [\"image\", \"foto\"]} (default: 125 characters and common placeholder words; usually set in a profile)",
+				"tags":                 "Labels stored with the scan, e.g. {\"release\": \"v2.3\", \"env\": \"staging\"} (optional, max 20)",
+			},
+			"query": map[string]interface{}{
+				"group_by":  "Group issues by \"page\" (default) or \"audit\"",
+				"impact":    "Only include issues with these impacts (comma-separated)",
+				"audit":     "Only include issues for these audit IDs (comma-separated)",
+				"url":       "Only include pages whose URL matches this pattern (* wildcard)",
+				"has_error": "Only include pages that failed (true) or succeeded (false)",
+				"view":      "\"full\" (default) or \"summary\" for scores and counts only",
+				"sort":      "Order pages worst first by score, severity, issues, or alphabetically by url",
+				"lang":      "Re-localize remediation guidance of a stored scan into this language",
+				"fields":    "Comma-separated fields to return, dot notation for nested (e.g. site_score,summary.average_score)",
+				"format":    "\"json\" (default), \"tap\" (Test Anything Protocol), \"checkstyle\" (Checkstyle XML), \"github\" (GitHub Actions annotations) for CI tools, or \"markdown\" for issues and wikis",
+			},
+			"example": map[string]interface{}{
+				"url":       "https://example.com",
+				"max_pages": 100,
+				"offset":    10,
+				"limit":     20,
+			},
+		},
+		"POST /api/v1/scan/element": map[string]interface{}{
+			"description": "Scan one URL and return only the issues for one element or audit, without crawling",
+			"body": map[string]interface{}{
+				"url":      "Page URL to scan (required)",
+				"selector": "CSS selector of the element, matched against the end of issue selectors",
+				"audit_id": "Lighthouse audit ID (selector or audit_id is required)",
+				"language": "Language for audit texts and remediation guidance (optional)",
+			},
+		},
+		"GET /api/v1/queue": map[string]interface{}{
+			"description": "Running and queued scans with the estimated wait for a new scan",
+		},
+		"GET /api/v1/usage": map[string]interface{}{
+			"description": "Requests, scans, pages scanned and Lighthouse calls of the calling API key, in total and per day",
+			"query": map[string]interface{}{
+				"days": "Days to report, including today (default: 30, max: 90)",
+			},
+		},
+		"GET /api/v1/scans": map[string]interface{}{
+			"description": "Stored scans, newest first (page_size, cursor)",
+			"query": map[string]interface{}{
+				"tag":       "Only scans with this tag: name=value, or name for any value (repeatable, all must match)",
+				"site":      "Only scans of this host",
+				"status":    "Only scans with this status (completed, partial, failed)",
+				"since":     "Only scans started at or after this RFC 3339 time",
+				"until":     "Only scans started at or before this RFC 3339 time",
+				"min_score": "Only scans with at least this site score (0-1)",
+				"max_score": "Only scans with at most this site score (0-1)",
+				"sort":      "Order by scan_time (default, newest first), score (lowest first), pages (most first) or site",
+				"order":     "asc or desc to override the direction of sort",
+			},
+		},
+		"GET /api/v1/scans/{id}": map[string]interface{}{
+			"description": "Retrieve a stored scan (accepts the same query parameters as POST /api/v1/scan)",
+		},
+		"GET /api/v1/scans/{id}/pages": map[string]interface{}{
+			"description": "Paginated page results of a stored scan (page_size, cursor, filters)",
+		},
+		"POST /api/v1/scans/{id}/rerun": map[string]interface{}{
+			"description": "Scan again with the stored scan's configuration (URL, filters, engine, profile settings, tags); the new result's rerun block lists the issues fixed and new since. Responds like POST /api/v1/scan",
+		},
+		"GET /api/v1/scans/{id}/pages/{n}": map[string]interface{}{
+			"description": "One page result of a stored scan, the nth of page_results counting from 0, without the rest of the scan",
+		},
+		"GET /api/v1/scans/{id}/page": map[string]interface{}{
+			"description": "The page result of a stored scan for a URL",
+			"query": map[string]interface{}{
+				"url": "URL of the page, as scanned or differing only by normalization such as a trailing slash",
+			},
+		},
+		"GET /api/v1/scans/{id}/pages/{n}/raw": map[string]interface{}{
+			"description": "Full Lighthouse report (LHR JSON) of the nth page of page_results, counting from 0, for scans run with store_raw",
+		},
+		"GET /api/v1/scans/{id}/issues": map[string]interface{}{
+			"description": "Paginated issues of a stored scan across all pages (page_size, cursor, filters)",
+		},
+		"GET /api/v1/scans/{id}/vpat": map[string]interface{}{
+			"description": "VPAT 2.x (WCAG) Accessibility Conformance Report pre-populated from a stored scan",
+			"query": map[string]interface{}{
+				"format":  "html (default), docx or json",
+				"product": "Product name (default: site host)",
+				"vendor":  "Vendor name (optional)",
+				"contact": "Contact information (optional)",
+			},
+		},
+		"POST /api/v1/scans/{id}/share": map[string]interface{}{
+			"description": "Create a signed, time-limited link serving the scan's HTML report (the VPAT HTML) without authentication; needs SHARE_SECRET",
+			"body": map[string]interface{}{
+				"expires_in_hours": "Lifetime of the link (default: 168, at most 2160)",
+				"product":          "Product name shown in the report (default: site host)",
+				"vendor":           "Vendor name (optional)",
+				"contact":          "Contact information (optional)",
+			},
+		},
+		"GET /api/v1/scans/{id}/site-health": map[string]interface{}{
+			"description": "A stored scan as WordPress Site Health test results (label, status, badge, description, actions, test)",
+		},
+		"POST /api/v1/orgs": map[string]interface{}{
+			"description": "Create an organization (GET lists them; GET, PUT and DELETE /api/v1/orgs/{org} manage one)",
+			"body": map[string]interface{}{
+				"name": "Display name (required)",
+				"id":   "Lowercase slug (default: derived from name)",
+			},
+		},
+		"POST /api/v1/orgs/{org}/projects": map[string]interface{}{
+			"description": "Create a project in an organization (GET lists them; GET, PUT and DELETE /api/v1/projects/{project} manage one)",
+			"body": map[string]interface{}{
+				"name":          "Display name (required)",
+				"id":            "Slug ID (default: derived from the name)",
+				"notifications": "{\"discord_webhook_url\": \"...\"} announces every scan of the project's sites (optional)",
+			},
+		},
+		"POST /api/v1/projects/{project}/sites": map[string]interface{}{
+			"description": "Register a site in a project (GET lists them)",
+			"body": map[string]interface{}{
+				"url":           "Base URL of the site; its host identifies the site (required unless host is given)",
+				"name":          "Display name (optional)",
+				"default_scan":  "Scan settings used when a scan request leaves them unset: max_pages, limit, language, sampling, timeout_seconds, ... (optional)",
+				"notifications": "{\"emails\": [...], \"webhook_url\": \"...\", \"discord_webhook_url\": \"...\"} (optional)",
+			},
+		},
+		"GET /api/v1/projects/{project}/audit-stats": map[string]interface{}{
+			"description": "Audits failing most often across the completed and partial scans of the project's sites: scans, sites, pages and issues per audit",
+			"query": map[string]interface{}{
+				"since": "Only scans at or after this RFC 3339 time",
+				"until": "Only scans at or before this RFC 3339 time",
+				"limit": "Audits listed (default: 50, at most 500)",
+			},
+		},
+		"GET /api/v1/projects/leaderboard": map[string]interface{}{
+			"description": "Every registered site ranked by its latest finished scan, lowest score first, with issue density and the score trend since the previous scan",
+			"query": map[string]interface{}{
+				"org":     "Only sites of this organization",
+				"project": "Only sites of this project",
+				"sort":    "score (default, lowest first, then densest issues) or issues_per_page (densest first)",
+			},
+		},
+		"GET /api/v1/sites/{host}": map[string]interface{}{
+			"description": "Site record with its project and defaults (PUT updates it, DELETE removes the record but keeps stored scans)",
+		},
+		"GET /api/v1/sites/{host}/scans": map[string]interface{}{
+			"description": "Stored scans of a site, newest first, with status and site score",
+		},
+		"GET /api/v1/sites/{host}/trend.csv": map[string]interface{}{
+			"description": "CSV of the site's completed and partial scans, oldest first: scan time, status, site and average score, pages, and issue counts by impact",
+			"query": map[string]interface{}{
+				"since": "Only scans at or after this RFC 3339 time",
+				"until": "Only scans at or before this RFC 3339 time",
+				"tag":   "Only scans with this tag (name=value or name, repeatable)",
+			},
+		},
+		"GET /api/v1/sites/{host}/site-health": map[string]interface{}{
+			"description": "The site's latest completed or partial scan as WordPress Site Health test results",
+		},
+		"GET /api/v1/sites/{host}/site-health/summary": map[string]interface{}{
+			"description": "Compact Site Health status of a site: overall status, score, test counts by status and issues by impact",
+		},
+		"GET /api/v1/compare": map[string]interface{}{
+			"description": "Compare two scans side by side: scores, issue density, and the audits failing in both or only one",
+			"query": map[string]interface{}{
+				"a":     "Site host, whose latest completed or partial scan is used, or scan ID",
+				"b":     "Site host or scan ID to compare with a",
+				"tag_a": "Only consider a's scans with this tag (name=value or name, repeatable), e.g. env=prod",
+				"tag_b": "Only consider b's scans with this tag, e.g. env=staging",
+			},
+		},
+		"POST /api/v1/profiles": map[string]interface{}{
+			"description": "Create a named scan profile (GET lists them; GET, PUT and DELETE /api/v1/profiles/{name} manage one)",
+			"body": map[string]interface{}{
+				"name":          "Lowercase slug used as {\"profile\": \"...\"} in scan requests (required)",
+				"description":   "Free text (optional)",
+				"settings":      "Any of max_pages, limit, language, sampling, timeout_seconds, page_timeout_seconds, max_lighthouse_calls, traffic_hints, include, exclude, engine, strategy, viewports, bot_challenge, amp, iframes, pagination_limit, store_raw, lighthouse_config, severity_overrides, ignore_audits, thresholds, alt_text",
+				"notifications": "{\"emails\": [...], \"webhook_url\": \"...\", \"discord_webhook_url\": \"...\"} (optional)",
+			},
+		},
+		"POST /api/v1/webhooks": map[string]interface{}{
+			"description": "Subscribe an endpoint to scan events (GET lists webhooks; GET, PUT and DELETE /api/v1/webhooks/{id} manage one)",
+			"body": map[string]interface{}{
+				"url":        "Endpoint receiving POSTed JSON events (required)",
+				"events":     "Any of scan.completed, scan.failed, score.regressed, monitor.triggered, monitor.resolved (required)",
+				"secret":     "Signs each delivery in X-Webhook-Signature as sha256=HMAC (optional)",
+				"project_id": "Only scans of this project's sites (optional)",
+				"site":       "Only scans of this host (optional)",
+				"score_drop": "site_score drop that counts as score.regressed (default: 0.05)",
+				"disabled":   "Pause deliveries; they are kept until the webhook is enabled again",
+			},
+		},
+		"GET /api/v1/webhooks/{id}/deliveries": map[string]interface{}{
+			"description": "Deliveries of a webhook, newest first, with each attempt's request and response snapshot and timing (paginated)",
+			"query": map[string]interface{}{
+				"status":  "pending, delivered or dead (optional)",
+				"event":   "Only this event type (optional)",
+				"scan_id": "Only deliveries about this scan (optional)",
+			},
+		},
+		"GET /api/v1/webhooks/dead-letters": map[string]interface{}{
+			"description": "Deliveries that failed every retry (POST /api/v1/webhooks/deliveries/{delivery}/redeliver queues one again)",
+		},
+		"POST /api/v1/hooks": map[string]interface{}{
+			"description": "Subscribe a REST hook for no-code platforms such as Zapier and Make (GET lists them; DELETE /api/v1/hooks/{id} unsubscribes)",
+			"body": map[string]interface{}{
+				"target_url": "URL the event is posted to (required)",
+				"event":      "scan.completed, scan.failed, score.regressed, monitor.triggered or monitor.resolved (required)",
+				"project_id": "Only scans of this project's sites (optional)",
+				"site":       "Only scans of this host (optional)",
+			},
+		},
+		"GET /api/v1/hooks/sample": map[string]interface{}{
+			"description": "Payloads of the newest scans for an event, or an example when there are none, for mapping fields",
+			"query": map[string]interface{}{
+				"event": "scan.completed (default), scan.failed, score.regressed, monitor.triggered or monitor.resolved",
+			},
+		},
+		"POST /api/v1/monitors": map[string]interface{}{
+			"description": "Create a monitor whose rules are checked after every completed or partial scan in its scope (GET lists monitors; GET, PUT and DELETE /api/v1/monitors/{id} manage one)",
+			"body": map[string]interface{}{
+				"name":          "Monitor name shown in alerts (required)",
+				"rules":         "[{\"metric\": \"site_score\", \"operator\": \"<\", \"value\": 0.9}]; operators <, <=, >, >=, increase, decrease (required)",
+				"site":          "Only scans of this host (optional)",
+				"project_id":    "Only scans of this project's sites (optional)",
+				"notifications": "{\"webhook_url\": \"...\", \"discord_webhook_url\": \"...\"} (optional; defaults to the site's Discord webhooks)",
+				"disabled":      "Pause evaluation",
+			},
+		},
+		"POST /api/v1/page-monitors": map[string]interface{}{
+			"description": "Audit one key URL every few hours, apart from full-site scans (GET lists page monitors; GET, PUT and DELETE /api/v1/page-monitors/{id} manage one)",
+			"body": map[string]interface{}{
+				"url":            "Page to audit (required)",
+				"name":           "Label shown in alerts (optional)",
+				"interval_hours": "Hours between checks, 1-168 (default: 6)",
+				"score_drop":     "Score drop from the previous check flagged as a regression (default: 0.05)",
+				"notifications":  "{\"webhook_url\": \"...\", \"discord_webhook_url\": \"...\"} alerted on regressions (optional)",
+				"disabled":       "Pause checks",
+			},
+		},
+		"GET /api/v1/page-monitors/{id}/checks": map[string]interface{}{
+			"description": "Time series of a page monitor's checks, oldest first (POST checks the page now)",
+			"query": map[string]interface{}{
+				"from": "Only checks at or after this RFC 3339 time (optional)",
+				"to":   "Only checks at or before this RFC 3339 time (optional)",
+			},
+		},
+		"POST /api/v1/grafana/query": map[string]interface{}{
+			"description": "Grafana simple JSON datasource over stored scans (GET /api/v1/grafana tests the connection; POST /api/v1/grafana/search lists targets)",
+			"body": map[string]interface{}{
+				"range":         "{\"from\": RFC 3339 time, \"to\": RFC 3339 time}",
+				"targets":       "[{\"target\": \"metric:host\", \"type\": \"timeserie\" or \"table\"}]; host * charts every site",
+				"maxDataPoints": "Newest points kept per series (optional)",
+			},
+		},
+		"GET /api/v1/sites/{host}/triage": map[string]interface{}{
+			"description": "List triage decisions recorded for a site, keyed by issue fingerprint",
+		},
+		"PUT /api/v1/sites/{host}/issues/{fingerprint}/triage": map[string]interface{}{
+			"description": "Set the triage state of an issue (DELETE resets it to open)",
+			"body": map[string]interface{}{
+				"state": "open, false_positive, wont_fix or fixed (required)",
+				"note":  "Free-text note (optional)",
+			},
+		},
+		"GET /api/v1/sites/{host}/issues": map[string]interface{}{
+			"description": "Issue history for a site: open, fixed and regressed issues across scans",
+			"query": map[string]interface{}{
+				"status":   "Only issues in this state: open, fixed or regressed",
+				"assignee": "Only issues assigned to this person",
+			},
+		},
+		"PUT /api/v1/sites/{host}/issues/{fingerprint}/assignee": map[string]interface{}{
+			"description": "Assign an issue to a team member (DELETE unassigns it)",
+			"body": map[string]interface{}{
+				"assignee": "Name, email or user ID (required)",
+			},
+		},
+		"POST /api/v1/sites/{host}/issues/{fingerprint}/comments": map[string]interface{}{
+			"description": "Comment on an issue (GET lists comments as threads)",
+			"body": map[string]interface{}{
+				"author":    "Comment author (required)",
+				"body":      "Comment text (required)",
+				"parent_id": "ID of the comment being replied to (optional)",
+			},
+		},
+		"DELETE /api/v1/sites/{host}/data": map[string]interface{}{
+			"description": "Erase all stored scans, continuation tokens, triage, issue history and comments of a site and return a deletion receipt (requires the admin token)",
+		},
+		"POST /api/v1/issues/{fingerprint}/verify": map[string]interface{}{
+			"description": "Re-audit the page of a tracked issue to confirm whether it is fixed",
+			"body": map[string]interface{}{
+				"site": "Site host the issue belongs to (optional, searched when omitted)",
+			},
+		},
+		"GET /api/v1/admin/scans": map[string]interface{}{
+			"description": "Active scans with progress and queue load (requires Authorization: Bearer <admin token>)",
+		},
+		"DELETE /api/v1/admin/scans/{id}": map[string]interface{}{
+			"description": "Cancel an active scan; it stops at the next page and returns its partial result (GET shows its progress, /frontier its pending URLs)",
+		},
+		"PUT /api/v1/admin/queue": map[string]interface{}{
+			"description": "Change scan concurrency at runtime (GET shows the queue)",
+			"body": map[string]interface{}{
+				"max_concurrent": "Scans running at once (optional)",
+				"max_queued":     "Scans waiting for a slot (optional)",
+			},
+		},
+		"GET /api/v1/admin/usage": map[string]interface{}{
+			"description": "Usage of every API key over the last days (?days=30), heaviest Lighthouse users first",
+		},
+		"GET /api/v1/admin/retention": map[string]interface{}{
+			"description": "Retention policy and space reclaimed by the cleanup janitor (POST runs it now)",
+		},
+		"GET /api/v1/admin/encryption": map[string]interface{}{
+			"description": "Stored scans, continuation tokens and site documents by encryption key (POST re-encrypts them with the active key)",
+		},
+		"GET /api/v1/admin/audit-stats": map[string]interface{}{
+			"description": "Audits failing most often across every scan of the instance (same query and response as /api/v1/projects/{project}/audit-stats)",
+		},
+		"GET /api/v1/admin/audit-trail": map[string]interface{}{
+			"description": "Authenticated API requests newest first: client, endpoint, IP, status and result (paginated)",
+			"query": map[string]string{
+				"client": "API client name, admin or anonymous (optional)",
+				"method": "HTTP method (optional)",
+				"path":   "Path prefix, e.g. /api/v1/scans (optional)",
+				"result": "success, denied or failure (optional)",
+				"since":  "RFC 3339 timestamp (optional)",
+				"until":  "RFC 3339 timestamp (optional)",
+			},
+		},
+		"GET /health": map[string]interface{}{
+			"description": "Health check endpoint (liveness)",
+			"query": map[string]interface{}{
+				"deep": "true to also check PageSpeed reachability and latency, storage, the scan queue and the event bus, each with its status and timing; 503 when one fails",
+			},
+		},
+		"GET /ready": map[string]interface{}{
+			"description": "Readiness check: API key, PageSpeed API reachability, storage and scan queue; 503 when any fails",
+		},
+		"GET /version": map[string]interface{}{
+			"description": "Version, git commit, build date and Go version of the running build, also recorded in each scan result as scanner",
+		},
+		"GET /share/{token}": map[string]interface{}{
+			"description": "HTML report of a shared scan, without authentication, until the link expires (410) or SHARE_SECRET changes (403)",
+		},
+		"GET /widget/{host}": map[string]interface{}{
+			"description": "Latest score (0-100), grade (A-F) and scan date of a registered site that set public_widget, without authentication, for embedded \"Accessibility checked\" widgets",
+		},
+		"GET /oembed": map[string]interface{}{
+			"description": "oEmbed rich response with the widget HTML of the registered site a URL belongs to, if it set public_widget, without authentication",
+			"query": map[string]interface{}{
+				"url":    "URL of a page of the site (required)",
+				"format": "json (default; other formats answer 501)",
+			},
+		},
+		"GET /openapi.json": map[string]interface{}{
+			"description": "OpenAPI 3.1 description of the v2 API, including the catalog of problem codes errors carry",
+		},
+	}
+}
+
 // sendError sends a standardized error response
 func sendError(w http.ResponseWriter, error string, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -554,8 +1796,9 @@ func sendError(w http.ResponseWriter, error string, code int, message string) {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, Idempotency-Key, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After, Idempotent-Replayed, Deprecation, Sunset, Link")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -573,45 +1816,300 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 		duration := time.Since(start)
 
-		log.Printf("%s %s %v", r.Method, r.URL.Path, duration)
+		logAt(logLevelInfo, "%s %s %s %v", clientIP(r), r.Method, r.URL.Path, duration)
 	})
 }
 
+// apiRoute is an endpoint served under both /api/v1 and /api/v2
+type apiRoute struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// apiRoutes lists the versioned API endpoints, relative to the version prefix
+var apiRoutes = []apiRoute{
+	{"/scan", handleScan},
+	{"/scan/element", handleElementScan},
+	{"/queue", handleQueue},
+	{"/usage", handleUsage},
+	{"/scans", handleListScans},
+	{"/scans/{id}", withETag(handleGetScan)},
+	{"/scans/{id}/rerun", handleRerunScan},
+	{"/scans/{id}/pages", withETag(handleScanPages)},
+	{"/scans/{id}/pages/{n}", withETag(handleScanPage)},
+	{"/scans/{id}/page", withETag(handleScanPageByURL)},
+	{"/scans/{id}/pages/{n}/raw", handleScanPageRaw},
+	{"/scans/{id}/issues", withETag(handleScanIssues)},
+	{"/scans/{id}/vpat", withETag(handleScanVPAT)},
+	{"/scans/{id}/share", handleShareScan},
+	{"/scans/{id}/site-health", withETag(handleScanSiteHealth)},
+	{"/orgs", handleOrganizations},
+	{"/orgs/{org}", handleOrganization},
+	{"/orgs/{org}/projects", handleOrgProjects},
+	{"/projects/leaderboard", handleLeaderboard},
+	{"/projects/{project}", handleProject},
+	{"/projects/{project}/sites", handleProjectSites},
+	{"/projects/{project}/audit-stats", handleProjectAuditStats},
+	{"/sites/{host}", handleSite},
+	{"/sites/{host}/scans", handleSiteScans},
+	{"/sites/{host}/trend.csv", handleSiteTrendCSV},
+	{"/sites/{host}/site-health", handleSiteHealth},
+	{"/sites/{host}/site-health/summary", handleSiteHealthSummary},
+	{"/compare", handleCompare},
+	{"/profiles", handleProfiles},
+	{"/profiles/{name}", handleProfile},
+	{"/webhooks", handleWebhooks},
+	{"/webhooks/dead-letters", handleWebhookDeadLetters},
+	{"/webhooks/deliveries/{delivery}/redeliver", handleWebhookRedeliver},
+	{"/webhooks/{id}", handleWebhook},
+	{"/webhooks/{id}/deliveries", handleWebhookDeliveries},
+	{"/hooks", handleHooks},
+	{"/hooks/sample", handleHookSample},
+	{"/hooks/{id}", handleHook},
+	{"/monitors", handleMonitors},
+	{"/monitors/{id}", handleMonitor},
+	{"/page-monitors", handlePageMonitors},
+	{"/page-monitors/{id}", handlePageMonitor},
+	{"/page-monitors/{id}/checks", handlePageMonitorChecks},
+	{"/grafana", handleGrafana},
+	{"/grafana/{$}", handleGrafana},
+	{"/grafana/search", handleGrafanaSearch},
+	{"/grafana/query", handleGrafanaQuery},
+	{"/sites/{host}/triage", handleSiteTriage},
+	{"/sites/{host}/issues/{fingerprint}/triage", handleIssueTriage},
+	{"/sites/{host}/issues", handleSiteIssues},
+	{"/sites/{host}/issues/{fingerprint}/assignee", handleIssueAssignee},
+	{"/sites/{host}/issues/{fingerprint}/comments", handleIssueComments},
+	{"/sites/{host}/data", requireAdmin(handleSiteData)},
+	{"/issues/{fingerprint}/verify", handleVerifyIssue},
+	{"/admin/scans", requireAdmin(handleAdminScans)},
+	{"/admin/scans/{id}", requireAdmin(handleAdminScan)},
+	{"/admin/scans/{id}/frontier", requireAdmin(handleAdminScanFrontier)},
+	{"/admin/queue", requireAdmin(handleAdminQueue)},
+	{"/admin/retention", requireAdmin(handleAdminRetention)},
+	{"/admin/usage", requireAdmin(handleAdminUsage)},
+	{"/admin/encryption", requireAdmin(handleAdminEncryption)},
+	{"/admin/audit-trail", requireAdmin(handleAdminAuditTrail)},
+	{"/admin/audit-stats", requireAdmin(handleAdminAuditStats)},
+}
+
 func main() {
+	// Convert a scan result into a CI report instead of serving the API
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// Load environment variables
 	if err := loadEnvFile(".env"); err != nil && !os.IsNotExist(err) {
 		log.Printf("Warning: Could not load .env file: %v", err)
 	}
 
+	// Load configuration (config file, environment, flags)
+	config, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	activeConfig.Store(config)
+
+	// Load remediation guidance (built-in catalog plus optional overrides)
+	catalogs, err := loadRemediationCatalogs(config.RemediationFile)
+	if err != nil {
+		log.Fatalf("Could not load remediation catalog: %v", err)
+	}
+	remediationCatalogs = catalogs
+
+	// Open scan storage
+	store, err := NewScanStore(filepath.Join(config.DataDir, "scans"))
+	if err != nil {
+		log.Fatalf("Could not open scan storage: %v", err)
+	}
+	scanStore = store
+	if err := scanStore.clearWorkingFrontiers(); err != nil {
+		logAt(logLevelWarn, "Warning: %v", err)
+	}
+
+	sites, err := NewSiteStore(filepath.Join(config.DataDir, "sites"))
+	if err != nil {
+		log.Fatalf("Could not open site storage: %v", err)
+	}
+	siteStore = sites
+
+	profiles, err := NewProfileStore(filepath.Join(config.DataDir, "profiles", "profiles.json"))
+	if err != nil {
+		log.Fatalf("Could not open profile storage: %v", err)
+	}
+	profileStore = profiles
+
+	orgs, err := NewOrgStore(filepath.Join(config.DataDir, "orgs", "orgs.json"))
+	if err != nil {
+		log.Fatalf("Could not open organization storage: %v", err)
+	}
+	orgStore = orgs
+
+	webhooks, err := NewWebhookStore(filepath.Join(config.DataDir, "webhooks"))
+	if err != nil {
+		log.Fatalf("Could not open webhook storage: %v", err)
+	}
+	webhookStore = webhooks
+	go webhookStore.run()
+
+	monitors, err := NewMonitorStore(filepath.Join(config.DataDir, "monitors", "monitors.json"))
+	if err != nil {
+		log.Fatalf("Could not open monitor storage: %v", err)
+	}
+	monitorStore = monitors
+
+	pageMonitors, err := NewPageMonitorStore(filepath.Join(config.DataDir, "page-monitors"))
+	if err != nil {
+		log.Fatalf("Could not open page monitor storage: %v", err)
+	}
+	pageMonitorStore = pageMonitors
+
+	bus, err := NewEventBus(config.EventBus)
+	if err != nil {
+		log.Fatalf("Could not connect to the event bus: %v", err)
+	}
+	eventBus = bus
+
+	usage, err := NewUsageStore(filepath.Join(config.DataDir, "usage", "usage.json"))
+	if err != nil {
+		log.Fatalf("Could not open usage storage: %v", err)
+	}
+	usageStore = usage
+	startUsageFlusher()
+
+	trail, err := NewAuditTrail(filepath.Join(config.DataDir, "audit"))
+	if err != nil {
+		log.Fatalf("Could not open the audit trail: %v", err)
+	}
+	auditTrail = trail
+	go runPageMonitors()
+
+	access, err := NewAccessLog(config.AccessLog)
+	if err != nil {
+		log.Fatalf("Could not open the access log: %v", err)
+	}
+	accessLog = access
+
+	// Limit concurrent scans
+	scanQueue = NewScanQueue(config.MaxConcurrentScans, *config.MaxQueuedScans)
+
 	// Validate API key exists
-	if getAPIKey() == "" {
-		log.Fatal("Google API key not found. Please set GOOGLE_API_KEY environment variable or add to .env file.")
+	if !psiConfigured() {
+		log.Fatal("Google API key not found. Please set GOOGLE_API_KEY environment variable, add it to .env or set google_api_key in the config file, or set GOOGLE_CREDENTIALS to OAuth credentials.")
 	}
 
+	// Prune stored scans according to the retention policy
+	startJanitor()
+
+	// Reload non-structural settings on SIGHUP
+	watchReloadSignal(os.Args[1:])
+	go runSecretRefresher()
+
 	// Setup routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleRoot)
 	mux.HandleFunc("/health", handleHealth)
-	mux.HandleFunc("/api/v1/scan", handleScan)
+	mux.HandleFunc("/ready", handleReady)
+	mux.HandleFunc("/version", handleVersion)
+	mux.HandleFunc("/openapi.json", handleOpenAPI)
+	mux.HandleFunc("/share/{token}", handleSharedReport)
+	mux.HandleFunc("/widget/{host}", handleWidget)
+	mux.HandleFunc("/oembed", handleOEmbed)
+	for _, route := range apiRoutes {
+		mux.HandleFunc("/api/v1"+route.pattern, apiV1(withClient(route.handler)))
+		mux.HandleFunc("/api/v2"+route.pattern, apiV2(withClient(route.handler)))
+	}
 
 	// Apply middleware
-	handler := corsMiddleware(loggingMiddleware(mux))
-
-	// Get port from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	handler := clientIPMiddleware(accessLogMiddleware(corsMiddleware(loggingMiddleware(compressionMiddleware(mux)))))
 
-	log.Printf("🚀 Accessibility Scanner API starting on port %s", port)
+	port := config.Port
+	build := buildInfo()
+	log.Printf("🚀 Accessibility Scanner API %s (%s) starting on port %s", build.Version, shortCommit(build.Commit), port)
 	log.Printf("🔑 Google API key configured: %t", getAPIKey() != "")
+	if config.google != nil {
+		log.Printf("🔑 Google OAuth credentials: %s", config.google.kind)
+	}
+	if config.file != "" {
+		log.Printf("⚙️  Config file: %s", config.file)
+	}
+	log.Printf("🔒 TLS: %s", config.TLS.mode())
+	log.Printf("📣 Event bus: %s", config.EventBus.mode())
+	log.Printf("📜 Access log: %s", config.AccessLog.mode())
+	log.Printf("🚦 Scan queue: %d concurrent, %d queued", config.MaxConcurrentScans, *config.MaxQueuedScans)
+	log.Printf("📝 Log level: %s", config.LogLevel)
+	log.Printf("🧹 Retention: %s", retentionPolicy())
+	log.Printf("🔐 Client API keys: %d configured (required: %t)", len(config.APIKeys), len(config.APIKeys) > 0)
 	log.Printf("🌐 Endpoints available:")
 	log.Printf("   GET  / - API documentation")
-	log.Printf("   GET  /health - Health check")
+	log.Printf("   GET  /health - Health check (?deep=true checks dependencies)")
+	log.Printf("   GET  /ready - Readiness check")
+	log.Printf("   GET  /version - Build version, commit and date")
+	log.Printf("   GET  /openapi.json - OpenAPI spec with the problem code catalog")
+	log.Printf("   GET  /widget/{host} - Public score widget of a site (or /oembed?url=)")
 	log.Printf("   POST /api/v1/scan - Scan website")
+	log.Printf("   POST /api/v1/scan/element - Check one element or audit on a page")
+	log.Printf("   GET  /api/v1/queue - Scan queue status")
+	log.Printf("   GET  /api/v1/usage - Usage of the calling API key")
+	log.Printf("   GET  /api/v1/scans - Stored scans, filtered by tag, site or status")
+	log.Printf("   GET  /api/v1/scans/{id} - Stored scan result")
+	log.Printf("   GET  /api/v1/scans/{id}/pages - Paginated page results")
+	log.Printf("   POST /api/v1/scans/{id}/rerun - Rerun a scan and compare with it")
+	log.Printf("   GET  /api/v1/scans/{id}/pages/{n} - One page result (or /page?url=)")
+	log.Printf("   GET  /api/v1/scans/{id}/pages/{n}/raw - Full Lighthouse report of a page")
+	log.Printf("   GET  /api/v1/scans/{id}/issues - Paginated issues")
+	log.Printf("   GET  /api/v1/scans/{id}/vpat - VPAT accessibility conformance report")
+	log.Printf("   POST /api/v1/scans/{id}/share - Signed public link to the HTML report")
+	log.Printf("   GET  /api/v1/scans/{id}/site-health - WordPress Site Health tests for a scan")
+	log.Printf("   POST /api/v1/orgs - Create an organization")
+	log.Printf("   POST /api/v1/orgs/{org}/projects - Create a project")
+	log.Printf("   POST /api/v1/projects/{project}/sites - Register a site")
+	log.Printf("   GET  /api/v1/projects/leaderboard - Sites ranked by score and issue density")
+	log.Printf("   GET  /api/v1/projects/{project}/audit-stats - Audits failing most often in a project")
+	log.Printf("   GET  /api/v1/sites/{host} - Site record")
+	log.Printf("   GET  /api/v1/sites/{host}/scans - Scan history of a site")
+	log.Printf("   GET  /api/v1/sites/{host}/trend.csv - Score and issue trend of a site as CSV")
+	log.Printf("   GET  /api/v1/sites/{host}/site-health - WordPress Site Health tests for a site's latest scan")
+	log.Printf("   GET  /api/v1/sites/{host}/site-health/summary - Compact Site Health status of a site")
+	log.Printf("   GET  /api/v1/compare - Compare the latest scans of two sites")
+	log.Printf("   POST /api/v1/profiles - Create a scan profile")
+	log.Printf("   GET  /api/v1/profiles/{name} - Scan profile")
+	log.Printf("   POST /api/v1/webhooks - Subscribe to scan events")
+	log.Printf("   GET  /api/v1/webhooks/{id}/deliveries - Delivery attempts of a webhook")
+	log.Printf("   GET  /api/v1/webhooks/dead-letters - Failed webhook deliveries")
+	log.Printf("   POST /api/v1/webhooks/deliveries/{delivery}/redeliver - Retry a failed delivery")
+	log.Printf("   POST /api/v1/hooks - Subscribe a REST hook (Zapier, Make)")
+	log.Printf("   DELETE /api/v1/hooks/{id} - Unsubscribe a REST hook")
+	log.Printf("   GET  /api/v1/hooks/sample - Sample payloads of an event")
+	log.Printf("   POST /api/v1/monitors - Create a score monitor")
+	log.Printf("   GET  /api/v1/monitors/{id} - Monitor and its last evaluation")
+	log.Printf("   POST /api/v1/page-monitors - Check a key page every few hours")
+	log.Printf("   GET  /api/v1/page-monitors/{id}/checks - Score series of a monitored page")
+	log.Printf("   POST /api/v1/grafana/search - Grafana JSON datasource metrics")
+	log.Printf("   POST /api/v1/grafana/query - Grafana JSON datasource time series and tables")
+	log.Printf("   GET  /api/v1/sites/{host}/triage - Triage decisions for a site")
+	log.Printf("   PUT  /api/v1/sites/{host}/issues/{fingerprint}/triage - Triage an issue")
+	log.Printf("   GET  /api/v1/sites/{host}/issues - Issue history for a site")
+	log.Printf("   PUT  /api/v1/sites/{host}/issues/{fingerprint}/assignee - Assign an issue")
+	log.Printf("   POST /api/v1/sites/{host}/issues/{fingerprint}/comments - Comment on an issue")
+	log.Printf("   DELETE /api/v1/sites/{host}/data - Erase all stored data of a site (admin)")
+	log.Printf("   POST /api/v1/issues/{fingerprint}/verify - Re-audit a page to verify a fix")
+	log.Printf("   GET  /api/v1/admin/scans - Active scans (admin)")
+	log.Printf("   DELETE /api/v1/admin/scans/{id} - Cancel an active scan (admin)")
+	log.Printf("   PUT  /api/v1/admin/queue - Adjust scan concurrency (admin)")
+	log.Printf("   GET  /api/v1/admin/usage - Usage of every API key (admin)")
+	log.Printf("   GET  /api/v1/admin/retention - Retention policy and cleanup stats (admin)")
+	log.Printf("   GET  /api/v1/admin/encryption - Stored records by encryption key; POST rotates (admin)")
+	log.Printf("   GET  /api/v1/admin/audit-trail - Authenticated API requests (admin)")
+	log.Printf("   GET  /api/v1/admin/audit-stats - Audits failing most often across the instance (admin)")
+	log.Printf("   *    /api/v2/... - Same endpoints with RFC 7807 problem+json errors")
 	log.Printf("📡 Server ready on port %s", port)
 
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
+	if err := serve(handler, port, config.TLS); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
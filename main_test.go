@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSite answers page fetches with pages linking to further pages, and
+// Lighthouse calls with an empty passing report, counting the calls
+type fakeSite struct {
+	mu        sync.Mutex
+	calls     int
+	onCall    func(n int) // called with the count after each Lighthouse call
+	pageCount int
+}
+
+func (f *fakeSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	contentType := "text/html"
+	if req.URL.Host == "www.googleapis.com" {
+		f.mu.Lock()
+		f.calls++
+		n := f.calls
+		f.mu.Unlock()
+		if f.onCall != nil {
+			f.onCall(n)
+		}
+		body = `{"lighthouseResult":{"categories":{"accessibility":{"score":1}},"audits":{}}}`
+		contentType = "application/json"
+	} else {
+		var links strings.Builder
+		for i := 0; i < f.pageCount; i++ {
+			fmt.Fprintf(&links, `<a href="/page-%d">Page %d</a>`, i, i)
+		}
+		body = "<html><body>" + links.String() + "</body></html>"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Calls returns how many Lighthouse calls were made
+func (f *fakeSite) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// setupTestScanner configures the service with defaults and temporary storage
+func setupTestScanner(t *testing.T) {
+	t.Helper()
+	config := &Config{DataDir: t.TempDir()}
+	if err := config.applyDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	activeConfig.Store(config)
+
+	var err error
+	if scanStore, err = NewScanStore(filepath.Join(config.DataDir, "scans")); err != nil {
+		t.Fatal(err)
+	}
+	if siteStore, err = NewSiteStore(filepath.Join(config.DataDir, "sites")); err != nil {
+		t.Fatal(err)
+	}
+	if orgStore, err = NewOrgStore(filepath.Join(config.DataDir, "orgs", "orgs.json")); err != nil {
+		t.Fatal(err)
+	}
+	if remediationCatalogs, err = loadRemediationCatalogs(""); err != nil {
+		t.Fatal(err)
+	}
+	if eventBus, err = NewEventBus(config.EventBus); err != nil {
+		t.Fatal(err)
+	}
+	scanQueue = NewScanQueue(1, 1)
+}
+
+func TestCrawlStopsLighthouseCallsWhenCancelled(t *testing.T) {
+	setupTestScanner(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const cancelAfter = 2
+	site := &fakeSite{pageCount: 20}
+	site.onCall = func(n int) {
+		if n == cancelAfter {
+			cancel()
+		}
+	}
+
+	scanner := NewAccessibilityScanner("test-key", "https://example.test/", 20, 0, 20)
+	scanner.client = &http.Client{Transport: site}
+
+	done := make(chan ScanResult)
+	go func() { done <- scanner.crawlAndScan(ctx) }()
+	var result ScanResult
+	select {
+	case result = <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("crawl did not stop after cancellation")
+	}
+
+	if result.Status != "cancelled" {
+		t.Errorf("status = %q, want cancelled", result.Status)
+	}
+	if calls := site.Calls(); calls != cancelAfter {
+		t.Errorf("Lighthouse calls = %d, want %d", calls, cancelAfter)
+	}
+	// Nothing left running may audit another page
+	time.Sleep(1500 * time.Millisecond)
+	if calls := site.Calls(); calls != cancelAfter {
+		t.Errorf("Lighthouse calls grew to %d after the crawl returned", calls)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxMarkdownElements is how many elements of each audit a Markdown report
+// shows; the rest are counted
+const maxMarkdownElements = 5
+
+// markdownCellEscaper escapes text for a Markdown table cell
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "\r", "", "\n", " ")
+
+// markdownAudit is one failing audit of a Markdown report
+type markdownAudit struct {
+	id       string
+	title    string
+	impact   string // the highest impact of its issues
+	pages    int
+	issues   []PageIssue
+	lastPage string
+}
+
+// codeFence returns a backtick fence longer than any backtick run in text,
+// so a snippet cannot close its own code block
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// countNoun returns a count with its noun, in the plural unless it is one
+func countNoun(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// writeMarkdown writes a scan as a concise Markdown report for GitHub issues,
+// pull requests and wikis: a summary table, the failing audits most severe
+// first with a few of their elements and snippets, and the pages that could
+// not be audited. Issues triaged as false positives are left out. It returns
+// the number of issues and page errors reported.
+func writeMarkdown(w io.Writer, result *ScanResult) int {
+	byImpact := make(map[string]int)
+	index := make(map[string]int)
+	audits := make([]markdownAudit, 0)
+	failedPages := make([]PageResult, 0)
+	total := 0
+	for _, page := range result.PageResults {
+		if page.Error != "" {
+			failedPages = append(failedPages, page)
+			continue
+		}
+		for _, issue := range page.Issues {
+			if isFalsePositive(issue) {
+				continue
+			}
+			i, ok := index[issue.AuditID]
+			if !ok {
+				i = len(audits)
+				index[issue.AuditID] = i
+				audits = append(audits, markdownAudit{id: issue.AuditID, title: issue.Title})
+			}
+			audit := &audits[i]
+			if impactWeights[issue.Impact] > impactWeights[audit.impact] {
+				audit.impact = issue.Impact
+			}
+			if audit.lastPage != page.URL {
+				audit.pages++
+				audit.lastPage = page.URL
+			}
+			audit.issues = append(audit.issues, PageIssue{PageURL: page.URL, AccessibilityIssue: issue})
+			byImpact[issue.Impact]++
+			total++
+		}
+	}
+	sort.SliceStable(audits, func(i, j int) bool {
+		if wi, wj := impactWeights[audits[i].impact], impactWeights[audits[j].impact]; wi != wj {
+			return wi > wj
+		}
+		if audits[i].pages != audits[j].pages {
+			return audits[i].pages > audits[j].pages
+		}
+		return len(audits[i].issues) > len(audits[j].issues)
+	})
+
+	fmt.Fprintf(w, "# Accessibility scan of %s\n\n", displayURL(result.BaseURL))
+	if result.ID != "" {
+		fmt.Fprintf(w, "Scan `%s`, %s, %s.\n\n", result.ID, result.ScanTime.UTC().Format("2006-01-02 15:04 MST"), result.Status)
+	}
+	fmt.Fprintln(w, "| Site score | Pages | Pages with errors | Issues | Critical | Serious | Moderate | Minor |")
+	fmt.Fprintln(w, "| ---: | ---: | ---: | ---: | ---: | ---: | ---: | ---: |")
+	fmt.Fprintf(w, "| %.0f | %d | %d | %d | %d | %d | %d | %d |\n",
+		result.SiteScore*100, len(result.PageResults), len(failedPages), total,
+		byImpact["critical"], byImpact["serious"], byImpact["moderate"], byImpact["minor"])
+
+	if len(audits) > 0 {
+		fmt.Fprintf(w, "\n## Issues by audit\n")
+	} else if len(failedPages) == 0 {
+		fmt.Fprintf(w, "\nNo accessibility issues found.\n")
+	}
+	for _, audit := range audits {
+		fmt.Fprintf(w, "\n### %s (`%s`)\n\n", strings.TrimSpace(audit.title), audit.id)
+		impact := audit.impact
+		if impact == "" {
+			impact = "unknown"
+		}
+		fmt.Fprintf(w, "**%s** · %s on %s\n", impact, countNoun(len(audit.issues), "issue"), countNoun(audit.pages, "page"))
+		for _, issue := range audit.issues[:min(len(audit.issues), maxMarkdownElements)] {
+			fmt.Fprintf(w, "\n- %s", displayURL(issue.PageURL))
+			if issue.Selector != "" {
+				fmt.Fprintf(w, " `%s`", strings.ReplaceAll(issue.Selector, "`", "'"))
+			}
+			fmt.Fprintln(w)
+			if issue.Snippet != "" {
+				fence := codeFence(issue.Snippet)
+				fmt.Fprintf(w, "\n  %shtml\n  %s\n  %s\n", fence, strings.ReplaceAll(issue.Snippet, "\n", "\n  "), fence)
+			}
+		}
+		if more := len(audit.issues) - maxMarkdownElements; more > 0 {
+			fmt.Fprintf(w, "\n…and %d more.\n", more)
+		}
+	}
+
+	if len(failedPages) > 0 {
+		fmt.Fprintf(w, "\n## Pages that could not be audited\n\n")
+		fmt.Fprintln(w, "| Page | Error |")
+		fmt.Fprintln(w, "| --- | --- |")
+		for _, page := range failedPages {
+			fmt.Fprintf(w, "| %s | %s |\n", markdownCellEscaper.Replace(displayURL(page.URL)), markdownCellEscaper.Replace(page.Error))
+		}
+	}
+	return total + len(failedPages)
+}
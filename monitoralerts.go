@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// monitorTimeout bounds the call to a monitor's own webhook_url
+const monitorTimeout = 10 * time.Second
+
+// matches reports whether a monitor watches a scan
+func (m *Monitor) matches(result *ScanResult) bool {
+	if m.Disabled {
+		return false
+	}
+	if m.Site != "" && m.Site != siteKey(result.BaseURL) {
+		return false
+	}
+	if m.ProjectID != "" && (result.Site == nil || result.Site.ProjectID != m.ProjectID) {
+		return false
+	}
+	return true
+}
+
+// check returns the rules of a monitor that match a scan. Increase and
+// decrease rules never match without a previous scan to compare with.
+func (m *Monitor) check(result, previous *ScanResult) []MonitorRuleResult {
+	triggered := make([]MonitorRuleResult, 0)
+	for _, rule := range m.Rules {
+		actual := scanMetric(rule.Metric, result.SiteScore, result.Summary)
+		var matched bool
+		var before *float64
+		switch rule.Operator {
+		case "<":
+			matched = actual < rule.Value
+		case "<=":
+			matched = actual <= rule.Value
+		case ">":
+			matched = actual > rule.Value
+		case ">=":
+			matched = actual >= rule.Value
+		case "increase", "decrease":
+			if previous == nil {
+				continue
+			}
+			value := scanMetric(rule.Metric, previous.SiteScore, previous.Summary)
+			before = &value
+			change := actual - value
+			if rule.Operator == "decrease" {
+				change = -change
+			}
+			matched = change > rule.Value
+		}
+		if matched {
+			triggered = append(triggered, MonitorRuleResult{Rule: rule.String(), Actual: actual, Previous: before})
+		}
+	}
+	return triggered
+}
+
+// apply records an evaluation and returns the alert it causes, if any: a
+// monitor alerts when its first rule matches and again when another rule
+// joins, and resolves when no rule matches any more
+func (m *Monitor) apply(evaluation *MonitorEvaluation) *MonitorAlert {
+	wasAlerting := m.State == monitorAlerting
+	var already []string
+	if wasAlerting && m.LastEvaluation != nil {
+		for _, rule := range m.LastEvaluation.Triggered {
+			already = append(already, rule.Rule)
+		}
+	}
+	m.LastEvaluation = evaluation
+
+	alert := &MonitorAlert{
+		MonitorID:   m.ID,
+		MonitorName: m.Name,
+		Site:        evaluation.Site,
+		ScanID:      evaluation.ScanID,
+		Triggered:   evaluation.Triggered,
+	}
+	if len(evaluation.Triggered) == 0 {
+		m.State = monitorOK
+		if !wasAlerting {
+			return nil
+		}
+		alert.State = monitorResolved
+		return alert
+	}
+	m.State = monitorAlerting
+	if wasAlerting && !slices.ContainsFunc(evaluation.Triggered, func(r MonitorRuleResult) bool {
+		return !slices.Contains(already, r.Rule)
+	}) {
+		return nil
+	}
+	alert.State = monitorTriggered
+	return alert
+}
+
+// Evaluate checks the monitors watching a finished scan and sends an alert for
+// every monitor that starts alerting, gains a rule, or recovers. Failed and
+// cancelled scans are not evaluated, so an outage does not resolve an alert.
+func (s *MonitorStore) Evaluate(result *ScanResult) {
+	if result.Status != "completed" && result.Status != "partial" {
+		return
+	}
+	s.mu.Lock()
+	watching := make([]Monitor, 0)
+	for _, monitor := range s.monitors {
+		if monitor.matches(result) {
+			watching = append(watching, *monitor)
+		}
+	}
+	s.mu.Unlock()
+	if len(watching) == 0 {
+		return
+	}
+
+	// Only load the previous scan when a rule compares with it
+	var previous *ScanResult
+	if slices.ContainsFunc(watching, func(m Monitor) bool {
+		return slices.ContainsFunc(m.Rules, func(r MonitorRule) bool {
+			return r.Operator == "increase" || r.Operator == "decrease"
+		})
+	}) {
+		if stored := previousScan(result); stored != nil {
+			if loaded, err := scanStore.Get(stored.ID); err == nil {
+				previous = &loaded
+			} else {
+				logAt(logLevelWarn, "Warning: Could not load scan %s to evaluate monitors: %v", stored.ID, err)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	site := siteKey(result.BaseURL)
+	alerts := make([]*MonitorAlert, 0)
+	notify := make(map[string]NotificationSettings)
+	s.mu.Lock()
+	for _, checked := range watching {
+		monitor, ok := s.monitors[checked.ID]
+		if !ok || !monitor.UpdatedAt.Equal(checked.UpdatedAt) {
+			continue // deleted or edited while the scan was being checked
+		}
+		evaluation := &MonitorEvaluation{ScanID: result.ID, Site: site, EvaluatedAt: now, Triggered: checked.check(result, previous)}
+		if alert := monitor.apply(evaluation); alert != nil {
+			alerts = append(alerts, alert)
+			notify[monitor.ID] = monitor.Notifications
+		}
+	}
+	err := s.save()
+	s.mu.Unlock()
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not store monitor states: %v", err)
+	}
+
+	for _, alert := range alerts {
+		logAt(logLevelInfo, "Monitor %q %s for scan %s of %s", alert.MonitorName, alert.State, result.ID, site)
+		notifyMonitorAlert(result, alert, notify[alert.MonitorID])
+	}
+}
+
+// notifyMonitorAlert sends an alert to the monitor's own channels, to the
+// Discord webhooks of the scan's site when the monitor has none, to webhooks
+// subscribed to monitor events, and to the event bus
+func notifyMonitorAlert(result *ScanResult, alert *MonitorAlert, settings NotificationSettings) {
+	event := eventMonitorTriggered
+	if alert.State == monitorResolved {
+		event = eventMonitorResolved
+	}
+	webhookStore.DispatchMonitorAlert(result, alert)
+	eventBus.publish(event, result.ID, alert)
+
+	targets := discordTargets(result)
+	if settings.DiscordWebhookURL != "" {
+		targets = []string{settings.DiscordWebhookURL}
+	}
+	message := discordWebhook{Username: "Accessibility Scanner", Embeds: []discordEmbed{monitorEmbed(result, alert)}}
+	for _, target := range targets {
+		go func() {
+			if err := postDiscord(target, message); err != nil {
+				logAt(logLevelWarn, "Warning: Could not send Discord alert of monitor %s: %v", alert.MonitorID, err)
+			}
+		}()
+	}
+
+	if settings.WebhookURL != "" {
+		payload := WebhookPayload{DeliveryID: newScanID(), Event: event, CreatedAt: time.Now().UTC(), Scan: webhookScan(result), Monitor: alert}
+		go func() {
+			if err := postJSON(settings.WebhookURL, payload); err != nil {
+				logAt(logLevelWarn, "Warning: Could not send alert of monitor %s to its webhook_url: %v", alert.MonitorID, err)
+			}
+		}()
+	}
+}
+
+// monitorEmbed describes a monitor alert as a Discord embed
+func monitorEmbed(result *ScanResult, alert *MonitorAlert) discordEmbed {
+	embed := discordEmbed{
+		Title:     fmt.Sprintf("Monitor %s %s on %s", alert.MonitorName, alert.State, alert.Site),
+		URL:       result.BaseURL,
+		Color:     discordColorFailing,
+		Footer:    &discordFooter{Text: "Accessibility Scanner API"},
+		Timestamp: result.ScanTime.UTC().Format(time.RFC3339),
+	}
+	if alert.State == monitorResolved {
+		embed.Color = discordColorExcellent
+		embed.Description = fmt.Sprintf("No rule matches scan `%s` any more.", alert.ScanID)
+		return embed
+	}
+	lines := make([]string, 0, len(alert.Triggered))
+	for _, rule := range alert.Triggered {
+		line := fmt.Sprintf("`%s`: %g", rule.Rule, rule.Actual)
+		if rule.Previous != nil {
+			line += fmt.Sprintf(" (was %g)", *rule.Previous)
+		}
+		lines = append(lines, line)
+	}
+	embed.Description = fmt.Sprintf("Scan `%s` matched:\n%s", alert.ScanID, strings.Join(lines, "\n"))
+	return embed
+}
+
+// postJSON posts an alert once to a monitor's webhook_url. Unlike registered
+// webhooks it is neither signed nor retried.
+func postJSON(target string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := newOutboundClient(monitorTimeout).Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
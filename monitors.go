@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Monitor rule operators: thresholds compare a scan's metric with the rule
+// value, changes compare it with the previous scan of the site
+var monitorOperators = map[string]bool{
+	"<": true, "<=": true, ">": true, ">=": true,
+	"increase": true, "decrease": true,
+}
+
+// Monitor states and alert kinds
+const (
+	monitorOK        = "ok"
+	monitorAlerting  = "alerting"
+	monitorTriggered = "triggered"
+	monitorResolved  = "resolved"
+)
+
+// maxMonitorRules bounds the rules of one monitor
+const maxMonitorRules = 20
+
+// errMonitorNotFound is returned for unknown monitor IDs
+var errMonitorNotFound = errors.New("monitor not found")
+
+// MonitorRule is one alert condition, such as site_score < 0.9 or
+// issues_critical increase
+type MonitorRule struct {
+	Metric   string  `json:"metric"`          // one of the scan metrics, e.g. site_score or issues_critical
+	Operator string  `json:"operator"`        // <, <=, >, >=, increase or decrease
+	Value    float64 `json:"value,omitempty"` // threshold, or the change needed for increase/decrease (default: any)
+}
+
+// String renders a rule the way it is shown in alerts
+func (r MonitorRule) String() string {
+	switch r.Operator {
+	case "increase", "decrease":
+		if r.Value > 0 {
+			return fmt.Sprintf("%s %ss by more than %g", r.Metric, r.Operator, r.Value)
+		}
+		return fmt.Sprintf("%s %ss", r.Metric, r.Operator)
+	}
+	return fmt.Sprintf("%s %s %g", r.Metric, r.Operator, r.Value)
+}
+
+// Monitor evaluates rules against every finished scan in its scope and
+// alerts when they start or stop matching
+type Monitor struct {
+	ID             string               `json:"id"`
+	Name           string               `json:"name"`
+	Site           string               `json:"site,omitempty"`       // only scans of this site
+	ProjectID      string               `json:"project_id,omitempty"` // only scans of this project's sites
+	Rules          []MonitorRule        `json:"rules"`
+	Notifications  NotificationSettings `json:"notifications"`
+	Disabled       bool                 `json:"disabled,omitempty"`
+	State          string               `json:"state,omitempty"` // "ok" or "alerting" once evaluated
+	LastEvaluation *MonitorEvaluation   `json:"last_evaluation,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+}
+
+// MonitorEvaluation is the outcome of checking a monitor against one scan
+type MonitorEvaluation struct {
+	ScanID      string              `json:"scan_id"`
+	Site        string              `json:"site"`
+	EvaluatedAt time.Time           `json:"evaluated_at"`
+	Triggered   []MonitorRuleResult `json:"triggered"`
+}
+
+// MonitorRuleResult is a rule that matched, with the values it compared
+type MonitorRuleResult struct {
+	Rule     string   `json:"rule"`
+	Actual   float64  `json:"actual"`
+	Previous *float64 `json:"previous,omitempty"` // for increase and decrease
+}
+
+// MonitorAlert is sent when a monitor starts alerting, when a rule that was
+// quiet joins an active alert, and when it recovers
+type MonitorAlert struct {
+	MonitorID   string              `json:"monitor_id"`
+	MonitorName string              `json:"monitor_name"`
+	State       string              `json:"state"` // "triggered" or "resolved"
+	Site        string              `json:"site"`
+	ScanID      string              `json:"scan_id"`
+	Triggered   []MonitorRuleResult `json:"triggered"`
+}
+
+// MonitorStore keeps monitors in one JSON file
+type MonitorStore struct {
+	mu       sync.Mutex
+	path     string
+	monitors map[string]*Monitor
+}
+
+// monitorStore holds the monitors
+var monitorStore *MonitorStore
+
+// NewMonitorStore loads the monitors stored in path, if any
+func NewMonitorStore(path string) (*MonitorStore, error) {
+	store := &MonitorStore{path: path, monitors: make(map[string]*Monitor)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.monitors); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// save writes the monitors; callers must hold s.mu
+func (s *MonitorStore) save() error {
+	data, err := json.Marshal(s.monitors)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// List returns every monitor, oldest first
+func (s *MonitorStore) List() []Monitor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitors := make([]Monitor, 0, len(s.monitors))
+	for _, monitor := range s.monitors {
+		monitors = append(monitors, *monitor)
+	}
+	sort.Slice(monitors, func(i, j int) bool { return monitors[i].CreatedAt.Before(monitors[j].CreatedAt) })
+	return monitors
+}
+
+// Get returns a monitor by ID
+func (s *MonitorStore) Get(id string) (Monitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor, ok := s.monitors[id]
+	if !ok {
+		return Monitor{}, errMonitorNotFound
+	}
+	return *monitor, nil
+}
+
+// Create adds a monitor
+func (s *MonitorStore) Create(monitor Monitor) (Monitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor.ID = newScanID()
+	monitor.CreatedAt = time.Now().UTC()
+	monitor.UpdatedAt = monitor.CreatedAt
+	s.monitors[monitor.ID] = &monitor
+	if err := s.save(); err != nil {
+		delete(s.monitors, monitor.ID)
+		return Monitor{}, err
+	}
+	return monitor, nil
+}
+
+// Update replaces a monitor's settings. Changing the rules or scope resets
+// its state, so the next scan alerts afresh if the new rules match.
+func (s *MonitorStore) Update(id string, update Monitor) (Monitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.monitors[id]
+	if !ok {
+		return Monitor{}, errMonitorNotFound
+	}
+	previous := *existing
+	update.ID = id
+	update.CreatedAt = existing.CreatedAt
+	update.UpdatedAt = time.Now().UTC()
+	if fmt.Sprint(update.Rules, update.Site, update.ProjectID) == fmt.Sprint(existing.Rules, existing.Site, existing.ProjectID) {
+		update.State = existing.State
+		update.LastEvaluation = existing.LastEvaluation
+	}
+	*existing = update
+	if err := s.save(); err != nil {
+		*existing = previous
+		return Monitor{}, err
+	}
+	return update, nil
+}
+
+// Delete removes a monitor
+func (s *MonitorStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor, ok := s.monitors[id]
+	if !ok {
+		return errMonitorNotFound
+	}
+	delete(s.monitors, id)
+	if err := s.save(); err != nil {
+		s.monitors[id] = monitor
+		return err
+	}
+	return nil
+}
+
+// sendMonitorStoreError reports a monitor store error
+func sendMonitorStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMonitorNotFound) {
+		sendError(w, "Monitor not found", http.StatusNotFound, "No monitor exists with this ID")
+		return
+	}
+	logAt(logLevelError, "Monitor storage error: %v", err)
+	sendError(w, "Storage error", http.StatusInternalServerError, "Could not access monitors")
+}
+
+// validate checks a monitor's rules, scope and channels
+func (m *Monitor) validate() error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(m.Rules) == 0 || len(m.Rules) > maxMonitorRules {
+		return fmt.Errorf("rules must list between 1 and %d rules", maxMonitorRules)
+	}
+	for i, rule := range m.Rules {
+		if !scanMetricKnown(rule.Metric) {
+			return fmt.Errorf("rules[%d]: metric must be one of %s", i, strings.Join(scanMetrics, ", "))
+		}
+		if !monitorOperators[rule.Operator] {
+			return fmt.Errorf("rules[%d]: operator must be one of <, <=, >, >=, increase, decrease", i)
+		}
+		if rule.Value < 0 {
+			return fmt.Errorf("rules[%d]: value cannot be negative", i)
+		}
+	}
+	m.Site = asciiHost(m.Site)
+	if m.Site != "" && !validSiteHost(m.Site) {
+		return errors.New("site must be a host name such as example.com")
+	}
+	if m.ProjectID != "" {
+		if _, err := orgStore.Project(m.ProjectID); err != nil {
+			return errors.New("project_id must name an existing project")
+		}
+	}
+	if len(m.Notifications.Emails) > 0 {
+		return errors.New("notifications: emails are not supported for monitors; use discord_webhook_url, webhook_url or webhooks subscribed to monitor events")
+	}
+	return m.Notifications.validate()
+}
+
+// decodeMonitor reads and validates a monitor body
+func decodeMonitor(w http.ResponseWriter, r *http.Request) (Monitor, bool) {
+	var monitor Monitor
+	if err := json.NewDecoder(r.Body).Decode(&monitor); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return monitor, false
+	}
+	if err := monitor.validate(); err != nil {
+		sendError(w, "Invalid monitor", http.StatusBadRequest, err.Error())
+		return monitor, false
+	}
+	monitor.State, monitor.LastEvaluation = "", nil
+	return monitor, true
+}
+
+// handleMonitors handles GET and POST /api/v1/monitors requests
+func handleMonitors(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, monitorStore.List())
+	case http.MethodPost:
+		monitor, ok := decodeMonitor(w, r)
+		if !ok {
+			return
+		}
+		monitor, err := monitorStore.Create(monitor)
+		if err != nil {
+			sendMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, monitor)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleMonitor handles GET, PUT and DELETE /api/v1/monitors/{id} requests
+func handleMonitor(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		monitor, err := monitorStore.Get(id)
+		if err != nil {
+			sendMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, monitor)
+	case http.MethodPut:
+		update, ok := decodeMonitor(w, r)
+		if !ok {
+			return
+		}
+		monitor, err := monitorStore.Update(id, update)
+		if err != nil {
+			sendMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, monitor)
+	case http.MethodDelete:
+		if err := monitorStore.Delete(id); err != nil {
+			sendMonitorStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
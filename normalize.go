@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Trailing slash policies of URL normalization
+const (
+	trailingSlashStrip = "strip" // /about/ becomes /about
+	trailingSlashAdd   = "add"   // /about becomes /about/, file names such as /a.html are kept
+)
+
+// urlNormalizer turns the URLs the crawler finds into one canonical form, so
+// the visited set and the reported URLs hold a single entry per page
+type urlNormalizer struct {
+	trailingSlash string
+	lowercasePath bool
+}
+
+// newURLNormalizer builds a normalizer for a trailing slash policy
+func newURLNormalizer(trailingSlash string, lowercasePath bool) (*urlNormalizer, error) {
+	if trailingSlash != trailingSlashStrip && trailingSlash != trailingSlashAdd {
+		return nil, fmt.Errorf("url_trailing_slash must be %q or %q", trailingSlashStrip, trailingSlashAdd)
+	}
+	return &urlNormalizer{trailingSlash: trailingSlash, lowercasePath: lowercasePath}, nil
+}
+
+// normalize drops the fragment, query and default port, lowercases the scheme
+// and host, converts internationalized host names to punycode, and applies
+// the trailing slash and path case policies. The root path is always "/". A
+// pagination parameter beyond the first page is kept, since it names a page
+// of its own. A nil normalizer only drops the fragment and query.
+func (n *urlNormalizer) normalize(u *url.URL) *url.URL {
+	clean := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	if n == nil {
+		return clean
+	}
+
+	clean.Scheme = strings.ToLower(clean.Scheme)
+	clean.Host = asciiHost(clean.Host)
+	if port := u.Port(); (clean.Scheme == "https" && port == "443") || (clean.Scheme == "http" && port == "80") {
+		clean.Host = strings.TrimSuffix(clean.Host, ":"+port)
+	}
+
+	if n.lowercasePath {
+		clean.Path = strings.ToLower(clean.Path)
+	}
+	switch {
+	case clean.Path == "" || strings.Trim(clean.Path, "/") == "":
+		clean.Path = "/"
+	case n.trailingSlash == trailingSlashStrip:
+		clean.Path = strings.TrimRight(clean.Path, "/")
+	case n.trailingSlash == trailingSlashAdd && !strings.HasSuffix(clean.Path, "/") && path.Ext(clean.Path) == "":
+		clean.Path += "/"
+	}
+	if param, number, ok := paginationParam(u.Query()); ok && number > 1 {
+		clean.RawQuery = url.Values{param: {strconv.Itoa(number)}}.Encode()
+	}
+	return clean
+}
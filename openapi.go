@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathParamPattern matches the {name} parameters of a route pattern
+var pathParamPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// handleOpenAPI handles GET /openapi.json requests
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openAPISpec())
+}
+
+// openAPISpec describes the v2 API as an OpenAPI 3.1 document, built from
+// the endpoint documentation and the problem catalog
+func openAPISpec() map[string]interface{} {
+	endpoints := endpointDocs()
+	keys := make([]string, 0, len(endpoints))
+	for key := range endpoints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	paths := make(map[string]map[string]interface{})
+	for _, key := range keys {
+		method, path, _ := strings.Cut(key, " ")
+		doc, _ := endpoints[key].(map[string]interface{})
+		versioned := strings.HasPrefix(path, "/api/v1/")
+		path = strings.Replace(path, "/api/v1/", "/api/v2/", 1)
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(method)] = openAPIOperation(path, doc, versioned)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "WPMUDEV Accessibility Scanner API",
+			"version":     buildInfo().Version,
+			"description": "Errors are RFC 7807 application/problem+json. Their code is stable; retry logic should use it and retryable rather than the title or detail.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Problem":     problemSchema(),
+				"ProblemCode": problemCodeSchema(),
+			},
+			"responses": map[string]interface{}{
+				"Problem": map[string]interface{}{
+					"description": "The request failed; see the problem code",
+					"content": map[string]interface{}{
+						"application/problem+json": map[string]interface{}{
+							"schema": map[string]interface{}{"$ref": "#/components/schemas/Problem"},
+						},
+					},
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"apiKey":     map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"adminToken": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// openAPIOperation describes one endpoint from its documentation
+func openAPIOperation(path string, doc map[string]interface{}, versioned bool) map[string]interface{} {
+	description, _ := doc["description"].(string)
+	operation := map[string]interface{}{"summary": description}
+
+	parameters := make([]map[string]interface{}, 0)
+	inPath := make(map[string]bool)
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		inPath[match[1]] = true
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, section := range []struct{ key, in string }{{"query", "query"}, {"headers", "header"}} {
+		described := docStrings(doc[section.key])
+		for _, name := range sortedKeys(described) {
+			if inPath[name] {
+				continue
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name": name, "in": section.in, "description": described[name],
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if body := docStrings(doc["body"]); len(body) > 0 {
+		properties := make(map[string]interface{}, len(body))
+		for name, text := range body {
+			properties[name] = map[string]interface{}{"description": text}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if example, ok := doc["example"]; ok {
+			schema["example"] = example
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+		}
+	}
+
+	responses := map[string]interface{}{"200": map[string]interface{}{"description": "Success"}}
+	if versioned {
+		responses["default"] = map[string]interface{}{"$ref": "#/components/responses/Problem"}
+		if strings.HasPrefix(path, "/api/v2/admin/") || strings.HasSuffix(path, "/data") {
+			operation["security"] = []map[string]interface{}{{"adminToken": []string{}}}
+		} else {
+			operation["security"] = []map[string]interface{}{{"apiKey": []string{}}, {}}
+		}
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+// problemSchema describes ProblemDetails
+func problemSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"type", "title", "status", "code", "retryable"},
+		"properties": map[string]interface{}{
+			"type":      map[string]interface{}{"type": "string", "description": problemTypePrefix + "<code>"},
+			"title":     map[string]interface{}{"type": "string", "description": "Short English summary; may change"},
+			"status":    map[string]interface{}{"type": "integer"},
+			"detail":    map[string]interface{}{"type": "string", "description": "English explanation of this occurrence; may change"},
+			"instance":  map[string]interface{}{"type": "string", "description": "Path of the request"},
+			"code":      map[string]interface{}{"$ref": "#/components/schemas/ProblemCode"},
+			"retryable": map[string]interface{}{"type": "boolean", "description": "Whether the same request may succeed later; wait for Retry-After when it is sent"},
+		},
+		"additionalProperties": true,
+	}
+}
+
+// problemCodeSchema lists the problem codes with their status and meaning
+func problemCodeSchema() map[string]interface{} {
+	codes := make([]map[string]interface{}, 0, len(problemTypes)+1)
+	for _, problem := range problemTypes {
+		description := strings.TrimSuffix(problem.Description, ".") + " (HTTP " + strconv.Itoa(problem.Status)
+		if problem.Retryable {
+			description += ", retryable"
+		}
+		codes = append(codes, map[string]interface{}{
+			"const":       problem.Code,
+			"title":       problem.Title,
+			"description": description + ").",
+		})
+	}
+	codes = append(codes, map[string]interface{}{
+		"pattern":     invalidFieldPattern,
+		"description": "A field or parameter of the request is invalid, e.g. invalid_max_pages (HTTP 400).",
+	})
+	return map[string]interface{}{"type": "string", "anyOf": codes}
+}
+
+// docStrings returns the string values of a documentation section
+func docStrings(section interface{}) map[string]string {
+	values := make(map[string]string)
+	switch section := section.(type) {
+	case map[string]string:
+		for name, text := range section {
+			values[name] = text
+		}
+	case map[string]interface{}:
+		for name, text := range section {
+			if text, ok := text.(string); ok {
+				values[name] = text
+			}
+		}
+	}
+	return values
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
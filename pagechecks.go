@@ -0,0 +1,84 @@
+package main
+
+import "golang.org/x/net/html"
+
+// pageCheck is a built-in audit of the markup of a page, for what Lighthouse
+// does not check or checks poorly. Its issues can be dropped with
+// ignore_audits like those of any audit.
+type pageCheck func(doc *html.Node) []AccessibilityIssue
+
+// pageChecks run on every crawled page
+var pageChecks = []pageCheck{
+	checkPageLanguage,
+	checkSkipLink,
+	checkHeadings,
+	checkLandmarks,
+	checkTables,
+	checkDuplicateIDs,
+}
+
+// checkTitle picks the catalog title of an issue found by a page check: the
+// variant among its audit's titles and the values of its placeholders
+type checkTitle struct {
+	variant string
+	args    []string // placeholders and their values, alternating
+}
+
+// titled returns the title variant of a check's issue; args alternate
+// placeholder names such as "{id}" and their values
+func titled(variant string, args ...string) *checkTitle {
+	return &checkTitle{variant: variant, args: args}
+}
+
+// pageFindings is what was found in the markup of a crawled page
+type pageFindings struct {
+	issues    []AccessibilityIssue
+	headings  []*Heading
+	landmarks []*Landmark
+	tabOrder  []TabStop
+}
+
+// checkPage runs the built-in page checks and the site's custom rules on a
+// page it crawled, and extracts its heading outline, landmarks and tab
+// order. The findings are kept until the page is audited.
+func (s *AccessibilityScanner) checkPage(pageURL string, doc *html.Node) {
+	findings := &pageFindings{
+		headings:  headingOutline(pageHeadings(doc)),
+		landmarks: pageLandmarks(doc),
+		tabOrder:  pageTabOrder(doc),
+	}
+	for _, check := range pageChecks {
+		findings.issues = append(findings.issues, check(doc)...)
+	}
+	findings.issues = append(findings.issues, checkAltText(doc, s.altText)...)
+	if len(s.rules) > 0 {
+		findings.issues = append(findings.issues, s.checkCustomRules(pageURL, doc)...)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageFindings[pageURL] = findings
+}
+
+// addPageFindings adds what was found in the markup of a page to its result,
+// with the remediation guidance the catalog has for the issues
+func (s *AccessibilityScanner) addPageFindings(page *PageResult) {
+	s.mu.Lock()
+	findings := s.pageFindings[page.URL]
+	delete(s.pageFindings, page.URL)
+	s.mu.Unlock()
+	if findings == nil {
+		return
+	}
+
+	page.Headings = findings.headings
+	page.Landmarks = findings.landmarks
+	page.TabOrder = findings.tabOrder
+	remediations := remediationCatalogs.For(s.language)
+	for _, issue := range findings.issues {
+		if issue.title != nil {
+			issue.Title = remediations.issueTitle(issue.AuditID, issue.title)
+		}
+		issue.Remediation = remediations.Lookup(issue.AuditID)
+		page.Issues = append(page.Issues, issue)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// lighthouseErrorMarkers map what PageSpeed Insights error responses contain
+// to problem codes, most specific first. Lighthouse runtime errors such as
+// DNS_FAILURE and Chrome net errors such as ERR_NAME_NOT_RESOLVED name the
+// reason a page could not be audited.
+var lighthouseErrorMarkers = []struct {
+	code    string
+	markers []string
+}{
+	{"lighthouse_quota_exceeded", []string{"RESOURCE_EXHAUSTED", "rateLimitExceeded", "dailyLimitExceeded", "quotaExceeded"}},
+	{"lighthouse_auth_failed", []string{"API_KEY_INVALID", "API key not valid", "API_KEY_SERVICE_BLOCKED", "PERMISSION_DENIED", "UNAUTHENTICATED"}},
+	{"target_dns_failure", []string{"DNS_FAILURE", "ERR_NAME_NOT_RESOLVED", "ERR_NAME_RESOLUTION_FAILED"}},
+	{"target_tls_error", []string{"INSECURE_DOCUMENT_REQUEST", "ERR_CERT_", "ERR_SSL_"}},
+	{"target_throttled", []string{"(Status code: 429)", "(Status code: 503)"}},
+	{"target_http_error", []string{"ERRORED_DOCUMENT_REQUEST"}},
+	{"target_not_html", []string{"NOT_HTML"}},
+	{"target_not_rendered", []string{"NO_FCP"}},
+	{"target_timeout", []string{"PROTOCOL_TIMEOUT", "PAGE_HUNG", "ERR_TIMED_OUT", "ERR_CONNECTION_TIMED_OUT"}},
+	{"target_unreachable", []string{"FAILED_DOCUMENT_REQUEST", "ERR_CONNECTION_", "ERR_ADDRESS_UNREACHABLE"}},
+}
+
+// lighthouseErrorCode classifies a PageSpeed Insights error response
+func lighthouseErrorCode(status int, body []byte) string {
+	if status == http.StatusTooManyRequests {
+		return "lighthouse_quota_exceeded"
+	}
+	text := string(body)
+	for _, kind := range lighthouseErrorMarkers {
+		for _, marker := range kind.markers {
+			if strings.Contains(text, marker) {
+				return kind.code
+			}
+		}
+	}
+	return "lighthouse_error"
+}
+
+// requestErrorCode classifies an error calling PageSpeed Insights
+func requestErrorCode(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "scan_cancelled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "page_timeout"
+	}
+	return "lighthouse_error"
+}
+
+// sendPageError reports a page that could not be audited. v2 reports the
+// problem code of the failure; v1 keeps the title it always used.
+func sendPageError(w http.ResponseWriter, r *http.Request, v1Title string, page PageResult) {
+	problem, ok := problemTypeByCode(page.ErrorCode)
+	if apiVersion(r) < 2 || !ok {
+		sendError(w, v1Title, http.StatusBadGateway, page.Error)
+		return
+	}
+	sendError(w, problem.Title, problem.Status, page.Error)
+}
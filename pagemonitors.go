@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page monitor limits
+const (
+	defaultPageCheckHours = 6
+	maxPageCheckHours     = 24 * 7
+	maxPageCheckPoints    = 1000 // newest checks kept per page monitor
+	pageMonitorTick       = time.Minute
+	pageCheckTimeout      = 2 * time.Minute
+)
+
+// eventPageRegressed is published on the event bus when a monitored page's
+// score drops
+const eventPageRegressed = "page.regressed"
+
+// Page monitor errors
+var (
+	errPageMonitorNotFound = errors.New("page monitor not found")
+	errPageCheckRunning    = errors.New("page check already running")
+)
+
+// PageMonitor audits one key URL on a schedule, separately from full-site
+// scans, and keeps a compact series of its results
+type PageMonitor struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name,omitempty"`
+	URL           string               `json:"url"`
+	IntervalHours int                  `json:"interval_hours"`
+	ScoreDrop     float64              `json:"score_drop,omitempty"` // drop from the previous check flagged as a regression (default: 0.05)
+	Notifications NotificationSettings `json:"notifications"`
+	Disabled      bool                 `json:"disabled,omitempty"`
+	Client        string               `json:"client"` // usage and quotas are charged to the client that created the monitor
+	LastCheck     *PageCheck           `json:"last_check,omitempty"`
+	NextCheckAt   time.Time            `json:"next_check_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// PageCheck is one point of a page monitor's series
+type PageCheck struct {
+	Time           time.Time      `json:"time"`
+	Score          float64        `json:"score"`
+	Issues         int            `json:"issues"`
+	IssuesByImpact map[string]int `json:"issues_by_impact,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	Regressed      bool           `json:"regressed,omitempty"`
+}
+
+// PageCheckSeries is returned by the checks endpoint
+type PageCheckSeries struct {
+	MonitorID string      `json:"monitor_id"`
+	URL       string      `json:"url"`
+	Checks    []PageCheck `json:"checks"`
+}
+
+// PageMonitorStore keeps page monitors in one JSON file and the checks of
+// each in a file of its own, so listing monitors never reads the series
+type PageMonitorStore struct {
+	mu       sync.Mutex
+	dir      string
+	monitors map[string]*PageMonitor
+	checking map[string]bool // monitors with a check in progress
+}
+
+// pageMonitorStore holds the page monitors
+var pageMonitorStore *PageMonitorStore
+
+// NewPageMonitorStore loads the page monitors stored in dir, if any
+func NewPageMonitorStore(dir string) (*PageMonitorStore, error) {
+	store := &PageMonitorStore{dir: dir, monitors: make(map[string]*PageMonitor), checking: make(map[string]bool)}
+	if err := os.MkdirAll(filepath.Join(dir, "checks"), 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "monitors.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.monitors); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// writeJSONFile replaces a file with the JSON encoding of v
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// save writes the monitors; callers must hold s.mu
+func (s *PageMonitorStore) save() error {
+	return writeJSONFile(filepath.Join(s.dir, "monitors.json"), s.monitors)
+}
+
+// checksPath returns the file holding a monitor's checks
+func (s *PageMonitorStore) checksPath(id string) string {
+	return filepath.Join(s.dir, "checks", id+".json")
+}
+
+// loadChecks reads a monitor's checks, oldest first; callers must hold s.mu
+func (s *PageMonitorStore) loadChecks(id string) ([]PageCheck, error) {
+	checks := make([]PageCheck, 0)
+	data, err := os.ReadFile(s.checksPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return checks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &checks); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// List returns every page monitor, oldest first
+func (s *PageMonitorStore) List() []PageMonitor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitors := make([]PageMonitor, 0, len(s.monitors))
+	for _, monitor := range s.monitors {
+		monitors = append(monitors, *monitor)
+	}
+	sort.Slice(monitors, func(i, j int) bool { return monitors[i].CreatedAt.Before(monitors[j].CreatedAt) })
+	return monitors
+}
+
+// Get returns a page monitor by ID
+func (s *PageMonitorStore) Get(id string) (PageMonitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor, ok := s.monitors[id]
+	if !ok {
+		return PageMonitor{}, errPageMonitorNotFound
+	}
+	return *monitor, nil
+}
+
+// Checks returns a monitor's checks between from and to (either may be
+// zero), oldest first
+func (s *PageMonitorStore) Checks(id string, from, to time.Time) ([]PageCheck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.monitors[id]; !ok {
+		return nil, errPageMonitorNotFound
+	}
+	checks, err := s.loadChecks(id)
+	if err != nil {
+		return nil, err
+	}
+	matching := checks[:0]
+	for _, check := range checks {
+		if (from.IsZero() || !check.Time.Before(from)) && (to.IsZero() || !check.Time.After(to)) {
+			matching = append(matching, check)
+		}
+	}
+	return matching, nil
+}
+
+// Create adds a page monitor; its first check runs right away
+func (s *PageMonitorStore) Create(monitor PageMonitor) (PageMonitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor.ID = newScanID()
+	monitor.CreatedAt = time.Now().UTC()
+	monitor.UpdatedAt = monitor.CreatedAt
+	monitor.NextCheckAt = monitor.CreatedAt
+	s.monitors[monitor.ID] = &monitor
+	if err := s.save(); err != nil {
+		delete(s.monitors, monitor.ID)
+		return PageMonitor{}, err
+	}
+	return monitor, nil
+}
+
+// Update replaces a page monitor's settings, keeping its checks. The next
+// check is scheduled from the last one with the new interval.
+func (s *PageMonitorStore) Update(id string, update PageMonitor) (PageMonitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.monitors[id]
+	if !ok {
+		return PageMonitor{}, errPageMonitorNotFound
+	}
+	previous := *existing
+	update.ID = id
+	update.Client = existing.Client
+	update.LastCheck = existing.LastCheck
+	update.CreatedAt = existing.CreatedAt
+	update.UpdatedAt = time.Now().UTC()
+	update.NextCheckAt = update.UpdatedAt
+	if update.LastCheck != nil {
+		update.NextCheckAt = update.LastCheck.Time.Add(time.Duration(update.IntervalHours) * time.Hour)
+	}
+	*existing = update
+	if err := s.save(); err != nil {
+		*existing = previous
+		return PageMonitor{}, err
+	}
+	return update, nil
+}
+
+// Delete removes a page monitor and its checks
+func (s *PageMonitorStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor, ok := s.monitors[id]
+	if !ok {
+		return errPageMonitorNotFound
+	}
+	delete(s.monitors, id)
+	if err := s.save(); err != nil {
+		s.monitors[id] = monitor
+		return err
+	}
+	if err := os.Remove(s.checksPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logAt(logLevelWarn, "Warning: Could not remove checks of page monitor %s: %v", id, err)
+	}
+	return nil
+}
+
+// begin marks a monitor as being checked, reporting false if it already is
+func (s *PageMonitorStore) begin(id string) (PageMonitor, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitor, ok := s.monitors[id]
+	if !ok {
+		return PageMonitor{}, false, errPageMonitorNotFound
+	}
+	if s.checking[id] {
+		return *monitor, false, nil
+	}
+	s.checking[id] = true
+	return *monitor, true, nil
+}
+
+// record appends a check to a monitor's series, flags a regression against
+// the previous successful check and schedules the next one
+func (s *PageMonitorStore) record(id string, check PageCheck) (PageMonitor, PageCheck, *PageCheck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checking, id)
+	monitor, ok := s.monitors[id]
+	if !ok {
+		return PageMonitor{}, check, nil, errPageMonitorNotFound // deleted during the check
+	}
+	checks, err := s.loadChecks(id)
+	if err != nil {
+		return PageMonitor{}, check, nil, err
+	}
+
+	var previous *PageCheck
+	for i := len(checks) - 1; i >= 0; i-- {
+		if checks[i].Error == "" {
+			previous = &checks[i]
+			break
+		}
+	}
+	if previous != nil && check.Error == "" {
+		drop := monitor.ScoreDrop
+		if drop == 0 {
+			drop = defaultScoreDrop
+		}
+		check.Regressed = previous.Score-check.Score >= drop
+	}
+
+	checks = append(checks, check)
+	if len(checks) > maxPageCheckPoints {
+		checks = checks[len(checks)-maxPageCheckPoints:]
+	}
+	if err := writeJSONFile(s.checksPath(id), checks); err != nil {
+		return PageMonitor{}, check, nil, err
+	}
+	monitor.LastCheck = &check
+	monitor.NextCheckAt = check.Time.Add(time.Duration(monitor.IntervalHours) * time.Hour)
+	if err := s.save(); err != nil {
+		return PageMonitor{}, check, nil, err
+	}
+	if previous != nil {
+		copied := *previous
+		previous = &copied
+	}
+	return *monitor, check, previous, nil
+}
+
+// due returns the IDs of enabled monitors whose next check is due
+func (s *PageMonitorStore) due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0)
+	for id, monitor := range s.monitors {
+		if !monitor.Disabled && !s.checking[id] && !monitor.NextCheckAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Check audits a monitor's page now and records the result. It returns
+// errPageCheckRunning when a check of the monitor is already in progress.
+func (s *PageMonitorStore) Check(ctx context.Context, id string) (PageCheck, error) {
+	monitor, ok, err := s.begin(id)
+	if err != nil {
+		return PageCheck{}, err
+	}
+	if !ok {
+		return PageCheck{}, errPageCheckRunning
+	}
+	check := runPageCheck(ctx, monitor)
+	monitor, check, previous, err := s.record(id, check)
+	if err != nil {
+		return check, err
+	}
+	if check.Regressed {
+		notifyPageRegression(monitor, check, *previous)
+	}
+	return check, nil
+}
+
+// runPageCheck audits a monitor's page with Lighthouse, charging the audit to
+// the monitor's client
+func runPageCheck(ctx context.Context, monitor PageMonitor) PageCheck {
+	check := PageCheck{Time: time.Now().UTC()}
+	apiKey := getAPIKey()
+	if !psiConfigured() {
+		check.Error = "Google API key not configured"
+		return check
+	}
+	if _, _, exceeded := admitQuota(monitor.Client, 1, false); exceeded != nil {
+		check.Error = exceeded.Message
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pageCheckTimeout)
+	defer cancel()
+	scanner := NewAccessibilityScanner(apiKey, monitor.URL, 1, 0, 1)
+	page := scanner.scanPageWithLighthouse(ctx, monitor.URL)
+	usageStore.Record(monitor.Client, UsageCounters{PagesScanned: 1, LighthouseCalls: int64(scanner.lighthouseCalls)})
+	if page.Error != "" {
+		check.Error = page.Error
+		return check
+	}
+	check.Score = page.AccessibilityScore
+	check.Issues = len(page.Issues)
+	for _, issue := range page.Issues {
+		if issue.Impact == "" {
+			continue
+		}
+		if check.IssuesByImpact == nil {
+			check.IssuesByImpact = make(map[string]int)
+		}
+		check.IssuesByImpact[issue.Impact]++
+	}
+	return check
+}
+
+// notifyPageRegression announces a score drop of a monitored page on the
+// monitor's Discord webhook and webhook_url, and on the event bus
+func notifyPageRegression(monitor PageMonitor, check, previous PageCheck) {
+	logAt(logLevelInfo, "Page monitor %s: score of %s dropped from %.2f to %.2f", monitor.ID, monitor.URL, previous.Score, check.Score)
+	alert := PageRegression{MonitorID: monitor.ID, Name: monitor.Name, URL: monitor.URL, Check: check, PreviousScore: previous.Score, PreviousTime: previous.Time}
+	eventBus.publish(eventPageRegressed, monitor.ID, alert)
+
+	if target := monitor.Notifications.DiscordWebhookURL; target != "" {
+		name := monitor.URL
+		if monitor.Name != "" {
+			name = monitor.Name
+		}
+		message := discordWebhook{Username: "Accessibility Scanner", Embeds: []discordEmbed{{
+			Title:       "Accessibility score of " + name + " dropped",
+			URL:         monitor.URL,
+			Description: fmt.Sprintf("Score fell from %.0f to %.0f / 100, with %d issues.", previous.Score*100, check.Score*100, check.Issues),
+			Color:       discordColorFailing,
+			Footer:      &discordFooter{Text: "Accessibility Scanner API"},
+			Timestamp:   check.Time.Format(time.RFC3339),
+		}}}
+		go func() {
+			if err := postDiscord(target, message); err != nil {
+				logAt(logLevelWarn, "Warning: Could not send Discord alert of page monitor %s: %v", monitor.ID, err)
+			}
+		}()
+	}
+	if target := monitor.Notifications.WebhookURL; target != "" {
+		go func() {
+			if err := postJSON(target, alert); err != nil {
+				logAt(logLevelWarn, "Warning: Could not send alert of page monitor %s to its webhook_url: %v", monitor.ID, err)
+			}
+		}()
+	}
+}
+
+// PageRegression is sent when a check scores score_drop or more below the
+// previous successful check
+type PageRegression struct {
+	MonitorID     string    `json:"monitor_id"`
+	Name          string    `json:"name,omitempty"`
+	URL           string    `json:"url"`
+	Check         PageCheck `json:"check"`
+	PreviousScore float64   `json:"previous_score"`
+	PreviousTime  time.Time `json:"previous_time"`
+}
+
+// runPageMonitors checks due page monitors until the process exits. Checks
+// run one at a time, so many monitors falling due together are spread out.
+func runPageMonitors() {
+	for range time.Tick(pageMonitorTick) {
+		for _, id := range pageMonitorStore.due(time.Now()) {
+			if _, err := pageMonitorStore.Check(context.Background(), id); err != nil && !errors.Is(err, errPageCheckRunning) && !errors.Is(err, errPageMonitorNotFound) {
+				logAt(logLevelWarn, "Warning: Could not record check of page monitor %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// sendPageMonitorStoreError reports a page monitor store error
+func sendPageMonitorStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errPageMonitorNotFound):
+		sendError(w, "Page monitor not found", http.StatusNotFound, "No page monitor exists with this ID")
+	case errors.Is(err, errPageCheckRunning):
+		sendError(w, "Check in progress", http.StatusConflict, "This page is being checked; try again shortly")
+	default:
+		logAt(logLevelError, "Page monitor storage error: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not access page monitors")
+	}
+}
+
+// validate checks a page monitor's URL, interval and channels
+func (m *PageMonitor) validate() error {
+	m.Name = strings.TrimSpace(m.Name)
+	parsed, err := url.Parse(m.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if m.IntervalHours == 0 {
+		m.IntervalHours = defaultPageCheckHours
+	}
+	if m.IntervalHours < 1 || m.IntervalHours > maxPageCheckHours {
+		return fmt.Errorf("interval_hours must be between 1 and %d", maxPageCheckHours)
+	}
+	if m.ScoreDrop < 0 || m.ScoreDrop > 1 {
+		return errors.New("score_drop must be between 0 and 1")
+	}
+	if len(m.Notifications.Emails) > 0 {
+		return errors.New("notifications: emails are not supported for page monitors; use discord_webhook_url or webhook_url")
+	}
+	return m.Notifications.validate()
+}
+
+// decodePageMonitor reads and validates a page monitor body
+func decodePageMonitor(w http.ResponseWriter, r *http.Request) (PageMonitor, bool) {
+	var monitor PageMonitor
+	if err := json.NewDecoder(r.Body).Decode(&monitor); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return monitor, false
+	}
+	if err := monitor.validate(); err != nil {
+		sendError(w, "Invalid page monitor", http.StatusBadRequest, err.Error())
+		return monitor, false
+	}
+	monitor.LastCheck = nil
+	monitor.Client = clientName(r)
+	return monitor, true
+}
+
+// handlePageMonitors handles GET and POST /api/v1/page-monitors requests
+func handlePageMonitors(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, pageMonitorStore.List())
+	case http.MethodPost:
+		monitor, ok := decodePageMonitor(w, r)
+		if !ok {
+			return
+		}
+		monitor, err := pageMonitorStore.Create(monitor)
+		if err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, monitor)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handlePageMonitor handles GET, PUT and DELETE /api/v1/page-monitors/{id} requests
+func handlePageMonitor(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		monitor, err := pageMonitorStore.Get(id)
+		if err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, monitor)
+	case http.MethodPut:
+		update, ok := decodePageMonitor(w, r)
+		if !ok {
+			return
+		}
+		monitor, err := pageMonitorStore.Update(id, update)
+		if err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, monitor)
+	case http.MethodDelete:
+		if err := pageMonitorStore.Delete(id); err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
+
+// handlePageMonitorChecks handles GET /api/v1/page-monitors/{id}/checks
+// requests, returning the monitor's series oldest first, and POST requests,
+// which check the page right away
+func handlePageMonitorChecks(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		var from, to time.Time
+		for name, value := range map[string]*time.Time{"from": &from, "to": &to} {
+			raw := r.URL.Query().Get(name)
+			if raw == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				sendError(w, "Invalid query parameter", http.StatusBadRequest, name+" must be an RFC 3339 time")
+				return
+			}
+			*value = parsed
+		}
+		monitor, err := pageMonitorStore.Get(id)
+		if err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		checks, err := pageMonitorStore.Checks(id, from, to)
+		if err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, PageCheckSeries{MonitorID: id, URL: monitor.URL, Checks: checks})
+	case http.MethodPost:
+		check, err := pageMonitorStore.Check(r.Context(), id)
+		if err != nil {
+			sendPageMonitorStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, check)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
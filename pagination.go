@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// paginationParams are query parameters that number the pages of a listing
+var paginationParams = []string{"page", "paged"}
+
+// paginationPathPattern matches paths such as /blog/page/2, as WordPress and
+// many other CMSs paginate archives
+var paginationPathPattern = regexp.MustCompile(`(?i)^(.*?)/page/(\d+)/?$`)
+
+// seriesPage places a page in a paginated series
+type seriesPage struct {
+	series string // URL of the series without the page number
+	number int    // 1 for the first page
+}
+
+// paginationParam returns the pagination query parameter of a URL and the
+// page number it gives
+func paginationParam(query url.Values) (string, int, bool) {
+	for _, param := range paginationParams {
+		if number, err := strconv.Atoi(query.Get(param)); err == nil && number > 0 {
+			return param, number, true
+		}
+	}
+	return "", 0, false
+}
+
+// paginationOf recognizes a page of a paginated series by its URL: a
+// /page/N path or a page or paged query parameter
+func paginationOf(pageURL string) (seriesPage, bool) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return seriesPage{}, false
+	}
+	if match := paginationPathPattern.FindStringSubmatch(u.Path); match != nil {
+		number, err := strconv.Atoi(match[2])
+		if err == nil && number > 0 {
+			series := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: match[1] + "/page/"}
+			return seriesPage{series: series.String(), number: number}, true
+		}
+	}
+	if param, number, ok := paginationParam(u.Query()); ok {
+		series := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+		return seriesPage{series: series.String() + "?" + param + "=", number: number}, true
+	}
+	return seriesPage{}, false
+}
+
+// hasRel reports whether a rel attribute value includes any of the given
+// link types
+func hasRel(rel string, types ...string) bool {
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if slices.Contains(types, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesPageOf places a page in its series, by URL or else by the rel="next"
+// links that led to it. Caller must hold s.mu.
+func (s *AccessibilityScanner) seriesPageOf(pageURL string) (seriesPage, bool) {
+	if page, ok := paginationOf(pageURL); ok {
+		return page, true
+	}
+	page, ok := s.nextPages[pageURL]
+	return page, ok
+}
+
+// recordNextPage remembers that a page links to the next page of its series
+// with rel="next", for series whose URLs follow no known pattern. A page
+// that is not yet part of a series starts one.
+func (s *AccessibilityScanner) recordNextPage(pageURL, nextURL string) {
+	if nextURL == "" || nextURL == pageURL {
+		return
+	}
+	if _, ok := paginationOf(nextURL); ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.nextPages[nextURL]; ok {
+		return
+	}
+	current, ok := s.seriesPageOf(pageURL)
+	if !ok {
+		current = seriesPage{series: pageURL, number: 1}
+	}
+	s.nextPages[nextURL] = seriesPage{series: current.series, number: current.number + 1}
+}
+
+// PaginatedSeries reports a paginated series whose later pages were left out
+// by pagination_limit
+type PaginatedSeries struct {
+	Series       string `json:"series"`
+	PagesSkipped int    `json:"pages_skipped"`
+	LastPageSeen int    `json:"last_page_seen"`
+}
+
+// skipsPaginated reports whether a URL is a page of a paginated series past
+// the scan's pagination_limit, remembering it for the scan result
+func (s *AccessibilityScanner) skipsPaginated(pageURL string) bool {
+	if s.paginationLimit == 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, ok := s.seriesPageOf(pageURL)
+	if !ok || page.number <= s.paginationLimit {
+		return false
+	}
+	s.paginationSkipped[pageURL] = page
+	return true
+}
+
+// paginationReport summarizes the skipped pages by series, in series order
+func (s *AccessibilityScanner) paginationReport() []PaginatedSeries {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bySeries := make(map[string]*PaginatedSeries)
+	var report []PaginatedSeries
+	for _, page := range s.paginationSkipped {
+		series, ok := bySeries[page.series]
+		if !ok {
+			series = &PaginatedSeries{Series: page.series}
+			bySeries[page.series] = series
+		}
+		series.PagesSkipped++
+		series.LastPageSeen = max(series.LastPageSeen, page.number)
+	}
+	for _, series := range bySeries {
+		report = append(report, *series)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Series < report[j].Series })
+	return report
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API v1 deprecation schedule, announced in the Deprecation and Sunset headers
+var (
+	v1DeprecatedAt = time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	v1Sunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// problemTypePrefix namespaces the machine-readable problem codes in the type URI
+const problemTypePrefix = "urn:accessibility-scanner:problem:"
+
+// problemCodes maps error titles to machine-readable codes where the
+// snake_cased title is not the right code
+var problemCodes = map[string]string{
+	"Missing URL":         "invalid_url",
+	"Scan failed":         "target_unreachable",
+	"Verification failed": "target_unreachable",
+	"Too many scans":      "queue_full",
+}
+
+// ProblemType documents a machine-readable problem code. Codes are stable:
+// clients can rely on them for retry logic instead of matching messages.
+type ProblemType struct {
+	Code        string `json:"code"`
+	Title       string `json:"title"`
+	Status      int    `json:"status"`
+	Retryable   bool   `json:"retryable"`
+	Description string `json:"description"`
+}
+
+// invalidFieldPattern matches the codes of request validation errors, one
+// per field or parameter (invalid_max_pages, invalid_thresholds...)
+const invalidFieldPattern = "^invalid_[a-z0-9_]+$"
+
+// problemTypes is the catalog of problem codes beyond the invalid_<field>
+// validation errors, documented in the OpenAPI spec
+var problemTypes = []ProblemType{
+	{"invalid_json", "Invalid JSON", http.StatusBadRequest, false, "The request body is not valid JSON for the endpoint."},
+	{"invalid_url", "Invalid URL", http.StatusBadRequest, false, "The URL to scan is missing, malformed or not http(s)."},
+	{"invalid_query_parameter", "Invalid query parameter", http.StatusBadRequest, false, "A query parameter has a value the endpoint does not accept."},
+	{"missing_name", "Missing name", http.StatusBadRequest, false, "The request body has no name."},
+	{"missing_filter", "Missing filter", http.StatusBadRequest, false, "The request needs at least one filter."},
+	{"invalid_api_key", "Invalid API key", http.StatusUnauthorized, false, "The X-API-Key header is missing or not a configured client key."},
+	{"unauthorized", "Unauthorized", http.StatusUnauthorized, false, "The admin API needs a valid admin bearer token."},
+	{"admin_api_disabled", "Admin API disabled", http.StatusForbidden, false, "No admin token is configured, so the admin API is off."},
+	{"quota_exceeded", "Quota exceeded", http.StatusTooManyRequests, false, "The client or its project used up a quota. A 429 can be retried after Retry-After; a 403 means the request can never fit the quota."},
+	{"method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed, false, "The endpoint does not support the HTTP method."},
+	{"scan_not_found", "Scan not found", http.StatusNotFound, false, "No stored scan has the ID."},
+	{"page_not_found", "Page not found", http.StatusNotFound, false, "The scan has no page with the index."},
+	{"report_not_stored", "Report not stored", http.StatusNotFound, false, "The scan did not store raw Lighthouse reports."},
+	{"issue_not_found", "Issue not found", http.StatusNotFound, false, "No issue has the fingerprint."},
+	{"continuation_not_found", "Continuation not found", http.StatusNotFound, false, "The continuation token is unknown or expired."},
+	{"site_not_found", "Site not found", http.StatusNotFound, false, "No site is registered for the host."},
+	{"organization_not_found", "Organization not found", http.StatusNotFound, false, "No organization has the ID."},
+	{"project_not_found", "Project not found", http.StatusNotFound, false, "No project has the ID."},
+	{"profile_not_found", "Profile not found", http.StatusNotFound, false, "No scan profile has the name."},
+	{"webhook_not_found", "Webhook not found", http.StatusNotFound, false, "No webhook has the ID."},
+	{"delivery_not_found", "Delivery not found", http.StatusNotFound, false, "No webhook delivery has the ID."},
+	{"monitor_not_found", "Monitor not found", http.StatusNotFound, false, "No monitor has the ID."},
+	{"page_monitor_not_found", "Page monitor not found", http.StatusNotFound, false, "No page monitor has the ID."},
+	{"share_link_invalid", "Share link invalid", http.StatusForbidden, false, "The share link's signature does not match, or sharing is disabled."},
+	{"share_link_expired", "Share link expired", http.StatusGone, false, "The share link's lifetime is over; a new link is needed."},
+	{"already_exists", "Already exists", http.StatusConflict, false, "A resource with the name or ID exists."},
+	{"not_empty", "Not empty", http.StatusConflict, false, "The resource still holds others and cannot be deleted."},
+	{"not_a_dead_letter", "Not a dead letter", http.StatusConflict, false, "Only deliveries that ran out of attempts can be redelivered."},
+	{"check_in_progress", "Check in progress", http.StatusConflict, true, "The page monitor is already being checked."},
+	{"sharing_not_configured", "Sharing not configured", http.StatusConflict, false, "No share secret is configured, so share links cannot be created."},
+	{"encryption_not_configured", "Encryption not configured", http.StatusConflict, false, "No encryption keys are configured."},
+	{"idempotency_key_reused", "Idempotency-Key reused", http.StatusUnprocessableEntity, false, "The Idempotency-Key was sent before with a different request body."},
+	{"queue_full", "Too many scans", http.StatusTooManyRequests, true, "The scan queue is full. Retry after Retry-After."},
+	{"storage_error", "Storage error", http.StatusInternalServerError, true, "Stored data could not be read or written."},
+	{"configuration_error", "Configuration error", http.StatusInternalServerError, false, "The service is missing a setting, such as the Google API key."},
+	{"report_error", "Report error", http.StatusInternalServerError, false, "The report could not be generated."},
+	{"encoding_error", "Encoding error", http.StatusInternalServerError, false, "The response could not be encoded."},
+	{"re_encryption_failed", "Re-encryption failed", http.StatusInternalServerError, true, "Some records could not be re-encrypted with the active key."},
+	{"deletion_incomplete", "Deletion incomplete", http.StatusInternalServerError, true, "Some data could not be deleted; retry to delete the rest."},
+	{"scan_cancelled", "Scan cancelled", http.StatusServiceUnavailable, true, "The scan was cancelled, by an operator or because the service is shutting down."},
+	{"lighthouse_quota_exceeded", "Lighthouse quota exceeded", http.StatusServiceUnavailable, true, "The PageSpeed Insights API rate limit or daily quota of the service's Google API key is used up."},
+	{"lighthouse_auth_failed", "Lighthouse authentication failed", http.StatusBadGateway, false, "The PageSpeed Insights API rejected the service's Google API key or OAuth credentials."},
+	{"lighthouse_error", "Lighthouse error", http.StatusBadGateway, true, "The PageSpeed Insights API failed or returned a response that could not be read."},
+	{"page_timeout", "Page timeout", http.StatusGatewayTimeout, true, "The page was not audited within page_timeout_seconds."},
+	{"target_dns_failure", "Target DNS failure", http.StatusBadGateway, false, "The host of the page does not resolve."},
+	{"target_tls_error", "Target TLS error", http.StatusBadGateway, false, "The page's HTTPS certificate or connection is invalid."},
+	{"target_http_error", "Target HTTP error", http.StatusBadGateway, false, "The page answered with an HTTP error status."},
+	{"target_not_html", "Target not HTML", http.StatusBadGateway, false, "The page is not an HTML document."},
+	{"target_not_rendered", "Target not rendered", http.StatusBadGateway, true, "The page loaded but never painted any content."},
+	{"target_timeout", "Target timeout", http.StatusGatewayTimeout, true, "The page took too long to load."},
+	{"target_throttled", "Target throttled", http.StatusServiceUnavailable, true, "The site rate-limited the scanner or failed under load (HTTP 429 or 503)."},
+	{"target_blocked", "Target blocked", http.StatusBadGateway, false, "A bot challenge or firewall answered instead of the page."},
+	{"target_unreachable", "Target unreachable", http.StatusBadGateway, true, "The page could not be loaded."},
+}
+
+// problemTypeByCode returns the catalog entry of a problem code
+func problemTypeByCode(code string) (ProblemType, bool) {
+	for _, problem := range problemTypes {
+		if problem.Code == code {
+			return problem, true
+		}
+	}
+	return ProblemType{}, false
+}
+
+// ProblemDetails is an RFC 7807 problem+json error
+type ProblemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	Retryable bool   `json:"retryable"`
+}
+
+// apiVersionKey is the request context key holding the API version
+type apiVersionKey struct{}
+
+// apiVersion returns the API version a request was made against
+func apiVersion(r *http.Request) int {
+	if version, ok := r.Context().Value(apiVersionKey{}).(int); ok {
+		return version
+	}
+	return 1
+}
+
+// problemCode returns the machine-readable code for an error title
+func problemCode(title string) string {
+	if code, ok := problemCodes[title]; ok {
+		return code
+	}
+	for _, problem := range problemTypes {
+		if problem.Title == title {
+			return problem.Code
+		}
+	}
+	var b strings.Builder
+	underscore := false
+	for _, c := range strings.ToLower(title) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// problemResponseWriter rewrites JSON error responses as problem+json
+type problemResponseWriter struct {
+	http.ResponseWriter
+	instance  string
+	status    int
+	capturing bool
+	body      bytes.Buffer
+}
+
+func (pw *problemResponseWriter) WriteHeader(code int) {
+	if pw.status != 0 {
+		return
+	}
+	pw.status = code
+
+	mediaType, _, _ := mime.ParseMediaType(pw.Header().Get("Content-Type"))
+	if code >= 400 && mediaType == "application/json" {
+		pw.capturing = true
+		return
+	}
+	pw.ResponseWriter.WriteHeader(code)
+}
+
+func (pw *problemResponseWriter) Write(b []byte) (int, error) {
+	if pw.status == 0 {
+		pw.WriteHeader(http.StatusOK)
+	}
+	if pw.capturing {
+		return pw.body.Write(b)
+	}
+	return pw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through for streaming responses
+func (pw *problemResponseWriter) Flush() {
+	if flusher, ok := pw.ResponseWriter.(http.Flusher); ok && !pw.capturing {
+		flusher.Flush()
+	}
+}
+
+// finish converts a captured ErrorResponse body, keeping any extra members as extensions
+func (pw *problemResponseWriter) finish() {
+	if !pw.capturing {
+		return
+	}
+
+	members := make(map[string]interface{})
+	json.Unmarshal(pw.body.Bytes(), &members)
+
+	title, _ := members["error"].(string)
+	if title == "" {
+		title = http.StatusText(pw.status)
+	}
+	detail, _ := members["message"].(string)
+	delete(members, "error")
+	delete(members, "message")
+
+	code := problemCode(title)
+	problem := ProblemDetails{
+		Type:     problemTypePrefix + code,
+		Title:    title,
+		Status:   pw.status,
+		Detail:   detail,
+		Instance: pw.instance,
+		Code:     code,
+	}
+	// Responses asking to come back later are retryable whatever the code
+	if known, ok := problemTypeByCode(code); ok {
+		problem.Retryable = known.Retryable
+	}
+	if pw.Header().Get("Retry-After") != "" {
+		problem.Retryable = true
+	}
+	encoded, _ := json.Marshal(problem)
+	json.Unmarshal(encoded, &members)
+
+	pw.Header().Set("Content-Type", "application/problem+json")
+	pw.Header().Del("Content-Length")
+	pw.ResponseWriter.WriteHeader(pw.status)
+	json.NewEncoder(pw.ResponseWriter).Encode(members)
+}
+
+// apiV2 serves a handler under /api/v2, where errors are RFC 7807 problem+json
+func apiV2(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, 2))
+		pw := &problemResponseWriter{ResponseWriter: w, instance: r.URL.Path}
+		defer pw.finish()
+		next(pw, r)
+	}
+}
+
+// apiV1 serves a handler under /api/v1, announcing its deprecation in favour of v2
+func apiV1(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := "/api/v2" + strings.TrimPrefix(r.URL.Path, "/api/v1")
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(v1DeprecatedAt.Unix(), 10))
+		w.Header().Set("Sunset", v1Sunset.Format(http.TimeFormat))
+		w.Header().Add("Link", "<"+successor+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultEngine is the audit engine scans run with
+const defaultEngine = "pagespeed"
+
+// Errors returned by the profile store
+var (
+	errProfileNotFound = errors.New("scan profile not found")
+	errProfileExists   = errors.New("scan profile already exists")
+)
+
+// Thresholds are the pass criteria of a scan; unset criteria are not checked
+type Thresholds struct {
+	MinSiteScore *float64       `json:"min_site_score,omitempty"`
+	MinPageScore *float64       `json:"min_page_score,omitempty"`
+	MaxIssues    map[string]int `json:"max_issues,omitempty"` // per impact, or "total"
+}
+
+// ThresholdFailure is a criterion a scan did not meet
+type ThresholdFailure struct {
+	Threshold string  `json:"threshold"`
+	Limit     float64 `json:"limit"`
+	Actual    float64 `json:"actual"`
+}
+
+// ThresholdReport tells whether a scan met its thresholds
+type ThresholdReport struct {
+	Passed   bool               `json:"passed"`
+	Failures []ThresholdFailure `json:"failures,omitempty"`
+}
+
+// ScanProfile is a named, reusable set of scan settings
+type ScanProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ScanSettings
+	Include    []string    `json:"include,omitempty"`
+	Exclude    []string    `json:"exclude,omitempty"`
+	Engine     string      `json:"engine,omitempty"`
+	Viewports  []Viewport  `json:"viewports,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+
+	// LighthouseConfig is a Lighthouse config JSON selecting the audits scans
+	// of the profile run
+	LighthouseConfig json.RawMessage `json:"lighthouse_config,omitempty"`
+
+	// SeverityOverrides remap the impact of audits' issues and IgnoreAudits
+	// drops audits altogether
+	SeverityOverrides map[string]string `json:"severity_overrides,omitempty"`
+	IgnoreAudits      []string          `json:"ignore_audits,omitempty"`
+
+	AltText *AltTextPolicy `json:"alt_text,omitempty"`
+
+	Notifications NotificationSettings `json:"notifications"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// ProfileStore keeps scan profiles in one JSON file
+type ProfileStore struct {
+	mu       sync.Mutex
+	path     string
+	profiles map[string]*ScanProfile
+}
+
+// profileStore holds the scan profiles
+var profileStore *ProfileStore
+
+// NewProfileStore loads the profiles stored in path, if any
+func NewProfileStore(path string) (*ProfileStore, error) {
+	store := &ProfileStore{path: path, profiles: make(map[string]*ScanProfile)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.profiles); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// save writes the profiles; callers must hold s.mu
+func (s *ProfileStore) save() error {
+	data, err := json.Marshal(s.profiles)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// List returns every profile by name
+func (s *ProfileStore) List() []ScanProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles := make([]ScanProfile, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		profiles = append(profiles, *profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// Get returns a profile by name
+func (s *ProfileStore) Get(name string) (ScanProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[name]
+	if !ok {
+		return ScanProfile{}, errProfileNotFound
+	}
+	return *profile, nil
+}
+
+// Put creates or replaces a profile; create fails if the name is taken and
+// replace fails if it is not
+func (s *ProfileStore) Put(profile ScanProfile, create bool) (ScanProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	existing, ok := s.profiles[profile.Name]
+	switch {
+	case create && ok:
+		return ScanProfile{}, errProfileExists
+	case !create && !ok:
+		return ScanProfile{}, errProfileNotFound
+	case ok:
+		profile.CreatedAt = existing.CreatedAt
+	default:
+		profile.CreatedAt = now
+	}
+	profile.UpdatedAt = now
+
+	previous := s.profiles[profile.Name]
+	s.profiles[profile.Name] = &profile
+	if err := s.save(); err != nil {
+		if previous != nil {
+			s.profiles[profile.Name] = previous
+		} else {
+			delete(s.profiles, profile.Name)
+		}
+		return ScanProfile{}, err
+	}
+	return profile, nil
+}
+
+// Delete removes a profile
+func (s *ProfileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[name]
+	if !ok {
+		return errProfileNotFound
+	}
+	delete(s.profiles, name)
+	if err := s.save(); err != nil {
+		s.profiles[name] = profile
+		return err
+	}
+	return nil
+}
+
+// applyTo fills the scan parameters a request left unset
+func (p ScanProfile) applyTo(req *ScanRequest) {
+	p.ScanSettings.applyTo(req)
+	if req.Include == nil {
+		req.Include = p.Include
+	}
+	if req.Exclude == nil {
+		req.Exclude = p.Exclude
+	}
+	if req.Engine == "" {
+		req.Engine = p.Engine
+	}
+	if req.Viewports == nil {
+		req.Viewports = p.Viewports
+	}
+	if req.LighthouseConfig == nil {
+		req.LighthouseConfig = p.LighthouseConfig
+	}
+	if req.SeverityOverrides == nil {
+		req.SeverityOverrides = p.SeverityOverrides
+	}
+	if req.IgnoreAudits == nil {
+		req.IgnoreAudits = p.IgnoreAudits
+	}
+	if req.Thresholds == nil {
+		req.Thresholds = p.Thresholds
+	}
+	if req.AltText == nil {
+		req.AltText = p.AltText
+	}
+}
+
+// validate checks the profile can be used for a scan
+func (p ScanProfile) validate() error {
+	if !slugPattern.MatchString(p.Name) {
+		return errors.New("name must be lowercase letters, digits and dashes")
+	}
+	if err := p.ScanSettings.validate(); err != nil {
+		return err
+	}
+	if err := validateEngine(p.Engine); err != nil {
+		return err
+	}
+	if err := validateViewports(p.Viewports, p.Engine); err != nil {
+		return err
+	}
+	if _, err := parseLighthouseConfig(p.LighthouseConfig); err != nil {
+		return err
+	}
+	if err := validateSeverityPolicy(p.SeverityOverrides, p.IgnoreAudits); err != nil {
+		return err
+	}
+	if err := p.AltText.validate(); err != nil {
+		return err
+	}
+	if err := p.Notifications.validate(); err != nil {
+		return err
+	}
+	return p.Thresholds.validate()
+}
+
+// validateEngine checks a scan names an audit engine this server runs
+func validateEngine(engine string) error {
+	if engine != "" && engine != defaultEngine {
+		return fmt.Errorf("engine %q is not available; supported engines: %s", engine, defaultEngine)
+	}
+	return nil
+}
+
+// validate checks the thresholds are within range
+func (t *Thresholds) validate() error {
+	if t == nil {
+		return nil
+	}
+	for _, score := range []*float64{t.MinSiteScore, t.MinPageScore} {
+		if score != nil && (*score < 0 || *score > 1) {
+			return errors.New("threshold scores must be between 0 and 1")
+		}
+	}
+	for impact, max := range t.MaxIssues {
+		if _, ok := impactWeights[impact]; !ok && impact != "total" {
+			return fmt.Errorf("max_issues keys must be impact levels or \"total\", got %q", impact)
+		}
+		if max < 0 {
+			return errors.New("max_issues values cannot be negative")
+		}
+	}
+	return nil
+}
+
+// evaluate checks a finished scan against the thresholds
+func (t *Thresholds) evaluate(result *ScanResult) *ThresholdReport {
+	report := &ThresholdReport{Passed: true}
+	fail := func(threshold string, limit, actual float64) {
+		report.Passed = false
+		report.Failures = append(report.Failures, ThresholdFailure{Threshold: threshold, Limit: limit, Actual: actual})
+	}
+
+	if t.MinSiteScore != nil && result.SiteScore < *t.MinSiteScore {
+		fail("min_site_score", *t.MinSiteScore, result.SiteScore)
+	}
+	if t.MinPageScore != nil {
+		lowest := 1.0
+		for _, page := range result.PageResults {
+			if page.Error == "" {
+				lowest = min(lowest, page.AccessibilityScore)
+			}
+		}
+		if lowest < *t.MinPageScore {
+			fail("min_page_score", *t.MinPageScore, lowest)
+		}
+	}
+
+	impacts := make([]string, 0, len(t.MaxIssues))
+	for impact := range t.MaxIssues {
+		impacts = append(impacts, impact)
+	}
+	sort.Strings(impacts)
+	for _, impact := range impacts {
+		actual := result.Summary.IssuesByImpact[impact]
+		if impact == "total" {
+			actual = result.Summary.TotalIssues
+		}
+		if max := t.MaxIssues[impact]; actual > max {
+			fail("max_issues."+impact, float64(max), float64(actual))
+		}
+	}
+	return report
+}
+
+// compilePathPatterns compiles include or exclude URL path patterns
+func compilePathPatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		compiled = append(compiled, globToRegexp(pattern))
+	}
+	return compiled
+}
+
+// inScope reports whether a URL path passes the scan's include and exclude patterns
+func (s *AccessibilityScanner) inScope(path string) bool {
+	for _, pattern := range s.exclude {
+		if pattern.MatchString(path) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, pattern := range s.include {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendProfileStoreError reports a profile store error
+func sendProfileStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errProfileNotFound):
+		sendError(w, "Profile not found", http.StatusNotFound, "No scan profile exists with this name")
+	case errors.Is(err, errProfileExists):
+		sendError(w, "Already exists", http.StatusConflict, "A scan profile with this name already exists")
+	default:
+		logAt(logLevelError, "Profile storage error: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not access scan profiles")
+	}
+}
+
+// decodeProfile reads and validates a profile body; name, when set, is the
+// profile named in the URL
+func decodeProfile(w http.ResponseWriter, r *http.Request, name string) (ScanProfile, bool) {
+	var profile ScanProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return profile, false
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	if name != "" && profile.Name == "" {
+		profile.Name = name
+	}
+	if name != "" && profile.Name != name {
+		sendError(w, "Invalid profile", http.StatusBadRequest, "name must match the profile in the URL")
+		return profile, false
+	}
+	if err := profile.validate(); err != nil {
+		sendError(w, "Invalid profile", http.StatusBadRequest, err.Error())
+		return profile, false
+	}
+	return profile, true
+}
+
+// handleProfiles handles GET and POST /api/v1/profiles requests
+func handleProfiles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, profileStore.List())
+	case http.MethodPost:
+		profile, ok := decodeProfile(w, r, "")
+		if !ok {
+			return
+		}
+		profile, err := profileStore.Put(profile, true)
+		if err != nil {
+			sendProfileStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, profile)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleProfile handles GET, PUT and DELETE /api/v1/profiles/{name} requests
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodGet:
+		profile, err := profileStore.Get(name)
+		if err != nil {
+			sendProfileStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+	case http.MethodPut:
+		profile, ok := decodeProfile(w, r, name)
+		if !ok {
+			return
+		}
+		profile, err := profileStore.Put(profile, false)
+		if err != nil {
+			sendProfileStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+	case http.MethodDelete:
+		if err := profileStore.Delete(name); err != nil {
+			sendProfileStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
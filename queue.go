@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Scan queue defaults
+const (
+	defaultMaxConcurrentScans = 2
+	defaultMaxQueuedScans     = 10
+	defaultScanDuration       = time.Minute // estimate used until a scan has completed
+
+	throttleCooldown = 30 * time.Second // 429s within this window of a cut count as one
+	recoveryCalls    = 20               // successful Lighthouse calls before a slot is restored
+)
+
+// errQueueFull is returned when every scan slot is busy and the queue is full
+var errQueueFull = errors.New("scan queue is full")
+
+// ScanQueue limits how many scans run at once and how many may wait for a slot
+type ScanQueue struct {
+	mu            sync.Mutex
+	waiters       []chan struct{} // scans waiting for a slot, oldest first
+	maxConcurrent int
+	maxQueued     int
+	running       int
+	queued        int
+	avgDuration   time.Duration
+
+	// effective is the concurrency actually allowed. It drops below
+	// maxConcurrent while the PageSpeed API rate-limits us and climbs back one
+	// slot per recoveryCalls successful calls.
+	effective    int
+	healthyCalls int
+	throttledAt  time.Time
+}
+
+// QueueStats describes the current load of the scan queue
+type QueueStats struct {
+	Running              int `json:"running"`
+	Queued               int `json:"queued"`
+	MaxConcurrent        int `json:"max_concurrent"`
+	EffectiveConcurrent  int `json:"effective_concurrent"`
+	MaxQueued            int `json:"max_queued"`
+	EstimatedWaitSeconds int `json:"estimated_wait_seconds"`
+}
+
+// QueueFullResponse is the 429 response sent when the queue cannot take another scan
+type QueueFullResponse struct {
+	ErrorResponse
+	QueueDepth           int `json:"queue_depth"`
+	Running              int `json:"running"`
+	EstimatedWaitSeconds int `json:"estimated_wait_seconds"`
+}
+
+// scanQueue limits concurrent full-site scans
+var scanQueue *ScanQueue
+
+// NewScanQueue creates a queue running up to maxConcurrent scans with up to maxQueued waiting
+func NewScanQueue(maxConcurrent, maxQueued int) *ScanQueue {
+	return &ScanQueue{
+		maxConcurrent: maxConcurrent,
+		maxQueued:     maxQueued,
+		effective:     maxConcurrent,
+	}
+}
+
+// Acquire waits for a scan slot and returns a function that releases it.
+// It fails immediately with errQueueFull when the queue is at capacity.
+func (q *ScanQueue) Acquire(ctx context.Context) (func(), error) {
+	q.mu.Lock()
+	if q.running < q.effective && len(q.waiters) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.releaser(), nil
+	}
+	if q.queued >= q.maxQueued {
+		q.mu.Unlock()
+		return nil, errQueueFull
+	}
+	ready := make(chan struct{})
+	q.waiters = append(q.waiters, ready)
+	q.queued++
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return q.releaser(), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, waiter := range q.waiters {
+			if waiter == ready {
+				q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+				q.queued--
+				return nil, ctx.Err()
+			}
+		}
+		// The slot was granted while the context was being cancelled
+		q.running--
+		q.promote()
+		return nil, ctx.Err()
+	}
+}
+
+// releaser returns a function that frees a running scan's slot once
+func (q *ScanQueue) releaser() func() {
+	started := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.running--
+			q.recordDuration(time.Since(started))
+			q.promote()
+		})
+	}
+}
+
+// promote hands free slots to waiting scans in arrival order. q.mu must be held.
+func (q *ScanQueue) promote() {
+	for q.running < q.effective && len(q.waiters) > 0 {
+		close(q.waiters[0])
+		q.waiters = q.waiters[1:]
+		q.queued--
+		q.running++
+	}
+}
+
+// SetLimits changes the queue capacity. Running scans are not interrupted when
+// the concurrency limit shrinks; new scans wait until enough of them finish.
+// A throttled queue keeps its reduced concurrency, capped at the new limit.
+func (q *ScanQueue) SetLimits(maxConcurrent, maxQueued int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.effective >= q.maxConcurrent {
+		q.effective = maxConcurrent
+	}
+	q.maxConcurrent = maxConcurrent
+	q.maxQueued = maxQueued
+	q.effective = min(q.effective, maxConcurrent)
+	q.promote()
+}
+
+// ReportThrottled halves the effective concurrency after the PageSpeed API
+// answered 429. Running scans finish; fewer new ones start until it recovers.
+func (q *ScanQueue) ReportThrottled() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.healthyCalls = 0
+	if time.Since(q.throttledAt) < throttleCooldown {
+		return
+	}
+	q.throttledAt = time.Now()
+	if q.effective > 1 {
+		q.effective = max(1, q.effective/2)
+		logAt(logLevelWarn, "Warning: PageSpeed API is rate limiting; scan concurrency reduced to %d of %d", q.effective, q.maxConcurrent)
+	}
+}
+
+// ReportHealthy counts a successful Lighthouse call, restoring one slot of
+// concurrency after every recoveryCalls of them
+func (q *ScanQueue) ReportHealthy() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.effective >= q.maxConcurrent {
+		return
+	}
+	q.healthyCalls++
+	if q.healthyCalls < recoveryCalls {
+		return
+	}
+	q.healthyCalls = 0
+	q.effective++
+	logAt(logLevelInfo, "Scan concurrency restored to %d of %d", q.effective, q.maxConcurrent)
+	q.promote()
+}
+
+// recordDuration folds a finished scan into the moving average duration
+func (q *ScanQueue) recordDuration(d time.Duration) {
+	if q.avgDuration == 0 {
+		q.avgDuration = d
+		return
+	}
+	q.avgDuration = (q.avgDuration*7 + d*3) / 10
+}
+
+// Stats returns the current queue load and the estimated wait for a new scan
+func (q *ScanQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	avg := q.avgDuration
+	if avg == 0 {
+		avg = defaultScanDuration
+	}
+
+	// Scans ahead of a new request that must finish before it gets a slot
+	ahead := q.running + q.queued - q.effective + 1
+	wait := 0
+	if ahead > 0 {
+		rounds := math.Ceil(float64(ahead) / float64(q.effective))
+		wait = int(math.Ceil(rounds * avg.Seconds()))
+	}
+
+	return QueueStats{
+		Running:              q.running,
+		Queued:               q.queued,
+		MaxConcurrent:        q.maxConcurrent,
+		EffectiveConcurrent:  q.effective,
+		MaxQueued:            q.maxQueued,
+		EstimatedWaitSeconds: wait,
+	}
+}
+
+// sendQueueFull reports that the service is too busy to accept another scan
+func sendQueueFull(w http.ResponseWriter) {
+	stats := scanQueue.Stats()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, stats.EstimatedWaitSeconds)))
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	json.NewEncoder(w).Encode(QueueFullResponse{
+		ErrorResponse: ErrorResponse{
+			Error:   "Too many scans",
+			Code:    http.StatusTooManyRequests,
+			Message: "The scan queue is full, please retry later",
+		},
+		QueueDepth:           stats.Queued,
+		Running:              stats.Running,
+		EstimatedWaitSeconds: stats.EstimatedWaitSeconds,
+	})
+}
+
+// handleQueue handles GET /api/v1/queue requests
+func handleQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scanQueue.Stats())
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Quota limits what an API key or project may consume; zero means unlimited.
+// Daily limits reset at midnight UTC.
+type Quota struct {
+	ScansPerDay        int `yaml:"scans_per_day" json:"scans_per_day,omitempty"`
+	PagesPerDay        int `yaml:"pages_per_day" json:"pages_per_day,omitempty"`
+	MaxConcurrentScans int `yaml:"max_concurrent_scans" json:"max_concurrent_scans,omitempty"`
+}
+
+// quotaScope is a quota together with the clients whose usage counts against it
+type quotaScope struct {
+	Scope   string // "key" or "project"
+	Name    string
+	Quota   Quota
+	members []string
+}
+
+// QuotaStatus reports a quota and the current consumption against it
+type QuotaStatus struct {
+	Scope       string        `json:"scope"`
+	Name        string        `json:"name"`
+	Limits      Quota         `json:"limits"`
+	UsedToday   UsageCounters `json:"used_today"`
+	ActiveScans int           `json:"active_scans"`
+	ResetsAt    time.Time     `json:"resets_at"`
+}
+
+// QuotaExceededResponse is sent when a request would exceed a quota
+type QuotaExceededResponse struct {
+	ErrorResponse
+	Scope    string     `json:"scope"`
+	Name     string     `json:"name"`
+	Limit    string     `json:"limit"`
+	Allowed  int        `json:"allowed"`
+	Used     int        `json:"used"`
+	ResetsAt *time.Time `json:"resets_at,omitempty"`
+}
+
+// activeQuotaScans counts running scans per quota scope, keyed by scope:name
+var activeQuotaScans = struct {
+	mu     sync.Mutex
+	counts map[string]int
+}{counts: make(map[string]int)}
+
+// quotaScopes returns the quotas that apply to a client: its own and its project's
+func quotaScopes(client string) []quotaScope {
+	config := currentConfig()
+	scopes := make([]quotaScope, 0, 2)
+	for _, apiClient := range config.APIKeys {
+		if apiClient.Name != client {
+			continue
+		}
+		if apiClient.Quota != (Quota{}) {
+			scopes = append(scopes, quotaScope{Scope: "key", Name: client, Quota: apiClient.Quota, members: []string{client}})
+		}
+		if quota, ok := config.ProjectQuotas[apiClient.Project]; ok && apiClient.Project != "" {
+			members := make([]string, 0)
+			for _, other := range config.APIKeys {
+				if other.Project == apiClient.Project {
+					members = append(members, other.Name)
+				}
+			}
+			scopes = append(scopes, quotaScope{Scope: "project", Name: apiClient.Project, Quota: quota, members: members})
+		}
+	}
+	return scopes
+}
+
+// quotaResetTime returns when the daily quotas reset
+func quotaResetTime() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// quotaStatuses reports the quotas of a client and its consumption against them
+func quotaStatuses(client string) []QuotaStatus {
+	activeQuotaScans.mu.Lock()
+	defer activeQuotaScans.mu.Unlock()
+
+	statuses := make([]QuotaStatus, 0)
+	for _, scope := range quotaScopes(client) {
+		statuses = append(statuses, QuotaStatus{
+			Scope:       scope.Scope,
+			Name:        scope.Name,
+			Limits:      scope.Quota,
+			UsedToday:   usageStore.Today(scope.members),
+			ActiveScans: activeQuotaScans.counts[scope.Scope+":"+scope.Name],
+			ResetsAt:    quotaResetTime(),
+		})
+	}
+	return statuses
+}
+
+// admitQuota checks a request for pages of Lighthouse audits (and a full scan,
+// when scan is set) against the client's quotas. On success it returns the
+// number of pages still allowed today (0 when unlimited) and a function
+// releasing the concurrent scan slot; otherwise it returns the rejection.
+func admitQuota(client string, pages int, scan bool) (int, func(), *QuotaExceededResponse) {
+	activeQuotaScans.mu.Lock()
+	defer activeQuotaScans.mu.Unlock()
+
+	scopes := quotaScopes(client)
+	resets := quotaResetTime()
+	remaining := 0
+	for _, scope := range scopes {
+		exceeded := func(code int, limit string, allowed, used int, message string) *QuotaExceededResponse {
+			return &QuotaExceededResponse{
+				ErrorResponse: ErrorResponse{Error: "Quota exceeded", Code: code, Message: message},
+				Scope:         scope.Scope,
+				Name:          scope.Name,
+				Limit:         limit,
+				Allowed:       allowed,
+				Used:          used,
+				ResetsAt:      &resets,
+			}
+		}
+		used := usageStore.Today(scope.members)
+		quota := scope.Quota
+
+		if quota.PagesPerDay > 0 {
+			if pages > quota.PagesPerDay {
+				// The request can never fit, so retrying tomorrow would not help
+				response := exceeded(http.StatusForbidden, "pages_per_day", quota.PagesPerDay, int(used.PagesScanned),
+					fmt.Sprintf("The request asks for %d pages but the %s %s may scan %d pages a day", pages, scope.Scope, scope.Name, quota.PagesPerDay))
+				response.ResetsAt = nil
+				return 0, nil, response
+			}
+			left := quota.PagesPerDay - int(used.PagesScanned)
+			if left <= 0 {
+				return 0, nil, exceeded(http.StatusTooManyRequests, "pages_per_day", quota.PagesPerDay, int(used.PagesScanned),
+					fmt.Sprintf("The %s %s has used its %d pages for today", scope.Scope, scope.Name, quota.PagesPerDay))
+			}
+			if remaining == 0 || left < remaining {
+				remaining = left
+			}
+		}
+		if !scan {
+			continue
+		}
+		if quota.ScansPerDay > 0 && int(used.Scans) >= quota.ScansPerDay {
+			return 0, nil, exceeded(http.StatusTooManyRequests, "scans_per_day", quota.ScansPerDay, int(used.Scans),
+				fmt.Sprintf("The %s %s has used its %d scans for today", scope.Scope, scope.Name, quota.ScansPerDay))
+		}
+		active := activeQuotaScans.counts[scope.Scope+":"+scope.Name]
+		if quota.MaxConcurrentScans > 0 && active >= quota.MaxConcurrentScans {
+			response := exceeded(http.StatusTooManyRequests, "max_concurrent_scans", quota.MaxConcurrentScans, active,
+				fmt.Sprintf("The %s %s already has %d scans running", scope.Scope, scope.Name, active))
+			response.ResetsAt = nil
+			return 0, nil, response
+		}
+	}
+
+	if scan {
+		for _, scope := range scopes {
+			activeQuotaScans.counts[scope.Scope+":"+scope.Name]++
+		}
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if !scan {
+				return
+			}
+			activeQuotaScans.mu.Lock()
+			defer activeQuotaScans.mu.Unlock()
+			for _, scope := range scopes {
+				key := scope.Scope + ":" + scope.Name
+				if activeQuotaScans.counts[key]--; activeQuotaScans.counts[key] <= 0 {
+					delete(activeQuotaScans.counts, key)
+				}
+			}
+		})
+	}
+	return remaining, release, nil
+}
+
+// sendQuotaExceeded reports a quota rejection with the current consumption
+func sendQuotaExceeded(w http.ResponseWriter, response *QuotaExceededResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if response.Code == http.StatusTooManyRequests && response.ResetsAt != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(*response.ResetsAt).Seconds())+1))
+	}
+	w.WriteHeader(response.Code)
+	json.NewEncoder(w).Encode(response)
+}
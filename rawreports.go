@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// errRawReportNotFound is returned for pages whose Lighthouse report was not stored
+var errRawReportNotFound = errors.New("raw Lighthouse report not found")
+
+// rawReportDir returns the directory holding the raw Lighthouse reports of a scan
+func (s *ScanStore) rawReportDir(scanID string) string {
+	return filepath.Join(s.dir, "raw", scanID)
+}
+
+// rawReportPath returns where the raw Lighthouse report of a page is stored.
+// Reports are named by a hash of the page URL, so pages keep their report
+// whatever their position in the result.
+func (s *ScanStore) rawReportPath(scanID, pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return filepath.Join(s.rawReportDir(scanID), hex.EncodeToString(sum[:])[:32]+".json.gz")
+}
+
+// SaveRawReport stores the full Lighthouse report of a page, encrypted at
+// rest when that is enabled. Reports are zstd-compressed when that is the
+// storage_compression codec, and otherwise gzip-compressed, since they are
+// always large.
+func (s *ScanStore) SaveRawReport(scanID, pageURL string, report []byte) error {
+	codec := compressionGzip
+	if currentConfig().StorageCompression == compressionZstd {
+		codec = compressionZstd
+	}
+	compressed, err := compressRecord(report, codec)
+	if err != nil {
+		return err
+	}
+
+	path := s.rawReportPath(scanID, pageURL)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeRecord(path, compressed)
+}
+
+// GetRawReport returns the Lighthouse report of a page as stored, gzip- or
+// zstd-compressed
+func (s *ScanStore) GetRawReport(scanID, pageURL string) ([]byte, error) {
+	data, err := readCompressedRecord(s.rawReportPath(scanID, pageURL))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errRawReportNotFound
+	}
+	return data, err
+}
+
+// LinkRawReports gives a scan continuing another the raw reports of the pages
+// it carries over. Files are hard-linked, or copied where links are not
+// supported, so deleting either scan leaves the other's reports in place.
+func (s *ScanStore) LinkRawReports(fromScanID, toScanID string) error {
+	entries, err := os.ReadDir(s.rawReportDir(fromScanID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.rawReportDir(toScanID), 0o755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		from := filepath.Join(s.rawReportDir(fromScanID), entry.Name())
+		to := filepath.Join(s.rawReportDir(toScanID), entry.Name())
+		if os.Link(from, to) == nil {
+			continue
+		}
+		data, err := os.ReadFile(from)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(to, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRawReports removes the raw reports of a scan and returns the bytes freed
+func (s *ScanStore) deleteRawReports(scanID string) int64 {
+	var freed int64
+	for _, path := range rawReportRecords(s.rawReportDir(scanID)) {
+		if info, err := os.Stat(path); err == nil {
+			freed += info.Size()
+		}
+	}
+	os.RemoveAll(s.rawReportDir(scanID))
+	return freed
+}
+
+// rawReportRecords lists the raw reports stored in a directory
+func rawReportRecords(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json.gz") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths
+}
+
+// saveRawReport stores the Lighthouse report of a crawled page, if the scan
+// keeps them, and marks the page as having one
+func (s *AccessibilityScanner) saveRawReport(page *PageResult) {
+	report := page.lighthouseReport
+	page.lighthouseReport = nil
+	if !s.storeRaw || len(report) == 0 {
+		return
+	}
+	if err := scanStore.SaveRawReport(s.id, page.URL, report); err != nil {
+		logAt(logLevelWarn, "Warning: Could not store the Lighthouse report of %s: %v", page.URL, err)
+		return
+	}
+	page.RawReport = true
+}
+
+// handleScanPageRaw handles GET /api/v1/scans/{id}/pages/{n}/raw requests,
+// returning the full Lighthouse report of the nth page of page_results,
+// counting from 0. The report opens in the Lighthouse Viewer.
+func handleScanPageRaw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 0 || n >= len(result.PageResults) {
+		sendError(w, "Page not found", http.StatusNotFound, "The scan has no page at this index of page_results")
+		return
+	}
+	page := result.PageResults[n]
+	if !page.RawReport {
+		sendError(w, "Report not stored", http.StatusNotFound, "The full Lighthouse report of this page was not stored; scan with store_raw set to keep it")
+		return
+	}
+
+	compressed, err := scanStore.GetRawReport(result.ID, page.URL)
+	if errors.Is(err, errRawReportNotFound) {
+		sendError(w, "Report not stored", http.StatusNotFound, "The full Lighthouse report of this page is no longer stored")
+		return
+	}
+	if err != nil {
+		logAt(logLevelError, "Failed to load the Lighthouse report of %s in scan %s: %v", page.URL, result.ID, err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the stored Lighthouse report")
+		return
+	}
+
+	// Clients accepting gzip get a gzip report as stored
+	if bytes.HasPrefix(compressed, gzipMagic) && negotiateEncoding(r.Header.Get("Accept-Encoding")) == "gzip" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+		w.Write(compressed)
+		return
+	}
+	report, err := decompressRecord(compressed)
+	if err != nil {
+		logAt(logLevelError, "Stored Lighthouse report of %s in scan %s is corrupt: %v", page.URL, result.ID, err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not read the stored Lighthouse report")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(report)
+}
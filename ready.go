@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Readiness check settings
+const (
+	pageSpeedEndpoint     = "https://www.googleapis.com/pagespeedonline/v5/runPagespeed"
+	pageSpeedCheckTimeout = 5 * time.Second
+	pageSpeedCheckTTL     = 30 * time.Second // reachability results are reused for this long
+)
+
+// DependencyCheck is the result of checking one dependency
+type DependencyCheck struct {
+	Status    string `json:"status"` // "ok", "failing" or "skipped"
+	Detail    string `json:"detail,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Cached    bool   `json:"cached,omitempty"` // the result of a recent check was reused
+}
+
+// ReadinessResponse reports whether the service can accept scans
+type ReadinessResponse struct {
+	Status    string                     `json:"status"` // "ready" or "not_ready"
+	Timestamp time.Time                  `json:"timestamp"`
+	Checks    map[string]DependencyCheck `json:"checks"`
+}
+
+// pageSpeedProbe caches the last PageSpeed reachability check
+var pageSpeedProbe struct {
+	mu      sync.Mutex
+	checked time.Time
+	result  DependencyCheck
+}
+
+// checkPageSpeed verifies the PageSpeed Insights API answers HTTP requests
+func checkPageSpeed(ctx context.Context) DependencyCheck {
+	pageSpeedProbe.mu.Lock()
+	defer pageSpeedProbe.mu.Unlock()
+
+	if time.Since(pageSpeedProbe.checked) < pageSpeedCheckTTL {
+		result := pageSpeedProbe.result
+		result.Cached = true
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pageSpeedCheckTimeout)
+	defer cancel()
+
+	result := DependencyCheck{Status: "ok"}
+	started := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, pageSpeedEndpoint, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = newOutboundClient(0).Do(req)
+		if err == nil {
+			drainAndClose(resp.Body)
+			// Any HTTP answer means the API is reachable; 5xx means it is not serving
+			if resp.StatusCode >= 500 {
+				result = DependencyCheck{Status: "failing", Detail: "PageSpeed API returned " + resp.Status}
+			}
+		}
+	}
+	if err != nil {
+		result = DependencyCheck{Status: "failing", Detail: "PageSpeed API unreachable: " + err.Error()}
+	}
+	result.LatencyMs = time.Since(started).Milliseconds()
+
+	pageSpeedProbe.checked = time.Now()
+	pageSpeedProbe.result = result
+	return result
+}
+
+// checkStorage verifies a storage directory is writable
+func checkStorage(dir string) DependencyCheck {
+	file, err := os.CreateTemp(dir, ".ready-*")
+	if err != nil {
+		return DependencyCheck{Status: "failing", Detail: "Storage not writable: " + err.Error()}
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return DependencyCheck{Status: "ok", Detail: filepath.Clean(dir)}
+}
+
+// checkQueue reports whether the scan queue can take another scan
+func checkQueue() DependencyCheck {
+	stats := scanQueue.Stats()
+	if stats.Running >= stats.MaxConcurrent && stats.Queued >= stats.MaxQueued {
+		return DependencyCheck{Status: "failing", Detail: "Scan queue is full"}
+	}
+	return DependencyCheck{Status: "ok", Detail: fmt.Sprintf("%d of %d running, %d of %d queued", stats.Running, stats.MaxConcurrent, stats.Queued, stats.MaxQueued)}
+}
+
+// timed runs a check and records how long it took
+func timed(check func() DependencyCheck) DependencyCheck {
+	started := time.Now()
+	result := check()
+	result.LatencyMs = time.Since(started).Milliseconds()
+	return result
+}
+
+// deepHealthChecks checks every external dependency of the service, each
+// with its own status and timing
+func deepHealthChecks(ctx context.Context) map[string]DependencyCheck {
+	checks := map[string]DependencyCheck{
+		"pagespeed":  checkPageSpeed(ctx),
+		"scan_store": timed(func() DependencyCheck { return checkStorage(scanStore.dir) }),
+		"site_store": timed(func() DependencyCheck { return checkStorage(siteStore.dir) }),
+		"scan_queue": checkQueue(),
+		// The only engine runs Lighthouse on Google's servers
+		"headless_browser": {Status: "skipped", Detail: "The pagespeed engine needs no local browser"},
+	}
+	if eventBus != nil {
+		checks["event_bus"] = eventBus.check(ctx)
+	}
+	return checks
+}
+
+// handleReady handles GET /ready requests
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	checks := map[string]DependencyCheck{
+		"api_key":    {Status: "ok"},
+		"pagespeed":  checkPageSpeed(r.Context()),
+		"scan_store": checkStorage(scanStore.dir),
+		"site_store": checkStorage(siteStore.dir),
+		"scan_queue": checkQueue(),
+	}
+	if getAPIKey() == "" {
+		checks["api_key"] = DependencyCheck{Status: "failing", Detail: "Google API key not configured"}
+	}
+
+	response := ReadinessResponse{Status: "ready", Timestamp: time.Now().UTC(), Checks: checks}
+	code := http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			response.Status = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog/remediation.yaml
+var defaultRemediationYAML []byte
+
+// localizedRemediationFS holds translations named remediation.<lang>.yaml
+//
+//go:embed catalog/remediation.*.yaml
+var localizedRemediationFS embed.FS
+
+// Remediation describes how to fix a specific accessibility audit failure
+type Remediation struct {
+	Summary string   `json:"summary" yaml:"summary"`
+	Steps   []string `json:"steps,omitempty" yaml:"steps"`
+	Example string   `json:"example,omitempty" yaml:"example"`
+	Links   []string `json:"links,omitempty" yaml:"links"`
+	// Titles are the issue titles of a built-in check by variant, with
+	// {placeholders} for the details of each issue
+	Titles map[string]string `json:"-" yaml:"titles"`
+}
+
+// RemediationCatalog maps audit IDs to their remediation guidance
+type RemediationCatalog map[string]Remediation
+
+// RemediationCatalogs holds one remediation catalog per language
+type RemediationCatalogs map[string]RemediationCatalog
+
+// remediationCatalogs are the catalogs attached to scan results
+var remediationCatalogs = RemediationCatalogs{}
+
+// loadRemediationCatalogs loads the built-in catalog and its translations, then applies
+// overrides from an optional YAML file. Language-specific overrides are read from files
+// next to it named like the override file with a language suffix (remediation.es.yaml).
+func loadRemediationCatalogs(overrideFile string) (RemediationCatalogs, error) {
+	base := RemediationCatalog{}
+	if err := yaml.Unmarshal(defaultRemediationYAML, &base); err != nil {
+		return nil, fmt.Errorf("invalid built-in remediation catalog: %v", err)
+	}
+
+	catalogs := RemediationCatalogs{defaultLanguage: base}
+
+	files, _ := localizedRemediationFS.ReadDir("catalog")
+	for _, file := range files {
+		lang := remediationFileLanguage(file.Name())
+		data, err := localizedRemediationFS.ReadFile(path.Join("catalog", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		translated, err := base.withOverrides(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid built-in %s remediation catalog: %v", lang, err)
+		}
+		catalogs[lang] = translated
+	}
+
+	if overrideFile == "" {
+		return catalogs, nil
+	}
+
+	data, err := os.ReadFile(overrideFile)
+	if err != nil {
+		return catalogs, err
+	}
+	for lang, catalog := range catalogs {
+		if catalogs[lang], err = catalog.withOverrides(data); err != nil {
+			return catalogs, fmt.Errorf("invalid remediation file %s: %v", overrideFile, err)
+		}
+	}
+
+	ext := filepath.Ext(overrideFile)
+	localized, _ := filepath.Glob(strings.TrimSuffix(overrideFile, ext) + ".*" + ext)
+	for _, file := range localized {
+		lang := remediationFileLanguage(filepath.Base(file))
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return catalogs, err
+		}
+		catalog, ok := catalogs[lang]
+		if !ok {
+			catalog = catalogs[defaultLanguage]
+		}
+		if catalogs[lang], err = catalog.withOverrides(data); err != nil {
+			return catalogs, fmt.Errorf("invalid remediation file %s: %v", file, err)
+		}
+	}
+
+	return catalogs, nil
+}
+
+// remediationFileLanguage extracts the language from a name like remediation.es.yaml
+func remediationFileLanguage(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+}
+
+// withOverrides returns a copy of the catalog with the YAML overrides merged in
+func (c RemediationCatalog) withOverrides(data []byte) (RemediationCatalog, error) {
+	overrides := RemediationCatalog{}
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, err
+	}
+
+	merged := make(RemediationCatalog, len(c))
+	for auditID, remediation := range c {
+		merged[auditID] = remediation
+	}
+	for auditID, override := range overrides {
+		merged[auditID] = merged[auditID].merge(override)
+	}
+	return merged, nil
+}
+
+// For returns the catalog for a language tag, falling back to its base
+// language (es-MX → es) and then to English
+func (c RemediationCatalogs) For(lang string) RemediationCatalog {
+	lang = strings.ToLower(lang)
+	if catalog, ok := c[lang]; ok {
+		return catalog
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if catalog, ok := c[base]; ok {
+			return catalog
+		}
+	}
+	return c[defaultLanguage]
+}
+
+// Languages returns the languages that have a remediation catalog
+func (c RemediationCatalogs) Languages() []string {
+	languages := make([]string, 0, len(c))
+	for lang := range c {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// merge returns a copy of r with every non-empty field of override applied
+func (r Remediation) merge(override Remediation) Remediation {
+	if override.Summary != "" {
+		r.Summary = override.Summary
+	}
+	if len(override.Steps) > 0 {
+		r.Steps = override.Steps
+	}
+	if override.Example != "" {
+		r.Example = override.Example
+	}
+	if len(override.Links) > 0 {
+		r.Links = override.Links
+	}
+	if len(override.Titles) > 0 {
+		titles := make(map[string]string, len(r.Titles)+len(override.Titles))
+		maps.Copy(titles, r.Titles)
+		maps.Copy(titles, override.Titles)
+		r.Titles = titles
+	}
+	return r
+}
+
+// Lookup returns the remediation guidance for an audit, or nil if none is known
+func (c RemediationCatalog) Lookup(auditID string) *Remediation {
+	remediation, ok := c[auditID]
+	if !ok {
+		return nil
+	}
+	return &remediation
+}
+
+// issueTitle returns the catalog title of an issue found by a built-in
+// check, with its placeholders filled in, or the audit ID if the catalog has
+// no such title
+func (c RemediationCatalog) issueTitle(auditID string, title *checkTitle) string {
+	template, ok := c[auditID].Titles[title.variant]
+	if !ok {
+		return auditID
+	}
+	return strings.NewReplacer(title.args...).Replace(template)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Exit codes of the report command
+const (
+	reportPassed = 0
+	reportFailed = 1 // the report has failures
+	reportError  = 2 // bad arguments or input
+)
+
+// reportFormat renders a scan result for a CI tool and returns the number of
+// failures it reported
+type reportFormat struct {
+	contentType string
+	write       func(w io.Writer, result *ScanResult) int
+}
+
+// reportFormats are the formats of ?format= and the report command besides
+// JSON, in the order they are listed in messages
+var reportFormats = []struct {
+	name string
+	reportFormat
+}{
+	{"tap", reportFormat{"text/plain; charset=utf-8", writeTAP}},
+	{"checkstyle", reportFormat{"application/xml; charset=utf-8", writeCheckstyle}},
+	{"github", reportFormat{"text/plain; charset=utf-8", writeGitHubAnnotations}},
+	{"markdown", reportFormat{"text/markdown; charset=utf-8", writeMarkdown}},
+}
+
+// lookupReportFormat returns a report format by name
+func lookupReportFormat(name string) (reportFormat, bool) {
+	for _, format := range reportFormats {
+		if format.name == name {
+			return format.reportFormat, true
+		}
+	}
+	return reportFormat{}, false
+}
+
+// reportFormatNames lists the report format names, comma-separated
+func reportFormatNames() string {
+	names := make([]string, 0, len(reportFormats))
+	for _, format := range reportFormats {
+		names = append(names, format.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// runReport implements "accessibility-scanner-api report": it reads a scan
+// result as returned by the API from a file or standard input and prints it
+// in a CI-friendly format, exiting with 1 when the report has failures
+func runReport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: accessibility-scanner-api report [flags] [scan.json]")
+		fmt.Fprintln(stderr, "Reads a scan result (from POST /api/v1/scan or GET /api/v1/scans/{id}) from the file, or standard input, and prints a report.")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "tap", "Report format: "+reportFormatNames())
+	impact := fs.String("impact", "", "Only report issues with these impacts (comma-separated)")
+	audit := fs.String("audit", "", "Only report issues of these audit IDs (comma-separated)")
+	pages := fs.String("url", "", "Only report pages whose URL matches this pattern (* wildcard)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return reportPassed
+		}
+		return reportError
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return reportError
+	}
+
+	opts, err := parseResultOptions(url.Values{
+		"format": {*format},
+		"impact": {*impact},
+		"audit":  {*audit},
+		"url":    {*pages},
+	})
+	if err == nil && opts.Format == "" {
+		err = errors.New("format must be one of: " + reportFormatNames())
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "report: %v\n", err)
+		return reportError
+	}
+
+	input := stdin
+	if name := fs.Arg(0); name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "report: %v\n", err)
+			return reportError
+		}
+		defer file.Close()
+		input = file
+	}
+	var result ScanResult
+	if err := json.NewDecoder(input).Decode(&result); err != nil {
+		fmt.Fprintf(stderr, "report: input is not a scan result: %v\n", err)
+		return reportError
+	}
+	result.PageResults = opts.Filter.apply(result.PageResults)
+
+	report, _ := lookupReportFormat(opts.Format)
+	if report.write(stdout, &result) > 0 {
+		return reportFailed
+	}
+	return reportPassed
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// maxRerunIssueChanges caps the fixed and new issues listed in a rerun comparison
+const maxRerunIssueChanges = 100
+
+// RerunComparison compares a rerun with the scan it repeats. Only pages
+// audited without errors in both scans are compared, so pages the rerun did
+// not reach do not count as fixed.
+type RerunComparison struct {
+	ScanID          string        `json:"scan_id"` // scan that was rerun
+	SiteScoreChange float64       `json:"site_score_change"`
+	PagesCompared   int           `json:"pages_compared"`
+	FixedIssues     int           `json:"fixed_issues"`
+	NewIssues       int           `json:"new_issues"`
+	UnchangedIssues int           `json:"unchanged_issues"`
+	Fixed           []IssueChange `json:"fixed,omitempty"` // first 100
+	New             []IssueChange `json:"new,omitempty"`   // first 100
+}
+
+// IssueChange is an issue that appeared or disappeared between two scans
+type IssueChange struct {
+	Fingerprint string `json:"fingerprint"`
+	PageURL     string `json:"page_url"`
+	AuditID     string `json:"audit_id"`
+	Impact      string `json:"impact"`
+	Selector    string `json:"selector,omitempty"`
+}
+
+// rerunOfKey is the request context key holding the scan a request reruns
+type rerunOfKey struct{}
+
+// rerunRequest rebuilds the request of a stored scan from its recorded
+// configuration, so the rerun crawls and audits the same way
+func rerunRequest(original ScanResult) ScanRequest {
+	config := original.ScanConfig
+	return ScanRequest{
+		URL:                original.BaseURL,
+		MaxPages:           config.MaxPages,
+		Offset:             config.Offset,
+		Limit:              config.Limit,
+		TrafficHints:       config.TrafficHints,
+		Language:           config.Language,
+		Sampling:           config.Sampling,
+		TimeoutSeconds:     config.TimeoutSeconds,
+		PageTimeoutSeconds: config.PageTimeoutSeconds,
+		MaxLighthouseCalls: config.MaxLighthouseCalls,
+		Frontier:           config.Frontier,
+		AMP:                config.AMP,
+		Iframes:            config.Iframes,
+		PaginationLimit:    config.PaginationLimit,
+		StoreRaw:           config.StoreRaw,
+		LighthouseConfig:   config.LighthouseConfig,
+		SeverityOverrides:  config.SeverityOverrides,
+		IgnoreAudits:       config.IgnoreAudits,
+		Tags:               original.Tags,
+		Profile:            config.Profile,
+		Include:            config.Include,
+		Exclude:            config.Exclude,
+		Engine:             config.Engine,
+		Thresholds:         config.Thresholds,
+		AltText:            config.AltText,
+		Strategy:           config.Strategy,
+		BotChallenge:       config.BotChallenge,
+	}
+}
+
+// handleRerunScan handles POST /api/v1/scans/{id}/rerun requests: it starts
+// a new scan with the stored scan's configuration and compares the two. The
+// response is that of POST /api/v1/scan, including streaming, quotas and
+// Idempotency-Key handling.
+func handleRerunScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+
+	original, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+	body, err := json.Marshal(rerunRequest(original))
+	if err != nil {
+		sendError(w, "Encoding error", http.StatusInternalServerError, "Could not rebuild the scan request")
+		return
+	}
+
+	rerun := r.WithContext(context.WithValue(r.Context(), rerunOfKey{}, &original))
+	rerun.Body = io.NopCloser(bytes.NewReader(body))
+	rerun.ContentLength = int64(len(body))
+	handleScan(w, rerun)
+}
+
+// compareRerun compares the issues of a rerun with those of the original scan
+func compareRerun(original, rerun ScanResult) *RerunComparison {
+	comparison := &RerunComparison{
+		ScanID:          original.ID,
+		SiteScoreChange: math.Round((rerun.SiteScore-original.SiteScore)*1000) / 1000,
+	}
+
+	before := make(map[string]PageResult, len(original.PageResults))
+	for _, page := range original.PageResults {
+		if page.Error == "" {
+			before[page.URL] = page
+		}
+	}
+	fingerprints := func(page PageResult) map[string]AccessibilityIssue {
+		issues := make(map[string]AccessibilityIssue, len(page.Issues))
+		for _, issue := range page.Issues {
+			issues[issueFingerprint(page.URL, issue)] = issue
+		}
+		return issues
+	}
+	change := func(fingerprint, pageURL string, issue AccessibilityIssue) IssueChange {
+		return IssueChange{
+			Fingerprint: fingerprint,
+			PageURL:     pageURL,
+			AuditID:     issue.AuditID,
+			Impact:      issue.Impact,
+			Selector:    issue.Selector,
+		}
+	}
+
+	for _, page := range rerun.PageResults {
+		previous, ok := before[page.URL]
+		if !ok || page.Error != "" {
+			continue
+		}
+		comparison.PagesCompared++
+		was, now := fingerprints(previous), fingerprints(page)
+		for fingerprint, issue := range now {
+			if _, seen := was[fingerprint]; seen {
+				comparison.UnchangedIssues++
+				continue
+			}
+			comparison.NewIssues++
+			comparison.New = append(comparison.New, change(fingerprint, page.URL, issue))
+		}
+		for fingerprint, issue := range was {
+			if _, still := now[fingerprint]; !still {
+				comparison.FixedIssues++
+				comparison.Fixed = append(comparison.Fixed, change(fingerprint, page.URL, issue))
+			}
+		}
+	}
+
+	// Most severe first, then by page, so the capped lists keep what matters
+	for _, changes := range []*[]IssueChange{&comparison.Fixed, &comparison.New} {
+		sort.SliceStable(*changes, func(i, j int) bool {
+			a, b := (*changes)[i], (*changes)[j]
+			if wa, wb := impactWeights[a.Impact], impactWeights[b.Impact]; wa != wb {
+				return wa > wb
+			}
+			if a.PageURL != b.PageURL {
+				return a.PageURL < b.PageURL
+			}
+			return a.Fingerprint < b.Fingerprint
+		})
+		if len(*changes) > maxRerunIssueChanges {
+			*changes = (*changes)[:maxRerunIssueChanges]
+		}
+	}
+	return comparison
+}
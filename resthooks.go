@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookSourceRESTHook marks webhooks created through the REST Hooks API
+const webhookSourceRESTHook = "rest_hook"
+
+// restHookSamples is the number of recent scans returned as sample payloads
+const restHookSamples = 3
+
+// RESTHook is a REST Hooks subscription: one event posted to one target URL.
+// Subscriptions are webhooks underneath, so they share retries, signing and
+// the delivery log.
+type RESTHook struct {
+	ID        string    `json:"id"`
+	TargetURL string    `json:"target_url"`
+	Event     string    `json:"event"`
+	ProjectID string    `json:"project_id,omitempty"`
+	Site      string    `json:"site,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// restHookRequest is the body of a subscribe request
+type restHookRequest struct {
+	TargetURL string `json:"target_url"`
+	Event     string `json:"event"`
+	ProjectID string `json:"project_id,omitempty"`
+	Site      string `json:"site,omitempty"`
+}
+
+// restHook returns the subscription view of a webhook
+func restHook(webhook Webhook) RESTHook {
+	hook := RESTHook{
+		ID:        webhook.ID,
+		TargetURL: webhook.URL,
+		ProjectID: webhook.ProjectID,
+		Site:      webhook.Site,
+		CreatedAt: webhook.CreatedAt,
+	}
+	if len(webhook.Events) > 0 {
+		hook.Event = webhook.Events[0]
+	}
+	return hook
+}
+
+// RESTHooks returns the REST Hooks subscriptions, oldest first
+func (s *WebhookStore) RESTHooks() []RESTHook {
+	hooks := make([]RESTHook, 0)
+	for _, webhook := range s.List() {
+		if webhook.Source == webhookSourceRESTHook {
+			hooks = append(hooks, restHook(webhook))
+		}
+	}
+	return hooks
+}
+
+// RESTHook returns a REST Hooks subscription by ID
+func (s *WebhookStore) RESTHook(id string) (RESTHook, error) {
+	webhook, err := s.Get(id)
+	if err != nil {
+		return RESTHook{}, err
+	}
+	if webhook.Source != webhookSourceRESTHook {
+		return RESTHook{}, errWebhookNotFound
+	}
+	return restHook(webhook), nil
+}
+
+// restHookSample returns payloads of the newest scans for an event, so a
+// no-code platform can show real fields while a subscription is being set up.
+// Monitor events, and other events with no matching scans stored, get a
+// made-up example.
+func restHookSample(event string) []WebhookPayload {
+	samples := make([]WebhookPayload, 0, restHookSamples)
+	scans, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not list scans for REST hook samples: %v", err)
+	}
+	monitorEvent := event == eventMonitorTriggered || event == eventMonitorResolved
+	for _, scan := range scans {
+		if len(samples) == restHookSamples || monitorEvent {
+			break
+		}
+		failed := scan.Status == "failed"
+		if failed != (event == eventScanFailed) || scan.Status == "cancelled" {
+			continue
+		}
+		result, err := scanStore.Get(scan.ID)
+		if err != nil {
+			continue
+		}
+		payload := WebhookPayload{DeliveryID: "sample-" + result.ID, Event: event, CreatedAt: result.ScanTime, Scan: webhookScan(&result)}
+		if event == eventScoreRegress {
+			previous := previousScan(&result)
+			if previous == nil {
+				continue
+			}
+			payload.Regression = &ScoreRegression{
+				PreviousScanID: previous.ID,
+				PreviousScore:  previous.SiteScore,
+				Drop:           previous.SiteScore - result.SiteScore,
+			}
+		}
+		samples = append(samples, payload)
+	}
+	if len(samples) > 0 {
+		return samples
+	}
+
+	example := WebhookPayload{
+		DeliveryID: "sample",
+		Event:      event,
+		CreatedAt:  time.Date(2025, 8, 8, 12, 5, 0, 0, time.UTC),
+		Scan: WebhookScan{
+			ID:             "3f2b9c1e8a7d4f60b5e2c9a1d3e4f5a6",
+			BaseURL:        "https://example.com/",
+			ScanTime:       time.Date(2025, 8, 8, 12, 0, 0, 0, time.UTC),
+			Status:         "completed",
+			SiteScore:      0.87,
+			TotalPages:     20,
+			TotalIssues:    14,
+			IssuesByImpact: map[string]int{"serious": 4, "moderate": 10},
+		},
+	}
+	switch event {
+	case eventScanFailed:
+		example.Scan.Status = "failed"
+		example.Scan.SiteScore, example.Scan.TotalIssues, example.Scan.IssuesByImpact = 0, 0, nil
+	case eventScoreRegress:
+		example.Regression = &ScoreRegression{PreviousScanID: "8d0c7b6a5f4e3d2c1b0a9f8e7d6c5b4a", PreviousScore: 0.94, Drop: 0.07}
+	case eventMonitorTriggered, eventMonitorResolved:
+		previous := 2.0
+		example.Monitor = &MonitorAlert{
+			MonitorID:   "c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9",
+			MonitorName: "Homepage quality",
+			State:       monitorTriggered,
+			Site:        "example.com",
+			ScanID:      example.Scan.ID,
+			Triggered: []MonitorRuleResult{
+				{Rule: "site_score < 0.9", Actual: 0.87},
+				{Rule: "issues_serious increases", Actual: 4, Previous: &previous},
+			},
+		}
+		if event == eventMonitorResolved {
+			example.Monitor.State, example.Monitor.Triggered = monitorResolved, []MonitorRuleResult{}
+			example.Scan.SiteScore = 0.93
+		}
+	}
+	return []WebhookPayload{example}
+}
+
+// handleHooks handles GET and POST /api/v1/hooks requests: listing and
+// subscribing REST Hooks
+func handleHooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, webhookStore.RESTHooks())
+	case http.MethodPost:
+		var req restHookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+			return
+		}
+		if parsed, err := url.Parse(req.TargetURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			sendError(w, "Invalid subscription", http.StatusBadRequest, "target_url must be an absolute http(s) URL")
+			return
+		}
+		if !webhookEvents[req.Event] {
+			sendError(w, "Invalid subscription", http.StatusBadRequest, "event must be one of scan.completed, scan.failed, score.regressed, monitor.triggered, monitor.resolved")
+			return
+		}
+		webhook := Webhook{
+			URL:       req.TargetURL,
+			Events:    []string{req.Event},
+			ProjectID: req.ProjectID,
+			Site:      req.Site,
+		}
+		if err := webhook.validate(); err != nil {
+			sendError(w, "Invalid subscription", http.StatusBadRequest, err.Error())
+			return
+		}
+		webhook.Source = webhookSourceRESTHook
+		webhook, err := webhookStore.Create(webhook)
+		if err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, restHook(webhook))
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleHook handles GET and DELETE /api/v1/hooks/{id} requests; DELETE
+// unsubscribes
+func handleHook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		hook, err := webhookStore.RESTHook(id)
+		if err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, hook)
+	case http.MethodDelete:
+		if _, err := webhookStore.RESTHook(id); err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		if err := webhookStore.Delete(id); err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and DELETE methods are supported")
+	}
+}
+
+// handleHookSample handles GET /api/v1/hooks/sample requests, returning
+// example payloads of an event for no-code platforms to map fields from
+func handleHookSample(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	event := r.URL.Query().Get("event")
+	if event == "" {
+		event = eventScanCompleted
+	}
+	if !webhookEvents[event] {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "event must be one of scan.completed, scan.failed, score.regressed, monitor.triggered, monitor.resolved")
+		return
+	}
+	writeJSON(w, http.StatusOK, restHookSample(event))
+}
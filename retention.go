@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Retention defaults
+const (
+	defaultJanitorIntervalMinutes = 60
+	continuationMaxAge            = 7 * 24 * time.Hour // unused continuation tokens expire after this long
+)
+
+// storedScan describes a stored scan file for retention decisions
+type storedScan struct {
+	ID         string
+	Site       string
+	ScanTime   time.Time
+	Size       int64
+	Status     string
+	SiteScore  float64
+	TotalPages int
+	Tags       map[string]string
+	Unreadable bool // the header could not be read, so Site is unknown
+}
+
+// RetentionPolicy is the retention configuration in effect
+type RetentionPolicy struct {
+	MaxAgeDays             int `json:"max_age_days"`       // 0 keeps scans forever
+	MaxScansPerSite        int `json:"max_scans_per_site"` // 0 keeps every scan
+	JanitorIntervalMinutes int `json:"janitor_interval_minutes"`
+}
+
+// JanitorRun reports what one janitor run removed
+type JanitorRun struct {
+	StartedAt            time.Time `json:"started_at"`
+	DurationMs           int64     `json:"duration_ms"`
+	ScansDeleted         int       `json:"scans_deleted"`
+	ContinuationsDeleted int       `json:"continuations_deleted"`
+	BytesReclaimed       int64     `json:"bytes_reclaimed"`
+	Error                string    `json:"error,omitempty"`
+}
+
+// RetentionStats accumulates janitor results since the service started
+type RetentionStats struct {
+	Runs                      int         `json:"runs"`
+	TotalScansDeleted         int         `json:"total_scans_deleted"`
+	TotalContinuationsDeleted int         `json:"total_continuations_deleted"`
+	TotalBytesReclaimed       int64       `json:"total_bytes_reclaimed"`
+	LastRun                   *JanitorRun `json:"last_run,omitempty"`
+}
+
+// RetentionResponse is returned by the admin retention endpoint
+type RetentionResponse struct {
+	Policy RetentionPolicy `json:"policy"`
+	Stats  RetentionStats  `json:"stats"`
+}
+
+// janitor serializes cleanup runs and records their results
+var janitor struct {
+	mu    sync.Mutex
+	stats RetentionStats
+}
+
+// retentionPolicy returns the retention settings of the active configuration
+func retentionPolicy() RetentionPolicy {
+	config := currentConfig()
+	return RetentionPolicy{
+		MaxAgeDays:             config.RetentionDays,
+		MaxScansPerSite:        config.RetentionMaxScans,
+		JanitorIntervalMinutes: config.JanitorIntervalMinutes,
+	}
+}
+
+// String describes the policy for the startup log
+func (p RetentionPolicy) String() string {
+	parts := make([]string, 0, 2)
+	if p.MaxAgeDays > 0 {
+		parts = append(parts, fmt.Sprintf("%d days", p.MaxAgeDays))
+	}
+	if p.MaxScansPerSite > 0 {
+		parts = append(parts, fmt.Sprintf("newest %d scans per site", p.MaxScansPerSite))
+	}
+	if len(parts) == 0 {
+		return "keep all scans"
+	}
+	return "keep " + strings.Join(parts, ", ")
+}
+
+// List returns every stored scan, newest first
+func (s *ScanStore) List() ([]storedScan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	scans := make([]storedScan, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || !scanIDPattern.MatchString(id) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		// A scan encrypted with a retired key is still listed, without its header
+		data, err := readRecord(s.path(id))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		var header struct {
+			BaseURL    string            `json:"base_url"`
+			ScanTime   time.Time         `json:"scan_time"`
+			Status     string            `json:"status"`
+			SiteScore  float64           `json:"site_score"`
+			TotalPages int               `json:"total_pages"`
+			Tags       map[string]string `json:"tags"`
+		}
+		scan := storedScan{ID: id, ScanTime: info.ModTime(), Size: info.Size()}
+		if err != nil || json.Unmarshal(data, &header) != nil {
+			scan.Unreadable = true
+		} else {
+			scan.Site = siteKey(header.BaseURL)
+			scan.Status = header.Status
+			scan.SiteScore = header.SiteScore
+			scan.TotalPages = header.TotalPages
+			scan.Tags = header.Tags
+			if !header.ScanTime.IsZero() {
+				scan.ScanTime = header.ScanTime
+			}
+		}
+		scans = append(scans, scan)
+	}
+
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].ScanTime.After(scans[j].ScanTime)
+	})
+	return scans, nil
+}
+
+// Delete removes a stored scan and returns the bytes freed
+func (s *ScanStore) Delete(id string) (int64, error) {
+	if !scanIDPattern.MatchString(id) {
+		return 0, errScanNotFound
+	}
+	freed, err := s.delete(id)
+	if err == nil {
+		s.indexDeleted(id)
+	}
+	return freed, err
+}
+
+func (s *ScanStore) delete(id string) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, errScanNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Remove(s.path(id)); err != nil {
+		return 0, err
+	}
+	return info.Size() + s.deleteRawReports(id), nil
+}
+
+// PruneFrontiers removes continuation tokens saved before cutoff and returns
+// how many were removed and the bytes freed
+func (s *ScanStore) PruneFrontiers(cutoff time.Time) (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, "continuations"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed, freed := 0, int64(0)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, "continuations", entry.Name())); err != nil {
+			return removed, freed, err
+		}
+		// A disk-backed frontier file belongs to the token saved next to it
+		if filepath.Ext(entry.Name()) == ".json" {
+			removed++
+		}
+		freed += info.Size()
+	}
+	return removed, freed, nil
+}
+
+// expiredScans selects the scans the policy no longer keeps: those older than
+// the age limit and, per site, those beyond the newest MaxScansPerSite
+func expiredScans(scans []storedScan, policy RetentionPolicy, now time.Time) []storedScan {
+	expired := make([]storedScan, 0)
+	kept := make(map[string]int)
+	for _, scan := range scans {
+		if policy.MaxAgeDays > 0 && now.Sub(scan.ScanTime) > time.Duration(policy.MaxAgeDays)*24*time.Hour {
+			expired = append(expired, scan)
+			continue
+		}
+		if policy.MaxScansPerSite > 0 && scan.Site != "" {
+			if kept[scan.Site] >= policy.MaxScansPerSite {
+				expired = append(expired, scan)
+				continue
+			}
+			kept[scan.Site]++
+		}
+	}
+	return expired
+}
+
+// runJanitor applies the retention policy once
+func runJanitor() JanitorRun {
+	janitor.mu.Lock()
+	defer janitor.mu.Unlock()
+
+	run := JanitorRun{StartedAt: time.Now().UTC()}
+	policy := retentionPolicy()
+
+	scans, err := scanStore.List()
+	if err == nil {
+		for _, scan := range expiredScans(scans, policy, run.StartedAt) {
+			freed, deleteErr := scanStore.Delete(scan.ID)
+			if deleteErr != nil && !errors.Is(deleteErr, errScanNotFound) {
+				err = deleteErr
+				break
+			}
+			if deleteErr == nil {
+				run.ScansDeleted++
+				run.BytesReclaimed += freed
+			}
+		}
+	}
+	if err == nil {
+		var freed int64
+		run.ContinuationsDeleted, freed, err = scanStore.PruneFrontiers(run.StartedAt.Add(-continuationMaxAge))
+		run.BytesReclaimed += freed
+	}
+	if err != nil {
+		run.Error = err.Error()
+		logAt(logLevelWarn, "Warning: Retention cleanup failed: %v", err)
+	}
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+
+	janitor.stats.Runs++
+	janitor.stats.TotalScansDeleted += run.ScansDeleted
+	janitor.stats.TotalContinuationsDeleted += run.ContinuationsDeleted
+	janitor.stats.TotalBytesReclaimed += run.BytesReclaimed
+	janitor.stats.LastRun = &run
+
+	if run.ScansDeleted > 0 || run.ContinuationsDeleted > 0 {
+		logAt(logLevelInfo, "🧹 Retention cleanup: %d scans and %d continuations deleted, %d bytes reclaimed",
+			run.ScansDeleted, run.ContinuationsDeleted, run.BytesReclaimed)
+	}
+	return run
+}
+
+// startJanitor runs the retention cleanup in the background. The interval is
+// re-read after every run, so configuration reloads take effect.
+func startJanitor() {
+	go func() {
+		for {
+			runJanitor()
+			time.Sleep(time.Duration(retentionPolicy().JanitorIntervalMinutes) * time.Minute)
+		}
+	}()
+}
+
+// retentionStats returns a copy of the accumulated janitor results
+func retentionStats() RetentionStats {
+	janitor.mu.Lock()
+	defer janitor.mu.Unlock()
+	stats := janitor.stats
+	if stats.LastRun != nil {
+		last := *stats.LastRun
+		stats.LastRun = &last
+	}
+	return stats
+}
+
+// handleAdminRetention handles GET and POST /api/v1/admin/retention requests;
+// POST runs the janitor immediately
+func handleAdminRetention(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		runJanitor()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetentionResponse{Policy: retentionPolicy(), Stats: retentionStats()})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+)
+
+// retriesPage reports whether a page that failed with the given error code
+// gets a second attempt at the end of the scan: retryable problems, and DNS
+// failures, which are often transient. Throttled pages were already retried
+// at the end of the queue, and slow pages are skipped so they cannot eat the
+// scan's time twice.
+func retriesPage(code string) bool {
+	switch code {
+	case "scan_cancelled", "target_throttled", "page_timeout":
+		return false
+	case "target_dns_failure":
+		return true
+	}
+	problem, ok := problemTypeByCode(code)
+	return ok && problem.Retryable
+}
+
+// retryFailedPages audits the pages that failed with a transient error once
+// more before the result is finalized, so a passing hiccup does not leave
+// the scan partial. A page that succeeds replaces the failure; either way
+// the page is marked retried. Retries stop when the scan is cancelled, times
+// out or uses up its Lighthouse budget.
+func (s *AccessibilityScanner) retryFailedPages(ctx context.Context, result *ScanResult) {
+	if result.Status == "cancelled" {
+		return
+	}
+	for i := range result.PageResults {
+		page := &result.PageResults[i]
+		if page.Error == "" || page.Retried || !retriesPage(page.ErrorCode) {
+			continue
+		}
+		if ctx.Err() != nil || s.budgetExhausted() || !s.pause(ctx) {
+			return
+		}
+
+		s.reportProgress(page.URL, len(result.PageResults))
+		logAt(logLevelDebug, "Retrying %s after %s", page.URL, page.ErrorCode)
+		pageCtx, cancelPage := context.WithTimeout(ctx, s.pageDeadline())
+		retried := s.scanPageWithLighthouse(pageCtx, page.URL)
+		cancelPage()
+		if ctx.Err() != nil {
+			return
+		}
+		page.Retried = true
+		if retried.Error != "" {
+			continue
+		}
+
+		// The crawl's findings and frame audits came with the first attempt
+		retried.Depth = page.Depth
+		retried.Headings, retried.Landmarks, retried.TabOrder = page.Headings, page.Landmarks, page.TabOrder
+		retried.Frames = page.Frames
+		retried.Issues = append(retried.Issues, page.Issues...)
+		retried.Retried = true
+		s.applySeverity(&retried)
+		s.saveRawReport(&retried)
+		*page = retried
+		s.notifyRetriedPage(retried)
+	}
+}
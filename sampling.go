@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Sampling defaults
+const (
+	defaultTemplateSimilarity = 0.8
+	signatureMaxDepth         = 8
+)
+
+// SamplingConfig requests WCAG-EM style page sampling instead of a plain crawl
+type SamplingConfig struct {
+	Mode        string  `json:"mode"`                   // "wcag-em"
+	RandomPages int     `json:"random_pages,omitempty"` // default: 10% of the structured sample, at least 1
+	Seed        int64   `json:"seed,omitempty"`         // default: random, recorded in the report
+	Similarity  float64 `json:"similarity,omitempty"`   // template clustering threshold (default: 0.8)
+}
+
+// validate checks the sampling configuration and applies defaults
+func (c *SamplingConfig) validate() error {
+	if c.Mode != "wcag-em" {
+		return fmt.Errorf("sampling.mode must be \"wcag-em\"")
+	}
+	if c.RandomPages < 0 {
+		return fmt.Errorf("sampling.random_pages cannot be negative")
+	}
+	if c.Similarity == 0 {
+		c.Similarity = defaultTemplateSimilarity
+	}
+	if c.Similarity <= 0 || c.Similarity > 1 {
+		return fmt.Errorf("sampling.similarity must be between 0 and 1")
+	}
+	return nil
+}
+
+// SamplingReport records how the scanned pages were selected
+type SamplingReport struct {
+	Method           string         `json:"method"`
+	PagesExplored    int            `json:"pages_explored"`
+	Similarity       float64        `json:"similarity_threshold"`
+	Seed             int64          `json:"seed"`
+	Templates        []PageTemplate `json:"templates"`
+	StructuredSample []string       `json:"structured_sample"`
+	RandomSample     []string       `json:"random_sample"`
+	Rationale        []string       `json:"rationale"`
+}
+
+// PageTemplate is a cluster of structurally similar pages
+type PageTemplate struct {
+	ID             int      `json:"id"`
+	Pages          int      `json:"pages"`
+	Representative string   `json:"representative"`
+	Examples       []string `json:"examples"`
+
+	signature map[string]bool
+	members   []string
+}
+
+// structuralSignature returns the set of element paths in a document's body,
+// ignoring text, attributes and repetition so pages built from the same
+// template produce near-identical sets
+func structuralSignature(doc *html.Node) map[string]bool {
+	signature := make(map[string]bool)
+
+	var walk func(n *html.Node, path string, depth int)
+	walk = func(n *html.Node, path string, depth int) {
+		if depth > signatureMaxDepth {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data == "script" || c.Data == "style" {
+				continue
+			}
+			childPath := path + "/" + c.Data
+			signature[childPath] = true
+			walk(c, childPath, depth+1)
+		}
+	}
+
+	var findBody func(n *html.Node) *html.Node
+	findBody = func(n *html.Node) *html.Node {
+		if n.Type == html.ElementNode && n.Data == "body" {
+			return n
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if body := findBody(c); body != nil {
+				return body
+			}
+		}
+		return nil
+	}
+
+	if body := findBody(doc); body != nil {
+		walk(body, "body", 0)
+	}
+	return signature
+}
+
+// jaccard returns the Jaccard similarity of two signatures
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for path := range a {
+		if b[path] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// sampleAndScan explores the site, clusters pages into templates and scans a
+// WCAG-EM sample: one representative per template plus randomly selected pages
+func (s *AccessibilityScanner) sampleAndScan(ctx context.Context, result *ScanResult) {
+	report := &SamplingReport{
+		Method:     "wcag-em",
+		Similarity: s.sampling.Similarity,
+		Seed:       s.sampling.Seed,
+	}
+	if report.Seed == 0 {
+		report.Seed = time.Now().UnixNano()
+	}
+	result.Sampling = report
+
+	// A continued scan evaluates the rest of its sample; one stopped while
+	// exploring has no sample yet and starts over
+	if s.resumed != nil {
+		result.ResumedFrom = s.resumed.ScanID
+		if s.resumed.sampling != nil && len(s.resumed.Queue) > 0 {
+			*report = *s.resumed.sampling
+			result.PageResults = append(result.PageResults, s.resumed.pages...)
+			for _, page := range s.resumed.pages {
+				s.notifyPage(page)
+			}
+			s.evaluateSample(ctx, result, s.resumed.Queue)
+			return
+		}
+	}
+
+	// Explore: fetch up to max_pages pages breadth-first, recording their structure
+	explored := make([]string, 0)
+	start := s.startURL()
+	s.urls.Visit(start, 0)
+	s.urls.Discover(start)
+	s.urls.Push(start)
+
+	for s.urls.Len() > 0 && len(explored) < s.maxPages {
+		if ctx.Err() != nil {
+			s.stopSampling(ctx, result, nil)
+			return
+		}
+
+		batch := s.popBatch(min(s.maxPages-len(explored), linkBatchSize))
+		s.reportProgress(batch[0], 0)
+
+		found, err := s.extractLinksConcurrently(ctx, batch)
+		if err != nil {
+			s.stopSampling(ctx, result, nil)
+			return
+		}
+		for i, pageURL := range batch {
+			if vendor := challengeVendor(found[i].err); vendor != "" {
+				if s.challengeMode() == challengeAbort {
+					result.StopReason = "bot_challenge"
+					return
+				}
+				// Reported like a blocked crawl page, as it cannot be explored or sampled
+				page := blockedPage(pageURL, vendor)
+				page.Depth = s.urls.Depth(pageURL)
+				result.PageResults = append(result.PageResults, page)
+				s.notifyPage(page)
+				continue
+			}
+			if isThrottled(found[i].err) {
+				s.throttle.GiveUp(pageURL)
+			}
+			if found[i].err != nil {
+				continue
+			}
+			explored = append(explored, pageURL)
+
+			depth := s.urls.Depth(pageURL) + 1
+			for _, link := range found[i].links {
+				if s.skipsAMP(link) || s.skipsPaginated(link) {
+					continue
+				}
+				s.urls.Discover(link)
+				if s.urls.Visited() < s.maxPages && s.urls.Visit(link, depth) {
+					s.urls.Push(link)
+				}
+			}
+		}
+	}
+	report.PagesExplored = len(explored)
+
+	// Cluster: pages are visited shallowest first, so each template's
+	// representative is its page closest to the homepage
+	templates := make([]*PageTemplate, 0)
+	for _, pageURL := range explored {
+		signature := s.signatures[pageURL]
+		var match *PageTemplate
+		for _, template := range templates {
+			if jaccard(signature, template.signature) >= report.Similarity {
+				match = template
+				break
+			}
+		}
+		if match == nil {
+			match = &PageTemplate{ID: len(templates) + 1, Representative: pageURL, signature: signature}
+			templates = append(templates, match)
+		}
+		match.members = append(match.members, pageURL)
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return len(templates[i].members) > len(templates[j].members)
+	})
+
+	report.Templates = make([]PageTemplate, 0, len(templates))
+	selected := make(map[string]bool)
+	for _, template := range templates {
+		template.Pages = len(template.members)
+		template.Examples = template.members[:min(5, len(template.members))]
+		report.Templates = append(report.Templates, *template)
+
+		if len(report.StructuredSample) < s.limit {
+			report.StructuredSample = append(report.StructuredSample, template.Representative)
+			selected[template.Representative] = true
+		}
+	}
+
+	report.Rationale = append(report.Rationale, fmt.Sprintf(
+		"Explored %d pages and identified %d distinct templates (structural similarity ≥ %.2f).",
+		len(explored), len(templates), report.Similarity))
+	if blocked := len(result.PageResults); blocked > 0 {
+		report.Rationale = append(report.Rationale, fmt.Sprintf(
+			"%d pages were blocked by a bot challenge and could not be explored or sampled.", blocked))
+	}
+	if len(templates) > len(report.StructuredSample) {
+		report.Rationale = append(report.Rationale, fmt.Sprintf(
+			"The scan limit of %d pages covers only the %d most common templates; %d rarer templates were not sampled.",
+			s.limit, len(report.StructuredSample), len(templates)-len(report.StructuredSample)))
+	} else {
+		report.Rationale = append(report.Rationale, fmt.Sprintf(
+			"Structured sample: one representative page (closest to the homepage) for each of the %d templates.",
+			len(templates)))
+	}
+
+	// Random sample: by default 10% of the structured sample, as WCAG-EM suggests
+	pool := make([]string, 0)
+	for _, pageURL := range explored {
+		if !selected[pageURL] {
+			pool = append(pool, pageURL)
+		}
+	}
+	randomSize := s.sampling.RandomPages
+	if randomSize == 0 {
+		randomSize = max(1, int(math.Ceil(float64(len(report.StructuredSample))*0.1)))
+	}
+	randomSize = min(randomSize, len(pool), s.limit-len(report.StructuredSample))
+
+	rng := rand.New(rand.NewSource(report.Seed))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	report.RandomSample = pool[:max(0, randomSize)]
+
+	report.Rationale = append(report.Rationale, fmt.Sprintf(
+		"Random sample: %d additional pages drawn from the %d remaining explored pages with seed %d.",
+		len(report.RandomSample), len(pool), report.Seed))
+
+	// Evaluate the combined sample
+	s.evaluateSample(ctx, result, append(append([]string{}, report.StructuredSample...), report.RandomSample...))
+}
+
+// evaluateSample audits the sample pages in order
+func (s *AccessibilityScanner) evaluateSample(ctx context.Context, result *ScanResult, sample []string) {
+	for i, pageURL := range sample {
+		if s.job != nil {
+			pending := sample[i+1:]
+			s.job.update(pageURL, pending, len(pending), len(result.PageResults), s.urls.Visited(), s.lighthouseCalls)
+		}
+		if ctx.Err() != nil {
+			s.stopSampling(ctx, result, sample[i:])
+			return
+		}
+
+		if s.budgetExhausted() {
+			result.StopReason = "budget_exhausted"
+			s.captureSample(sample[i:])
+			break
+		}
+
+		pageCtx, cancelPage := context.WithTimeout(ctx, s.pageDeadline())
+		pageResult := s.scanPageWithLighthouse(pageCtx, pageURL)
+		s.addPageFindings(&pageResult)
+		if s.iframes {
+			s.auditFrames(pageCtx, &pageResult)
+		}
+		cancelPage()
+		s.markSlowPage(&pageResult)
+		s.applySeverity(&pageResult)
+		if ctx.Err() != nil {
+			s.stopSampling(ctx, result, sample[i:])
+			return
+		}
+		pageResult.Depth = s.urls.Depth(pageURL)
+		s.saveRawReport(&pageResult)
+		result.PageResults = append(result.PageResults, pageResult)
+		s.notifyPage(pageResult)
+
+		if !s.pause(ctx) {
+			s.stopSampling(ctx, result, sample[i+1:])
+			return
+		}
+	}
+
+	result.Sampling.Rationale = append(result.Sampling.Rationale, fmt.Sprintf("Evaluated %d pages in total.", len(result.PageResults)))
+}
+
+// stopSampling ends a sampled scan like the crawl's stop: a timeout saves the
+// sample pages not evaluated yet so the scan can be continued, anything else
+// cancels it
+func (s *AccessibilityScanner) stopSampling(ctx context.Context, result *ScanResult, pending []string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.StopReason = "timeout"
+		s.captureSample(pending)
+	} else {
+		result.Status = "cancelled"
+	}
+}
+
+// captureSample records the sample pages still to evaluate as the frontier
+// of a sampled scan. Without any, the continued scan explores the site again.
+func (s *AccessibilityScanner) captureSample(pending []string) {
+	s.frontier = &CrawlFrontier{
+		Token:     newScanID(),
+		CreatedAt: time.Now().UTC(),
+		Queue:     append([]string{}, pending...),
+		Visited:   append([]string{}, pending...),
+		Depth:     make(map[string]int, len(pending)),
+	}
+	for _, pageURL := range pending {
+		s.frontier.Depth[pageURL] = s.urls.Depth(pageURL)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// scanIndex keeps the headers of the stored scans in memory by site, so
+// finding a site's scans does not read and decrypt every stored record. It
+// is loaded from the store on first use and kept current by Save and Delete.
+type scanIndex struct {
+	mu     sync.Mutex
+	loaded bool
+	sites  map[string]map[string]storedScan // site to its scans by ID
+	siteOf map[string]string                // scan ID to its site
+}
+
+// loadIndex lists the store into the index unless that was already done.
+// The caller holds index.mu.
+func (s *ScanStore) loadIndex() error {
+	if s.index.loaded {
+		return nil
+	}
+	scans, err := s.List()
+	if err != nil {
+		return err
+	}
+	s.index.sites = make(map[string]map[string]storedScan)
+	s.index.siteOf = make(map[string]string, len(scans))
+	for _, scan := range scans {
+		if !scan.Unreadable {
+			s.index.add(scan)
+		}
+	}
+	s.index.loaded = true
+	return nil
+}
+
+// add records a scan in the index, replacing an earlier entry for its ID
+func (x *scanIndex) add(scan storedScan) {
+	x.remove(scan.ID)
+	if x.sites[scan.Site] == nil {
+		x.sites[scan.Site] = make(map[string]storedScan)
+	}
+	x.sites[scan.Site][scan.ID] = scan
+	x.siteOf[scan.ID] = scan.Site
+}
+
+// remove drops a scan from the index
+func (x *scanIndex) remove(id string) {
+	site, ok := x.siteOf[id]
+	if !ok {
+		return
+	}
+	delete(x.sites[site], id)
+	if len(x.sites[site]) == 0 {
+		delete(x.sites, site)
+	}
+	delete(x.siteOf, id)
+}
+
+// indexSaved updates a loaded index with a scan just saved
+func (s *ScanStore) indexSaved(result *ScanResult) {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+	if !s.index.loaded {
+		return
+	}
+	scanTime := result.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now()
+	}
+	s.index.add(storedScan{
+		ID:         result.ID,
+		Site:       siteKey(result.BaseURL),
+		ScanTime:   scanTime,
+		Status:     result.Status,
+		SiteScore:  result.SiteScore,
+		TotalPages: result.TotalPages,
+		Tags:       result.Tags,
+	})
+}
+
+// indexDeleted removes a deleted scan from a loaded index
+func (s *ScanStore) indexDeleted(id string) {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+	if s.index.loaded {
+		s.index.remove(id)
+	}
+}
+
+// PreviousSiteScan returns the ID of a site's newest completed or partial
+// scan from before a time, other than exclude, or "" if there is none
+func (s *ScanStore) PreviousSiteScan(site string, before time.Time, exclude string) (string, error) {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+	if err := s.loadIndex(); err != nil {
+		return "", err
+	}
+
+	var previous storedScan
+	for id, scan := range s.index.sites[site] {
+		if id == exclude || !scan.ScanTime.Before(before) || (scan.Status != "completed" && scan.Status != "partial") {
+			continue
+		}
+		if previous.ID == "" || scan.ScanTime.After(previous.ScanTime) {
+			previous = scan
+		}
+	}
+	return previous.ID, nil
+}
+
+// Version identifies the stored content of a scan without reading it: the
+// size and modification time of its record, and the site's previous scan,
+// which its links point to. It returns errScanNotFound for unknown scans.
+func (s *ScanStore) Version(id string) (string, error) {
+	if !scanIDPattern.MatchString(id) {
+		return "", errScanNotFound
+	}
+	s.mu.RLock()
+	info, err := os.Stat(s.path(id))
+	s.mu.RUnlock()
+	if os.IsNotExist(err) {
+		return "", errScanNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.index.mu.Lock()
+	err = s.loadIndex()
+	scan := s.index.sites[s.index.siteOf[id]][id]
+	s.index.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	previous := ""
+	if scan.ID != "" {
+		if previous, err = s.PreviousSiteScan(scan.Site, scan.ScanTime, id); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%s:%d:%d:%s", id, info.Size(), info.ModTime().UnixNano(), previous), nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Pagination limits for page and issue listings
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// PageIssue is an accessibility issue annotated with the page it was found on
+type PageIssue struct {
+	PageURL string `json:"page_url"`
+	AccessibilityIssue
+}
+
+// Link is a HAL hypermedia link
+type Link struct {
+	Href string `json:"href"`
+}
+
+// PaginatedResponse is a cursor-paginated list of items from a stored scan, or of stored scans
+type PaginatedResponse struct {
+	ScanID     string          `json:"scan_id,omitempty"`
+	Items      interface{}     `json:"items"`
+	Total      int             `json:"total"`
+	PageSize   int             `json:"page_size"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Links      map[string]Link `json:"_links"`
+}
+
+// loadStoredScan fetches the scan named in the request path, writing an error response on failure
+func loadStoredScan(w http.ResponseWriter, r *http.Request) (ScanResult, bool) {
+	result, err := scanStore.Get(r.PathValue("id"))
+	if errors.Is(err, errScanNotFound) {
+		sendError(w, "Scan not found", http.StatusNotFound, "No stored scan exists with this ID")
+		return result, false
+	}
+	if err != nil {
+		logAt(logLevelError, "Failed to load scan %s: %v", r.PathValue("id"), err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the stored scan")
+		return result, false
+	}
+	return result, true
+}
+
+// handleGetScan handles GET /api/v1/scans/{id} requests
+func handleGetScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	opts, err := parseResultOptions(r.URL.Query())
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+
+	writeScanResult(w, r, result, opts)
+}
+
+// handleScanPages handles GET /api/v1/scans/{id}/pages requests
+func handleScanPages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	opts, err := parseResultOptions(r.URL.Query())
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Format != "" {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "format is only supported on full scan results")
+		return
+	}
+
+	start, pageSize, err := parsePagination(r)
+	if err != nil {
+		sendError(w, "Invalid pagination", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+
+	pages := sortPages(opts.Filter.apply(result.PageResults), opts.Sort)
+	end := min(start+pageSize, len(pages))
+	start = min(start, end)
+
+	writePaginated(w, r, result.ID, pages[start:end], len(pages), start, pageSize)
+}
+
+// StoredPage is one page result of a stored scan
+type StoredPage struct {
+	ScanID string `json:"scan_id"`
+	Index  int    `json:"index"` // position in page_results
+	PageResult
+	Links map[string]Link `json:"_links"`
+}
+
+// writeStoredPage writes the page at an index of a stored scan's page results
+func writeStoredPage(w http.ResponseWriter, r *http.Request, result ScanResult, index int) {
+	page := StoredPage{
+		ScanID:     result.ID,
+		Index:      index,
+		PageResult: result.PageResults[index],
+		Links:      storedPageLinks(apiVersion(r), &result, index),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// handleScanPage handles GET /api/v1/scans/{id}/pages/{n} requests, serving
+// the nth page of page_results without the rest of the scan
+func handleScanPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 0 || n >= len(result.PageResults) {
+		sendError(w, "Page not found", http.StatusNotFound, "The scan has no page at this index of page_results")
+		return
+	}
+	writeStoredPage(w, r, result, n)
+}
+
+// handleScanPageByURL handles GET /api/v1/scans/{id}/page?url= requests.
+// URLs match as scanned or after normalization, so a trailing slash or a
+// fragment does not matter.
+func handleScanPageByURL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	pageURL := r.URL.Query().Get("url")
+	parsed, err := url.Parse(pageURL)
+	if pageURL == "" || err != nil || parsed.Host == "" {
+		sendError(w, "Invalid URL", http.StatusBadRequest, "url must be the absolute URL of a scanned page")
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+	normalizer := currentConfig().urls
+	wanted := normalizer.normalize(parsed).String()
+	match := -1
+	for i, page := range result.PageResults {
+		if page.URL == pageURL {
+			match = i
+			break
+		}
+		if scanned, err := url.Parse(page.URL); err == nil && match < 0 && normalizer.normalize(scanned).String() == wanted {
+			match = i
+		}
+	}
+	if match < 0 {
+		sendError(w, "Page not found", http.StatusNotFound, "The scan has no result for this URL")
+		return
+	}
+	writeStoredPage(w, r, result, match)
+}
+
+// handleScanIssues handles GET /api/v1/scans/{id}/issues requests
+func handleScanIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	opts, err := parseResultOptions(r.URL.Query())
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Format != "" {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "format is only supported on full scan results")
+		return
+	}
+
+	start, pageSize, err := parsePagination(r)
+	if err != nil {
+		sendError(w, "Invalid pagination", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+
+	issues := make([]PageIssue, 0)
+	for _, page := range sortPages(opts.Filter.apply(result.PageResults), opts.Sort) {
+		for _, issue := range page.Issues {
+			issues = append(issues, PageIssue{PageURL: page.URL, AccessibilityIssue: issue})
+		}
+	}
+
+	// Most critical issues first across the whole site, not just within each page
+	if opts.Sort == "severity" {
+		sort.SliceStable(issues, func(i, j int) bool {
+			return issueWeight(issues[i].AccessibilityIssue) > issueWeight(issues[j].AccessibilityIssue)
+		})
+	}
+
+	end := min(start+pageSize, len(issues))
+	start = min(start, end)
+
+	writePaginated(w, r, result.ID, issues[start:end], len(issues), start, pageSize)
+}
+
+// parsePagination reads the cursor and page_size query parameters
+func parsePagination(r *http.Request) (start, pageSize int, err error) {
+	pageSize = defaultPageSize
+	if value := r.URL.Query().Get("page_size"); value != "" {
+		pageSize, err = strconv.Atoi(value)
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			return 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		start, err = decodeCursor(cursor)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cursor is invalid")
+		}
+	}
+
+	return start, pageSize, nil
+}
+
+// encodeCursor returns an opaque cursor pointing at an item offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the item offset encoded in a cursor
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor offset")
+	}
+	return offset, nil
+}
+
+// writePaginated sends one page of items with self and next links
+func writePaginated(w http.ResponseWriter, r *http.Request, scanID string, items interface{}, total, start, pageSize int) {
+	response := PaginatedResponse{
+		ScanID:   scanID,
+		Items:    items,
+		Total:    total,
+		PageSize: pageSize,
+		Links: map[string]Link{
+			"self": {Href: r.URL.RequestURI()},
+		},
+	}
+
+	if start+pageSize < total {
+		response.NextCursor = encodeCursor(start + pageSize)
+
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", response.NextCursor)
+		query.Set("page_size", strconv.Itoa(pageSize))
+		next.RawQuery = query.Encode()
+		response.Links["next"] = Link{Href: next.RequestURI()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
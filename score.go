@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"net/url"
+)
+
+// impactWeights is the relative severity of each axe impact level
+var impactWeights = map[string]float64{
+	"critical": 1.0,
+	"serious":  0.6,
+	"moderate": 0.3,
+	"minor":    0.1,
+}
+
+// defaultImpactWeight applies to issues without a known impact level
+const defaultImpactWeight = 0.2
+
+// impactSaturation controls how quickly issue penalties approach their maximum;
+// a page whose weighted issues sum to this value loses half of its score
+const impactSaturation = 10.0
+
+// computeSiteScore returns a site-level score in the range 0-1.
+//
+// Each page contributes its Lighthouse score reduced by an impact penalty
+// that saturates as weighted issues accumulate. Pages are weighted by their
+// traffic hint when one is provided, or else by how close to the homepage they
+// were discovered, so a failing checkout page outweighs a deep archive page.
+func computeSiteScore(pages []PageResult, trafficHints map[string]float64) float64 {
+	weightTotal := 0.0
+	scoreTotal := 0.0
+
+	for _, page := range pages {
+		if page.Error != "" {
+			continue
+		}
+
+		weight := pageWeight(page, trafficHints)
+		if weight <= 0 {
+			continue
+		}
+
+		impact := 0.0
+		for _, issue := range page.Issues {
+			if !isFalsePositive(issue) {
+				impact += issueWeight(issue)
+			}
+		}
+		penalty := impact / (impact + impactSaturation)
+
+		scoreTotal += weight * page.AccessibilityScore * (1 - penalty)
+		weightTotal += weight
+	}
+
+	if weightTotal == 0 {
+		return 0
+	}
+	return math.Round(scoreTotal/weightTotal*1000) / 1000
+}
+
+// pageWeight returns the weight of a page from traffic hints or discovery depth
+func pageWeight(page PageResult, trafficHints map[string]float64) float64 {
+	if len(trafficHints) > 0 {
+		if weight, ok := trafficHints[page.URL]; ok {
+			return weight
+		}
+		if parsed, err := url.Parse(page.URL); err == nil {
+			if weight, ok := trafficHints[parsed.Path]; ok {
+				return weight
+			}
+		}
+	}
+	return 1 / float64(1+page.Depth)
+}
+
+// issueWeight returns the severity weight of a single issue
+func issueWeight(issue AccessibilityIssue) float64 {
+	if weight, ok := impactWeights[issue.Impact]; ok {
+		return weight
+	}
+	return defaultImpactWeight
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret provider settings
+const (
+	secretFetchTimeout           = 10 * time.Second
+	defaultSecretsRefreshMinutes = 60
+	maxSecretResponseLength      = 1 << 20
+)
+
+// secretProvider fetches a secret from a secrets manager. ref is the part of
+// a secret reference after "<provider>:", optionally ending in "#<key>" to
+// pick one field of a JSON secret.
+type secretProvider interface {
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// secretProviders are the providers secret references can name, by prefix
+var secretProviders = map[string]secretProvider{
+	"file":  fileSecrets{},
+	"vault": vaultSecrets{},
+	"awssm": awsSecrets{},
+	"gcpsm": gcpSecrets{},
+}
+
+// parseSecretRef splits a secret reference such as vault:secret/data/app#key
+// into its provider and reference. Other values are not references.
+func parseSecretRef(value string) (secretProvider, string, bool) {
+	name, ref, ok := strings.Cut(value, ":")
+	if !ok || ref == "" {
+		return nil, "", false
+	}
+	provider, ok := secretProviders[name]
+	return provider, ref, ok
+}
+
+// fetchSecret resolves a secret reference
+func fetchSecret(value string) (string, error) {
+	provider, ref, ok := parseSecretRef(value)
+	if !ok {
+		return "", errors.New("not a secret reference")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretFetchTimeout)
+	defer cancel()
+	secret, err := provider.Fetch(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", strings.SplitN(value, "#", 2)[0], err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%s: secret is empty", strings.SplitN(value, "#", 2)[0])
+	}
+	return secret, nil
+}
+
+// secretField is a config setting whose value came from a secrets manager
+type secretField struct {
+	name string
+	ref  string
+	set  func(c *Config, value string)
+}
+
+// secretFields lists the settings that may hold secret references
+func (c *Config) secretFields() []secretField {
+	fields := []secretField{
+		{"google_api_key", c.GoogleAPIKey, func(c *Config, v string) { c.GoogleAPIKey = v }},
+		{"admin_token", c.AdminToken, func(c *Config, v string) { c.AdminToken = v }},
+		{"share_secret", c.ShareSecret, func(c *Config, v string) { c.ShareSecret = v }},
+		{"event_bus.kafka_api_key", c.EventBus.KafkaAPIKey, func(c *Config, v string) { c.EventBus.KafkaAPIKey = v }},
+		{"event_bus.kafka_secret", c.EventBus.KafkaSecret, func(c *Config, v string) { c.EventBus.KafkaSecret = v }},
+	}
+	for i, client := range c.APIKeys {
+		fields = append(fields, secretField{fmt.Sprintf("api_keys[%d].key", i), client.Key, func(c *Config, v string) { c.APIKeys[i].Key = v }})
+	}
+	for i, key := range c.EncryptionKeys {
+		fields = append(fields, secretField{fmt.Sprintf("encryption_keys[%d]", i), key, func(c *Config, v string) { c.EncryptionKeys[i] = v }})
+	}
+	return fields
+}
+
+// resolveSecrets replaces secret references in the settings with the secrets
+// they name, remembering the references for refreshes
+func (c *Config) resolveSecrets() error {
+	for _, field := range c.secretFields() {
+		if _, _, ok := parseSecretRef(field.ref); !ok {
+			continue
+		}
+		value, err := fetchSecret(field.ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+		field.set(c, value)
+		c.secrets = append(c.secrets, field)
+	}
+	return nil
+}
+
+// refreshSecrets fetches the secrets of the active configuration again, so
+// rotated secrets are picked up without a reload. A secret that cannot be
+// fetched keeps its current value. If the configuration is reloaded while
+// the secrets are fetched, the reload wins: it fetched them itself.
+func refreshSecrets() {
+	previous := currentConfig()
+	if len(previous.secrets) == 0 {
+		return
+	}
+	next := *previous
+	next.APIKeys = slices.Clone(previous.APIKeys)
+	next.EncryptionKeys = slices.Clone(previous.EncryptionKeys)
+	changed := 0
+	for _, field := range previous.secrets {
+		value, err := fetchSecret(field.ref)
+		if err != nil {
+			logAt(logLevelWarn, "Warning: Could not refresh secret %s, keeping the current value: %v", field.name, err)
+			continue
+		}
+		field.set(&next, value)
+		changed++
+	}
+	if keys, err := parseEncryptionKeys(next.EncryptionKeys); err != nil {
+		logAt(logLevelWarn, "Warning: Refreshed encryption keys are invalid, keeping the current keys: %v", err)
+		next.EncryptionKeys, next.keys = previous.EncryptionKeys, previous.keys
+	} else {
+		next.keys = keys
+	}
+	if !activeConfig.CompareAndSwap(previous, &next) {
+		logAt(logLevelDebug, "Configuration reloaded while refreshing secrets, keeping the reloaded settings")
+		return
+	}
+	logAt(logLevelDebug, "Refreshed %d of %d secrets", changed, len(previous.secrets))
+}
+
+// runSecretRefresher refreshes secrets every secrets_refresh_minutes
+func runSecretRefresher() {
+	for {
+		time.Sleep(time.Duration(currentConfig().SecretsRefreshMinutes) * time.Minute)
+		refreshSecrets()
+	}
+}
+
+// cachedSecret is a secret fetched for a webhook
+type cachedSecret struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// webhookSecrets caches the secrets webhook signing secrets refer to
+var webhookSecrets = struct {
+	sync.Mutex
+	values map[string]cachedSecret
+}{values: make(map[string]cachedSecret)}
+
+// resolveWebhookSecret returns the signing secret of a webhook. A secret
+// reference is fetched and cached for secrets_refresh_minutes.
+func resolveWebhookSecret(secret string) (string, error) {
+	if _, _, ok := parseSecretRef(secret); !ok {
+		return secret, nil
+	}
+	webhookSecrets.Lock()
+	cached, ok := webhookSecrets.values[secret]
+	webhookSecrets.Unlock()
+	if ok && time.Since(cached.fetchedAt) < time.Duration(currentConfig().SecretsRefreshMinutes)*time.Minute {
+		return cached.value, nil
+	}
+
+	value, err := fetchSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	webhookSecrets.Lock()
+	webhookSecrets.values[secret] = cachedSecret{value: value, fetchedAt: time.Now()}
+	webhookSecrets.Unlock()
+	return value, nil
+}
+
+// secretKey picks one field of a JSON secret when the reference ends in #key
+func secretKey(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so it has no key %q", key)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string key %q", key)
+	}
+	return value, nil
+}
+
+// getSecretJSON sends a secrets manager request and decodes its JSON answer
+func getSecretJSON(req *http.Request, v interface{}) error {
+	resp, err := newOutboundClient(secretFetchTimeout).Do(req)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer drainAndClose(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSecretResponseLength))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP error %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// fileSecrets reads secrets from files, such as Docker and Kubernetes
+// secrets: file:/run/secrets/google_api_key
+type fileSecrets struct{}
+
+func (fileSecrets) Fetch(ctx context.Context, ref string) (string, error) {
+	path, key, _ := strings.Cut(ref, "#")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return secretKey(strings.TrimRight(string(data), "\r\n"), key)
+}
+
+// vaultSecrets reads HashiCorp Vault KV secrets, version 1 or 2, with
+// VAULT_ADDR, VAULT_TOKEN and optionally VAULT_NAMESPACE:
+// vault:secret/data/scanner#google_api_key
+type vaultSecrets struct{}
+
+func (vaultSecrets) Fetch(ctx context.Context, ref string) (string, error) {
+	path, key, _ := strings.Cut(ref, "#")
+	address, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if address == "" || token == "" {
+		return "", errors.New("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	if key == "" {
+		return "", errors.New("vault references need a #key")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(address, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	var answer struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := getSecretJSON(req, &answer); err != nil {
+		return "", err
+	}
+	fields := answer.Data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		fields = nested // KV version 2
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string key %q", key)
+	}
+	return value, nil
+}
+
+// awsSecrets reads AWS Secrets Manager secrets with the credentials and region
+// of the standard AWS_* environment variables: awssm:prod/scanner#google_api_key
+type awsSecrets struct{}
+
+func (awsSecrets) Fetch(ctx context.Context, ref string) (string, error) {
+	id, key, _ := strings.Cut(ref, "#")
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	accessKey, secretAccessKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if region == "" || accessKey == "" || secretAccessKey == "" {
+		return "", errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	body, _ := json.Marshal(map[string]string{"SecretId": id})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, "secretsmanager", region, accessKey, secretAccessKey, os.Getenv("AWS_SESSION_TOKEN"), time.Now())
+
+	var answer struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := getSecretJSON(req, &answer); err != nil {
+		return "", err
+	}
+	return secretKey(answer.SecretString, key)
+}
+
+// signAWSRequest signs a request with AWS Signature Version 4
+func signAWSRequest(req *http.Request, body []byte, service, region, accessKey, secretAccessKey, sessionToken string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	sign := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+	signingKey := sign(sign(sign(sign([]byte("AWS4"+secretAccessKey), date), region), service), "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, hex.EncodeToString(sign(signingKey, stringToSign))))
+}
+
+// gcpSecrets reads Google Cloud Secret Manager secrets, with GCP_ACCESS_TOKEN
+// or the service account of the metadata server:
+// gcpsm:projects/my-project/secrets/scanner-api-key (latest version)
+type gcpSecrets struct{}
+
+func (gcpSecrets) Fetch(ctx context.Context, ref string) (string, error) {
+	name, key, _ := strings.Cut(ref, "#")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token := os.Getenv("GCP_ACCESS_TOKEN")
+	if token == "" {
+		metadata, err := gcpMetadataToken(ctx)
+		if err != nil {
+			return "", fmt.Errorf("no GCP_ACCESS_TOKEN and no metadata server token: %w", err)
+		}
+		token = metadata.AccessToken
+	}
+
+	endpoint := "https://secretmanager.googleapis.com/v1/" + strings.TrimLeft(name, "/") + ":access"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var answer struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := getSecretJSON(req, &answer); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(answer.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("secret payload is not base64: %w", err)
+	}
+	return secretKey(string(data), key)
+}
+
+// gcpMetadataToken gets an access token for the instance's service account
+func gcpMetadataToken(ctx context.Context) (googleToken, error) {
+	var token googleToken
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	endpoint := (&url.URL{Scheme: "http", Host: host, Path: "/computeMetadata/v1/instance/service-accounts/default/token"}).String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return token, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	err = getSecretJSON(req, &token)
+	return token, err
+}
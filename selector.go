@@ -0,0 +1,414 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// cssSelector is a parsed selector list such as "table, div[role=table]". It
+// supports type, universal, ID, class and attribute selectors, :not() of
+// those, and the descendant and child combinators.
+type cssSelector []complexSelector
+
+// complexSelector is a chain of compound selectors; combinators[i] joins
+// parts[i] and parts[i+1] and is ' ' (descendant) or '>' (child)
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+// compoundSelector matches one element
+type compoundSelector struct {
+	tag     string // empty or "*" for any element
+	id      string
+	classes []string
+	attrs   []attrSelector
+	not     []compoundSelector
+}
+
+// attrSelector matches an attribute: present (op ""), or compared to value
+type attrSelector struct {
+	name  string
+	op    string
+	value string
+}
+
+// parseSelector parses a CSS selector list
+func parseSelector(selector string) (cssSelector, error) {
+	p := &selectorParser{input: strings.TrimSpace(selector)}
+	if p.input == "" {
+		return nil, fmt.Errorf("selector is empty")
+	}
+	var list cssSelector
+	for {
+		complex, err := p.complex()
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", selector, err)
+		}
+		list = append(list, complex)
+		p.skipSpace()
+		if p.done() {
+			return list, nil
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("selector %q: unexpected %q", selector, p.peek())
+		}
+		p.pos++
+	}
+}
+
+// selectorParser reads a selector one character at a time
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) done() bool { return p.pos >= len(p.input) }
+func (p *selectorParser) peek() byte { return p.input[p.pos] }
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t\n\r\f", p.peek()) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// complex reads compound selectors joined by combinators, up to a comma
+func (p *selectorParser) complex() (complexSelector, error) {
+	var complex complexSelector
+	p.skipSpace()
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return complex, err
+		}
+		complex.parts = append(complex.parts, compound)
+
+		spaced := p.skipSpace()
+		if p.done() || p.peek() == ',' || p.peek() == ')' {
+			return complex, nil
+		}
+		switch p.peek() {
+		case '>':
+			p.pos++
+			p.skipSpace()
+			complex.combinators = append(complex.combinators, '>')
+		case '+', '~':
+			return complex, fmt.Errorf("the %q combinator is not supported", p.peek())
+		default:
+			if !spaced {
+				return complex, fmt.Errorf("unexpected %q", p.peek())
+			}
+			complex.combinators = append(complex.combinators, ' ')
+		}
+	}
+}
+
+// compound reads a type selector followed by ID, class, attribute and :not()
+// selectors
+func (p *selectorParser) compound() (compoundSelector, error) {
+	var compound compoundSelector
+	if !p.done() && p.peek() == '*' {
+		p.pos++
+		compound.tag = "*"
+	} else {
+		compound.tag = strings.ToLower(p.name())
+	}
+	for !p.done() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			if compound.id = p.name(); compound.id == "" {
+				return compound, fmt.Errorf("'#' must be followed by an ID")
+			}
+		case '.':
+			p.pos++
+			class := p.name()
+			if class == "" {
+				return compound, fmt.Errorf("'.' must be followed by a class name")
+			}
+			compound.classes = append(compound.classes, class)
+		case '[':
+			attr, err := p.attribute()
+			if err != nil {
+				return compound, err
+			}
+			compound.attrs = append(compound.attrs, attr)
+		case ':':
+			if !strings.HasPrefix(p.input[p.pos:], ":not(") {
+				return compound, fmt.Errorf("pseudo-classes other than :not() are not supported")
+			}
+			p.pos += len(":not(")
+			p.skipSpace()
+			negated, err := p.compound()
+			if err != nil {
+				return compound, err
+			}
+			p.skipSpace()
+			if p.done() || p.peek() != ')' {
+				return compound, fmt.Errorf(":not() takes one compound selector")
+			}
+			p.pos++
+			compound.not = append(compound.not, negated)
+		default:
+			if compound.tag == "" && compound.id == "" && len(compound.classes) == 0 && len(compound.attrs) == 0 && len(compound.not) == 0 {
+				return compound, fmt.Errorf("unexpected %q", p.peek())
+			}
+			return compound, nil
+		}
+	}
+	if compound.tag == "" && compound.id == "" && len(compound.classes) == 0 && len(compound.attrs) == 0 && len(compound.not) == 0 {
+		return compound, fmt.Errorf("a selector is missing")
+	}
+	return compound, nil
+}
+
+// name reads an identifier: a tag, ID, class or attribute name
+func (p *selectorParser) name() string {
+	start := p.pos
+	for !p.done() {
+		c := p.peek()
+		if c == '-' || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80 {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.input[start:p.pos]
+}
+
+// attribute reads [name], [name=value] and the ~=, |=, ^=, $= and *=
+// comparisons; values may be quoted
+func (p *selectorParser) attribute() (attrSelector, error) {
+	p.pos++ // [
+	p.skipSpace()
+	attr := attrSelector{name: strings.ToLower(p.name())}
+	if attr.name == "" {
+		return attr, fmt.Errorf("'[' must be followed by an attribute name")
+	}
+	p.skipSpace()
+	if p.done() {
+		return attr, fmt.Errorf("unclosed '['")
+	}
+	if p.peek() == ']' {
+		p.pos++
+		return attr, nil
+	}
+
+	if strings.IndexByte("~|^$*", p.peek()) >= 0 {
+		attr.op = p.input[p.pos : p.pos+1]
+		p.pos++
+	}
+	if p.done() || p.peek() != '=' {
+		return attr, fmt.Errorf("invalid attribute selector")
+	}
+	attr.op += "="
+	p.pos++
+	p.skipSpace()
+
+	if !p.done() && (p.peek() == '"' || p.peek() == '\'') {
+		quote := p.peek()
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return attr, fmt.Errorf("unclosed quote")
+		}
+		attr.value = p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		attr.value = p.name()
+	}
+	p.skipSpace()
+	if p.done() || p.peek() != ']' {
+		return attr, fmt.Errorf("unclosed '['")
+	}
+	p.pos++
+	return attr, nil
+}
+
+// matches reports whether an element matches any selector of the list
+func (s cssSelector) matches(n *html.Node) bool {
+	for _, complex := range s {
+		if complex.matchesAt(n, len(complex.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAt matches parts[0..k] right to left, parts[k] against n
+func (c complexSelector) matchesAt(n *html.Node, k int) bool {
+	if !c.parts[k].matches(n) {
+		return false
+	}
+	if k == 0 {
+		return true
+	}
+	for parent := n.Parent; parent != nil && parent.Type == html.ElementNode; parent = parent.Parent {
+		if c.matchesAt(parent, k-1) {
+			return true
+		}
+		if c.combinators[k-1] == '>' {
+			return false
+		}
+	}
+	return false
+}
+
+// matches reports whether an element matches the compound selector
+func (c compoundSelector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (c.tag != "" && c.tag != "*" && n.Data != c.tag) {
+		return false
+	}
+	if c.id != "" && attrValue(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attrValue(n, "class"))
+		for _, class := range c.classes {
+			if !slices.Contains(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.matches(n) {
+			return false
+		}
+	}
+	for _, negated := range c.not {
+		if negated.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches compares an element's attribute
+func (a attrSelector) matches(n *html.Node) bool {
+	value, ok := attrLookup(n, a.name)
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		return slices.Contains(strings.Fields(value), a.value)
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return false
+}
+
+// selectAll returns the elements under root matching a selector, in document order
+func (s cssSelector) selectAll(root *html.Node) []*html.Node {
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && s.matches(n) {
+			found = append(found, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		walk(c)
+	}
+	return found
+}
+
+// attrLookup returns an attribute of an element and whether it is set
+func attrLookup(n *html.Node, name string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// attrValue returns an attribute of an element, or "" when it is not set
+func attrValue(n *html.Node, name string) string {
+	value, _ := attrLookup(n, name)
+	return value
+}
+
+// elementPath builds a selector locating an element in its document, from
+// the nearest ancestor with an ID, such as "#content > table:nth-of-type(2)"
+func elementPath(n *html.Node) string {
+	return elementPathFrom(n, nil)
+}
+
+// elementPathFrom builds a selector like elementPath, starting only from
+// IDs usable accepts, so elements sharing an ID get selectors of their own
+func elementPathFrom(n *html.Node, usable func(id string) bool) string {
+	var steps []string
+	for ; n != nil && n.Type == html.ElementNode && n.Parent != nil; n = n.Parent {
+		if id := attrValue(n, "id"); id != "" && strings.IndexFunc(id, func(r rune) bool { return r == ' ' || r == '"' }) < 0 && (usable == nil || usable(id)) {
+			steps = append(steps, "#"+id)
+			break
+		}
+		step := n.Data
+		index, count := 0, 0
+		for sibling := n.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+			if sibling.Type == html.ElementNode && sibling.Data == n.Data {
+				count++
+				if sibling == n {
+					index = count
+				}
+			}
+		}
+		if count > 1 {
+			step += fmt.Sprintf(":nth-of-type(%d)", index)
+		}
+		steps = append(steps, step)
+	}
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return strings.Join(steps, " > ")
+}
+
+// maxSnippetLength caps the markup of an element reported in an issue
+const maxSnippetLength = 200
+
+// elementSnippet renders the start tag of an element, the way Lighthouse
+// reports an issue's node
+func elementSnippet(n *html.Node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, attr := range n.Attr {
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	b.WriteString(">")
+	snippet := b.String()
+	if len(snippet) > maxSnippetLength {
+		snippet = truncateUTF8(snippet, maxSnippetLength) + "…>"
+	}
+	return snippet
+}
+
+// truncateUTF8 cuts a string to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+)
+
+// validateSeverityPolicy checks the severity overrides and ignored audits of a
+// scan: audit IDs mapped to impact levels, and audit IDs
+func validateSeverityPolicy(overrides map[string]string, ignore []string) error {
+	auditIDs := make([]string, 0, len(overrides))
+	for auditID := range overrides {
+		auditIDs = append(auditIDs, auditID)
+	}
+	sort.Strings(auditIDs)
+	for _, auditID := range auditIDs {
+		if !auditIDPattern.MatchString(auditID) {
+			return fmt.Errorf("severity_overrides: %q is not an audit ID such as \"image-alt\"", auditID)
+		}
+		if _, ok := impactWeights[overrides[auditID]]; !ok {
+			return fmt.Errorf("severity_overrides.%s must be critical, serious, moderate or minor", auditID)
+		}
+	}
+	for _, auditID := range ignore {
+		if !auditIDPattern.MatchString(auditID) {
+			return fmt.Errorf("ignore_audits: %q is not an audit ID such as \"image-alt\"", auditID)
+		}
+	}
+	return nil
+}
+
+// without returns the audit set less some audits. Ignored audits leave the
+// Lighthouse score as well as the issues.
+func (a *auditSet) without(auditIDs []string) *auditSet {
+	if len(auditIDs) == 0 {
+		return a
+	}
+	set := &auditSet{skip: make(map[string]bool)}
+	if a != nil {
+		set.only = a.only
+		maps.Copy(set.skip, a.skip)
+	}
+	for _, auditID := range auditIDs {
+		set.skip[auditID] = true
+	}
+	return set
+}
+
+// reportsAudit reports whether the scan's results include the issues of an
+// audit, so that an issue of it missing from a page was fixed rather than
+// left out by ignore_audits or the Lighthouse config
+func (s *AccessibilityScanner) reportsAudit(auditID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ignoreAudits[auditID] && !s.skippedAudits[auditID]
+}
+
+// applySeverity drops the issues of ignored audits from a page, including
+// custom rule and iframe issues, and remaps the impact of overridden ones. The
+// original impact is kept on the issue.
+func (s *AccessibilityScanner) applySeverity(page *PageResult) {
+	if len(s.severityOverrides) == 0 && len(s.ignoreAudits) == 0 {
+		return
+	}
+	issues := page.Issues[:0]
+	for _, issue := range page.Issues {
+		if s.ignoreAudits[issue.AuditID] {
+			continue
+		}
+		if impact, ok := s.severityOverrides[issue.AuditID]; ok && impact != issue.Impact {
+			issue.OriginalImpact = issue.Impact
+			issue.Impact = impact
+		}
+		issues = append(issues, issue)
+	}
+	page.Issues = issues
+}
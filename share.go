@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Share link lifetimes
+const (
+	defaultShareHours = 7 * 24
+	maxShareHours     = 90 * 24
+	minShareSecretLen = 16
+)
+
+// Errors verifying a share token
+var (
+	errShareInvalid = errors.New("share link is invalid")
+	errShareExpired = errors.New("share link has expired")
+)
+
+// shareClaims are the signed contents of a share token
+type shareClaims struct {
+	ScanID    string `json:"s"`
+	ExpiresAt int64  `json:"e"` // Unix seconds
+	Product   string `json:"p,omitempty"`
+	Vendor    string `json:"v,omitempty"`
+	Contact   string `json:"c,omitempty"`
+}
+
+// ShareRequest is the optional body of POST /api/v1/scans/{id}/share
+type ShareRequest struct {
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"` // default 168 (7 days), at most 2160 (90 days)
+	Product        string `json:"product,omitempty"`          // shown in the report, as for the VPAT endpoint
+	Vendor         string `json:"vendor,omitempty"`
+	Contact        string `json:"contact,omitempty"`
+}
+
+// ShareLink is a signed URL serving a scan's HTML report without authentication
+type ShareLink struct {
+	ScanID    string    `json:"scan_id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// signShare returns the base64url HMAC-SHA256 of a token payload
+func signShare(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newShareToken encodes and signs share claims as <payload>.<signature>
+func newShareToken(secret string, claims shareClaims) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signShare(secret, payload), nil
+}
+
+// verifyShareToken checks a share token's signature and expiry
+func verifyShareToken(secret, token string, now time.Time) (shareClaims, error) {
+	var claims shareClaims
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signShare(secret, payload))) {
+		return claims, errShareInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, &claims) != nil || !scanIDPattern.MatchString(claims.ScanID) {
+		return claims, errShareInvalid
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, errShareExpired
+	}
+	return claims, nil
+}
+
+// requestOrigin returns the scheme and host the client addressed, honoring
+// X-Forwarded-Proto and X-Forwarded-Host from trusted proxies only
+func requestOrigin(r *http.Request) string {
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if ip := net.ParseIP(remote); ip != nil && isTrustedProxy(ip) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			host = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	return scheme + "://" + host
+}
+
+// handleShareScan handles POST /api/v1/scans/{id}/share requests, creating a
+// time-limited link to the scan's HTML report for people without an API key
+func handleShareScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+	secret := currentConfig().ShareSecret
+	if secret == "" {
+		sendError(w, "Sharing not configured", http.StatusConflict, "Set SHARE_SECRET to create share links")
+		return
+	}
+
+	var req ShareRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+			return
+		}
+	}
+	if req.ExpiresInHours == 0 {
+		req.ExpiresInHours = defaultShareHours
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxShareHours {
+		sendError(w, "Invalid expires_in_hours", http.StatusBadRequest, fmt.Sprintf("expires_in_hours must be between 1 and %d", maxShareHours))
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+	expiresAt := time.Now().UTC().Add(time.Duration(req.ExpiresInHours) * time.Hour).Truncate(time.Second)
+	token, err := newShareToken(secret, shareClaims{
+		ScanID:    result.ID,
+		ExpiresAt: expiresAt.Unix(),
+		Product:   req.Product,
+		Vendor:    req.Vendor,
+		Contact:   req.Contact,
+	})
+	if err != nil {
+		sendError(w, "Encoding error", http.StatusInternalServerError, "Could not create the share link")
+		return
+	}
+	writeJSON(w, http.StatusCreated, ShareLink{
+		ScanID:    result.ID,
+		URL:       requestOrigin(r) + "/share/" + token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// handleSharedReport handles GET /share/{token} requests, serving the HTML
+// report of a shared scan without authentication while the link is valid
+func handleSharedReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	secret := currentConfig().ShareSecret
+	if secret == "" {
+		sendError(w, "Share link invalid", http.StatusForbidden, "Sharing is disabled on this service")
+		return
+	}
+	claims, err := verifyShareToken(secret, r.PathValue("token"), time.Now())
+	if errors.Is(err, errShareExpired) {
+		sendError(w, "Share link expired", http.StatusGone, "This share link has expired; ask for a new one")
+		return
+	}
+	if err != nil {
+		sendError(w, "Share link invalid", http.StatusForbidden, "This share link is not valid")
+		return
+	}
+
+	result, err := scanStore.Get(claims.ScanID)
+	if errors.Is(err, errScanNotFound) {
+		sendError(w, "Scan not found", http.StatusNotFound, "The shared scan no longer exists")
+		return
+	}
+	if err != nil {
+		logAt(logLevelError, "Failed to load shared scan %s: %v", claims.ScanID, err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the shared scan")
+		return
+	}
+
+	// Keep the token out of search engines, caches and Referer headers
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "private, no-store")
+	writeACRHTML(w, buildACRReport(result, claims.Product, claims.Vendor, claims.Contact))
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Site Health test statuses, in increasing order of severity
+const (
+	siteHealthGood        = "good"
+	siteHealthRecommended = "recommended"
+	siteHealthCritical    = "critical"
+)
+
+// siteHealthTestPrefix namespaces the test names so they never clash with
+// WordPress core or other plugins
+const siteHealthTestPrefix = "accessibility_scanner_"
+
+// siteHealthBadge groups every test under one badge in wp-admin
+var siteHealthBadge = SiteHealthBadge{Label: "Accessibility", Color: "blue"}
+
+// errNoSiteScan is returned when a site has no finished scan to report on
+var errNoSiteScan = errors.New("no finished scan of this site")
+
+// SiteHealthTest is one test result in the schema WordPress Site Health
+// expects from a test callback. Description and actions are HTML.
+type SiteHealthTest struct {
+	Label       string          `json:"label"`
+	Status      string          `json:"status"` // "good", "recommended" or "critical"
+	Badge       SiteHealthBadge `json:"badge"`
+	Description string          `json:"description"`
+	Actions     string          `json:"actions"`
+	Test        string          `json:"test"`
+}
+
+// SiteHealthBadge is the category badge shown next to a test
+type SiteHealthBadge struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// SiteHealthReport is the Site Health view of a stored scan
+type SiteHealthReport struct {
+	ScanID    string           `json:"scan_id"`
+	Site      string           `json:"site"`
+	ScannedAt time.Time        `json:"scanned_at"`
+	Tests     []SiteHealthTest `json:"tests"`
+}
+
+// SiteHealthSummary is the compact status of a site for a dashboard widget
+type SiteHealthSummary struct {
+	Site      string         `json:"site"`
+	ScanID    string         `json:"scan_id"`
+	ScannedAt time.Time      `json:"scanned_at"`
+	Status    string         `json:"status"` // worst status of the site's tests
+	Score     int            `json:"score"`  // 0-100
+	Label     string         `json:"label"`
+	Tests     map[string]int `json:"tests"` // number of tests per status
+	Issues    map[string]int `json:"issues_by_impact"`
+}
+
+// worseStatus returns the more severe of two Site Health statuses
+func worseStatus(a, b string) string {
+	rank := map[string]int{siteHealthGood: 0, siteHealthRecommended: 1, siteHealthCritical: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// siteHealthTestName turns an identifier into a Site Health test name
+func siteHealthTestName(id string) string {
+	return siteHealthTestPrefix + strings.NewReplacer("-", "_", ".", "_").Replace(id)
+}
+
+// siteHealthPageList renders up to five page URLs as an HTML list
+func siteHealthPageList(pages []string) string {
+	const shown = 5
+	var b strings.Builder
+	b.WriteString("<ul>")
+	for i, page := range pages {
+		if i == shown {
+			fmt.Fprintf(&b, "<li>and %d more</li>", len(pages)-shown)
+			break
+		}
+		fmt.Fprintf(&b, "<li><code>%s</code></li>", html.EscapeString(displayURL(page)))
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+// buildSiteHealthReport turns a stored scan into Site Health tests: the site
+// score, blocking issues, thresholds, pages that could not be audited and one
+// test per widespread audit
+func buildSiteHealthReport(result ScanResult) SiteHealthReport {
+	report := SiteHealthReport{
+		ScanID:    result.ID,
+		Site:      siteKey(result.BaseURL),
+		ScannedAt: result.ScanTime,
+		Tests:     make([]SiteHealthTest, 0, 4+len(result.Summary.WidespreadAudits)),
+	}
+	add := func(test SiteHealthTest) {
+		test.Badge = siteHealthBadge
+		report.Tests = append(report.Tests, test)
+	}
+
+	score := int(result.SiteScore*100 + 0.5)
+	scoreTest := SiteHealthTest{
+		Test: siteHealthTestName("score"),
+		Description: fmt.Sprintf("<p>An automated scan of %d pages on %s scored %d out of 100 for accessibility.</p>",
+			result.TotalPages, html.EscapeString(report.Site), score),
+	}
+	switch {
+	case result.Summary.PagesScanned == result.Summary.PagesWithErrors:
+		scoreTest.Status = siteHealthRecommended
+		scoreTest.Label = "Your site's accessibility score could not be measured"
+		scoreTest.Description = "<p>None of the scanned pages could be audited, so the site has no accessibility score yet.</p>"
+	case score >= 90:
+		scoreTest.Status = siteHealthGood
+		scoreTest.Label = "Your site's accessibility score is good"
+	case score >= 50:
+		scoreTest.Status = siteHealthRecommended
+		scoreTest.Label = "Your site's accessibility score could be improved"
+	default:
+		scoreTest.Status = siteHealthCritical
+		scoreTest.Label = "Your site's accessibility score is low"
+	}
+	add(scoreTest)
+
+	critical := result.Summary.IssuesByImpact["critical"]
+	serious := result.Summary.IssuesByImpact["serious"]
+	issuesTest := SiteHealthTest{Test: siteHealthTestName("blocking_issues")}
+	switch {
+	case critical > 0:
+		issuesTest.Status = siteHealthCritical
+		issuesTest.Label = fmt.Sprintf("Your site has %d critical accessibility issues", critical)
+	case serious > 0:
+		issuesTest.Status = siteHealthRecommended
+		issuesTest.Label = fmt.Sprintf("Your site has %d serious accessibility issues", serious)
+	default:
+		issuesTest.Status = siteHealthGood
+		issuesTest.Label = "No critical or serious accessibility issues were found"
+	}
+	issuesTest.Description = fmt.Sprintf("<p>Critical and serious issues can stop visitors who rely on assistive technology from using your site. The scan found %d critical, %d serious, %d moderate and %d minor issues.</p>",
+		critical, serious, result.Summary.IssuesByImpact["moderate"], result.Summary.IssuesByImpact["minor"])
+	add(issuesTest)
+
+	if result.Thresholds != nil {
+		thresholdTest := SiteHealthTest{Test: siteHealthTestName("thresholds")}
+		if result.Thresholds.Passed {
+			thresholdTest.Status = siteHealthGood
+			thresholdTest.Label = "Your site meets its accessibility thresholds"
+			thresholdTest.Description = "<p>Every accessibility threshold configured for this site was met.</p>"
+		} else {
+			thresholdTest.Status = siteHealthCritical
+			thresholdTest.Label = "Your site does not meet its accessibility thresholds"
+			var b strings.Builder
+			b.WriteString("<p>These thresholds configured for this site were not met:</p><ul>")
+			for _, failure := range result.Thresholds.Failures {
+				fmt.Fprintf(&b, "<li>%s</li>", html.EscapeString(failure.Threshold))
+			}
+			b.WriteString("</ul>")
+			thresholdTest.Description = b.String()
+		}
+		add(thresholdTest)
+	}
+
+	if result.Summary.PagesWithErrors > 0 {
+		failed := make([]string, 0, result.Summary.PagesWithErrors)
+		for _, page := range result.PageResults {
+			if page.Error != "" {
+				failed = append(failed, page.URL)
+			}
+		}
+		add(SiteHealthTest{
+			Test:        siteHealthTestName("unscanned_pages"),
+			Status:      siteHealthRecommended,
+			Label:       fmt.Sprintf("%d pages could not be checked for accessibility", result.Summary.PagesWithErrors),
+			Description: "<p>The scanner could not audit these pages, so their issues are not included:</p>" + siteHealthPageList(failed),
+		})
+	}
+
+	catalog := remediationCatalogs.For(result.ScanConfig.Language)
+	for _, spread := range result.Summary.WidespreadAudits {
+		add(siteHealthAuditTest(result, spread, catalog.Lookup(spread.AuditID)))
+	}
+	return report
+}
+
+// siteHealthAuditTest describes one failing audit, rated by the worst impact
+// it has on any page
+func siteHealthAuditTest(result ScanResult, spread AuditSpread, remediation *Remediation) SiteHealthTest {
+	status := siteHealthGood
+	pages := make([]string, 0, spread.PagesAffected)
+	for _, page := range result.PageResults {
+		found := false
+		for _, issue := range page.Issues {
+			if issue.AuditID != spread.AuditID || isFalsePositive(issue) {
+				continue
+			}
+			found = true
+			if issue.Impact == "critical" {
+				status = siteHealthCritical
+			} else {
+				status = worseStatus(status, siteHealthRecommended)
+			}
+		}
+		if found {
+			pages = append(pages, page.URL)
+		}
+	}
+
+	test := SiteHealthTest{
+		Test:   siteHealthTestName(spread.AuditID),
+		Status: status,
+		Label:  strings.TrimSuffix(spread.Title, "."),
+		Description: fmt.Sprintf("<p>Found %d times on %d pages:</p>%s",
+			spread.IssueCount, spread.PagesAffected, siteHealthPageList(pages)),
+	}
+	if remediation != nil {
+		test.Description += "<p>" + html.EscapeString(remediation.Summary) + "</p>"
+		if len(remediation.Links) > 0 {
+			test.Actions = fmt.Sprintf(`<p><a href="%s" target="_blank" rel="noopener">Learn how to fix this</a></p>`,
+				html.EscapeString(remediation.Links[0]))
+		}
+	}
+	return test
+}
+
+// buildSiteHealthSummary condenses a report to its overall status and counts
+func buildSiteHealthSummary(result ScanResult, report SiteHealthReport) SiteHealthSummary {
+	summary := SiteHealthSummary{
+		Site:      report.Site,
+		ScanID:    report.ScanID,
+		ScannedAt: report.ScannedAt,
+		Status:    siteHealthGood,
+		Score:     int(result.SiteScore*100 + 0.5),
+		Tests:     map[string]int{siteHealthGood: 0, siteHealthRecommended: 0, siteHealthCritical: 0},
+		Issues:    result.Summary.IssuesByImpact,
+	}
+	for _, test := range report.Tests {
+		summary.Tests[test.Status]++
+		summary.Status = worseStatus(summary.Status, test.Status)
+	}
+	switch summary.Status {
+	case siteHealthGood:
+		summary.Label = "Good"
+	case siteHealthRecommended:
+		summary.Label = "Should be improved"
+	default:
+		summary.Label = "Needs attention"
+	}
+	return summary
+}
+
+// latestSiteScan loads the newest completed or partial scan of a site
+func latestSiteScan(host string) (ScanResult, error) {
+	return latestTaggedScan(host, nil)
+}
+
+// latestTaggedScan loads the newest completed or partial scan of a site
+// with the given tags
+func latestTaggedScan(host string, filters []tagFilter) (ScanResult, error) {
+	scans, err := scanStore.List()
+	if err != nil {
+		return ScanResult{}, err
+	}
+	var latest *storedScan
+	for i, scan := range scans {
+		if scan.Site != host || (scan.Status != "completed" && scan.Status != "partial") || !matchTags(scan.Tags, filters) {
+			continue
+		}
+		if latest == nil || scan.ScanTime.After(latest.ScanTime) {
+			latest = &scans[i]
+		}
+	}
+	if latest == nil {
+		return ScanResult{}, errNoSiteScan
+	}
+	return scanStore.Get(latest.ID)
+}
+
+// loadSiteHealthScan loads the latest scan of the site named in the request
+// path, writing an error response on failure
+func loadSiteHealthScan(w http.ResponseWriter, r *http.Request) (ScanResult, bool) {
+	host := siteHostParam(r)
+	if !validSiteHost(host) {
+		sendSiteStoreError(w, errInvalidSite)
+		return ScanResult{}, false
+	}
+	result, err := latestSiteScan(host)
+	if errors.Is(err, errNoSiteScan) || errors.Is(err, errScanNotFound) {
+		sendError(w, "Scan not found", http.StatusNotFound, "This site has no completed scan yet")
+		return result, false
+	}
+	if err != nil {
+		logAt(logLevelError, "Failed to load the latest scan of %s: %v", host, err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the latest scan")
+		return result, false
+	}
+	return result, true
+}
+
+// handleScanSiteHealth handles GET /api/v1/scans/{id}/site-health requests
+func handleScanSiteHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, buildSiteHealthReport(result))
+}
+
+// handleSiteHealth handles GET /api/v1/sites/{host}/site-health requests,
+// reporting on the site's latest finished scan
+func handleSiteHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	result, ok := loadSiteHealthScan(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, buildSiteHealthReport(result))
+}
+
+// handleSiteHealthSummary handles GET /api/v1/sites/{host}/site-health/summary requests
+func handleSiteHealthSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	result, ok := loadSiteHealthScan(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, buildSiteHealthSummary(result, buildSiteHealthReport(result)))
+}
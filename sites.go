@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// errInvalidSite is returned for site keys that are not valid host names
+var errInvalidSite = errors.New("invalid site host")
+
+// siteHostPattern matches host names, optionally with a port
+var siteHostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]{1,5})?$`)
+
+// SiteStore keeps small per-site JSON documents (triage state, issue history, ...)
+// in one directory per host
+type SiteStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// siteStore holds per-site data shared across scans
+var siteStore *SiteStore
+
+// NewSiteStore creates a store rooted at dir, creating the directory if needed
+func NewSiteStore(dir string) (*SiteStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &SiteStore{dir: dir}, nil
+}
+
+// siteKey returns the site host for a URL, used to key per-site data
+func siteKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return asciiHost(parsed.Host)
+}
+
+// validSiteHost reports whether host can be used as a site key
+func validSiteHost(host string) bool {
+	return siteHostPattern.MatchString(host) && !strings.Contains(host, "..")
+}
+
+// path returns the file path of a site document, validating the host
+func (s *SiteStore) path(host, name string) (string, error) {
+	if !validSiteHost(host) {
+		return "", errInvalidSite
+	}
+	return filepath.Join(s.dir, host, name+".json"), nil
+}
+
+// Load reads a site document into v; a missing document leaves v unchanged
+func (s *SiteStore) Load(host, name string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(host, name, v)
+}
+
+// Update loads a site document into v, calls modify and saves the result atomically
+func (s *SiteStore) Update(host, name string, v interface{}, modify func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(host, name, v); err != nil {
+		return err
+	}
+	if err := modify(); err != nil {
+		return err
+	}
+	return s.save(host, name, v)
+}
+
+func (s *SiteStore) load(host, name string, v interface{}) error {
+	path, err := s.path(host, name)
+	if err != nil {
+		return err
+	}
+	data, err := readRecord(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *SiteStore) save(host, name string, v interface{}) error {
+	path, err := s.path(host, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeRecord(path, data)
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxSkipLinkPosition is how far down the focus order a skip link may be,
+// counting from the first focusable element of the page
+const maxSkipLinkPosition = 5
+
+// minBypassedElements is how many focusable elements must come before the
+// main content for a page to need a skip link
+const minBypassedElements = 3
+
+// skipLinkTextPattern recognizes the text of skip links in common languages
+var skipLinkTextPattern = regexp.MustCompile(`(?i)\b(skip|jump|bypass)\b|main content|zum (haupt)?inhalt|inhalt springen|aller au contenu|contenu principal|saltar|ir al contenido|vai al contenuto|salta al|pular para|ir para o conteúdo|naar (de )?(hoofd)?inhoud|hoppa till|gå til|przejdź do|перейти к`)
+
+// hiddenStylePattern matches inline styles that keep an element from ever
+// showing, even when it has focus
+var hiddenStylePattern = regexp.MustCompile(`(?i)(^|;)\s*(display\s*:\s*none|visibility\s*:\s*hidden)`)
+
+// focusableElements returns the elements of a document that take keyboard
+// focus by default or through tabindex, in document order
+func focusableElements(root *html.Node) []*html.Node {
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "template", "head":
+				return
+			}
+			if isFocusable(n) {
+				found = append(found, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// isFocusable reports whether an element is in the focus order. Disabled
+// form controls are not, whatever their tabindex.
+func isFocusable(n *html.Node) bool {
+	switch n.Data {
+	case "button", "input", "select", "textarea":
+		if _, disabled := attrLookup(n, "disabled"); disabled {
+			return false
+		}
+	}
+	if tabindex, ok := attrLookup(n, "tabindex"); ok {
+		if value, err := strconv.Atoi(strings.TrimSpace(tabindex)); err == nil {
+			return value >= 0
+		}
+	}
+	switch n.Data {
+	case "a", "area":
+		_, ok := attrLookup(n, "href")
+		return ok
+	case "input":
+		return !strings.EqualFold(attrValue(n, "type"), "hidden")
+	case "button", "select", "textarea", "iframe", "summary":
+		return true
+	}
+	return strings.EqualFold(attrValue(n, "contenteditable"), "true")
+}
+
+// neverShown reports whether an element or one of its ancestors is hidden in
+// a way focus cannot undo
+func neverShown(n *html.Node) bool {
+	return notRendered(n) || ariaHidden(n)
+}
+
+// notRendered reports whether an element or one of its ancestors is not
+// rendered at all: hidden, or display:none or visibility:hidden in its style
+func notRendered(n *html.Node) bool {
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if _, hidden := attrLookup(n, "hidden"); hidden {
+			return true
+		}
+		if hiddenStylePattern.MatchString(attrValue(n, "style")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ariaHidden reports whether an element or one of its ancestors is hidden
+// from assistive technology with aria-hidden="true"
+func ariaHidden(n *html.Node) bool {
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if attrValue(n, "aria-hidden") == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// elementByID finds the element a fragment identifier points to: the element
+// with that ID, or an anchor with that name
+func elementByID(root *html.Node, id string) *html.Node {
+	var found *html.Node
+	var walk func(*html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && (attrValue(n, "id") == id || (n.Data == "a" && attrValue(n, "name") == id)) {
+			found = n
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(root)
+	return found
+}
+
+// isMainContent reports whether an element starts the main content of a page
+func isMainContent(n *html.Node) bool {
+	return n.Type == html.ElementNode && (n.Data == "main" || attrValue(n, "role") == "main")
+}
+
+// skipLinkFragment returns the target ID of a same-page link
+func skipLinkFragment(n *html.Node) (string, bool) {
+	href := strings.TrimSpace(attrValue(n, "href"))
+	if n.Data != "a" || len(href) < 2 || href[0] != '#' || href == "#top" {
+		return "", false
+	}
+	id, err := url.PathUnescape(href[1:])
+	if err != nil {
+		id = href[1:]
+	}
+	return id, true
+}
+
+// checkSkipLink checks that a page whose main content comes after navigation
+// starts with a working "skip to main content" link (WCAG 2.4.1): among the
+// first focusable elements, pointing to an element on the page, and not
+// hidden in a way that keeps it from showing on focus. Styles from
+// stylesheets are not evaluated.
+func checkSkipLink(doc *html.Node) []AccessibilityIssue {
+	focusable := focusableElements(doc)
+	if len(focusable) == 0 {
+		return nil
+	}
+
+	for _, n := range focusable[:min(maxSkipLinkPosition, len(focusable))] {
+		id, ok := skipLinkFragment(n)
+		if !ok {
+			continue
+		}
+		target := elementByID(doc, id)
+		if !skipLinkTextPattern.MatchString(textContent(n)+" "+attrValue(n, "aria-label")) && (target == nil || !isMainContent(target)) {
+			continue
+		}
+
+		issue := AccessibilityIssue{
+			AuditID:     "skip-link-broken",
+			Impact:      "serious",
+			Selector:    elementPath(n),
+			Snippet:     elementSnippet(n),
+			Description: "Keyboard users rely on the skip link to get past the navigation repeated on every page.",
+		}
+		switch {
+		case target == nil:
+			issue.title = titled("missing-target", "{id}", id)
+		case neverShown(n):
+			issue.title = titled("hidden")
+		default:
+			return nil
+		}
+		return []AccessibilityIssue{issue}
+	}
+
+	// Pages that start with their main content have nothing to skip
+	bypassed := 0
+	if start := firstElement(doc, isMainContent); start != nil {
+		bypassed = focusableBefore(doc, start)
+	} else if h1 := firstElement(doc, func(n *html.Node) bool { return n.Data == "h1" }); h1 != nil {
+		bypassed = focusableBefore(doc, h1)
+	} else {
+		for _, n := range focusable {
+			if hasAncestor(n, isNavigation) {
+				bypassed++
+			}
+		}
+	}
+	if bypassed < minBypassedElements {
+		return nil
+	}
+	return []AccessibilityIssue{{
+		AuditID:     "skip-link-missing",
+		title:       titled("default", "{count}", strconv.Itoa(bypassed)),
+		Description: "Keyboard users have to tab through the navigation repeated on every page before they reach the content.",
+		Impact:      "moderate",
+		Selector:    elementPath(focusable[0]),
+		Snippet:     elementSnippet(focusable[0]),
+	}}
+}
+
+// hasAncestor reports whether an ancestor of an element satisfies a predicate
+func hasAncestor(n *html.Node, predicate func(*html.Node) bool) bool {
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		if predicate(parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNavigation reports whether an element holds the navigation or header
+// repeated across a site
+func isNavigation(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	role := attrValue(n, "role")
+	return n.Data == "nav" || n.Data == "header" || role == "navigation" || role == "banner"
+}
+
+// firstElement returns the first element in document order satisfying a predicate
+func firstElement(root *html.Node, predicate func(*html.Node) bool) *html.Node {
+	if root.Type == html.ElementNode && predicate(root) {
+		return root
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if found := firstElement(c, predicate); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// focusableBefore counts the focusable elements that come before an element
+// in document order
+func focusableBefore(root, stop *html.Node) int {
+	count := 0
+	var walk func(*html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n == stop {
+			return true
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "template", "head":
+				return false
+			}
+			if isFocusable(n) {
+				count++
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(root)
+	return count
+}
+
+// SkipLinkReport lists the templates whose pages lack a working skip link.
+// Pages built from one template share the markup the issue points at, so
+// they are grouped by it.
+type SkipLinkReport struct {
+	PagesWithout int                `json:"pages_without"`
+	Templates    []SkipLinkTemplate `json:"templates"`
+}
+
+// SkipLinkTemplate is a group of pages with the same skip link problem at
+// the same place
+type SkipLinkTemplate struct {
+	AuditID  string   `json:"audit_id"` // skip-link-missing or skip-link-broken
+	Title    string   `json:"title"`
+	Selector string   `json:"selector"`
+	Snippet  string   `json:"snippet"`
+	Pages    int      `json:"pages"`
+	Examples []string `json:"examples"`
+}
+
+// skipLinkReport groups the skip link issues of a scan's pages, most
+// widespread first. It is nil when every page passed.
+func skipLinkReport(pages []PageResult) *SkipLinkReport {
+	report := &SkipLinkReport{}
+	byKey := make(map[string]*SkipLinkTemplate)
+	var templates []*SkipLinkTemplate
+	for _, page := range pages {
+		for _, issue := range page.Issues {
+			if issue.AuditID != "skip-link-missing" && issue.AuditID != "skip-link-broken" {
+				continue
+			}
+			report.PagesWithout++
+			key := issue.AuditID + "\x00" + issue.Selector + "\x00" + issue.Snippet
+			template, ok := byKey[key]
+			if !ok {
+				template = &SkipLinkTemplate{AuditID: issue.AuditID, Title: issue.Title, Selector: issue.Selector, Snippet: issue.Snippet}
+				byKey[key] = template
+				templates = append(templates, template)
+			}
+			template.Pages++
+			if len(template.Examples) < 5 {
+				template.Examples = append(template.Examples, page.URL)
+			}
+			break
+		}
+	}
+	if report.PagesWithout == 0 {
+		return nil
+	}
+	sort.SliceStable(templates, func(i, j int) bool { return templates[i].Pages > templates[j].Pages })
+	for _, template := range templates {
+		report.Templates = append(report.Templates, *template)
+	}
+	return report
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// skippedSlow marks pages skipped for exceeding the page timeout
+const skippedSlow = "slow"
+
+// slowPageFactor bounds the time spent on one page, including both
+// strategies, its iframes and its pauses, to this many page timeouts
+const slowPageFactor = 3
+
+// pageDeadline returns how long one page may take before it is skipped as slow
+func (s *AccessibilityScanner) pageDeadline() time.Duration {
+	return slowPageFactor * s.client.Timeout
+}
+
+// markSlowPage records a page that was not audited in time as skipped, so
+// the scan moves on without retrying it
+func (s *AccessibilityScanner) markSlowPage(page *PageResult) {
+	if page.ErrorCode != "page_timeout" {
+		return
+	}
+	page.Skipped = skippedSlow
+	page.Error = fmt.Sprintf("Skipped as slow: not audited within %s", s.pageDeadline())
+}
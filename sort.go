@@ -0,0 +1,61 @@
+package main
+
+import "sort"
+
+// sortPages returns the pages ordered by the given key, worst first.
+// With "severity", issues within each page are also ordered most critical first.
+// Pages that failed to scan always sort last.
+func sortPages(pages []PageResult, key string) []PageResult {
+	if key == "" || len(pages) == 0 {
+		return pages
+	}
+
+	sorted := make([]PageResult, len(pages))
+	copy(sorted, pages)
+
+	if key == "severity" {
+		for i := range sorted {
+			sorted[i].Issues = sortIssuesBySeverity(sorted[i].Issues)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if (a.Error != "") != (b.Error != "") {
+			return a.Error == ""
+		}
+
+		switch key {
+		case "score":
+			return a.AccessibilityScore < b.AccessibilityScore
+		case "severity":
+			return pageSeverity(a) > pageSeverity(b)
+		case "issues":
+			return len(a.Issues) > len(b.Issues)
+		case "url":
+			return a.URL < b.URL
+		}
+		return false
+	})
+
+	return sorted
+}
+
+// sortIssuesBySeverity returns the issues ordered from critical to minor
+func sortIssuesBySeverity(issues []AccessibilityIssue) []AccessibilityIssue {
+	sorted := make([]AccessibilityIssue, len(issues))
+	copy(sorted, issues)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return issueWeight(sorted[i]) > issueWeight(sorted[j])
+	})
+	return sorted
+}
+
+// pageSeverity is the total impact weight of a page's issues
+func pageSeverity(page PageResult) float64 {
+	total := 0.0
+	for _, issue := range page.Issues {
+		total += issueWeight(issue)
+	}
+	return total
+}
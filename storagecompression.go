@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs stored scans and raw Lighthouse reports are compressed with
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+	compressionNone = "none"
+)
+
+// Magic bytes a compressed record starts with
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// zstdDecoder decompresses whole zstd records; it is safe for concurrent use
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+
+// validateStorageCompression checks a storage_compression setting
+func validateStorageCompression(codec string) error {
+	switch codec {
+	case compressionGzip, compressionZstd, compressionNone:
+		return nil
+	}
+	return fmt.Errorf("storage_compression must be %q, %q or %q", compressionGzip, compressionZstd, compressionNone)
+}
+
+// nopWriteCloser adds a Close that does nothing to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// compressWriter returns a writer compressing into w with a codec. Closing it
+// ends the compressed stream but leaves w open.
+func compressWriter(w io.Writer, codec string) (io.WriteCloser, error) {
+	switch codec {
+	case compressionGzip:
+		return gzip.NewWriter(w), nil
+	case compressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// compressRecord returns data compressed with a codec
+func compressRecord(data []byte, codec string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := compressWriter(&buf, codec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		zw.Close()
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressRecord returns the uncompressed content of a record. The codec
+// is recognized by the record's magic bytes, so records written before
+// compression was enabled, or with another codec, read the same.
+func decompressRecord(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(zr)
+	case bytes.HasPrefix(data, zstdMagic):
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return data, nil
+}
+
+// decompressReader returns a reader streaming the uncompressed content of a
+// record, recognizing its codec like decompressRecord
+func decompressReader(r io.Reader) (io.ReadCloser, error) {
+	reader := bufio.NewReader(r)
+	magic, _ := reader.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(reader)
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return io.NopCloser(reader), nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// errScanNotFound is returned when a stored scan does not exist
+var errScanNotFound = errors.New("scan not found")
+
+// scanIDPattern matches IDs generated by newScanID
+var scanIDPattern = regexp.MustCompile(`^[a-f0-9]{32}$`)
+
+// ScanStore persists scan results as JSON files in a directory
+type ScanStore struct {
+	mu    sync.RWMutex
+	dir   string
+	index scanIndex
+}
+
+// scanStore holds completed scans for later retrieval
+var scanStore *ScanStore
+
+// NewScanStore creates a store rooted at dir, creating the directory if needed
+func NewScanStore(dir string) (*ScanStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &ScanStore{dir: dir}, nil
+}
+
+// newScanID generates a random scan identifier
+func newScanID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// path returns the file path of a stored scan
+func (s *ScanStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save stores a scan result, assigning it an ID if it has none, compressed
+// with the configured storage_compression codec. Pages are encoded one at a
+// time straight to the file, or to memory first when the result is encrypted
+// at rest.
+func (s *ScanStore) Save(result *ScanResult) error {
+	if result.ID == "" {
+		result.ID = newScanID()
+	}
+	if err := s.save(result); err != nil {
+		return err
+	}
+	s.indexSaved(result)
+	return nil
+}
+
+func (s *ScanStore) save(result *ScanResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	config := currentConfig()
+	if config.keys != nil {
+		var buf bytes.Buffer
+		if err := encodeStoredScan(&buf, result, config.StorageCompression); err != nil {
+			return err
+		}
+		return writeRecord(s.path(result.ID), buf.Bytes())
+	}
+
+	tmp := s.path(result.ID) + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = encodeStoredScan(file, result, config.StorageCompression)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, s.path(result.ID))
+}
+
+// Get loads a stored scan result by ID
+func (s *ScanStore) Get(id string) (ScanResult, error) {
+	var result ScanResult
+	if !scanIDPattern.MatchString(id) {
+		return result, errScanNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, err := os.Open(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return result, errScanNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+
+	reader, err := openRecord(file)
+	if err != nil {
+		return result, err
+	}
+	plain, err := decompressReader(reader)
+	if err != nil {
+		return result, err
+	}
+	defer plain.Close()
+	err = json.NewDecoder(plain).Decode(&result)
+	return result, err
+}
+
+// encodeStoredScan encodes a scan result to w, compressed with codec
+func encodeStoredScan(w io.Writer, result *ScanResult, codec string) error {
+	zw, err := compressWriter(w, codec)
+	if err != nil {
+		return err
+	}
+	if err := encodeScanResult(zw, result); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
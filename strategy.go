@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// Lighthouse strategies of a scan. By default PageSpeed Insights audits
+// pages as a desktop browser.
+const (
+	strategyMobile  = "mobile"
+	strategyDesktop = "desktop"
+	strategyBoth    = "both" // audit every page as mobile and as desktop
+)
+
+// StrategyAudits compares the mobile and desktop audits of a page scanned
+// with both strategies
+type StrategyAudits struct {
+	Mobile            StrategyAudit `json:"mobile"`
+	Desktop           StrategyAudit `json:"desktop"`
+	MobileOnlyIssues  int           `json:"mobile_only_issues"`
+	DesktopOnlyIssues int           `json:"desktop_only_issues"`
+	MobileOnlyAudits  []string      `json:"mobile_only_audits,omitempty"` // audits failing on mobile only
+	DesktopOnlyAudits []string      `json:"desktop_only_audits,omitempty"`
+}
+
+// StrategyAudit is the outcome of auditing a page with one strategy
+type StrategyAudit struct {
+	Score     float64 `json:"score"`
+	Issues    int     `json:"issues"`
+	Error     string  `json:"error,omitempty"`
+	ErrorCode string  `json:"error_code,omitempty"`
+}
+
+// validateStrategy checks a strategy setting
+func validateStrategy(strategy string) error {
+	if strategy != "" && strategy != strategyMobile && strategy != strategyDesktop && strategy != strategyBoth {
+		return errors.New("strategy must be \"mobile\", \"desktop\" or \"both\"")
+	}
+	return nil
+}
+
+// auditBothStrategies audits a page as mobile and as desktop and merges the
+// results. The page gets the lower of the two scores and every issue found
+// by either audit, each listing the strategies that found it; the mobile
+// audit provides the environment and raw report. The page only fails when
+// both audits do.
+func (s *AccessibilityScanner) auditBothStrategies(ctx context.Context, pageURL string) PageResult {
+	mobile := s.runLighthouse(ctx, pageURL, strategyMobile)
+	if ctx.Err() != nil {
+		return mobile
+	}
+	desktop := s.runLighthouse(ctx, pageURL, strategyDesktop)
+
+	audits := &StrategyAudits{
+		Mobile:  strategyAudit(mobile),
+		Desktop: strategyAudit(desktop),
+	}
+	switch {
+	case mobile.Error != "" && desktop.Error != "":
+		mobile.Strategies = audits
+		return mobile
+	case mobile.Error != "":
+		desktop.Strategies = audits
+		tagStrategy(desktop.Issues, strategyDesktop)
+		return desktop
+	case desktop.Error != "":
+		mobile.Strategies = audits
+		tagStrategy(mobile.Issues, strategyMobile)
+		return mobile
+	}
+
+	page := mobile
+	page.AccessibilityScore = min(mobile.AccessibilityScore, desktop.AccessibilityScore)
+	page.Issues = make([]AccessibilityIssue, 0, len(mobile.Issues)+len(desktop.Issues))
+
+	// Issues match by audit and element; repeated matches pair up one to one
+	key := func(issue AccessibilityIssue) string { return issue.AuditID + "|" + issue.Selector }
+	onDesktop := make(map[string]int)
+	for _, issue := range desktop.Issues {
+		onDesktop[key(issue)]++
+	}
+	mobileOnlyAudits, desktopOnlyAudits := make(map[string]bool), make(map[string]bool)
+	for _, issue := range mobile.Issues {
+		if onDesktop[key(issue)] > 0 {
+			onDesktop[key(issue)]--
+			issue.Strategies = []string{strategyMobile, strategyDesktop}
+		} else {
+			issue.Strategies = []string{strategyMobile}
+			audits.MobileOnlyIssues++
+			mobileOnlyAudits[issue.AuditID] = true
+		}
+		page.Issues = append(page.Issues, issue)
+	}
+	for _, issue := range desktop.Issues {
+		if onDesktop[key(issue)] == 0 {
+			continue
+		}
+		onDesktop[key(issue)]--
+		issue.Strategies = []string{strategyDesktop}
+		audits.DesktopOnlyIssues++
+		desktopOnlyAudits[issue.AuditID] = true
+		page.Issues = append(page.Issues, issue)
+	}
+
+	// An audit is only strategy-specific when it does not fail on the other at all
+	for _, issue := range desktop.Issues {
+		delete(mobileOnlyAudits, issue.AuditID)
+	}
+	for _, issue := range mobile.Issues {
+		delete(desktopOnlyAudits, issue.AuditID)
+	}
+	audits.MobileOnlyAudits = sortedAuditIDs(mobileOnlyAudits)
+	audits.DesktopOnlyAudits = sortedAuditIDs(desktopOnlyAudits)
+	page.Strategies = audits
+	return page
+}
+
+// strategyAudit summarizes a page audited with one strategy
+func strategyAudit(page PageResult) StrategyAudit {
+	return StrategyAudit{
+		Score:     page.AccessibilityScore,
+		Issues:    len(page.Issues),
+		Error:     page.Error,
+		ErrorCode: page.ErrorCode,
+	}
+}
+
+// tagStrategy marks issues as found by one strategy
+func tagStrategy(issues []AccessibilityIssue, strategy string) {
+	for i := range issues {
+		issues[i].Strategies = []string{strategy}
+	}
+}
+
+// sortedAuditIDs returns the audit IDs of a set in order
+func sortedAuditIDs(set map[string]bool) []string {
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ndjsonMediaType is the media type of streamed scan results
+const ndjsonMediaType = "application/x-ndjson"
+
+// StreamLine is one line of a streamed scan: a page result as soon as it is
+// scanned, and finally the scan summary. A page audited again by the retry
+// pass is sent once more with Replaces set.
+type StreamLine struct {
+	Type     string         `json:"type"` // "page" or "summary"
+	Page     *PageResult    `json:"page,omitempty"`
+	Replaces bool           `json:"replaces,omitempty"` // the page replaces the earlier line with the same URL
+	Scan     *SummaryResult `json:"scan,omitempty"`
+}
+
+// ndjsonStream writes scan progress to the client one JSON line at a time
+type ndjsonStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	filter  ResultFilter
+}
+
+// wantsNDJSON reports whether the client asked for a streamed response
+func wantsNDJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == ndjsonMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// newNDJSONStream starts a streamed response
+func newNDJSONStream(w http.ResponseWriter, filter ResultFilter) *ndjsonStream {
+	w.Header().Set("Content-Type", ndjsonMediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	return &ndjsonStream{w: w, encoder: json.NewEncoder(w), filter: filter}
+}
+
+// page sends a page result line, unless the result filter excludes the page
+func (st *ndjsonStream) page(page PageResult, replaces bool) {
+	pages := st.filter.apply([]PageResult{page})
+	if len(pages) == 0 {
+		return
+	}
+	page = pages[0]
+	for i := range page.Issues {
+		page.Issues[i].Fingerprint = issueFingerprint(page.URL, page.Issues[i])
+	}
+	st.send(StreamLine{Type: "page", Page: &page, Replaces: replaces})
+}
+
+// finish sends the closing summary line
+func (st *ndjsonStream) finish(result ScanResult) {
+	summary := summarizeResult(result)
+	st.send(StreamLine{Type: "summary", Scan: &summary})
+}
+
+func (st *ndjsonStream) send(line StreamLine) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.encoder.Encode(line)
+	if flusher, ok := st.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
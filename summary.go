@@ -0,0 +1,217 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// summaryTopN is the number of entries kept in the worst pages and widespread audits lists
+const summaryTopN = 5
+
+// scanMetrics lists the numeric metrics of a scan that can be charted and
+// monitored. Scores are 0-1 like everywhere else in the API.
+var scanMetrics = []string{
+	"site_score",
+	"total_issues",
+	"issues_critical",
+	"issues_serious",
+	"issues_moderate",
+	"issues_minor",
+	"pages_scanned",
+	"pages_with_errors",
+}
+
+// scanMetricKnown reports whether a metric name is in scanMetrics
+func scanMetricKnown(metric string) bool {
+	return slices.Contains(scanMetrics, metric)
+}
+
+// scanMetric returns one metric of a scan from its site score and summary
+func scanMetric(metric string, siteScore float64, summary ScanSummary) float64 {
+	switch metric {
+	case "site_score":
+		return siteScore
+	case "total_issues":
+		return float64(summary.TotalIssues)
+	case "pages_scanned":
+		return float64(summary.PagesScanned)
+	case "pages_with_errors":
+		return float64(summary.PagesWithErrors)
+	}
+	impact, _ := strings.CutPrefix(metric, "issues_")
+	return float64(summary.IssuesByImpact[impact])
+}
+
+// ScanSummary aggregates the page results of a scan at site level
+type ScanSummary struct {
+	PagesScanned     int            `json:"pages_scanned"`
+	PagesWithErrors  int            `json:"pages_with_errors"`
+	SkippedSlow      int            `json:"skipped_slow_pages"` // pages skipped for exceeding the page timeout
+	AverageScore     float64        `json:"average_score"`
+	TotalIssues      int            `json:"total_issues"`
+	FalsePositives   int            `json:"false_positives"`
+	IssuesByImpact   map[string]int `json:"issues_by_impact"`
+	IssuesByAudit    map[string]int `json:"issues_by_audit"`
+	WorstPages       []PageScore    `json:"worst_pages"`
+	WidespreadAudits []AuditSpread  `json:"widespread_audits"`
+	ScoreHistogram   []ScoreBucket  `json:"score_histogram"`
+}
+
+// ScoreBucket counts the pages whose score (0-100) falls in [Min, Max)
+type ScoreBucket struct {
+	Label   string  `json:"label"`
+	Min     int     `json:"min"`
+	Max     int     `json:"max"`
+	Pages   int     `json:"pages"`
+	Percent float64 `json:"percent"`
+}
+
+// scoreBucketBounds are the histogram boundaries; the last bucket includes 100
+var scoreBucketBounds = []struct {
+	label    string
+	min, max int
+}{
+	{"failing", 0, 50},
+	{"needs_improvement", 50, 80},
+	{"good", 80, 90},
+	{"excellent", 90, 100},
+}
+
+// PageScore is a compact reference to a scanned page and its score
+type PageScore struct {
+	URL        string  `json:"url"`
+	Score      float64 `json:"accessibility_score"`
+	IssueCount int     `json:"issue_count"`
+}
+
+// AuditSpread describes how many pages a single audit fails on
+type AuditSpread struct {
+	AuditID       string `json:"audit_id"`
+	Title         string `json:"title"`
+	PagesAffected int    `json:"pages_affected"`
+	IssueCount    int    `json:"issue_count"`
+}
+
+// buildSummary computes the site-level summary for a set of page results
+func buildSummary(pages []PageResult) ScanSummary {
+	summary := ScanSummary{
+		PagesScanned:     len(pages),
+		IssuesByImpact:   make(map[string]int),
+		IssuesByAudit:    make(map[string]int),
+		WorstPages:       make([]PageScore, 0),
+		WidespreadAudits: make([]AuditSpread, 0),
+	}
+
+	spreads := make(map[string]*AuditSpread)
+	scoreTotal := 0.0
+
+	for _, page := range pages {
+		if page.Error != "" {
+			summary.PagesWithErrors++
+			if page.Skipped == skippedSlow {
+				summary.SkippedSlow++
+			}
+			continue
+		}
+
+		scoreTotal += page.AccessibilityScore
+		summary.WorstPages = append(summary.WorstPages, PageScore{
+			URL:        page.URL,
+			Score:      page.AccessibilityScore,
+			IssueCount: countIssues(page),
+		})
+
+		seenOnPage := make(map[string]bool)
+		for _, issue := range page.Issues {
+			if isFalsePositive(issue) {
+				summary.FalsePositives++
+				continue
+			}
+			summary.TotalIssues++
+			summary.IssuesByImpact[issue.Impact]++
+			summary.IssuesByAudit[issue.AuditID]++
+
+			spread, ok := spreads[issue.AuditID]
+			if !ok {
+				spread = &AuditSpread{AuditID: issue.AuditID, Title: issue.Title}
+				spreads[issue.AuditID] = spread
+			}
+			spread.IssueCount++
+			if !seenOnPage[issue.AuditID] {
+				seenOnPage[issue.AuditID] = true
+				spread.PagesAffected++
+			}
+		}
+	}
+
+	scanned := summary.PagesScanned - summary.PagesWithErrors
+	if scanned > 0 {
+		summary.AverageScore = scoreTotal / float64(scanned)
+	}
+	summary.ScoreHistogram = buildScoreHistogram(summary.WorstPages, scanned)
+
+	sort.SliceStable(summary.WorstPages, func(i, j int) bool {
+		return summary.WorstPages[i].Score < summary.WorstPages[j].Score
+	})
+	if len(summary.WorstPages) > summaryTopN {
+		summary.WorstPages = summary.WorstPages[:summaryTopN]
+	}
+
+	for _, spread := range spreads {
+		summary.WidespreadAudits = append(summary.WidespreadAudits, *spread)
+	}
+	sort.Slice(summary.WidespreadAudits, func(i, j int) bool {
+		a, b := summary.WidespreadAudits[i], summary.WidespreadAudits[j]
+		if a.PagesAffected != b.PagesAffected {
+			return a.PagesAffected > b.PagesAffected
+		}
+		if a.IssueCount != b.IssueCount {
+			return a.IssueCount > b.IssueCount
+		}
+		return a.AuditID < b.AuditID
+	})
+	if len(summary.WidespreadAudits) > summaryTopN {
+		summary.WidespreadAudits = summary.WidespreadAudits[:summaryTopN]
+	}
+
+	return summary
+}
+
+// buildScoreHistogram distributes page scores over the histogram buckets
+func buildScoreHistogram(pages []PageScore, scanned int) []ScoreBucket {
+	buckets := make([]ScoreBucket, len(scoreBucketBounds))
+	for i, bound := range scoreBucketBounds {
+		buckets[i] = ScoreBucket{Label: bound.label, Min: bound.min, Max: bound.max}
+	}
+
+	for _, page := range pages {
+		score := int(math.Round(page.Score * 100))
+		for i := range buckets {
+			if score < buckets[i].Max || i == len(buckets)-1 {
+				buckets[i].Pages++
+				break
+			}
+		}
+	}
+
+	if scanned > 0 {
+		for i := range buckets {
+			buckets[i].Percent = math.Round(float64(buckets[i].Pages)/float64(scanned)*1000) / 10
+		}
+	}
+
+	return buckets
+}
+
+// countIssues returns the number of issues on a page, excluding false positives
+func countIssues(page PageResult) int {
+	count := 0
+	for _, issue := range page.Issues {
+		if !isFalsePositive(issue) {
+			count++
+		}
+	}
+	return count
+}
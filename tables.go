@@ -0,0 +1,265 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// tableShape is the structure of a table: its rows and what its header
+// cells and spans look like
+type tableShape struct {
+	rows        [][]*html.Node
+	columns     int
+	headerCells []*html.Node
+	headerRows  int // leading rows made only of header and empty cells
+	headerCols  int // leading columns made only of header and empty cells, below the header rows
+	spans       bool
+	usesHeaders bool // some cell names its headers with the headers attribute
+}
+
+// isHeaderCell reports whether a table cell is a header cell
+func isHeaderCell(n *html.Node) bool {
+	role := attrValue(n, "role")
+	return (n.Data == "th" && role == "") || role == "columnheader" || role == "rowheader"
+}
+
+// cellSpan returns a cell's colspan or rowspan, 1 when unset or invalid
+func cellSpan(n *html.Node, attr string) int {
+	span, err := strconv.Atoi(strings.TrimSpace(attrValue(n, attr)))
+	if err != nil || span < 1 {
+		return 1
+	}
+	return span
+}
+
+// tableRows returns the rows of a table and their cells, leaving out those of
+// nested tables
+func tableRows(table *html.Node) [][]*html.Node {
+	var rows [][]*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			case "tr":
+				var cells []*html.Node
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "th" || cell.Data == "td") {
+						cells = append(cells, cell)
+					}
+				}
+				rows = append(rows, cells)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// measureTable works out the shape of a table
+func measureTable(table *html.Node) tableShape {
+	shape := tableShape{rows: tableRows(table)}
+	for _, row := range shape.rows {
+		columns := 0
+		for _, cell := range row {
+			columns += cellSpan(cell, "colspan")
+			if cellSpan(cell, "colspan") > 1 || cellSpan(cell, "rowspan") > 1 {
+				shape.spans = true
+			}
+			if isHeaderCell(cell) {
+				shape.headerCells = append(shape.headerCells, cell)
+			}
+			if strings.TrimSpace(attrValue(cell, "headers")) != "" {
+				shape.usesHeaders = true
+			}
+		}
+		shape.columns = max(shape.columns, columns)
+	}
+
+	// An empty cell, like the corner of a table with headers on two sides,
+	// does not keep a row or column from being headers
+	allHeaders := func(cells []*html.Node) bool {
+		headers := 0
+		for _, cell := range cells {
+			switch {
+			case isHeaderCell(cell):
+				headers++
+			case strings.TrimSpace(visibleText(cell)) != "":
+				return false
+			}
+		}
+		return headers > 0
+	}
+	for _, row := range shape.rows {
+		if !allHeaders(row) {
+			break
+		}
+		shape.headerRows++
+	}
+	body := shape.rows[shape.headerRows:]
+	for col := 0; len(body) > 0; col++ {
+		column := make([]*html.Node, 0, len(body))
+		for _, row := range body {
+			if col < len(row) {
+				column = append(column, row[col])
+			}
+		}
+		if len(column) < len(body) || !allHeaders(column) {
+			break
+		}
+		shape.headerCols++
+	}
+	return shape
+}
+
+// looksLikeLayout reports whether a table without header cells or a caption
+// arranges content rather than presenting data: it holds another table,
+// headings or forms, or has a single row or column
+func looksLikeLayout(table *html.Node, shape tableShape) bool {
+	if len(shape.rows) <= 1 || shape.columns <= 1 {
+		return true
+	}
+	for c := table.FirstChild; c != nil; c = c.NextSibling {
+		found := firstElement(c, func(n *html.Node) bool {
+			return n.Data == "table" || n.Data == "form" || headingLevel(n) > 0
+		})
+		if found != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTables audits the structure of a page's tables (WCAG 1.3.1): data
+// tables need header cells, tables with headers in both directions or on
+// several levels need scope or headers attributes and a caption, and layout
+// tables need role="presentation" and no data table markup
+func checkTables(doc *html.Node) []AccessibilityIssue {
+	var tables []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "template", "head":
+				return
+			}
+			if hiddenFromAT(n) {
+				return
+			}
+			if n.Data == "table" {
+				tables = append(tables, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var issues []AccessibilityIssue
+	for _, table := range tables {
+		issue := func(auditID, impact string, title *checkTitle, description string) {
+			issues = append(issues, AccessibilityIssue{
+				AuditID:     auditID,
+				title:       title,
+				Description: description,
+				Impact:      impact,
+				Selector:    elementPath(table),
+				Snippet:     elementSnippet(table),
+			})
+		}
+		shape := measureTable(table)
+		caption := firstElement(table, func(n *html.Node) bool { return n.Data == "caption" && n.Parent == table })
+		named := caption != nil || attrValue(table, "aria-label") != "" || attrValue(table, "aria-labelledby") != ""
+
+		if role := attrValue(table, "role"); role == "presentation" || role == "none" {
+			if len(shape.headerCells) > 0 || caption != nil || shape.usesHeaders {
+				issue("table-layout-role", "moderate",
+					titled("role-with-headers", "{role}", role),
+					"A layout table is read cell by cell without headers. Remove role=\"presentation\" if the table holds data, or the data table markup if it does not.")
+			}
+			continue
+		}
+		if len(shape.headerCells) == 0 {
+			if !named && looksLikeLayout(table, shape) {
+				issue("table-layout-role", "minor",
+					titled("unmarked"),
+					"Screen readers announce rows and columns for tables not marked with role=\"presentation\", which is noise when the table only arranges content.")
+				continue
+			}
+			issue("table-header-missing", "serious",
+				titled("default"),
+				"Screen readers announce the header of each cell's row and column. Without <th> cells, users cannot tell what a value means.")
+			continue
+		}
+
+		// Headers in both directions or on several levels are ambiguous
+		// without scope or headers attributes
+		complex := shape.headerRows > 1 || shape.headerCols > 1 || shape.spans
+		twoSided := shape.headerRows > 0 && shape.headerCols > 0
+		if (complex || twoSided) && !shape.usesHeaders {
+			// The corner cell of headers on two sides heads both ways and is
+			// left alone
+			var corner *html.Node
+			if twoSided && len(shape.rows[0]) > 0 {
+				corner = shape.rows[0][0]
+			}
+			unscoped := 0
+			for _, cell := range shape.headerCells {
+				if cell != corner && strings.TrimSpace(attrValue(cell, "scope")) == "" {
+					unscoped++
+				}
+			}
+			if unscoped > 0 {
+				issue("table-header-association", "moderate",
+					titled("unscoped", "{unscoped}", strconv.Itoa(unscoped), "{total}", strconv.Itoa(len(shape.headerCells))),
+					"In tables with headers across the top and down the side, or on several levels, screen readers need scope or headers attributes to tell which headers apply to a cell.")
+			}
+		}
+		if shape.usesHeaders {
+			if missing := missingHeaderIDs(shape); len(missing) > 0 {
+				issue("table-header-association", "moderate",
+					titled("missing-ids", "{ids}", strings.Join(missing, ", ")),
+					"Screen readers only announce headers whose id is in the same table.")
+			}
+		}
+		if complex && !named {
+			issue("table-caption-missing", "minor",
+				titled("default"),
+				"A caption tells screen reader users what a complex table holds before they start reading its cells.")
+		}
+	}
+	return issues
+}
+
+// missingHeaderIDs returns the IDs named by the headers attributes of a
+// table's cells that no cell of the table has
+func missingHeaderIDs(shape tableShape) []string {
+	ids := make(map[string]bool)
+	for _, row := range shape.rows {
+		for _, cell := range row {
+			if id := attrValue(cell, "id"); id != "" {
+				ids[id] = true
+			}
+		}
+	}
+	var missing []string
+	seen := make(map[string]bool)
+	for _, row := range shape.rows {
+		for _, cell := range row {
+			for _, id := range strings.Fields(attrValue(cell, "headers")) {
+				if !ids[id] && !seen[id] {
+					seen[id] = true
+					missing = append(missing, "#"+id)
+				}
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxTabStops caps the tab order kept for a page
+const maxTabStops = 300
+
+// TabStop is an element keyboard users reach with Tab, in the order they
+// reach it
+type TabStop struct {
+	Position   int    `json:"position"`
+	Selector   string `json:"selector"`
+	Element    string `json:"element"`
+	Name       string `json:"name"`
+	TabIndex   *int   `json:"tabindex,omitempty"`    // set when the element has a tabindex
+	AriaHidden bool   `json:"aria_hidden,omitempty"` // reached with Tab but hidden from screen readers
+}
+
+// controlName returns the accessible name of a focusable element: its
+// accessibleName, or for form controls their label, alt, title, placeholder
+// or button value
+func controlName(n *html.Node) string {
+	switch n.Data {
+	case "input", "select", "textarea":
+		if name := labelledByText(n); name != "" {
+			return name
+		}
+		if label := strings.TrimSpace(attrValue(n, "aria-label")); label != "" {
+			return strings.Join(strings.Fields(label), " ")
+		}
+		if label := controlLabel(n); label != nil {
+			if name := strings.Join(strings.Fields(visibleText(label)), " "); name != "" {
+				return name
+			}
+		}
+		inputType := strings.ToLower(attrValue(n, "type"))
+		for _, attr := range []string{"alt", "title", "placeholder"} {
+			if attr == "alt" && inputType != "image" {
+				continue
+			}
+			if value := strings.TrimSpace(attrValue(n, attr)); value != "" {
+				return value
+			}
+		}
+		if inputType == "submit" || inputType == "reset" || inputType == "button" {
+			return strings.TrimSpace(attrValue(n, "value"))
+		}
+		return ""
+	}
+	if name := accessibleName(n); name != "" {
+		return name
+	}
+	return strings.TrimSpace(attrValue(n, "title"))
+}
+
+// controlLabel returns the <label> of a form control: the one whose for
+// attribute names it, or the one wrapping it
+func controlLabel(n *html.Node) *html.Node {
+	if id := attrValue(n, "id"); id != "" {
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		if label := firstElement(root, func(l *html.Node) bool { return l.Data == "label" && attrValue(l, "for") == id }); label != nil {
+			return label
+		}
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "label" {
+			return p
+		}
+	}
+	return nil
+}
+
+// pageTabOrder returns the elements of a page in the order Tab moves focus
+// through them: positive tabindex values first, in ascending order, then
+// the rest in document order. Elements that are not rendered are left out,
+// but those inside aria-hidden content stay, since Tab still reaches them.
+// Styles from stylesheets and focus moved by scripts are not taken into
+// account.
+func pageTabOrder(doc *html.Node) []TabStop {
+	type stop struct {
+		node     *html.Node
+		tabIndex *int
+	}
+	var stops []stop
+	for _, n := range focusableElements(doc) {
+		if notRendered(n) {
+			continue
+		}
+		s := stop{node: n}
+		if value, ok := attrLookup(n, "tabindex"); ok {
+			if tabIndex, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				s.tabIndex = &tabIndex
+			}
+		}
+		stops = append(stops, s)
+	}
+	positive := func(s stop) bool { return s.tabIndex != nil && *s.tabIndex > 0 }
+	sort.SliceStable(stops, func(i, j int) bool {
+		if positive(stops[i]) && positive(stops[j]) {
+			return *stops[i].tabIndex < *stops[j].tabIndex
+		}
+		return positive(stops[i]) && !positive(stops[j])
+	})
+
+	order := make([]TabStop, 0, min(len(stops), maxTabStops))
+	for i, s := range stops[:min(len(stops), maxTabStops)] {
+		name := controlName(s.node)
+		if len(name) > maxHeadingTextLength {
+			name = truncateUTF8(name, maxHeadingTextLength) + "…"
+		}
+		order = append(order, TabStop{
+			Position:   i + 1,
+			Selector:   elementPath(s.node),
+			Element:    s.node.Data,
+			Name:       name,
+			TabIndex:   s.tabIndex,
+			AriaHidden: ariaHidden(s.node),
+		})
+	}
+	return order
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scan tag limits
+const (
+	maxScanTags       = 20
+	maxTagValueLength = 256
+)
+
+// tagKeyPattern matches tag names such as release, env or ci.pipeline
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ScanListEntry summarizes a stored scan in scan listings
+type ScanListEntry struct {
+	ID         string            `json:"id"`
+	Site       string            `json:"site"`
+	ScanTime   time.Time         `json:"scan_time"`
+	Status     string            `json:"status"`
+	SiteScore  float64           `json:"site_score"`
+	TotalPages int               `json:"total_pages"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// validateTags checks the tags of a scan request
+func validateTags(tags map[string]string) error {
+	if len(tags) > maxScanTags {
+		return fmt.Errorf("at most %d tags are allowed", maxScanTags)
+	}
+	for key, value := range tags {
+		if !tagKeyPattern.MatchString(key) {
+			return fmt.Errorf("tag name %q must be 1-64 letters, digits, dots, dashes or underscores", key)
+		}
+		if len(value) > maxTagValueLength {
+			return fmt.Errorf("value of tag %q must be at most %d characters", key, maxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// tagFilter is one tag query: a name, optionally with the value it must have
+type tagFilter struct {
+	key      string
+	value    string
+	anyValue bool
+}
+
+// parseTagFilters reads the repeatable tag query parameter (name=value, or name for any value)
+func parseTagFilters(values []string) ([]tagFilter, error) {
+	filters := make([]tagFilter, 0, len(values))
+	for _, raw := range values {
+		key, value, found := strings.Cut(raw, "=")
+		if !tagKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("tag must be name=value or name, got %q", raw)
+		}
+		filters = append(filters, tagFilter{key: key, value: value, anyValue: !found})
+	}
+	return filters, nil
+}
+
+// matchTags reports whether tags satisfy every filter
+func matchTags(tags map[string]string, filters []tagFilter) bool {
+	for _, filter := range filters {
+		value, ok := tags[filter.key]
+		if !ok || (!filter.anyValue && value != filter.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseScanTime reads an RFC 3339 timestamp query parameter
+func parseScanTime(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
+// scanListQuery filters and orders a scan listing
+type scanListQuery struct {
+	tags         []tagFilter
+	site, status string
+	since, until time.Time
+	minScore     float64
+	maxScore     float64
+	sort         string // scan_time, score, pages or site
+	descending   bool
+}
+
+// scanSortKeys are the orders of scan listings, with their default direction:
+// newest, worst, largest first, and sites alphabetically
+var scanSortKeys = map[string]bool{"scan_time": true, "score": false, "pages": true, "site": false}
+
+// parseScanScore parses a site score bound between 0 and 1
+func parseScanScore(r *http.Request, name string, fallback float64) (float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil || score < 0 || score > 1 {
+		return 0, fmt.Errorf("%s must be a number between 0 and 1", name)
+	}
+	return score, nil
+}
+
+// parseScanListQuery reads the filters and order of a scan listing; it
+// returns the title of the error to send with a parse error
+func parseScanListQuery(r *http.Request) (scanListQuery, string, error) {
+	query := r.URL.Query()
+	q := scanListQuery{
+		site:   strings.ToLower(query.Get("site")),
+		status: query.Get("status"),
+		sort:   "scan_time",
+	}
+	var err error
+	if q.tags, err = parseTagFilters(query["tag"]); err != nil {
+		return q, "Invalid tag", err
+	}
+	if q.since, err = parseScanTime(r, "since"); err != nil {
+		return q, "Invalid query parameter", err
+	}
+	if q.until, err = parseScanTime(r, "until"); err != nil {
+		return q, "Invalid query parameter", err
+	}
+	if q.minScore, err = parseScanScore(r, "min_score", 0); err != nil {
+		return q, "Invalid query parameter", err
+	}
+	if q.maxScore, err = parseScanScore(r, "max_score", 1); err != nil {
+		return q, "Invalid query parameter", err
+	}
+	if q.minScore > q.maxScore {
+		return q, "Invalid query parameter", fmt.Errorf("min_score cannot be above max_score")
+	}
+
+	if key := query.Get("sort"); key != "" {
+		if _, ok := scanSortKeys[key]; !ok {
+			return q, "Invalid query parameter", fmt.Errorf("sort must be scan_time, score, pages or site")
+		}
+		q.sort = key
+	}
+	q.descending = scanSortKeys[q.sort]
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		q.descending = false
+	case "desc":
+		q.descending = true
+	default:
+		return q, "Invalid query parameter", fmt.Errorf("order must be asc or desc")
+	}
+	return q, "", nil
+}
+
+// matches reports whether a stored scan passes the filters
+func (q scanListQuery) matches(scan storedScan) bool {
+	if (q.site != "" && scan.Site != q.site) || (q.status != "" && scan.Status != q.status) {
+		return false
+	}
+	if (!q.since.IsZero() && scan.ScanTime.Before(q.since)) || (!q.until.IsZero() && scan.ScanTime.After(q.until)) {
+		return false
+	}
+	if scan.SiteScore < q.minScore || scan.SiteScore > q.maxScore {
+		return false
+	}
+	return matchTags(scan.Tags, q.tags)
+}
+
+// order sorts scan listing entries; ties keep the newest first
+func (q scanListQuery) order(entries []ScanListEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if q.descending {
+			a, b = b, a
+		}
+		switch q.sort {
+		case "score":
+			return a.SiteScore < b.SiteScore
+		case "pages":
+			return a.TotalPages < b.TotalPages
+		case "site":
+			return a.Site < b.Site
+		}
+		return a.ScanTime.Before(b.ScanTime)
+	})
+}
+
+// handleListScans handles GET /api/v1/scans requests, listing stored scans
+// filtered by tags, site, status, scan time and site score, newest first
+// unless sorted otherwise
+func handleListScans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	query, title, err := parseScanListQuery(r)
+	if err != nil {
+		sendError(w, title, http.StatusBadRequest, err.Error())
+		return
+	}
+	start, pageSize, err := parsePagination(r)
+	if err != nil {
+		sendError(w, "Invalid pagination", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	listScans(w, r, query, start, pageSize)
+}
+
+// listScans writes one page of the stored scans matching the query
+func listScans(w http.ResponseWriter, r *http.Request, query scanListQuery, start, pageSize int) {
+	scans, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelError, "Failed to list scans: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not list stored scans")
+		return
+	}
+
+	entries := make([]ScanListEntry, 0)
+	for _, scan := range scans {
+		if query.matches(scan) {
+			entries = append(entries, scan.entry())
+		}
+	}
+	query.order(entries)
+
+	end := min(start+pageSize, len(entries))
+	start = min(start, end)
+	writePaginated(w, r, "", entries[start:end], len(entries), start, pageSize)
+}
+
+// entry returns the listing entry of a stored scan
+func (s storedScan) entry() ScanListEntry {
+	return ScanListEntry{
+		ID:         s.ID,
+		Site:       s.Site,
+		ScanTime:   s.ScanTime,
+		Status:     s.Status,
+		SiteScore:  s.SiteScore,
+		TotalPages: s.TotalPages,
+		Tags:       s.Tags,
+	}
+}
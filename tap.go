@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// tapTest is one line of a TAP report
+type tapTest struct {
+	ok          bool
+	description string
+	diagnostics []string // YAML lines shown under a failing test
+}
+
+// writeTAP writes a scan as a TAP version 13 report with one test per audit
+// per page: a test fails when the page has issues of that audit. Audits are
+// those failing on any page of the scan, since passing audits are not stored.
+// Pages that could not be audited are one failing test each. It returns the
+// number of failed tests.
+func writeTAP(w io.Writer, result *ScanResult) int {
+	titles := make(map[string]string)
+	for _, page := range result.PageResults {
+		for _, issue := range page.Issues {
+			if !isFalsePositive(issue) && titles[issue.AuditID] == "" {
+				titles[issue.AuditID] = issue.Title
+			}
+		}
+	}
+	audits := make([]string, 0, len(titles))
+	for audit := range titles {
+		audits = append(audits, audit)
+	}
+	sort.Strings(audits)
+
+	tests := make([]tapTest, 0, len(result.PageResults)*max(len(audits), 1))
+	for _, page := range result.PageResults {
+		if page.Error != "" {
+			tests = append(tests, tapTest{
+				description: page.URL + " could not be audited",
+				diagnostics: []string{"message: " + yamlString(page.Error)},
+			})
+			continue
+		}
+		if len(audits) == 0 {
+			tests = append(tests, tapTest{ok: true, description: page.URL + " has no accessibility issues"})
+			continue
+		}
+		byAudit := make(map[string][]AccessibilityIssue)
+		for _, issue := range page.Issues {
+			if !isFalsePositive(issue) {
+				byAudit[issue.AuditID] = append(byAudit[issue.AuditID], issue)
+			}
+		}
+		for _, audit := range audits {
+			issues := byAudit[audit]
+			test := tapTest{ok: len(issues) == 0, description: page.URL + " " + audit}
+			if !test.ok {
+				test.diagnostics = tapDiagnostics(titles[audit], issues)
+			}
+			tests = append(tests, test)
+		}
+	}
+
+	fmt.Fprintln(w, "TAP version 13")
+	if len(tests) == 0 {
+		fmt.Fprintln(w, "1..0 # SKIP no pages were scanned")
+		return 0
+	}
+	fmt.Fprintf(w, "1..%d\n", len(tests))
+	fmt.Fprintf(w, "# Accessibility scan %s of %s: site score %.2f\n", result.ID, result.BaseURL, result.SiteScore)
+	failed := 0
+	for i, test := range tests {
+		status := "ok"
+		if !test.ok {
+			status = "not ok"
+			failed++
+		}
+		// A # would start a directive, so escape it in URLs
+		fmt.Fprintf(w, "%s %d - %s\n", status, i+1, strings.ReplaceAll(test.description, "#", "\\#"))
+		if len(test.diagnostics) > 0 {
+			fmt.Fprintln(w, "  ---")
+			for _, line := range test.diagnostics {
+				fmt.Fprintln(w, "  "+line)
+			}
+			fmt.Fprintln(w, "  ...")
+		}
+	}
+	return failed
+}
+
+// tapDiagnostics describes the issues of one audit on a page as YAML
+func tapDiagnostics(title string, issues []AccessibilityIssue) []string {
+	severity := "unknown"
+	for _, issue := range issues {
+		if impactWeights[issue.Impact] > impactWeights[severity] {
+			severity = issue.Impact
+		}
+	}
+	lines := []string{
+		"message: " + yamlString(title),
+		"severity: " + yamlString(severity),
+		fmt.Sprintf("found: %d", len(issues)),
+		"elements:",
+	}
+	for _, issue := range issues {
+		lines = append(lines, "  - selector: "+yamlString(issue.Selector))
+		if issue.Snippet != "" {
+			lines = append(lines, "    snippet: "+yamlString(issue.Snippet))
+		}
+	}
+	return lines
+}
+
+// yamlString quotes a string for YAML; JSON strings are valid YAML scalars
+func yamlString(s string) string {
+	var quoted strings.Builder
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(quoted.String(), "\n")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Target throttling limits
+const (
+	minTargetDelay        = 1 * time.Second  // first slowdown after a 429 or 503
+	maxTargetDelay        = 30 * time.Second // slowest pace between pages
+	maxTargetRetryAfter   = 5 * time.Minute  // longest Retry-After honored
+	maxTargetFetchRetries = 2                // fetches of a throttled page repeated before giving up on its links
+	targetRecoveryPages   = 5                // pages answered normally before the delay halves
+)
+
+// throttledError is returned for a page the target site answered with 429
+// or 503
+type throttledError struct {
+	status     int
+	retryAfter time.Duration // zero without a usable Retry-After
+}
+
+func (e *throttledError) Error() string {
+	if e.status == 0 {
+		return "target site is throttling"
+	}
+	return fmt.Sprintf("target site answered HTTP %d", e.status)
+}
+
+// ThrottleReport tells how much the target site slowed a scan down
+type ThrottleReport struct {
+	Responses       int      `json:"responses"`         // 429 and 503 answers to the crawler
+	MaxDelaySeconds float64  `json:"max_delay_seconds"` // slowest pace between pages
+	RetriedPages    []string `json:"retried_pages,omitempty"`
+	ThrottledPages  []string `json:"throttled_pages,omitempty"` // still throttled after retries, missing their audit or links
+}
+
+// targetThrottle slows a scan down while the target site rate-limits it or
+// fails under load. Each 429 or 503 doubles the delay between pages, or
+// waits as long as Retry-After asks; pages answered normally bring it back
+// down.
+type targetThrottle struct {
+	mu       sync.Mutex
+	delay    time.Duration
+	until    time.Time // no fetches before, from Retry-After
+	healthy  int
+	report   ThrottleReport
+	deferred map[string]bool // pages put back at the end of the queue
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, maxTargetRetryAfter)
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return min(date.Sub(now), maxTargetRetryAfter)
+	}
+	return 0
+}
+
+// throttledResponse returns the error for a 429 or 503 response, or nil
+func throttledResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	return &throttledError{status: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// isThrottled reports whether err is a throttled response
+func isThrottled(err error) bool {
+	var throttled *throttledError
+	return errors.As(err, &throttled)
+}
+
+// ReportThrottled slows the scan down after a 429 or 503
+func (t *targetThrottle) ReportThrottled(err error) {
+	var throttled *throttledError
+	if !errors.As(err, &throttled) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.healthy = 0
+	t.report.Responses++
+	t.delay = min(max(minTargetDelay, t.delay*2), maxTargetDelay)
+	if throttled.retryAfter > 0 {
+		t.delay = min(max(t.delay, throttled.retryAfter), maxTargetDelay)
+		if until := time.Now().Add(throttled.retryAfter); until.After(t.until) {
+			t.until = until
+		}
+	}
+	t.report.MaxDelaySeconds = max(t.report.MaxDelaySeconds, math.Round(t.delay.Seconds()*10)/10)
+	logAt(logLevelDebug, "Slowing down, %v; pausing %s between pages", throttled, t.delay)
+}
+
+// ReportHealthy counts a page answered normally, halving the delay after
+// every targetRecoveryPages of them
+func (t *targetThrottle) ReportHealthy() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.delay == 0 {
+		return
+	}
+	t.healthy++
+	if t.healthy < targetRecoveryPages {
+		return
+	}
+	t.healthy = 0
+	if t.delay /= 2; t.delay < minTargetDelay {
+		t.delay = 0
+	}
+}
+
+// Delay returns how long to wait before the next fetch: the current delay
+// or what is left of a Retry-After, whichever is longer
+func (t *targetThrottle) Delay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return max(t.delay, time.Until(t.until))
+}
+
+// Wait waits for Retry-After to pass, returning false if ctx is done first
+func (t *targetThrottle) Wait(ctx context.Context) bool {
+	t.mu.Lock()
+	wait := time.Until(t.until)
+	t.mu.Unlock()
+	return sleepContext(ctx, wait)
+}
+
+// sleepContext waits for d, returning false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Defer records a throttled page put back in the queue, reporting false if
+// it was already retried once
+func (t *targetThrottle) Defer(pageURL string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.deferred[pageURL] {
+		return false
+	}
+	if t.deferred == nil {
+		t.deferred = make(map[string]bool)
+	}
+	t.deferred[pageURL] = true
+	t.report.RetriedPages = append(t.report.RetriedPages, pageURL)
+	return true
+}
+
+// GiveUp records a page that was still throttled when retried
+func (t *targetThrottle) GiveUp(pageURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.report.ThrottledPages = append(t.report.ThrottledPages, pageURL)
+}
+
+// Report returns the scan's throttling report, or nil if the target never
+// throttled it
+func (t *targetThrottle) Report() *ThrottleReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.report.Responses == 0 && len(t.report.RetriedPages) == 0 {
+		return nil
+	}
+	report := t.report
+	return &report
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSConfig selects how the server terminates TLS
+type TLSConfig struct {
+	CertFile     string   `yaml:"cert_file"`          // TLS_CERT_FILE
+	KeyFile      string   `yaml:"key_file"`           // TLS_KEY_FILE
+	Domains      []string `yaml:"autocert_domains"`   // AUTOCERT_DOMAINS: obtain certificates from Let's Encrypt
+	Email        string   `yaml:"autocert_email"`     // AUTOCERT_EMAIL
+	CacheDir     string   `yaml:"autocert_cache_dir"` // AUTOCERT_CACHE_DIR
+	RedirectPort string   `yaml:"http_redirect_port"` // HTTP_REDIRECT_PORT: plain HTTP port redirecting to HTTPS
+}
+
+// validate checks the TLS settings and fills in the autocert defaults
+func (c *TLSConfig) validate(dataDir string) error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if c.CertFile != "" && len(c.Domains) > 0 {
+		return fmt.Errorf("use either cert_file/key_file or autocert_domains, not both")
+	}
+	if len(c.Domains) > 0 {
+		if c.CacheDir == "" {
+			c.CacheDir = filepath.Join(dataDir, "autocert")
+		}
+		// The ACME HTTP-01 challenge is answered on port 80
+		if c.RedirectPort == "" {
+			c.RedirectPort = "80"
+		}
+	}
+	return nil
+}
+
+// enabled reports whether the server should serve HTTPS
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || len(c.Domains) > 0
+}
+
+// mode describes the TLS setup for the startup log
+func (c TLSConfig) mode() string {
+	switch {
+	case len(c.Domains) > 0:
+		return "autocert (" + strings.Join(c.Domains, ", ") + ")"
+	case c.CertFile != "":
+		return "certificate " + c.CertFile
+	default:
+		return "disabled"
+	}
+}
+
+// redirectToHTTPS redirects plain HTTP requests to the HTTPS server
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// serve runs the API over HTTP, or over HTTPS with an optional HTTP redirect listener
+func serve(handler http.Handler, port string, config TLSConfig) error {
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if !config.enabled() {
+		return server.ListenAndServe()
+	}
+
+	server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	redirect := redirectToHTTPS(port)
+
+	if len(config.Domains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(config.Domains...),
+			Cache:      autocert.DirCache(config.CacheDir),
+			Email:      config.Email,
+		}
+		server.TLSConfig = manager.TLSConfig()
+		server.TLSConfig.MinVersion = tls.VersionTLS12
+		redirect = manager.HTTPHandler(redirect)
+	}
+
+	if config.RedirectPort != "" {
+		go func() {
+			redirectServer := &http.Server{
+				Addr:              ":" + config.RedirectPort,
+				Handler:           redirect,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			if err := redirectServer.ListenAndServe(); err != nil {
+				logAt(logLevelWarn, "Warning: HTTP redirect listener on port %s stopped: %v", config.RedirectPort, err)
+			}
+		}()
+	}
+
+	return server.ListenAndServeTLS(config.CertFile, config.KeyFile)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Outbound connection settings shared by every scan
+const (
+	dialTimeout           = 10 * time.Second
+	dialKeepAlive         = 30 * time.Second
+	tlsHandshakeTimeout   = 10 * time.Second
+	idleConnTimeout       = 90 * time.Second
+	maxIdleConns          = 200
+	maxIdleConnsPerHost   = 16 // keeps the pool warm for the PageSpeed API and the crawled site
+	expectContinueTimeout = time.Second
+	maxDrainBytes         = 64 << 10
+)
+
+// outboundTransport carries all requests to crawled sites and the PageSpeed
+// API. Sharing one transport lets crawls reuse pooled connections per host
+// instead of dialing and handshaking for every page.
+var outboundTransport = newOutboundTransport()
+
+// newOutboundTransport returns a transport with explicit pooling, keep-alive
+// and timeout settings, negotiating HTTP/2 where the server supports it
+func newOutboundTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		IdleConnTimeout:       idleConnTimeout,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		ExpectContinueTimeout: expectContinueTimeout,
+	}
+}
+
+// newOutboundClient returns a client on the shared transport with its own
+// overall request timeout
+func newOutboundClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: outboundTransport, Timeout: timeout}
+}
+
+// drainAndClose discards what is left of a small response body before closing
+// it, so the connection goes back to the pool rather than being torn down
+func drainAndClose(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	body.Close()
+}
+
+// withoutURL strips the request URL from a client error, keeping tokens that
+// webhook URLs carry out of logs and stored results
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
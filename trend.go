@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trendCSVHeader names the columns of a site's score trend
+var trendCSVHeader = []string{
+	"scan_id", "scan_time", "status", "site_score", "average_score", "pages_scanned",
+	"total_issues", "critical", "serious", "moderate", "minor", "tags",
+}
+
+// trendCSVRow renders one scan of a site's score trend
+func trendCSVRow(result ScanResult) []string {
+	tags := make([]string, 0, len(result.Tags))
+	for name, value := range result.Tags {
+		tags = append(tags, name+"="+value)
+	}
+	sort.Strings(tags)
+
+	row := []string{
+		result.ID,
+		result.ScanTime.UTC().Format(time.RFC3339),
+		result.Status,
+		strconv.FormatFloat(result.SiteScore, 'f', 3, 64),
+		strconv.FormatFloat(result.Summary.AverageScore, 'f', 3, 64),
+		strconv.Itoa(result.Summary.PagesScanned),
+		strconv.Itoa(result.Summary.TotalIssues),
+	}
+	for _, impact := range []string{"critical", "serious", "moderate", "minor"} {
+		row = append(row, strconv.Itoa(result.Summary.IssuesByImpact[impact]))
+	}
+	return append(row, strings.Join(tags, ";"))
+}
+
+// handleSiteTrendCSV handles GET /api/v1/sites/{host}/trend.csv requests,
+// exporting the score and issue counts of each finished scan of a site,
+// oldest first, for BI tools
+func handleSiteTrendCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	host := siteHostParam(r)
+	if !validSiteHost(host) {
+		sendSiteStoreError(w, errInvalidSite)
+		return
+	}
+	since, err := parseScanTime(r, "since")
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := parseScanTime(r, "until")
+	if err != nil {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, err.Error())
+		return
+	}
+	filters, err := parseTagFilters(r.URL.Query()["tag"])
+	if err != nil {
+		sendError(w, "Invalid tag", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scans, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelError, "Failed to list scans: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not list stored scans")
+		return
+	}
+	history := make([]storedScan, 0)
+	for _, scan := range scans {
+		if scan.Site != host || (scan.Status != "completed" && scan.Status != "partial") || !matchTags(scan.Tags, filters) {
+			continue
+		}
+		if (!since.IsZero() && scan.ScanTime.Before(since)) || (!until.IsZero() && scan.ScanTime.After(until)) {
+			continue
+		}
+		history = append(history, scan)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].ScanTime.Before(history[j].ScanTime) })
+
+	// Load every scan before writing, so a storage error can still be reported
+	rows := make([][]string, 0, len(history))
+	for _, scan := range history {
+		result, err := scanStore.Get(scan.ID)
+		if err != nil {
+			logAt(logLevelError, "Failed to load scan %s: %v", scan.ID, err)
+			sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the site's scans")
+			return
+		}
+		rows = append(rows, trendCSVRow(result))
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-trend.csv"`, strings.ReplaceAll(host, ":", "_")))
+	writer := csv.NewWriter(w)
+	writer.Write(trendCSVHeader)
+	writer.WriteAll(rows)
+}
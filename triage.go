@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// Triage states for issues
+const (
+	triageOpen          = "open"
+	triageFalsePositive = "false_positive"
+	triageWontFix       = "wont_fix"
+	triageFixed         = "fixed"
+)
+
+// fingerprintPattern matches fingerprints generated by issueFingerprint
+var fingerprintPattern = regexp.MustCompile(`^[a-f0-9]{16}$`)
+
+// IssueTriage is the triage decision recorded for an issue fingerprint
+type IssueTriage struct {
+	State     string    `json:"state"`
+	Note      string    `json:"note,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TriageRequest represents an API request to triage an issue
+type TriageRequest struct {
+	State string `json:"state"`
+	Note  string `json:"note,omitempty"`
+}
+
+// SiteTriage maps issue fingerprints to triage decisions for one site
+type SiteTriage map[string]IssueTriage
+
+// issueFingerprint identifies an issue across scans of the same site by its
+// audit, page path and element selector
+func issueFingerprint(pageURL string, issue AccessibilityIssue) string {
+	path := pageURL
+	if parsed, err := url.Parse(pageURL); err == nil {
+		path = parsed.Path
+	}
+	selector := issue.Selector
+	if issue.FrameURL != "" {
+		selector = issue.FrameURL + " " + selector
+	}
+	sum := sha256.Sum256([]byte(issue.AuditID + "|" + path + "|" + selector))
+	return hex.EncodeToString(sum[:8])
+}
+
+// applyTriage fingerprints every issue and attaches the site's triage decisions
+func applyTriage(result *ScanResult) {
+	var triage SiteTriage
+	if err := siteStore.Load(siteKey(result.BaseURL), "triage", &triage); err != nil && !errors.Is(err, errInvalidSite) {
+		logAt(logLevelWarn, "Warning: Could not load triage state for %s: %v", result.BaseURL, err)
+	}
+
+	for i := range result.PageResults {
+		page := &result.PageResults[i]
+		for j := range page.Issues {
+			issue := &page.Issues[j]
+			issue.Fingerprint = issueFingerprint(page.URL, *issue)
+			if decision, ok := triage[issue.Fingerprint]; ok {
+				issue.Triage = &decision
+			}
+		}
+	}
+}
+
+// isFalsePositive reports whether an issue was triaged as a false positive
+func isFalsePositive(issue AccessibilityIssue) bool {
+	return issue.Triage != nil && issue.Triage.State == triageFalsePositive
+}
+
+// handleSiteTriage handles GET /api/v1/sites/{host}/triage requests
+func handleSiteTriage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	triage := SiteTriage{}
+	if err := siteStore.Load(siteHostParam(r), "triage", &triage); err != nil {
+		sendSiteStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(triage)
+}
+
+// handleIssueTriage handles PUT and DELETE /api/v1/sites/{host}/issues/{fingerprint}/triage requests
+func handleIssueTriage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only PUT and DELETE methods are supported")
+		return
+	}
+
+	fingerprint := r.PathValue("fingerprint")
+	if !fingerprintPattern.MatchString(fingerprint) {
+		sendError(w, "Invalid fingerprint", http.StatusBadRequest, "fingerprint must be a 16 character hex string")
+		return
+	}
+
+	var req TriageRequest
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+			return
+		}
+		switch req.State {
+		case triageOpen, triageFalsePositive, triageWontFix, triageFixed:
+		default:
+			sendError(w, "Invalid state", http.StatusBadRequest, "state must be one of: open, false_positive, wont_fix, fixed")
+			return
+		}
+	}
+
+	decision := IssueTriage{State: req.State, Note: req.Note, UpdatedAt: time.Now().UTC()}
+	triage := SiteTriage{}
+	err := siteStore.Update(siteHostParam(r), "triage", &triage, func() error {
+		if r.Method == http.MethodDelete || req.State == triageOpen {
+			delete(triage, fingerprint)
+		} else {
+			triage[fingerprint] = decision
+		}
+		return nil
+	})
+	if err != nil {
+		sendSiteStoreError(w, err)
+		return
+	}
+
+	if r.Method == http.MethodDelete || req.State == triageOpen {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decision)
+}
+
+// sendSiteStoreError reports a site store failure
+func sendSiteStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidSite) {
+		sendError(w, "Invalid site", http.StatusBadRequest, "host must be a valid host name such as example.com")
+		return
+	}
+	logAt(logLevelError, "Site storage error: %v", err)
+	sendError(w, "Storage error", http.StatusInternalServerError, "Could not access site data")
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Crawl frontier modes and limits
+const (
+	frontierMemory      = "memory"
+	frontierDisk        = "disk"
+	maxPagesInMemory    = 1000   // max_pages limit of in-memory crawls
+	maxPagesOnDisk      = 500000 // max_pages limit of disk-backed crawls
+	diskDiscoveredLimit = 10000  // urls_discovered entries returned by disk-backed crawls
+	frontierPeekLimit   = 1000   // pending URLs shown in job progress
+	maxStoredURLLength  = 8192   // longer URLs are not crawled in disk mode
+)
+
+// urlStore holds the crawl frontier of a scan: the URLs it has queued or
+// visited with their link depth, the same-site URLs it has discovered and the
+// queue of URLs still to visit
+type urlStore interface {
+	// Visit marks a URL as seen at depth, reporting false if it was seen before
+	Visit(pageURL string, depth int) bool
+	Depth(pageURL string) int
+	Visited() int
+	// Discover records a same-site URL found on a page
+	Discover(pageURL string)
+	// Discovered returns discovered URLs in discovery order and their total count
+	Discovered() ([]string, int)
+	Push(pageURL string)
+	PushFront(pageURL string)
+	Pop() (string, bool)
+	Pending(max int) []string
+	Len() int
+	// Err returns the first storage error; the crawl stops when it is set
+	Err() error
+	// Close releases the store; keep retains disk state for a continuation
+	// and returns its path
+	Close(keep bool) (string, error)
+}
+
+// memoryURLStore keeps the crawl frontier in memory
+type memoryURLStore struct {
+	depth      map[string]int
+	discovered map[string]bool
+	order      []string
+	queue      []string
+}
+
+// newMemoryURLStore returns an empty in-memory frontier
+func newMemoryURLStore() *memoryURLStore {
+	return &memoryURLStore{
+		depth:      make(map[string]int),
+		discovered: make(map[string]bool),
+		order:      make([]string, 0),
+	}
+}
+
+func (m *memoryURLStore) Visit(pageURL string, depth int) bool {
+	if _, ok := m.depth[pageURL]; ok {
+		return false
+	}
+	m.depth[pageURL] = depth
+	return true
+}
+
+func (m *memoryURLStore) Depth(pageURL string) int { return m.depth[pageURL] }
+func (m *memoryURLStore) Visited() int             { return len(m.depth) }
+
+func (m *memoryURLStore) Discover(pageURL string) {
+	if !m.discovered[pageURL] {
+		m.discovered[pageURL] = true
+		m.order = append(m.order, pageURL)
+	}
+}
+
+func (m *memoryURLStore) Discovered() ([]string, int) { return m.order, len(m.order) }
+func (m *memoryURLStore) Push(pageURL string)         { m.queue = append(m.queue, pageURL) }
+
+func (m *memoryURLStore) PushFront(pageURL string) {
+	m.queue = append([]string{pageURL}, m.queue...)
+}
+
+func (m *memoryURLStore) Pop() (string, bool) {
+	if len(m.queue) == 0 {
+		return "", false
+	}
+	pageURL := m.queue[0]
+	m.queue = m.queue[1:]
+	return pageURL, true
+}
+
+func (m *memoryURLStore) Pending(max int) []string {
+	return append([]string{}, m.queue[:min(max, len(m.queue))]...)
+}
+
+func (m *memoryURLStore) Len() int                        { return len(m.queue) }
+func (m *memoryURLStore) Err() error                      { return nil }
+func (m *memoryURLStore) Close(keep bool) (string, error) { return "", nil }
+
+// Buckets of a disk-backed frontier
+var (
+	visitedBucket        = []byte("visited")         // URL -> depth
+	discoveredBucket     = []byte("discovered")      // URL -> nothing
+	discoveryOrderBucket = []byte("discovery_order") // sequence -> URL
+	queueBucket          = []byte("queue")           // sequence -> URL
+	metaBucket           = []byte("meta")            // counters of a kept frontier
+)
+
+// queueStart is the sequence of the first queued URL, leaving room to push to the front
+const queueStart = uint64(1) << 62
+
+// diskURLStore keeps the crawl frontier in a bbolt file so that memory use
+// stays flat however many URLs a site has
+type diskURLStore struct {
+	db         *bolt.DB
+	path       string
+	head, tail uint64 // queued URLs have sequences in [head, tail)
+	visited    int
+	discovered int
+	err        error
+}
+
+// openDiskURLStore opens the frontier file at path, restoring the state of a
+// frontier kept for a continuation
+func openDiskURLStore(path string) (*diskURLStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	// The file is scratch space, so skip fsync on every write
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second, NoSync: true})
+	if err != nil {
+		return nil, err
+	}
+
+	store := &diskURLStore{db: db, path: path, head: queueStart, tail: queueStart}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{visitedBucket, discoveredBucket, discoveryOrderBucket, queueBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		meta := tx.Bucket(metaBucket)
+		if counters := meta.Get([]byte("counters")); len(counters) == 32 {
+			store.head = binary.BigEndian.Uint64(counters[0:])
+			store.tail = binary.BigEndian.Uint64(counters[8:])
+			store.visited = int(binary.BigEndian.Uint64(counters[16:]))
+			store.discovered = int(binary.BigEndian.Uint64(counters[24:]))
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// sequenceKey encodes a sequence number as a sortable key
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// update runs fn in a write transaction, recording the first failure
+func (d *diskURLStore) update(fn func(tx *bolt.Tx) error) bool {
+	if d.err != nil {
+		return false
+	}
+	if err := d.db.Update(fn); err != nil {
+		d.err = err
+		return false
+	}
+	return true
+}
+
+// view runs fn in a read transaction, recording the first failure
+func (d *diskURLStore) view(fn func(tx *bolt.Tx) error) {
+	if d.err != nil {
+		return
+	}
+	if err := d.db.View(fn); err != nil {
+		d.err = err
+	}
+}
+
+func (d *diskURLStore) Visit(pageURL string, depth int) bool {
+	if len(pageURL) > maxStoredURLLength {
+		return false
+	}
+	added := false
+	d.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(visitedBucket)
+		if bucket.Get([]byte(pageURL)) != nil {
+			return nil
+		}
+		added = true
+		return bucket.Put([]byte(pageURL), binary.AppendUvarint(nil, uint64(depth)))
+	})
+	if added && d.err == nil {
+		d.visited++
+		return true
+	}
+	return false
+}
+
+func (d *diskURLStore) Depth(pageURL string) int {
+	depth := 0
+	d.view(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(visitedBucket).Get([]byte(pageURL)); value != nil {
+			n, _ := binary.Uvarint(value)
+			depth = int(n)
+		}
+		return nil
+	})
+	return depth
+}
+
+func (d *diskURLStore) Visited() int { return d.visited }
+
+func (d *diskURLStore) Discover(pageURL string) {
+	if len(pageURL) > maxStoredURLLength {
+		return
+	}
+	added := false
+	d.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(discoveredBucket)
+		if bucket.Get([]byte(pageURL)) != nil {
+			return nil
+		}
+		if err := bucket.Put([]byte(pageURL), []byte{}); err != nil {
+			return err
+		}
+		added = true
+		return tx.Bucket(discoveryOrderBucket).Put(sequenceKey(uint64(d.discovered)), []byte(pageURL))
+	})
+	if added && d.err == nil {
+		d.discovered++
+	}
+}
+
+func (d *diskURLStore) Discovered() ([]string, int) {
+	urls := make([]string, 0, min(d.discovered, diskDiscoveredLimit))
+	d.view(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(discoveryOrderBucket).Cursor()
+		for key, value := cursor.First(); key != nil && len(urls) < diskDiscoveredLimit; key, value = cursor.Next() {
+			urls = append(urls, string(value))
+		}
+		return nil
+	})
+	return urls, d.discovered
+}
+
+func (d *diskURLStore) Push(pageURL string) {
+	if d.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).Put(sequenceKey(d.tail), []byte(pageURL))
+	}) {
+		d.tail++
+	}
+}
+
+func (d *diskURLStore) PushFront(pageURL string) {
+	if d.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).Put(sequenceKey(d.head-1), []byte(pageURL))
+	}) {
+		d.head--
+	}
+}
+
+func (d *diskURLStore) Pop() (string, bool) {
+	if d.head == d.tail {
+		return "", false
+	}
+	var pageURL string
+	if !d.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		key := sequenceKey(d.head)
+		pageURL = string(bucket.Get(key))
+		return bucket.Delete(key)
+	}) {
+		return "", false
+	}
+	d.head++
+	return pageURL, true
+}
+
+func (d *diskURLStore) Pending(max int) []string {
+	pending := make([]string, 0, min(max, d.Len()))
+	d.view(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(queueBucket).Cursor()
+		for key, value := cursor.Seek(sequenceKey(d.head)); key != nil && len(pending) < max; key, value = cursor.Next() {
+			pending = append(pending, string(value))
+		}
+		return nil
+	})
+	return pending
+}
+
+func (d *diskURLStore) Len() int   { return int(d.tail - d.head) }
+func (d *diskURLStore) Err() error { return d.err }
+
+func (d *diskURLStore) Close(keep bool) (string, error) {
+	err := d.err
+	if keep && err == nil {
+		err = d.db.Update(func(tx *bolt.Tx) error {
+			counters := make([]byte, 32)
+			binary.BigEndian.PutUint64(counters[0:], d.head)
+			binary.BigEndian.PutUint64(counters[8:], d.tail)
+			binary.BigEndian.PutUint64(counters[16:], uint64(d.visited))
+			binary.BigEndian.PutUint64(counters[24:], uint64(d.discovered))
+			return tx.Bucket(metaBucket).Put([]byte("counters"), counters)
+		})
+		if err == nil {
+			err = d.db.Sync()
+		}
+	}
+	if closeErr := d.db.Close(); err == nil {
+		err = closeErr
+	}
+
+	if keep && err == nil {
+		return d.path, nil
+	}
+	os.Remove(d.path)
+	if keep {
+		return "", err
+	}
+	return "", nil
+}
+
+// workingFrontierPath returns where an active scan keeps its disk-backed frontier
+func (s *ScanStore) workingFrontierPath(scanID string) string {
+	return filepath.Join(s.dir, "frontiers", scanID+".db")
+}
+
+// frontierStorePath returns where a continuation keeps its disk-backed frontier
+func (s *ScanStore) frontierStorePath(token string) string {
+	return filepath.Join(s.dir, "continuations", token+".db")
+}
+
+// TakeFrontierStore moves the disk-backed frontier of a continuation to the
+// working file of the scan resuming it and returns its new path
+func (s *ScanStore) TakeFrontierStore(token, scanID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.workingFrontierPath(scanID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(s.frontierStorePath(token), path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errContinuationNotFound
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// clearWorkingFrontiers removes the frontiers of scans interrupted by a restart
+func (s *ScanStore) clearWorkingFrontiers() error {
+	if err := os.RemoveAll(filepath.Join(s.dir, "frontiers")); err != nil {
+		return fmt.Errorf("could not remove stale crawl frontiers: %w", err)
+	}
+	return nil
+}
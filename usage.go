@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Usage accounting settings
+const (
+	usageHistoryDays   = 90 // daily usage is kept this long
+	defaultUsageDays   = 30
+	usageFlushInterval = 30 * time.Second
+	usageDateLayout    = "2006-01-02"
+)
+
+// UsageCounters counts what a client consumed
+type UsageCounters struct {
+	Requests        int64 `json:"requests"`
+	Scans           int64 `json:"scans"`
+	PagesScanned    int64 `json:"pages_scanned"`
+	LighthouseCalls int64 `json:"lighthouse_calls"`
+}
+
+// add accumulates other into c
+func (c *UsageCounters) add(other UsageCounters) {
+	c.Requests += other.Requests
+	c.Scans += other.Scans
+	c.PagesScanned += other.PagesScanned
+	c.LighthouseCalls += other.LighthouseCalls
+}
+
+// ClientUsage is the stored usage of one client, in total and per UTC day
+type ClientUsage struct {
+	Total    UsageCounters            `json:"total"`
+	Days     map[string]UsageCounters `json:"days"`
+	LastSeen time.Time                `json:"last_seen"`
+}
+
+// DailyUsage is one day of a client's usage
+type DailyUsage struct {
+	Date string `json:"date"`
+	UsageCounters
+}
+
+// UsageReport summarizes a client's usage over recent days
+type UsageReport struct {
+	Client     string        `json:"client"`
+	LastSeen   *time.Time    `json:"last_seen,omitempty"`
+	PeriodDays int           `json:"period_days"`
+	Period     UsageCounters `json:"period"`
+	Total      UsageCounters `json:"total"`
+	Daily      []DailyUsage  `json:"daily,omitempty"`
+	Quotas     []QuotaStatus `json:"quotas,omitempty"`
+}
+
+// UsageRollup is the admin view of every client's usage
+type UsageRollup struct {
+	PeriodDays int           `json:"period_days"`
+	Period     UsageCounters `json:"period"`
+	Clients    []UsageReport `json:"clients"`
+}
+
+// UsageStore accounts API usage per client and persists it to a JSON file
+type UsageStore struct {
+	mu      sync.Mutex
+	path    string
+	clients map[string]*ClientUsage
+	dirty   bool
+}
+
+// usageStore accounts API usage per client
+var usageStore = &UsageStore{clients: make(map[string]*ClientUsage)}
+
+// NewUsageStore loads the usage recorded in path, if any
+func NewUsageStore(path string) (*UsageStore, error) {
+	store := &UsageStore{path: path, clients: make(map[string]*ClientUsage)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.clients); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Record adds consumption to a client's usage for today
+func (s *UsageStore) Record(client string, delta UsageCounters) {
+	now := time.Now().UTC()
+	today := now.Format(usageDateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, ok := s.clients[client]
+	if !ok {
+		usage = &ClientUsage{Days: make(map[string]UsageCounters)}
+		s.clients[client] = usage
+	}
+	usage.Total.add(delta)
+	day := usage.Days[today]
+	day.add(delta)
+	usage.Days[today] = day
+	usage.LastSeen = now
+
+	cutoff := now.AddDate(0, 0, -usageHistoryDays).Format(usageDateLayout)
+	for date := range usage.Days {
+		if date < cutoff {
+			delete(usage.Days, date)
+		}
+	}
+	s.dirty = true
+}
+
+// Report summarizes a client's usage over the last days, including today
+func (s *UsageStore) Report(client string, days int, daily bool) UsageReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report(client, days, daily)
+}
+
+// report builds a usage report; callers must hold s.mu
+func (s *UsageStore) report(client string, days int, daily bool) UsageReport {
+	report := UsageReport{Client: client, PeriodDays: days}
+	usage, ok := s.clients[client]
+	if !ok {
+		return report
+	}
+	lastSeen := usage.LastSeen
+	report.LastSeen = &lastSeen
+	report.Total = usage.Total
+
+	today := time.Now().UTC()
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format(usageDateLayout)
+		counters := usage.Days[date]
+		report.Period.add(counters)
+		if daily {
+			report.Daily = append(report.Daily, DailyUsage{Date: date, UsageCounters: counters})
+		}
+	}
+	return report
+}
+
+// Today returns the combined usage of clients for the current UTC day
+func (s *UsageStore) Today(clients []string) UsageCounters {
+	today := time.Now().UTC().Format(usageDateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total UsageCounters
+	for _, client := range clients {
+		if usage, ok := s.clients[client]; ok {
+			total.add(usage.Days[today])
+		}
+	}
+	return total
+}
+
+// Rollup summarizes every client's usage over the last days, heaviest users first
+func (s *UsageStore) Rollup(days int) UsageRollup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rollup := UsageRollup{PeriodDays: days, Clients: make([]UsageReport, 0, len(s.clients))}
+	for client := range s.clients {
+		report := s.report(client, days, false)
+		rollup.Period.add(report.Period)
+		rollup.Clients = append(rollup.Clients, report)
+	}
+	sort.Slice(rollup.Clients, func(i, j int) bool {
+		a, b := rollup.Clients[i].Period, rollup.Clients[j].Period
+		if a.LighthouseCalls != b.LighthouseCalls {
+			return a.LighthouseCalls > b.LighthouseCalls
+		}
+		return rollup.Clients[i].Client < rollup.Clients[j].Client
+	})
+	return rollup
+}
+
+// Flush writes the usage to disk if it changed since the last flush
+func (s *UsageStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty || s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.clients)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// startUsageFlusher persists usage periodically in the background
+func startUsageFlusher() {
+	go func() {
+		for range time.Tick(usageFlushInterval) {
+			if err := usageStore.Flush(); err != nil {
+				logAt(logLevelWarn, "Warning: Could not store usage: %v", err)
+			}
+		}
+	}()
+}
+
+// parseUsageDays reads the days query parameter
+func parseUsageDays(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("days")
+	if value == "" {
+		return defaultUsageDays, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > usageHistoryDays {
+		return 0, false
+	}
+	return days, true
+}
+
+// handleUsage handles GET /api/v1/usage requests, reporting the caller's own usage
+func handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	days, ok := parseUsageDays(r)
+	if !ok {
+		sendError(w, "Invalid days", http.StatusBadRequest, "days must be between 1 and 90")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	report := usageStore.Report(clientName(r), days, true)
+	report.Quotas = quotaStatuses(clientName(r))
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleAdminUsage handles GET /api/v1/admin/usage requests, reporting every client's usage
+func handleAdminUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	days, ok := parseUsageDays(r)
+	if !ok {
+		sendError(w, "Invalid days", http.StatusBadRequest, "days must be between 1 and 90")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usageStore.Rollup(days))
+}
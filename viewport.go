@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Viewport limits, in CSS pixels
+const (
+	minViewportWidth  = 320 // the WCAG 1.4.10 reflow width
+	maxViewportWidth  = 3840
+	maxViewportHeight = 2160
+	maxViewports      = 8
+)
+
+// Viewport is a screen a local engine emulates while auditing a page
+type Viewport struct {
+	Name              string  `json:"name,omitempty"`
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"device_scale_factor,omitempty"` // default 1
+	Mobile            bool    `json:"mobile,omitempty"`              // emulate touch and a mobile user agent
+}
+
+// deviceProfiles are the named viewports a scan can ask for instead of
+// giving sizes. reflow is the 320px-wide check of WCAG 1.4.10.
+var deviceProfiles = map[string]Viewport{
+	"reflow":  {Name: "reflow", Width: 320, Height: 256, DeviceScaleFactor: 1},
+	"mobile":  {Name: "mobile", Width: 412, Height: 823, DeviceScaleFactor: 1.75, Mobile: true},
+	"tablet":  {Name: "tablet", Width: 768, Height: 1024, DeviceScaleFactor: 2, Mobile: true},
+	"laptop":  {Name: "laptop", Width: 1366, Height: 768, DeviceScaleFactor: 1},
+	"desktop": {Name: "desktop", Width: 1920, Height: 1080, DeviceScaleFactor: 1},
+}
+
+// UnmarshalJSON reads a viewport given as a device profile name or as an
+// object with its sizes. An unknown name is kept without sizes, for
+// validateViewports to report.
+func (v *Viewport) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		profile, ok := deviceProfiles[strings.ToLower(name)]
+		if !ok {
+			profile = Viewport{Name: name}
+		}
+		*v = profile
+		return nil
+	}
+	type viewport Viewport
+	return json.Unmarshal(data, (*viewport)(v))
+}
+
+// deviceProfileNames lists the device profiles in order
+func deviceProfileNames() []string {
+	names := make([]string, 0, len(deviceProfiles))
+	for name := range deviceProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateViewports checks the viewports of a scan and that its engine can
+// emulate them. The pagespeed engine audits with the fixed mobile or desktop
+// screen of its strategy, so custom viewports are rejected rather than
+// silently ignored.
+func validateViewports(viewports []Viewport, engine string) error {
+	if len(viewports) == 0 {
+		return nil
+	}
+	if len(viewports) > maxViewports {
+		return fmt.Errorf("at most %d viewports can be audited per scan", maxViewports)
+	}
+	names := make(map[string]bool)
+	for i, viewport := range viewports {
+		if viewport.Width == 0 && viewport.Height == 0 && viewport.Name != "" {
+			return fmt.Errorf("unknown device profile %q; known profiles: %s", viewport.Name, strings.Join(deviceProfileNames(), ", "))
+		}
+		if viewport.Width < minViewportWidth || viewport.Width > maxViewportWidth {
+			return fmt.Errorf("viewports[%d].width must be between %d and %d", i, minViewportWidth, maxViewportWidth)
+		}
+		if viewport.Height < 1 || viewport.Height > maxViewportHeight {
+			return fmt.Errorf("viewports[%d].height must be between 1 and %d", i, maxViewportHeight)
+		}
+		if viewport.DeviceScaleFactor != 0 && (viewport.DeviceScaleFactor < 1 || viewport.DeviceScaleFactor > 4) {
+			return fmt.Errorf("viewports[%d].device_scale_factor must be between 1 and 4", i)
+		}
+		if viewport.Name != "" {
+			if names[viewport.Name] {
+				return fmt.Errorf("viewport %q is listed twice", viewport.Name)
+			}
+			names[viewport.Name] = true
+		}
+	}
+	if engine == "" || engine == defaultEngine {
+		return errors.New("viewports need a local audit engine; the pagespeed engine emulates a fixed mobile (412x823) or desktop (1350x940) screen, chosen with strategy")
+	}
+	return nil
+}
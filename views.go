@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ResultOptions controls how a scan result is rendered in API responses
+type ResultOptions struct {
+	GroupBy  string // "" (by page) or "audit"
+	View     string // "" (full) or "summary"
+	Sort     string // "" (discovery order), "score", "severity", "issues" or "url"
+	Language string
+	Fields   []string
+	Filter   ResultFilter
+	Format   string // "" (JSON) or one of reportFormats
+}
+
+// ResultFilter narrows the pages and issues included in a response
+type ResultFilter struct {
+	Impacts    map[string]bool
+	AuditIDs   map[string]bool
+	URLPattern *regexp.Regexp
+	HasError   *bool
+}
+
+// parseResultOptions reads the result rendering options from the query string
+func parseResultOptions(query url.Values) (ResultOptions, error) {
+	opts := ResultOptions{
+		GroupBy:  query.Get("group_by"),
+		View:     query.Get("view"),
+		Sort:     query.Get("sort"),
+		Language: query.Get("lang"),
+		Format:   query.Get("format"),
+		Filter: ResultFilter{
+			Impacts:  splitList(query.Get("impact")),
+			AuditIDs: splitList(query.Get("audit")),
+		},
+	}
+
+	switch opts.GroupBy {
+	case "", "page", "audit":
+	default:
+		return opts, fmt.Errorf("group_by must be one of: page, audit")
+	}
+
+	switch opts.View {
+	case "", "full", "summary":
+	default:
+		return opts, fmt.Errorf("view must be one of: full, summary")
+	}
+
+	switch opts.Sort {
+	case "", "score", "severity", "issues", "url":
+	default:
+		return opts, fmt.Errorf("sort must be one of: score, severity, issues, url")
+	}
+
+	if opts.Language != "" && !languageTagPattern.MatchString(opts.Language) {
+		return opts, fmt.Errorf("lang must be a language tag such as \"en\" or \"pt-BR\"")
+	}
+
+	if opts.View == "summary" && opts.GroupBy == "audit" {
+		return opts, fmt.Errorf("view=summary cannot be combined with group_by=audit")
+	}
+
+	for field := range splitList(query.Get("fields")) {
+		opts.Fields = append(opts.Fields, field)
+	}
+
+	for impact := range opts.Filter.Impacts {
+		if _, ok := impactWeights[impact]; !ok && impact != "unknown" {
+			return opts, fmt.Errorf("impact must be a comma-separated list of: critical, serious, moderate, minor, unknown")
+		}
+	}
+
+	if pattern := query.Get("url"); pattern != "" {
+		opts.Filter.URLPattern = globToRegexp(pattern)
+	}
+
+	if value := query.Get("has_error"); value != "" {
+		hasError, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("has_error must be true or false")
+		}
+		opts.Filter.HasError = &hasError
+	}
+
+	if opts.Format == "json" {
+		opts.Format = ""
+	}
+	if opts.Format != "" {
+		if _, ok := lookupReportFormat(opts.Format); !ok {
+			return opts, fmt.Errorf("format must be one of: json, %s", reportFormatNames())
+		}
+		if opts.GroupBy == "audit" || opts.View == "summary" || len(opts.Fields) > 0 {
+			return opts, fmt.Errorf("format=%s cannot be combined with group_by=audit, view=summary or fields", opts.Format)
+		}
+	}
+
+	return opts, nil
+}
+
+// splitList parses a comma-separated query value into a set
+func splitList(value string) map[string]bool {
+	if value == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
+
+// globToRegexp converts a URL pattern where * matches any characters into a regexp.
+// Patterns without a wildcard match any URL containing them.
+func globToRegexp(pattern string) *regexp.Regexp {
+	if !strings.Contains(pattern, "*") {
+		return regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	quoted := regexp.QuoteMeta(pattern)
+	return regexp.MustCompile("^" + strings.ReplaceAll(quoted, `\*`, ".*") + "$")
+}
+
+// isEmpty reports whether the filter lets every page and issue through
+func (f ResultFilter) isEmpty() bool {
+	return len(f.Impacts) == 0 && len(f.AuditIDs) == 0 && f.URLPattern == nil && f.HasError == nil
+}
+
+// apply returns a copy of the page results containing only matching pages and issues.
+// Page-level filters (url, has_error) drop whole pages; issue-level filters (impact,
+// audit) drop issues and then any successfully scanned page left without issues.
+func (f ResultFilter) apply(pages []PageResult) []PageResult {
+	if f.isEmpty() {
+		return pages
+	}
+
+	filtered := make([]PageResult, 0, len(pages))
+	for _, page := range pages {
+		if f.URLPattern != nil && !f.URLPattern.MatchString(page.URL) {
+			continue
+		}
+		if f.HasError != nil && *f.HasError != (page.Error != "") {
+			continue
+		}
+
+		if len(f.Impacts) > 0 || len(f.AuditIDs) > 0 {
+			issues := make([]AccessibilityIssue, 0, len(page.Issues))
+			for _, issue := range page.Issues {
+				if len(f.Impacts) > 0 && !f.Impacts[issue.Impact] {
+					continue
+				}
+				if len(f.AuditIDs) > 0 && !f.AuditIDs[issue.AuditID] {
+					continue
+				}
+				issues = append(issues, issue)
+			}
+			if len(issues) == 0 && page.Error == "" {
+				continue
+			}
+			page.Issues = issues
+		}
+
+		filtered = append(filtered, page)
+	}
+
+	return filtered
+}
+
+// AffectedElement is a single element failing an audit
+type AffectedElement struct {
+	Impact   string `json:"impact"`
+	Selector string `json:"selector"`
+	Snippet  string `json:"snippet"`
+}
+
+// AffectedPage lists the elements failing an audit on one page
+type AffectedPage struct {
+	URL      string            `json:"url"`
+	Elements []AffectedElement `json:"elements"`
+}
+
+// AuditGroup collects every occurrence of one audit across all scanned pages
+type AuditGroup struct {
+	AuditID       string         `json:"audit_id"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	Remediation   *Remediation   `json:"remediation,omitempty"`
+	PagesAffected int            `json:"pages_affected"`
+	IssueCount    int            `json:"issue_count"`
+	Pages         []AffectedPage `json:"pages"`
+}
+
+// AuditGroupedResult is a scan result inverted to list issues per audit.
+// PageResults shadows the embedded field so per-page issues are not sent twice.
+type AuditGroupedResult struct {
+	ScanResult
+	PageResults []PageResult `json:"page_results,omitempty"`
+	PageErrors  []PageResult `json:"page_errors,omitempty"`
+	Audits      []AuditGroup `json:"audits"`
+}
+
+// groupByAudit inverts page results into per-audit groups, most widespread first
+func groupByAudit(result ScanResult) AuditGroupedResult {
+	grouped := AuditGroupedResult{
+		ScanResult: result,
+		Audits:     make([]AuditGroup, 0),
+	}
+
+	index := make(map[string]int)
+	for _, page := range result.PageResults {
+		if page.Error != "" {
+			grouped.PageErrors = append(grouped.PageErrors, page)
+			continue
+		}
+
+		for _, issue := range page.Issues {
+			i, ok := index[issue.AuditID]
+			if !ok {
+				i = len(grouped.Audits)
+				index[issue.AuditID] = i
+				grouped.Audits = append(grouped.Audits, AuditGroup{
+					AuditID:     issue.AuditID,
+					Title:       issue.Title,
+					Description: issue.Description,
+					Remediation: issue.Remediation,
+				})
+			}
+
+			group := &grouped.Audits[i]
+			if n := len(group.Pages); n == 0 || group.Pages[n-1].URL != page.URL {
+				group.Pages = append(group.Pages, AffectedPage{URL: page.URL})
+				group.PagesAffected++
+			}
+			affected := &group.Pages[len(group.Pages)-1]
+			affected.Elements = append(affected.Elements, AffectedElement{
+				Impact:   issue.Impact,
+				Selector: issue.Selector,
+				Snippet:  issue.Snippet,
+			})
+			group.IssueCount++
+		}
+	}
+
+	sort.SliceStable(grouped.Audits, func(i, j int) bool {
+		if grouped.Audits[i].PagesAffected != grouped.Audits[j].PagesAffected {
+			return grouped.Audits[i].PagesAffected > grouped.Audits[j].PagesAffected
+		}
+		return grouped.Audits[i].IssueCount > grouped.Audits[j].IssueCount
+	})
+
+	return grouped
+}
+
+// PageSummary is a page result reduced to its score and issue count
+type PageSummary struct {
+	URL                string  `json:"url"`
+	Depth              int     `json:"depth"`
+	AccessibilityScore float64 `json:"accessibility_score"`
+	IssueCount         int     `json:"issue_count"`
+	Error              string  `json:"error,omitempty"`
+	ErrorCode          string  `json:"error_code,omitempty"`
+}
+
+// SummaryResult is the lightweight view of a scan: scores and counts, no issue details
+type SummaryResult struct {
+	ScanResult
+	UrlsDiscovered []string      `json:"urls_discovered,omitempty"`
+	UrlsVisited    []string      `json:"urls_visited,omitempty"`
+	PageResults    []PageSummary `json:"page_results"`
+}
+
+// summarizeResult drops issue details and URL lists from a scan result
+func summarizeResult(result ScanResult) SummaryResult {
+	summary := SummaryResult{
+		ScanResult:  result,
+		PageResults: make([]PageSummary, 0, len(result.PageResults)),
+	}
+	for _, page := range result.PageResults {
+		summary.PageResults = append(summary.PageResults, PageSummary{
+			URL:                page.URL,
+			Depth:              page.Depth,
+			AccessibilityScore: page.AccessibilityScore,
+			IssueCount:         countIssues(page),
+			Error:              page.Error,
+			ErrorCode:          page.ErrorCode,
+		})
+	}
+	return summary
+}
+
+// writeScanResult renders a scan result according to the requested options
+func writeScanResult(w http.ResponseWriter, r *http.Request, result ScanResult, opts ResultOptions) {
+	result.PageResults = sortPages(opts.Filter.apply(result.PageResults), opts.Sort)
+	if opts.Language != "" {
+		result.PageResults = localizeRemediation(result.PageResults, opts.Language)
+	}
+
+	if report, ok := lookupReportFormat(opts.Format); ok {
+		w.Header().Set("Content-Type", report.contentType)
+		report.write(w, &result)
+		return
+	}
+	if result.ID != "" {
+		result.Links = scanLinks(apiVersion(r), &result)
+	}
+
+	var response interface{} = result
+	if opts.GroupBy == "audit" {
+		response = groupByAudit(result)
+	} else if opts.View == "summary" {
+		response = summarizeResult(result)
+	} else if len(opts.Fields) == 0 {
+		// Full results can be large, so stream them page by page
+		w.Header().Set("Content-Type", "application/json")
+		if err := encodeScanResult(w, &result); err != nil {
+			logAt(logLevelWarn, "Warning: Could not write scan %s: %v", result.ID, err)
+		}
+		return
+	}
+
+	writeJSONFields(w, response, opts.Fields)
+}
+
+// writeJSONFields encodes v as JSON, keeping only the selected fields when any are given
+func writeJSONFields(w http.ResponseWriter, v interface{}, fields []string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(fields) == 0 {
+		json.NewEncoder(w).Encode(v)
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		sendError(w, "Encoding error", http.StatusInternalServerError, "Could not encode response")
+		return
+	}
+
+	var generic interface{}
+	json.Unmarshal(data, &generic)
+
+	json.NewEncoder(w).Encode(selectFields(generic, newFieldTree(fields)))
+}
+
+// fieldTree is a set of JSON field paths, nested by dot-separated segment
+type fieldTree map[string]fieldTree
+
+// newFieldTree builds a tree from paths like "summary.average_score"
+func newFieldTree(fields []string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range fields {
+		node := tree
+		for _, segment := range strings.Split(field, ".") {
+			child, ok := node[segment]
+			if !ok {
+				child = fieldTree{}
+				node[segment] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+// selectFields keeps only the fields in tree; selections apply to every element of arrays.
+// A field selected without sub-fields is kept whole.
+func selectFields(value interface{}, tree fieldTree) interface{} {
+	if len(tree) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		selected := make(map[string]interface{})
+		for key, subtree := range tree {
+			if child, ok := v[key]; ok {
+				selected[key] = selectFields(child, subtree)
+			}
+		}
+		return selected
+	case []interface{}:
+		selected := make([]interface{}, len(v))
+		for i, item := range v {
+			selected[i] = selectFields(item, tree)
+		}
+		return selected
+	default:
+		return value
+	}
+}
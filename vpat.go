@@ -0,0 +1,341 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VPAT conformance levels
+const (
+	conformanceSupports          = "Supports"
+	conformancePartiallySupports = "Partially Supports"
+	conformanceDoesNotSupport    = "Does Not Support"
+	conformanceNotEvaluated      = "Not Evaluated"
+)
+
+// ACRReport is a VPAT 2.x (WCAG edition) Accessibility Conformance Report skeleton
+type ACRReport struct {
+	ScanID            string     `json:"scan_id"`
+	Product           string     `json:"product"`
+	Vendor            string     `json:"vendor,omitempty"`
+	Contact           string     `json:"contact,omitempty"`
+	ReportDate        string     `json:"report_date"`
+	EvaluationMethods string     `json:"evaluation_methods"`
+	Notes             string     `json:"notes"`
+	Tables            []ACRTable `json:"tables"`
+}
+
+// ACRTable holds the rows for one WCAG conformance level
+type ACRTable struct {
+	Title string   `json:"title"`
+	Level string   `json:"level"`
+	Rows  []ACRRow `json:"rows"`
+}
+
+// ACRRow is the conformance claim for a single success criterion
+type ACRRow struct {
+	Criterion   WCAGCriterion `json:"criterion"`
+	Conformance string        `json:"conformance"`
+	Remarks     string        `json:"remarks"`
+}
+
+// buildACRReport pre-populates an ACR from a stored scan. Criteria with automated
+// audits are marked by how many scanned pages fail them; everything else needs a
+// manual review and is left as "Not Evaluated".
+func buildACRReport(result ScanResult, product, vendor, contact string) ACRReport {
+	if product == "" {
+		if parsed, err := url.Parse(result.BaseURL); err == nil && parsed.Host != "" {
+			product = unicodeHost(parsed.Host)
+		} else {
+			product = displayURL(result.BaseURL)
+		}
+	}
+
+	scanned := 0
+	failingPages := make(map[string]map[string]bool) // criterion → page URLs
+	failingAudits := make(map[string]map[string]int) // criterion → audit title → issues
+	for _, page := range result.PageResults {
+		if page.Error != "" {
+			continue
+		}
+		scanned++
+		for _, issue := range page.Issues {
+			if isFalsePositive(issue) {
+				continue
+			}
+			for _, criterion := range auditCriteria[issue.AuditID] {
+				if failingPages[criterion] == nil {
+					failingPages[criterion] = make(map[string]bool)
+					failingAudits[criterion] = make(map[string]int)
+				}
+				failingPages[criterion][page.URL] = true
+				failingAudits[criterion][issue.Title]++
+			}
+		}
+	}
+
+	report := ACRReport{
+		ScanID:     result.ID,
+		Product:    product,
+		Vendor:     vendor,
+		Contact:    contact,
+		ReportDate: time.Now().UTC().Format("January 2, 2006"),
+		EvaluationMethods: fmt.Sprintf(
+			"Automated testing of %d pages of %s with Google Lighthouse (axe-core) on %s. "+
+				"Criteria marked \"%s\" require manual evaluation.",
+			scanned, displayURL(result.BaseURL), result.ScanTime.UTC().Format("January 2, 2006"), conformanceNotEvaluated,
+		),
+		Notes: "This report was generated from automated scan results. Automated tools detect only a " +
+			"subset of accessibility barriers; \"Supports\" means no automated check failed and must be " +
+			"confirmed by manual review before publication.",
+		Tables: []ACRTable{
+			{Title: "Table 1: Success Criteria, Level A", Level: "A"},
+			{Title: "Table 2: Success Criteria, Level AA", Level: "AA"},
+		},
+	}
+
+	audited := criterionAudits()
+	for _, criterion := range wcagCriteria {
+		row := ACRRow{Criterion: criterion}
+		pages := len(failingPages[criterion.ID])
+
+		switch {
+		case len(audited[criterion.ID]) == 0 || scanned == 0:
+			row.Conformance = conformanceNotEvaluated
+			row.Remarks = "Not covered by automated testing; requires manual evaluation."
+		case pages == 0:
+			row.Conformance = conformanceSupports
+			row.Remarks = fmt.Sprintf("No failures of automated checks on %d tested pages.", scanned)
+		default:
+			row.Conformance = conformancePartiallySupports
+			if pages == scanned {
+				row.Conformance = conformanceDoesNotSupport
+			}
+			row.Remarks = fmt.Sprintf("Failures on %d of %d tested pages: %s.",
+				pages, scanned, describeFailures(failingAudits[criterion.ID]))
+		}
+
+		for i := range report.Tables {
+			if report.Tables[i].Level == criterion.Level {
+				report.Tables[i].Rows = append(report.Tables[i].Rows, row)
+			}
+		}
+	}
+
+	return report
+}
+
+// describeFailures lists failing audit titles with their issue counts
+func describeFailures(audits map[string]int) string {
+	titles := make([]string, 0, len(audits))
+	for title := range audits {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	parts := make([]string, len(titles))
+	for i, title := range titles {
+		parts[i] = fmt.Sprintf("%s (%d)", strings.TrimSuffix(title, "."), audits[title])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// handleScanVPAT handles GET /api/v1/scans/{id}/vpat requests
+func handleScanVPAT(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "html", "docx", "json":
+	default:
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "format must be one of: html, docx, json")
+		return
+	}
+
+	result, ok := loadStoredScan(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	report := buildACRReport(result, query.Get("product"), query.Get("vendor"), query.Get("contact"))
+
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	case "docx":
+		var buf bytes.Buffer
+		if err := writeACRDocx(&buf, report); err != nil {
+			sendError(w, "Report error", http.StatusInternalServerError, "Could not generate DOCX report")
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="acr-%s.docx"`, result.ID))
+		w.Write(buf.Bytes())
+	default:
+		writeACRHTML(w, report)
+	}
+}
+
+// writeACRHTML writes an ACR as an HTML document
+func writeACRHTML(w http.ResponseWriter, report ACRReport) {
+	var buf bytes.Buffer
+	if err := acrHTMLTemplate.Execute(&buf, report); err != nil {
+		sendError(w, "Report error", http.StatusInternalServerError, "Could not generate HTML report")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// acrHTMLTemplate renders an ACR as a standalone HTML document
+var acrHTMLTemplate = template.Must(template.New("acr").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Product}} Accessibility Conformance Report</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { border: 1px solid #767676; padding: .5rem; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<h1>{{.Product}} Accessibility Conformance Report</h1>
+<p>WCAG Edition, based on VPAT® Version 2.5</p>
+<h2>Product Information</h2>
+<dl>
+<dt>Name of Product/Version</dt><dd>{{.Product}}</dd>
+<dt>Report Date</dt><dd>{{.ReportDate}}</dd>
+{{if .Vendor}}<dt>Vendor</dt><dd>{{.Vendor}}</dd>{{end}}
+{{if .Contact}}<dt>Contact Information</dt><dd>{{.Contact}}</dd>{{end}}
+<dt>Notes</dt><dd>{{.Notes}}</dd>
+<dt>Evaluation Methods Used</dt><dd>{{.EvaluationMethods}}</dd>
+</dl>
+<h2>Applicable Standards/Guidelines</h2>
+<p>Web Content Accessibility Guidelines 2.2, Level A and Level AA.</p>
+<h2>Terms</h2>
+<ul>
+<li><strong>Supports</strong>: The functionality of the product has at least one method that meets the criterion without known defects or meets with equivalent facilitation.</li>
+<li><strong>Partially Supports</strong>: Some functionality of the product does not meet the criterion.</li>
+<li><strong>Does Not Support</strong>: The majority of product functionality does not meet the criterion.</li>
+<li><strong>Not Evaluated</strong>: The product has not been evaluated against the criterion.</li>
+</ul>
+{{range .Tables}}
+<h2>{{.Title}}</h2>
+<table>
+<thead><tr><th scope="col">Criteria</th><th scope="col">Conformance Level</th><th scope="col">Remarks and Explanations</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><th scope="row">{{.Criterion.ID}} {{.Criterion.Name}} (Level {{.Criterion.Level}})</th><td>{{.Conformance}}</td><td>{{.Remarks}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// writeACRDocx writes an ACR as a minimal Office Open XML (DOCX) document
+func writeACRDocx(buf *bytes.Buffer, report ACRReport) error {
+	var body strings.Builder
+
+	paragraph := func(text, style string) {
+		body.WriteString("<w:p>")
+		if style != "" {
+			body.WriteString(`<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`)
+		}
+		body.WriteString("<w:r><w:t xml:space=\"preserve\">" + xmlEscape(text) + "</w:t></w:r></w:p>")
+	}
+	cell := func(text string, bold bool) {
+		body.WriteString("<w:tc><w:p><w:r>")
+		if bold {
+			body.WriteString("<w:rPr><w:b/></w:rPr>")
+		}
+		body.WriteString("<w:t xml:space=\"preserve\">" + xmlEscape(text) + "</w:t></w:r></w:p></w:tc>")
+	}
+
+	paragraph(report.Product+" Accessibility Conformance Report", "Title")
+	paragraph("WCAG Edition, based on VPAT® Version 2.5", "")
+	paragraph("Product Information", "Heading1")
+	paragraph("Name of Product/Version: "+report.Product, "")
+	paragraph("Report Date: "+report.ReportDate, "")
+	if report.Vendor != "" {
+		paragraph("Vendor: "+report.Vendor, "")
+	}
+	if report.Contact != "" {
+		paragraph("Contact Information: "+report.Contact, "")
+	}
+	paragraph("Notes: "+report.Notes, "")
+	paragraph("Evaluation Methods Used: "+report.EvaluationMethods, "")
+	paragraph("Applicable Standards/Guidelines", "Heading1")
+	paragraph("Web Content Accessibility Guidelines 2.2, Level A and Level AA.", "")
+
+	for _, table := range report.Tables {
+		paragraph(table.Title, "Heading1")
+		body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/>` +
+			`<w:tblBorders><w:top w:val="single" w:sz="4"/><w:left w:val="single" w:sz="4"/>` +
+			`<w:bottom w:val="single" w:sz="4"/><w:right w:val="single" w:sz="4"/>` +
+			`<w:insideH w:val="single" w:sz="4"/><w:insideV w:val="single" w:sz="4"/></w:tblBorders></w:tblPr>`)
+		body.WriteString(`<w:tr><w:trPr><w:tblHeader/></w:trPr>`)
+		cell("Criteria", true)
+		cell("Conformance Level", true)
+		cell("Remarks and Explanations", true)
+		body.WriteString("</w:tr>")
+		for _, row := range table.Rows {
+			body.WriteString("<w:tr>")
+			cell(fmt.Sprintf("%s %s (Level %s)", row.Criterion.ID, row.Criterion.Name, row.Criterion.Level), false)
+			cell(row.Conformance, false)
+			cell(row.Remarks, false)
+			body.WriteString("</w:tr>")
+		}
+		body.WriteString("</w:tbl>")
+	}
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			body.String() + `</w:body></w:document>`},
+	}
+
+	archive := zip.NewWriter(buf)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write([]byte(file.content)); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// xmlEscape escapes text for inclusion in XML content
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
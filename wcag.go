@@ -0,0 +1,150 @@
+package main
+
+// WCAGCriterion is a WCAG 2.2 success criterion
+type WCAGCriterion struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// wcagCriteria lists the WCAG 2.2 Level A and AA success criteria in document order
+var wcagCriteria = []WCAGCriterion{
+	{"1.1.1", "Non-text Content", "A"},
+	{"1.2.1", "Audio-only and Video-only (Prerecorded)", "A"},
+	{"1.2.2", "Captions (Prerecorded)", "A"},
+	{"1.2.3", "Audio Description or Media Alternative (Prerecorded)", "A"},
+	{"1.2.4", "Captions (Live)", "AA"},
+	{"1.2.5", "Audio Description (Prerecorded)", "AA"},
+	{"1.3.1", "Info and Relationships", "A"},
+	{"1.3.2", "Meaningful Sequence", "A"},
+	{"1.3.3", "Sensory Characteristics", "A"},
+	{"1.3.4", "Orientation", "AA"},
+	{"1.3.5", "Identify Input Purpose", "AA"},
+	{"1.4.1", "Use of Color", "A"},
+	{"1.4.2", "Audio Control", "A"},
+	{"1.4.3", "Contrast (Minimum)", "AA"},
+	{"1.4.4", "Resize Text", "AA"},
+	{"1.4.5", "Images of Text", "AA"},
+	{"1.4.10", "Reflow", "AA"},
+	{"1.4.11", "Non-text Contrast", "AA"},
+	{"1.4.12", "Text Spacing", "AA"},
+	{"1.4.13", "Content on Hover or Focus", "AA"},
+	{"2.1.1", "Keyboard", "A"},
+	{"2.1.2", "No Keyboard Trap", "A"},
+	{"2.1.4", "Character Key Shortcuts", "A"},
+	{"2.2.1", "Timing Adjustable", "A"},
+	{"2.2.2", "Pause, Stop, Hide", "A"},
+	{"2.3.1", "Three Flashes or Below Threshold", "A"},
+	{"2.4.1", "Bypass Blocks", "A"},
+	{"2.4.2", "Page Titled", "A"},
+	{"2.4.3", "Focus Order", "A"},
+	{"2.4.4", "Link Purpose (In Context)", "A"},
+	{"2.4.5", "Multiple Ways", "AA"},
+	{"2.4.6", "Headings and Labels", "AA"},
+	{"2.4.7", "Focus Visible", "AA"},
+	{"2.4.11", "Focus Not Obscured (Minimum)", "AA"},
+	{"2.5.1", "Pointer Gestures", "A"},
+	{"2.5.2", "Pointer Cancellation", "A"},
+	{"2.5.3", "Label in Name", "A"},
+	{"2.5.4", "Motion Actuation", "A"},
+	{"2.5.7", "Dragging Movements", "AA"},
+	{"2.5.8", "Target Size (Minimum)", "AA"},
+	{"3.1.1", "Language of Page", "A"},
+	{"3.1.2", "Language of Parts", "AA"},
+	{"3.2.1", "On Focus", "A"},
+	{"3.2.2", "On Input", "A"},
+	{"3.2.3", "Consistent Navigation", "AA"},
+	{"3.2.4", "Consistent Identification", "AA"},
+	{"3.2.6", "Consistent Help", "A"},
+	{"3.3.1", "Error Identification", "A"},
+	{"3.3.2", "Labels or Instructions", "A"},
+	{"3.3.3", "Error Suggestion", "AA"},
+	{"3.3.4", "Error Prevention (Legal, Financial, Data)", "AA"},
+	{"3.3.7", "Redundant Entry", "A"},
+	{"3.3.8", "Accessible Authentication (Minimum)", "AA"},
+	{"4.1.2", "Name, Role, Value", "A"},
+	{"4.1.3", "Status Messages", "AA"},
+}
+
+// auditCriteria maps audit IDs to the WCAG success criteria they test.
+// Best-practice audits without a WCAG requirement are not listed.
+var auditCriteria = map[string][]string{
+	"aria-allowed-attr":           {"4.1.2"},
+	"aria-command-name":           {"4.1.2"},
+	"aria-conditional-attr":       {"4.1.2"},
+	"aria-deprecated-role":        {"4.1.2"},
+	"aria-hidden-body":            {"4.1.2"},
+	"aria-hidden-focus":           {"4.1.2"},
+	"aria-input-field-name":       {"4.1.2"},
+	"aria-meter-name":             {"1.1.1"},
+	"aria-progressbar-name":       {"1.1.1"},
+	"aria-prohibited-attr":        {"4.1.2"},
+	"aria-required-attr":          {"4.1.2"},
+	"aria-required-children":      {"1.3.1"},
+	"aria-required-parent":        {"1.3.1"},
+	"aria-roles":                  {"4.1.2"},
+	"aria-toggle-field-name":      {"4.1.2"},
+	"aria-tooltip-name":           {"4.1.2"},
+	"aria-treeitem-name":          {"4.1.2"},
+	"aria-valid-attr":             {"4.1.2"},
+	"alt-text-duplicate":          {"1.1.1"},
+	"alt-text-filename":           {"1.1.1"},
+	"alt-text-placeholder":        {"1.1.1"},
+	"aria-valid-attr-value":       {"4.1.2"},
+	"button-name":                 {"4.1.2"},
+	"bypass":                      {"2.4.1"},
+	"color-contrast":              {"1.4.3"},
+	"definition-list":             {"1.3.1"},
+	"dlitem":                      {"1.3.1"},
+	"document-title":              {"2.4.2"},
+	"duplicate-id-aria":           {"4.1.2"},
+	"duplicate-id-referenced":     {"1.3.1", "4.1.2"},
+	"form-field-multiple-labels":  {"3.3.2"},
+	"frame-title":                 {"4.1.2"},
+	"heading-empty":               {"2.4.6"},
+	"heading-level-skipped":       {"1.3.1"},
+	"heading-order":               {"1.3.1"},
+	"html-has-lang":               {"3.1.1"},
+	"html-lang-valid":             {"3.1.1"},
+	"html-xml-lang-mismatch":      {"3.1.1"},
+	"image-alt":                   {"1.1.1"},
+	"input-button-name":           {"4.1.2"},
+	"input-image-alt":             {"1.1.1", "4.1.2"},
+	"label":                       {"4.1.2", "3.3.2"},
+	"label-content-name-mismatch": {"2.5.3"},
+	"link-in-text-block":          {"1.4.1"},
+	"link-name":                   {"2.4.4", "4.1.2"},
+	"list":                        {"1.3.1"},
+	"listitem":                    {"1.3.1"},
+	"meta-refresh":                {"2.2.1"},
+	"meta-viewport":               {"1.4.4"},
+	"object-alt":                  {"1.1.1"},
+	"page-lang-mismatch":          {"3.1.1"},
+	"page-lang-missing":           {"3.1.1"},
+	"select-name":                 {"4.1.2"},
+	"skip-link":                   {"2.4.1"},
+	"skip-link-broken":            {"2.4.1"},
+	"skip-link-missing":           {"2.4.1"},
+	"tabindex":                    {"2.4.3"},
+	"table-fake-caption":          {"1.3.1"},
+	"table-header-association":    {"1.3.1"},
+	"table-header-missing":        {"1.3.1"},
+	"table-layout-role":           {"1.3.1"},
+	"td-has-header":               {"1.3.1"},
+	"td-headers-attr":             {"1.3.1"},
+	"th-has-data-cells":           {"1.3.1"},
+	"target-size":                 {"2.5.8"},
+	"valid-lang":                  {"3.1.2"},
+	"video-caption":               {"1.2.2"},
+}
+
+// criterionAudits returns the audit IDs that test each success criterion
+func criterionAudits() map[string][]string {
+	byCriterion := make(map[string][]string)
+	for auditID, criteria := range auditCriteria {
+		for _, criterion := range criteria {
+			byCriterion[criterion] = append(byCriterion[criterion], auditID)
+		}
+	}
+	return byCriterion
+}
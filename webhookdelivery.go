@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	DeliveryID string           `json:"delivery_id"`
+	Event      string           `json:"event"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Scan       WebhookScan      `json:"scan"`
+	Regression *ScoreRegression `json:"regression,omitempty"`
+	Monitor    *MonitorAlert    `json:"monitor,omitempty"`
+}
+
+// WebhookScan summarizes the scan an event is about
+type WebhookScan struct {
+	ID             string            `json:"id"`
+	BaseURL        string            `json:"base_url"`
+	Site           *SiteRef          `json:"site,omitempty"`
+	ScanTime       time.Time         `json:"scan_time"`
+	Status         string            `json:"status"`
+	StopReason     string            `json:"stop_reason,omitempty"`
+	SiteScore      float64           `json:"site_score"`
+	TotalPages     int               `json:"total_pages"`
+	TotalIssues    int               `json:"total_issues"`
+	IssuesByImpact map[string]int    `json:"issues_by_impact,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	Thresholds     *ThresholdReport  `json:"thresholds,omitempty"`
+}
+
+// ScoreRegression compares a scan with the previous scan of its site
+type ScoreRegression struct {
+	PreviousScanID string  `json:"previous_scan_id"`
+	PreviousScore  float64 `json:"previous_score"`
+	Drop           float64 `json:"drop"`
+}
+
+// webhookScan summarizes a scan result for webhook payloads
+func webhookScan(result *ScanResult) WebhookScan {
+	return WebhookScan{
+		ID:             result.ID,
+		BaseURL:        result.BaseURL,
+		Site:           result.Site,
+		ScanTime:       result.ScanTime,
+		Status:         result.Status,
+		StopReason:     result.StopReason,
+		SiteScore:      result.SiteScore,
+		TotalPages:     result.TotalPages,
+		TotalIssues:    result.Summary.TotalIssues,
+		IssuesByImpact: result.Summary.IssuesByImpact,
+		Tags:           result.Tags,
+		Thresholds:     result.Thresholds,
+	}
+}
+
+// previousScan returns the newest earlier scan of the same site that has a
+// site score, or nil if there is none
+func previousScan(result *ScanResult) *storedScan {
+	scans, err := scanStore.List()
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not list scans to check for a score regression: %v", err)
+		return nil
+	}
+	site := siteKey(result.BaseURL)
+	var previous *storedScan
+	for i, scan := range scans {
+		if scan.Site != site || scan.ID == result.ID || !scan.ScanTime.Before(result.ScanTime) {
+			continue
+		}
+		if scan.Status != "completed" && scan.Status != "partial" {
+			continue
+		}
+		if previous == nil || scan.ScanTime.After(previous.ScanTime) {
+			previous = &scans[i]
+		}
+	}
+	return previous
+}
+
+// matches reports whether a webhook wants events about a scan
+func (w *Webhook) matches(result *ScanResult) bool {
+	if w.Disabled {
+		return false
+	}
+	if w.Site != "" && w.Site != siteKey(result.BaseURL) {
+		return false
+	}
+	if w.ProjectID != "" && (result.Site == nil || result.Site.ProjectID != w.ProjectID) {
+		return false
+	}
+	return true
+}
+
+// Dispatch queues the events of a finished scan for every subscribed webhook
+func (s *WebhookStore) Dispatch(result *ScanResult) {
+	if result.Status == "cancelled" {
+		return
+	}
+	event := eventScanCompleted
+	if result.Status == "failed" {
+		event = eventScanFailed
+	}
+
+	s.mu.Lock()
+	subscribers := make([]Webhook, 0)
+	for _, webhook := range s.webhooks {
+		if webhook.matches(result) {
+			subscribers = append(subscribers, *webhook)
+		}
+	}
+	s.mu.Unlock()
+	if len(subscribers) == 0 {
+		return
+	}
+
+	// Only look up the previous scan when someone listens for regressions
+	var previous *storedScan
+	if event == eventScanCompleted && slices.ContainsFunc(subscribers, func(w Webhook) bool {
+		return slices.Contains(w.Events, eventScoreRegress)
+	}) {
+		previous = previousScan(result)
+	}
+
+	deliveries := make([]*WebhookDelivery, 0, len(subscribers))
+	queue := func(webhook Webhook, event string, regression *ScoreRegression) {
+		if !slices.Contains(webhook.Events, event) {
+			return
+		}
+		if delivery := newWebhookDelivery(webhook.ID, WebhookPayload{Event: event, Scan: webhookScan(result), Regression: regression}); delivery != nil {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	for _, webhook := range subscribers {
+		queue(webhook, event, nil)
+		if previous == nil {
+			continue
+		}
+		threshold := webhook.ScoreDrop
+		if threshold == 0 {
+			threshold = defaultScoreDrop
+		}
+		if drop := previous.SiteScore - result.SiteScore; drop >= threshold {
+			queue(webhook, eventScoreRegress, &ScoreRegression{
+				PreviousScanID: previous.ID,
+				PreviousScore:  previous.SiteScore,
+				Drop:           drop,
+			})
+		}
+	}
+	s.enqueue(deliveries)
+}
+
+// newWebhookDelivery creates a pending delivery of a payload, filling in its
+// delivery ID and time. It returns nil if the payload cannot be encoded.
+func newWebhookDelivery(webhookID string, payload WebhookPayload) *WebhookDelivery {
+	now := time.Now().UTC()
+	delivery := &WebhookDelivery{
+		ID:            newScanID(),
+		WebhookID:     webhookID,
+		Event:         payload.Event,
+		ScanID:        payload.Scan.ID,
+		Status:        deliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	payload.DeliveryID = delivery.ID
+	payload.CreatedAt = now
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logAt(logLevelError, "Could not encode webhook payload: %v", err)
+		return nil
+	}
+	delivery.Payload = data
+	return delivery
+}
+
+// enqueue stores new deliveries and wakes the dispatcher
+func (s *WebhookStore) enqueue(deliveries []*WebhookDelivery) {
+	if len(deliveries) == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.deliveries = append(s.deliveries, deliveries...)
+	s.prune()
+	err := s.saveDeliveries()
+	s.mu.Unlock()
+	if err != nil {
+		logAt(logLevelWarn, "Warning: Could not store webhook deliveries: %v", err)
+	}
+	s.notify()
+}
+
+// DispatchMonitorAlert queues a monitor alert for the webhooks subscribed to
+// its event that match the scan
+func (s *WebhookStore) DispatchMonitorAlert(result *ScanResult, alert *MonitorAlert) {
+	event := eventMonitorTriggered
+	if alert.State == monitorResolved {
+		event = eventMonitorResolved
+	}
+	s.mu.Lock()
+	deliveries := make([]*WebhookDelivery, 0)
+	for _, webhook := range s.webhooks {
+		if !webhook.matches(result) || !slices.Contains(webhook.Events, event) {
+			continue
+		}
+		if delivery := newWebhookDelivery(webhook.ID, WebhookPayload{Event: event, Scan: webhookScan(result), Monitor: alert}); delivery != nil {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	s.mu.Unlock()
+	s.enqueue(deliveries)
+}
+
+// prune drops the oldest delivered entries beyond maxDeliveriesPerWebhook.
+// Pending deliveries and dead letters are kept. Callers must hold s.mu.
+func (s *WebhookStore) prune() {
+	delivered := make(map[string]int)
+	keep := make([]bool, len(s.deliveries))
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		delivery := s.deliveries[i]
+		if delivery.Status == deliveryDelivered {
+			delivered[delivery.WebhookID]++
+			keep[i] = delivered[delivery.WebhookID] <= maxDeliveriesPerWebhook
+		} else {
+			keep[i] = true
+		}
+	}
+	kept := s.deliveries[:0]
+	for i, delivery := range s.deliveries {
+		if keep[i] {
+			kept = append(kept, delivery)
+		}
+	}
+	s.deliveries = kept
+}
+
+// notify wakes the dispatcher
+func (s *WebhookStore) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run attempts due deliveries until the process exits
+func (s *WebhookStore) run() {
+	ticker := time.NewTicker(webhookDispatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		for _, due := range s.due(time.Now()) {
+			go s.attempt(due.delivery, due.webhook)
+		}
+	}
+}
+
+// dueDelivery is a delivery to attempt with the webhook it goes to
+type dueDelivery struct {
+	delivery WebhookDelivery
+	webhook  Webhook
+}
+
+// due claims the pending deliveries whose next attempt time has come.
+// Deliveries to disabled webhooks wait until the webhook is enabled again.
+func (s *WebhookStore) due(now time.Time) []dueDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]dueDelivery, 0)
+	for _, delivery := range s.deliveries {
+		if delivery.Status != deliveryPending || s.inflight[delivery.ID] {
+			continue
+		}
+		if delivery.NextAttemptAt != nil && delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		webhook, ok := s.webhooks[delivery.WebhookID]
+		if !ok || webhook.Disabled {
+			continue
+		}
+		s.inflight[delivery.ID] = true
+		due = append(due, dueDelivery{delivery: *delivery, webhook: *webhook})
+	}
+	return due
+}
+
+// attempt posts a delivery once and records the outcome
+func (s *WebhookStore) attempt(delivery WebhookDelivery, webhook Webhook) {
+	record, err := postWebhook(webhook, delivery)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, delivery.ID)
+	index := slices.IndexFunc(s.deliveries, func(d *WebhookDelivery) bool { return d.ID == delivery.ID })
+	if index < 0 {
+		return // the webhook was deleted meanwhile
+	}
+	current := s.deliveries[index]
+	now := time.Now().UTC()
+	current.Attempts++
+	current.LastStatusCode = 0
+	if record.Response != nil {
+		current.LastStatusCode = record.Response.StatusCode
+	}
+	current.NextAttemptAt = nil
+	record.Number = len(current.AttemptLog) + 1
+	if len(current.AttemptLog) > 0 {
+		record.Number = current.AttemptLog[len(current.AttemptLog)-1].Number + 1
+	}
+	current.AttemptLog = append(current.AttemptLog, record)
+	if excess := len(current.AttemptLog) - maxDeliveryAttemptLog; excess > 0 {
+		current.AttemptLog = current.AttemptLog[excess:]
+	}
+	switch {
+	case webhook.Source == webhookSourceRESTHook && current.LastStatusCode == http.StatusGone:
+		// REST Hooks convention: the target answering 410 cancels the subscription
+		logAt(logLevelInfo, "REST hook %s answered 410 Gone and was unsubscribed", webhook.ID)
+		if err := s.remove(webhook.ID); err != nil {
+			logAt(logLevelWarn, "Warning: Could not remove REST hook %s: %v", webhook.ID, err)
+		}
+		return
+	case err == nil:
+		current.Status = deliveryDelivered
+		current.LastError = ""
+		current.DeliveredAt = &now
+	case current.Attempts >= maxWebhookAttempts:
+		current.Status = deliveryDead
+		current.LastError = err.Error()
+		logAt(logLevelWarn, "Warning: Webhook delivery %s to %s failed %d times and was moved to the dead-letter list: %v",
+			current.ID, webhook.ID, current.Attempts, err)
+	default:
+		current.LastError = err.Error()
+		next := now.Add(webhookRetryBase << (current.Attempts - 1))
+		current.NextAttemptAt = &next
+	}
+	if err := s.saveDeliveries(); err != nil {
+		logAt(logLevelWarn, "Warning: Could not store webhook deliveries: %v", err)
+	}
+}
+
+// signWebhook returns the signature header value of a payload
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// snapshotHeaders flattens headers for an attempt record
+func snapshotHeaders(header http.Header) map[string]string {
+	snapshot := make(map[string]string, len(header))
+	for name, values := range header {
+		snapshot[name] = strings.Join(values, ", ")
+	}
+	return snapshot
+}
+
+// postWebhook sends a delivery, returning a record of the attempt and an
+// error unless the endpoint answered 2xx
+func postWebhook(webhook Webhook, delivery WebhookDelivery) (DeliveryAttempt, error) {
+	record := DeliveryAttempt{StartedAt: time.Now().UTC()}
+	fail := func(err error) (DeliveryAttempt, error) {
+		record.Error = err.Error()
+		return record, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AccessibilityScannerAPI-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	if webhook.Secret != "" {
+		secret, err := resolveWebhookSecret(webhook.Secret)
+		if err != nil {
+			return fail(fmt.Errorf("could not fetch the signing secret: %w", err))
+		}
+		req.Header.Set("X-Webhook-Signature", signWebhook(secret, delivery.Payload))
+	}
+	record.Request = DeliveryRequest{Method: req.Method, URL: webhook.URL, Headers: snapshotHeaders(req.Header)}
+
+	resp, err := newOutboundClient(webhookTimeout).Do(req)
+	if err != nil {
+		record.DurationMs = time.Since(record.StartedAt).Milliseconds()
+		return fail(withoutURL(err))
+	}
+	defer drainAndClose(resp.Body)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseLength+1))
+	record.DurationMs = time.Since(record.StartedAt).Milliseconds()
+	record.Response = &DeliveryResponse{
+		StatusCode:    resp.StatusCode,
+		Headers:       snapshotHeaders(resp.Header),
+		Body:          string(body[:min(len(body), maxWebhookResponseLength)]),
+		BodyTruncated: len(body) > maxWebhookResponseLength,
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fail(fmt.Errorf("endpoint answered %s: %s", resp.Status, bytes.TrimSpace(body[:min(len(body), maxWebhookResponseLength)])))
+	}
+	return record, nil
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Webhook event types
+const (
+	eventScanCompleted = "scan.completed"
+	eventScanFailed    = "scan.failed"
+	eventScoreRegress  = "score.regressed"
+
+	eventMonitorTriggered = "monitor.triggered"
+	eventMonitorResolved  = "monitor.resolved"
+)
+
+// webhookEvents lists the events a webhook can subscribe to
+var webhookEvents = map[string]bool{
+	eventScanCompleted: true,
+	eventScanFailed:    true,
+	eventScoreRegress:  true,
+
+	eventMonitorTriggered: true,
+	eventMonitorResolved:  true,
+}
+
+// Delivery states
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryDead      = "dead"
+)
+
+// Webhook and delivery limits
+const (
+	defaultScoreDrop         = 0.05 // site_score drop that counts as a regression
+	maxDeliveriesPerWebhook  = 200  // delivered entries kept per webhook; dead letters are kept until redelivered
+	maxWebhookSecretLength   = 256
+	maxWebhookAttempts       = 6
+	webhookRetryBase         = 30 * time.Second
+	webhookTimeout           = 10 * time.Second
+	webhookDispatchInterval  = 5 * time.Second
+	maxWebhookResponseLength = 1024
+	maxDeliveryAttemptLog    = 20 // attempts kept per delivery, newest last
+)
+
+// Errors returned by the webhook store
+var (
+	errWebhookNotFound  = errors.New("webhook not found")
+	errDeliveryNotFound = errors.New("webhook delivery not found")
+	errNotDeadLetter    = errors.New("webhook delivery is not in the dead-letter list")
+)
+
+// Webhook is a subscription of an HTTP endpoint to scan events
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"` // signs deliveries; never returned
+	HasSecret bool      `json:"has_secret"`
+	ProjectID string    `json:"project_id,omitempty"` // only scans of this project's sites
+	Site      string    `json:"site,omitempty"`       // only scans of this site
+	ScoreDrop float64   `json:"score_drop,omitempty"` // for score.regressed (default: 0.05)
+	Disabled  bool      `json:"disabled,omitempty"`
+	Source    string    `json:"source,omitempty"` // "rest_hook" for subscriptions made through /hooks
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent, or still to be sent, to a webhook
+type WebhookDelivery struct {
+	ID             string            `json:"id"`
+	WebhookID      string            `json:"webhook_id"`
+	Event          string            `json:"event"`
+	ScanID         string            `json:"scan_id"`
+	Status         string            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	LastStatusCode int               `json:"last_status_code,omitempty"`
+	LastError      string            `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time        `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage   `json:"payload"`
+	AttemptLog     []DeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+// DeliveryAttempt records one try at sending a delivery
+type DeliveryAttempt struct {
+	Number     int               `json:"number"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Request    DeliveryRequest   `json:"request"`
+	Response   *DeliveryResponse `json:"response,omitempty"` // absent when no response arrived
+	Error      string            `json:"error,omitempty"`
+}
+
+// DeliveryRequest is what was sent; the body is the delivery's payload
+type DeliveryRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// DeliveryResponse is what the endpoint answered, with the body cut to 1 KB
+type DeliveryResponse struct {
+	StatusCode    int               `json:"status_code"`
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body,omitempty"`
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+}
+
+// WebhookStore keeps webhooks and their deliveries in two JSON files
+type WebhookStore struct {
+	mu         sync.Mutex
+	dir        string
+	webhooks   map[string]*Webhook
+	deliveries []*WebhookDelivery // oldest first
+	inflight   map[string]bool    // deliveries being attempted right now
+	wake       chan struct{}
+}
+
+// webhookStore holds the outgoing webhooks
+var webhookStore *WebhookStore
+
+// NewWebhookStore loads the webhooks and deliveries stored in dir, if any
+func NewWebhookStore(dir string) (*WebhookStore, error) {
+	store := &WebhookStore{
+		dir:      dir,
+		webhooks: make(map[string]*Webhook),
+		inflight: make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for name, target := range map[string]interface{}{"webhooks.json": &store.webhooks, "deliveries.json": &store.deliveries} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return store, nil
+}
+
+// writeFile replaces one of the store's files; callers must hold s.mu
+func (s *WebhookStore) writeFile(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveWebhooks writes the webhooks; callers must hold s.mu
+func (s *WebhookStore) saveWebhooks() error {
+	return s.writeFile("webhooks.json", s.webhooks)
+}
+
+// saveDeliveries writes the deliveries; callers must hold s.mu
+func (s *WebhookStore) saveDeliveries() error {
+	return s.writeFile("deliveries.json", s.deliveries)
+}
+
+// redacted returns the webhook as shown by the API, without its secret
+func (w Webhook) redacted() Webhook {
+	w.HasSecret = w.Secret != ""
+	w.Secret = ""
+	return w
+}
+
+// List returns every webhook, oldest first
+func (s *WebhookStore) List() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhooks := make([]Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, webhook.redacted())
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+	return webhooks
+}
+
+// Get returns a webhook by ID
+func (s *WebhookStore) Get(id string) (Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return Webhook{}, errWebhookNotFound
+	}
+	return webhook.redacted(), nil
+}
+
+// Create adds a webhook
+func (s *WebhookStore) Create(webhook Webhook) (Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook.ID = newScanID()
+	webhook.CreatedAt = time.Now().UTC()
+	webhook.UpdatedAt = webhook.CreatedAt
+	s.webhooks[webhook.ID] = &webhook
+	if err := s.saveWebhooks(); err != nil {
+		delete(s.webhooks, webhook.ID)
+		return Webhook{}, err
+	}
+	return webhook.redacted(), nil
+}
+
+// Update replaces a webhook's settings; an empty secret keeps the current one
+func (s *WebhookStore) Update(id string, update Webhook) (Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.webhooks[id]
+	if !ok {
+		return Webhook{}, errWebhookNotFound
+	}
+	previous := *existing
+	update.ID = id
+	update.CreatedAt = existing.CreatedAt
+	update.UpdatedAt = time.Now().UTC()
+	if update.Secret == "" {
+		update.Secret = existing.Secret
+	}
+	update.Source = existing.Source
+	*existing = update
+	if err := s.saveWebhooks(); err != nil {
+		*existing = previous
+		return Webhook{}, err
+	}
+	return update.redacted(), nil
+}
+
+// Delete removes a webhook and its deliveries
+func (s *WebhookStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(id)
+}
+
+// remove deletes a webhook and its deliveries; callers must hold s.mu
+func (s *WebhookStore) remove(id string) error {
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return errWebhookNotFound
+	}
+	delete(s.webhooks, id)
+	if err := s.saveWebhooks(); err != nil {
+		s.webhooks[id] = webhook
+		return err
+	}
+	kept := s.deliveries[:0]
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID != id {
+			kept = append(kept, delivery)
+		}
+	}
+	s.deliveries = kept
+	return s.saveDeliveries()
+}
+
+// Deliveries returns the deliveries of a webhook, newest first
+func (s *WebhookStore) Deliveries(webhookID string) ([]WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[webhookID]; !ok {
+		return nil, errWebhookNotFound
+	}
+	deliveries := make([]WebhookDelivery, 0)
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].WebhookID == webhookID {
+			deliveries = append(deliveries, *s.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+// DeadLetters returns the deliveries that ran out of attempts, newest first
+func (s *WebhookStore) DeadLetters() []WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dead := make([]WebhookDelivery, 0)
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].Status == deliveryDead {
+			dead = append(dead, *s.deliveries[i])
+		}
+	}
+	return dead
+}
+
+// Redeliver puts a dead letter back in the queue with a fresh set of attempts
+func (s *WebhookStore) Redeliver(id string) (WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range s.deliveries {
+		if delivery.ID != id {
+			continue
+		}
+		if delivery.Status != deliveryDead {
+			return WebhookDelivery{}, errNotDeadLetter
+		}
+		previous := *delivery
+		now := time.Now().UTC()
+		delivery.Status = deliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = &now
+		if err := s.saveDeliveries(); err != nil {
+			*delivery = previous
+			return WebhookDelivery{}, err
+		}
+		s.notify()
+		return *delivery, nil
+	}
+	return WebhookDelivery{}, errDeliveryNotFound
+}
+
+// sendWebhookStoreError reports a webhook store error
+func sendWebhookStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errWebhookNotFound):
+		sendError(w, "Webhook not found", http.StatusNotFound, "No webhook exists with this ID")
+	case errors.Is(err, errDeliveryNotFound):
+		sendError(w, "Delivery not found", http.StatusNotFound, "No webhook delivery exists with this ID")
+	case errors.Is(err, errNotDeadLetter):
+		sendError(w, "Not a dead letter", http.StatusConflict, "Only deliveries that ran out of attempts can be redelivered")
+	default:
+		logAt(logLevelError, "Webhook storage error: %v", err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not access webhooks")
+	}
+}
+
+// validate checks a webhook can be delivered to
+func (w *Webhook) validate() error {
+	parsed, err := url.Parse(w.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if len(w.Events) == 0 {
+		return errors.New("events must list at least one of scan.completed, scan.failed, score.regressed, monitor.triggered, monitor.resolved")
+	}
+	for _, event := range w.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("unknown event %q; supported events: scan.completed, scan.failed, score.regressed, monitor.triggered, monitor.resolved", event)
+		}
+	}
+	if len(w.Secret) > maxWebhookSecretLength {
+		return fmt.Errorf("secret must be at most %d characters", maxWebhookSecretLength)
+	}
+	if w.ScoreDrop < 0 || w.ScoreDrop > 1 {
+		return errors.New("score_drop must be between 0 and 1")
+	}
+	w.Site = asciiHost(w.Site)
+	if w.Site != "" && !validSiteHost(w.Site) {
+		return errors.New("site must be a host name such as example.com")
+	}
+	if w.ProjectID != "" {
+		if _, err := orgStore.Project(w.ProjectID); err != nil {
+			return errors.New("project_id must name an existing project")
+		}
+	}
+	return nil
+}
+
+// decodeWebhook reads and validates a webhook body
+func decodeWebhook(w http.ResponseWriter, r *http.Request) (Webhook, bool) {
+	var webhook Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		sendError(w, "Invalid JSON", http.StatusBadRequest, "Request body must be valid JSON")
+		return webhook, false
+	}
+	if err := webhook.validate(); err != nil {
+		sendError(w, "Invalid webhook", http.StatusBadRequest, err.Error())
+		return webhook, false
+	}
+	webhook.HasSecret = false
+	webhook.Source = ""
+	return webhook, true
+}
+
+// handleWebhooks handles GET and POST /api/v1/webhooks requests
+func handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, webhookStore.List())
+	case http.MethodPost:
+		webhook, ok := decodeWebhook(w, r)
+		if !ok {
+			return
+		}
+		webhook, err := webhookStore.Create(webhook)
+		if err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, webhook)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are supported")
+	}
+}
+
+// handleWebhook handles GET, PUT and DELETE /api/v1/webhooks/{id} requests
+func handleWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		webhook, err := webhookStore.Get(id)
+		if err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, webhook)
+	case http.MethodPut:
+		update, ok := decodeWebhook(w, r)
+		if !ok {
+			return
+		}
+		webhook, err := webhookStore.Update(id, update)
+		if err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, webhook)
+	case http.MethodDelete:
+		if err := webhookStore.Delete(id); err != nil {
+			sendWebhookStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET, PUT and DELETE methods are supported")
+	}
+}
+
+// handleWebhookDeliveries handles GET /api/v1/webhooks/{id}/deliveries
+// requests, listing deliveries newest first with every attempt
+func handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	start, pageSize, err := parsePagination(r)
+	if err != nil {
+		sendError(w, "Invalid pagination", http.StatusBadRequest, err.Error())
+		return
+	}
+	deliveries, err := webhookStore.Deliveries(r.PathValue("id"))
+	if err != nil {
+		sendWebhookStoreError(w, err)
+		return
+	}
+
+	status, event, scanID := r.URL.Query().Get("status"), r.URL.Query().Get("event"), r.URL.Query().Get("scan_id")
+	matching := make([]WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		if (status == "" || delivery.Status == status) && (event == "" || delivery.Event == event) && (scanID == "" || delivery.ScanID == scanID) {
+			matching = append(matching, delivery)
+		}
+	}
+
+	end := min(start+pageSize, len(matching))
+	start = min(start, end)
+	writePaginated(w, r, "", matching[start:end], len(matching), start, pageSize)
+}
+
+// handleWebhookDeadLetters handles GET /api/v1/webhooks/dead-letters requests
+func handleWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookStore.DeadLetters())
+}
+
+// handleWebhookRedeliver handles POST /api/v1/webhooks/deliveries/{delivery}/redeliver requests
+func handleWebhookRedeliver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is supported")
+		return
+	}
+	delivery, err := webhookStore.Redeliver(r.PathValue("delivery"))
+	if err != nil {
+		sendWebhookStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Widget settings
+const (
+	widgetLabel      = "Accessibility checked"
+	widgetCacheAge   = 900 // seconds
+	widgetWidth      = 320
+	widgetHeight     = 32
+	widgetDateFormat = "2 Jan 2006"
+)
+
+// scoreGrades map the lowest site score of each letter grade, best first
+var scoreGrades = []struct {
+	grade    string
+	minScore float64
+	color    string // background of the grade, with white text at 4.5:1 or better
+}{
+	{"A", 0.9, "#1a7f37"},
+	{"B", 0.8, "#3f6212"},
+	{"C", 0.7, "#8a6100"},
+	{"D", 0.5, "#a34a00"},
+	{"F", 0, "#b91c1c"},
+}
+
+// scoreGrade returns the letter grade of a site score and its color
+func scoreGrade(score float64) (string, string) {
+	for _, grade := range scoreGrades {
+		if score >= grade.minScore {
+			return grade.grade, grade.color
+		}
+	}
+	last := scoreGrades[len(scoreGrades)-1]
+	return last.grade, last.color
+}
+
+// ScoreWidget is the public summary of a site for "Accessibility checked"
+// widgets embedded on the site itself
+type ScoreWidget struct {
+	Site      string    `json:"site"`
+	Name      string    `json:"name,omitempty"`
+	Score     int       `json:"score"` // 0-100
+	Grade     string    `json:"grade"` // A-F
+	Label     string    `json:"label"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// OEmbedResponse is an oEmbed 1.0 rich response
+type OEmbedResponse struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	CacheAge     int    `json:"cache_age"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// widgetHTMLTemplate renders the embeddable badge with inline styles, so it
+// looks the same on any page
+var widgetHTMLTemplate = template.Must(template.New("widget").Parse(
+	`<span class="a11y-widget" style="display:inline-flex;align-items:center;gap:.5em;` +
+		`font:14px/1.4 system-ui,sans-serif;color:#1a1a1a;background:#fff;border:1px solid #767676;border-radius:4px;padding:2px 8px 2px 2px">` +
+		`<strong style="background:{{.Color}};color:#fff;border-radius:3px;padding:0 .5em">` +
+		`<span style="position:absolute;width:1px;height:1px;overflow:hidden;clip:rect(0 0 0 0)">Grade </span>{{.Grade}}</strong>` +
+		`<span>{{.Label}} · {{.Score}}/100 · <time datetime="{{.DateTime}}">{{.Date}}</time></span></span>`))
+
+// widgetHTML renders a widget as an HTML snippet
+func widgetHTML(widget ScoreWidget) (string, error) {
+	_, color := scoreGrade(float64(widget.Score) / 100)
+	var buf bytes.Buffer
+	err := widgetHTMLTemplate.Execute(&buf, map[string]interface{}{
+		"Color":    template.CSS(color),
+		"Grade":    widget.Grade,
+		"Label":    widget.Label,
+		"Score":    widget.Score,
+		"DateTime": widget.ScannedAt.Format(time.RFC3339),
+		"Date":     widget.ScannedAt.Format(widgetDateFormat),
+	})
+	return buf.String(), err
+}
+
+// loadScoreWidget builds the widget of a registered site from its latest
+// finished scan, writing an error response on failure. Only registered
+// sites that set public_widget have widgets, so nothing is published
+// without the site's consent. Other sites answer as if not registered.
+func loadScoreWidget(w http.ResponseWriter, host string) (ScoreWidget, bool) {
+	host = asciiHost(host)
+	if !validSiteHost(host) {
+		sendSiteStoreError(w, errInvalidSite)
+		return ScoreWidget{}, false
+	}
+	site, err := orgStore.Site(host)
+	if err == nil && !site.PublicWidget {
+		err = errSiteRecordNotFound
+	}
+	if err != nil {
+		sendOrgStoreError(w, err)
+		return ScoreWidget{}, false
+	}
+	result, err := latestSiteScan(host)
+	if errors.Is(err, errNoSiteScan) || errors.Is(err, errScanNotFound) {
+		sendError(w, "Scan not found", http.StatusNotFound, "This site has no completed scan yet")
+		return ScoreWidget{}, false
+	}
+	if err != nil {
+		logAt(logLevelError, "Failed to load the latest scan of %s: %v", host, err)
+		sendError(w, "Storage error", http.StatusInternalServerError, "Could not load the site's latest scan")
+		return ScoreWidget{}, false
+	}
+
+	score := int(result.SiteScore*100 + 0.5)
+	grade, _ := scoreGrade(float64(score) / 100)
+	return ScoreWidget{
+		Site:      host,
+		Name:      site.Name,
+		Score:     score,
+		Grade:     grade,
+		Label:     widgetLabel,
+		ScannedAt: result.ScanTime.UTC(),
+	}, true
+}
+
+// handleWidget handles GET /widget/{host} requests, serving a registered
+// site's latest score without authentication
+func handleWidget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	widget, ok := loadScoreWidget(w, siteHostParam(r))
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(widgetCacheAge))
+	writeJSON(w, http.StatusOK, widget)
+}
+
+// handleOEmbed handles GET /oembed?url= requests, describing the widget of
+// the site a URL belongs to as oEmbed rich content
+func handleOEmbed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is supported")
+		return
+	}
+	query := r.URL.Query()
+	// oEmbed providers answer 501 for formats they do not support
+	if format := query.Get("format"); format != "" && format != "json" {
+		sendError(w, "Not implemented", http.StatusNotImplemented, "Only the json format is supported")
+		return
+	}
+	target := query.Get("url")
+	if target == "" {
+		sendError(w, "Invalid query parameter", http.StatusBadRequest, "url is required")
+		return
+	}
+	widget, ok := loadScoreWidget(w, siteKey(target))
+	if !ok {
+		return
+	}
+	html, err := widgetHTML(widget)
+	if err != nil {
+		sendError(w, "Report error", http.StatusInternalServerError, "Could not render the widget")
+		return
+	}
+
+	title := widget.Name
+	if title == "" {
+		title = widget.Site
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(widgetCacheAge))
+	writeJSON(w, http.StatusOK, OEmbedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        title + ": " + widgetLabel,
+		ProviderName: "Accessibility Scanner",
+		ProviderURL:  requestOrigin(r),
+		CacheAge:     widgetCacheAge,
+		HTML:         html,
+		Width:        widgetWidth,
+		Height:       widgetHeight,
+	})
+}